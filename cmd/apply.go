@@ -93,7 +93,15 @@ func runApply(cmd *cobra.Command, args []string) {
 	fmt.Printf("Found %v tables\n", len(loadedSchema.Tables))
 
 	// diff
-	diff := schema.DiffSchemas(introspectedSchema, loadedSchema)
+	diff, err := schema.DiffSchemas(introspectedSchema, loadedSchema)
+	if err != nil {
+		log.Fatalf("Failed to diff schemas: %v", err)
+	}
+
+	// The database isn't introspected for domains yet, so every domain would
+	// show up as added
+	diff.AddedDomains, diff.RemovedDomains, diff.ModifiedDomains = nil, nil, nil
+	clearForeignKeyChanges(diff)
 
 	// Check if there are any changes
 	if diff.IsEmpty() {
@@ -1,40 +1,91 @@
 package cmd
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 
+	"github.com/lockplane/lockplane/internal/config"
 	"github.com/lockplane/lockplane/internal/schema"
 	"github.com/spf13/cobra"
 )
 
 var checkPrintSchema bool
+var checkWithIDs bool
+var checkOutput string
+var checkFormat string
+var checkFailOn string
+var checkFailFast bool
+var checkConfig string
+var checkCoverage bool
+var checkListExternal bool
+var checkByFile bool
+var checkMulti bool
+var checkSkipGenerated bool
+var checkStripMetaCommands bool
+var checkNoCache bool
+var checkInclude []string
+var checkExclude []string
 
 func init() {
 	rootCmd.AddCommand(checkCmd)
 	checkCmd.Flags().BoolVar(&checkPrintSchema, "print-schema", false, "Print the parsed schema as JSON to stdout")
+	checkCmd.Flags().BoolVar(&checkWithIDs, "with-ids", false, "With --print-schema, print every object with a stable ID, and foreign keys by ID")
+	checkCmd.Flags().StringVar(&checkFormat, "format", "text", "Output format: text, json, ndjson or sarif")
+	checkCmd.Flags().StringVar(&checkOutput, "output", "text", "Alias for --format")
+	checkCmd.Flags().StringVar(&checkFailOn, "fail-on", "error", "Exit with status 1 on: error, warning or never")
+	checkCmd.Flags().BoolVar(&checkFailFast, "fail-fast", false, "Stop at the first error and report only that error")
+	checkCmd.Flags().StringVar(&checkConfig, "config", "", "Path to lockplane.toml (default: search upward from the schema path)")
+	checkCmd.Flags().BoolVar(&checkCoverage, "coverage", false, "Report how many statements lockplane modeled")
+	checkCmd.Flags().BoolVar(&checkListExternal, "list-external", false, "List the tables foreign keys reference that the schema doesn't define")
+	checkCmd.Flags().BoolVar(&checkByFile, "by-file", false, "List the tables and views each schema file defines")
+	checkCmd.Flags().BoolVar(&checkSkipGenerated, "skip-generated", false, "Don't report lint warnings in files whose first line is -- lockplane:generated")
+	checkCmd.Flags().BoolVar(&checkStripMetaCommands, "strip-meta-commands", false, "Ignore psql meta-commands such as \\connect, with a warning for each")
+	checkCmd.Flags().BoolVar(&checkNoCache, "no-cache", false, "Parse every schema file instead of reusing parse results cached in "+schema.DefaultCacheDir)
+	checkCmd.Flags().StringSliceVar(&checkInclude, "include", nil, "Only check schema files whose name matches one of these globs, such as auth_*")
+	checkCmd.Flags().StringSliceVar(&checkExclude, "exclude", nil, "Skip schema files whose name matches one of these globs")
+	checkCmd.Flags().BoolVar(&checkMulti, "multi", false, "Check each argument as a separate schema root with its own lockplane.toml, and report the results by root")
 }
 
 var checkCmd = &cobra.Command{
 	Use:   "check [schema dir or .lp.sql file]",
 	Short: "Check .lp.sql schema files for errors",
-	Long: `Check .lp.sql schema files for errors and print a JSON summary
+	Long: `Check .lp.sql schema files for errors and warnings
 
 When provided a directory, lockplane will check all .lp.sql files in the root
-of that directory.
+of that directory. --include and --exclude narrow the check to the files whose
+names match their globs. Only those files are parsed, so references to tables
+in the skipped files may be reported as missing.
 
 Examples:
 lockplane check schema/
 lockplane check my-schema.lp.sql
-lockplane check my-schema.lp.sql > report.json
+lockplane check --format json my-schema.lp.sql > report.json
+lockplane check --format sarif schema/ > lockplane.sarif
+lockplane check --format ndjson schema/  # One JSON object per line, then a summary
+lockplane check --fail-on warning schema/  # Fail the build on warnings too
+lockplane check --config ci/lockplane.toml schema/
+lockplane check --coverage schema/      # Report modeled vs ignored statements
+lockplane check --list-external schema/ # List tables the schema assumes exist
+lockplane check --by-file schema/       # List the tables and views of each file
+lockplane check --multi services/*/schema/  # Check each root with its own config
+lockplane check --include 'auth_*' schema/  # Check only some of the files
 lockplane check --print-schema schema/  # Print parsed schema as JSON
+lockplane check --print-schema --with-ids schema/  # Print objects and foreign keys by ID
 `,
 	Run: runCheck,
 }
 
 func runCheck(cmd *cobra.Command, args []string) {
+	if checkMulti {
+		runCheckMulti(cmd, args)
+		return
+	}
 	if len(args) != 1 {
 		fmt.Printf(`Missing a schema file.
 
@@ -52,19 +103,364 @@ Help: lockplane check --help
 			log.Fatalf("Failed to load schema: %v", err)
 		}
 
-		schemaJson, err := json.MarshalIndent(loadedSchema, "", "  ")
+		if checkWithIDs {
+			printJSON(loadedSchema.ObjectGraph())
+			return
+		}
+		printSchemaJSON(loadedSchema)
+		return
+	}
+
+	format := checkOutputFormat(cmd)
+
+	if checkListExternal {
+		loadedSchema, err := schema.LoadSchema(schemaPath)
 		if err != nil {
-			log.Fatalf("Failed to marshal schema to JSON: %v", err)
+			log.Fatalf("Failed to load schema: %v", err)
 		}
 
-		fmt.Println(string(schemaJson))
+		externals := schema.ExternalTables(loadedSchema)
+		switch format {
+		case "json":
+			printJSON(externals)
+		case "ndjson", "sarif":
+			log.Fatalf("--list-external supports text and json output")
+		default:
+			printExternalTablesText(externals)
+		}
 		return
 	}
 
+	if checkByFile {
+		loadedSchema, err := schema.LoadSchema(schemaPath)
+		if err != nil {
+			log.Fatalf("Failed to load schema: %v", err)
+		}
+
+		files := schema.ObjectsByFile(loadedSchema)
+		switch format {
+		case "json":
+			printJSON(files)
+		case "ndjson", "sarif":
+			log.Fatalf("--by-file supports text and json output")
+		default:
+			printObjectsByFileText(files)
+		}
+		return
+	}
+
+	opts, err := loadCheckOptions(schemaPath, checkConfig)
+	if err != nil {
+		log.Fatalf("Failed to load lint configuration: %v", err)
+	}
+	opts.Coverage = checkCoverage
+	opts.FailFast = checkFailFast
+	opts.SkipGenerated = checkSkipGenerated
+	opts.StripMetaCommands = checkStripMetaCommands
+	opts.CacheDir = checkCacheDir()
+	opts.Include = checkInclude
+	opts.Exclude = checkExclude
+
+	// ndjson streams diagnostics as they are found. With --fail-fast the
+	// report is trimmed to the first error afterwards, so it is written from
+	// the output instead.
+	ndjson := schema.NewNDJSONWriter(os.Stdout)
+	if format == "ndjson" && !checkFailFast {
+		opts.Sink = ndjson
+	}
+
 	// Normal check behavior
-	reportJson, err := schema.CheckSchema(schemaPath)
+	output, err := schema.CheckSchemaWithOptions(schemaPath, opts)
+	if err != nil {
+		log.Fatalf("Failed to check schema: %v", err)
+	}
+
+	switch format {
+	case "ndjson":
+		if opts.Sink == nil {
+			for _, d := range output.Diagnostics {
+				ndjson.Report(d)
+			}
+		}
+		if err := ndjson.WriteSummary(output.Summary); err != nil {
+			log.Fatalf("Failed to write check output: %v", err)
+		}
+
+	case "json":
+		reportJson, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to marshal check output to JSON: %v", err)
+		}
+		fmt.Println(string(reportJson))
+
+	case "sarif":
+		sarif, err := schema.MarshalSARIF(output, getVersion())
+		if err != nil {
+			log.Fatalf("Failed to marshal check output to SARIF: %v", err)
+		}
+		fmt.Println(string(sarif))
+
+	default:
+		printCheckText(output)
+	}
+
+	if checkFailed(output, checkFailOn) {
+		os.Exit(1)
+	}
+}
+
+// runCheckMulti checks each argument as its own schema root, with the config
+// found by searching upward from it, and prints the reports by root
+func runCheckMulti(cmd *cobra.Command, roots []string) {
+	if len(roots) == 0 {
+		log.Fatalf("--multi needs at least one schema root")
+	}
+	if checkPrintSchema || checkListExternal || checkByFile || checkConfig != "" {
+		log.Fatalf("--multi can't be combined with --print-schema, --list-external, --by-file or --config")
+	}
+	format := checkOutputFormat(cmd)
+
+	output, err := checkSchemaRoots(roots, schema.CheckOptions{
+		Coverage:          checkCoverage,
+		FailFast:          checkFailFast,
+		SkipGenerated:     checkSkipGenerated,
+		StripMetaCommands: checkStripMetaCommands,
+		CacheDir:          checkCacheDir(),
+		Include:           checkInclude,
+		Exclude:           checkExclude,
+	})
 	if err != nil {
 		log.Fatalf("Failed to check schema: %v", err)
 	}
-	fmt.Print(reportJson)
+	merged := output.merged()
+
+	switch format {
+	case "json":
+		reportJson, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to marshal check output to JSON: %v", err)
+		}
+		fmt.Println(string(reportJson))
+
+	case "ndjson":
+		ndjson := schema.NewNDJSONWriter(os.Stdout)
+		for _, root := range roots {
+			for _, d := range output.Roots[root].Diagnostics {
+				ndjson.Report(d)
+			}
+		}
+		if err := ndjson.WriteSummary(merged.Summary); err != nil {
+			log.Fatalf("Failed to write check output: %v", err)
+		}
+
+	case "sarif":
+		sarif, err := schema.MarshalSARIF(merged, getVersion())
+		if err != nil {
+			log.Fatalf("Failed to marshal check output to SARIF: %v", err)
+		}
+		fmt.Println(string(sarif))
+
+	default:
+		for i, root := range roots {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", root)
+			printCheckText(output.Roots[root])
+		}
+		if len(roots) > 1 {
+			fmt.Printf("\nTotal: %d error(s), %d warning(s)\n", merged.Summary.Errors, merged.Summary.Warnings)
+		}
+	}
+
+	if checkFailed(merged, checkFailOn) {
+		os.Exit(1)
+	}
+}
+
+// multiCheckOutput is the report of check --multi: a report per schema root,
+// keyed by the root as given, and the combined summary
+type multiCheckOutput struct {
+	Roots   map[string]*schema.CheckOutput `json:"roots"`
+	Summary schema.Summary                 `json:"summary"`
+}
+
+// merged returns the reports of every root combined into one
+func (o *multiCheckOutput) merged() *schema.CheckOutput {
+	outputs := make([]*schema.CheckOutput, 0, len(o.Roots))
+	for _, output := range o.Roots {
+		outputs = append(outputs, output)
+	}
+	return schema.MergeCheckOutputs(outputs...)
+}
+
+// checkSchemaRoots checks each schema root with the lint options of its own
+// lockplane.toml. flags holds the options set by command-line flags, which
+// apply to every root.
+func checkSchemaRoots(roots []string, flags schema.CheckOptions) (*multiCheckOutput, error) {
+	output := &multiCheckOutput{Roots: make(map[string]*schema.CheckOutput, len(roots))}
+	for _, root := range roots {
+		opts, err := loadCheckOptions(root, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to load lint configuration: %w", root, err)
+		}
+		opts.Coverage = flags.Coverage
+		opts.FailFast = flags.FailFast
+		opts.SkipGenerated = flags.SkipGenerated
+		opts.StripMetaCommands = flags.StripMetaCommands
+		opts.CacheDir = flags.CacheDir
+		opts.Include = flags.Include
+		opts.Exclude = flags.Exclude
+
+		rootOutput, err := schema.CheckSchemaWithOptions(root, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", root, err)
+		}
+		output.Roots[root] = rootOutput
+	}
+	output.Summary = output.merged().Summary
+	return output, nil
+}
+
+// checkCacheDir returns the directory to cache parse results in, or "" with
+// --no-cache
+func checkCacheDir() string {
+	if checkNoCache {
+		return ""
+	}
+	return schema.DefaultCacheDir
+}
+
+// checkOutputFormat returns the validated --format, exiting on an unknown
+// format or --fail-on value
+func checkOutputFormat(cmd *cobra.Command) string {
+	// --output predates --format and is kept as an alias
+	format := checkFormat
+	if cmd.Flags().Changed("output") && !cmd.Flags().Changed("format") {
+		format = checkOutput
+	}
+	if format != "text" && format != "json" && format != "ndjson" && format != "sarif" {
+		log.Fatalf("Unknown output format %q: expected text, json, ndjson or sarif", format)
+	}
+	if checkFailOn != "error" && checkFailOn != "warning" && checkFailOn != "never" {
+		log.Fatalf("Unknown --fail-on value %q: expected error, warning or never", checkFailOn)
+	}
+	return format
+}
+
+// checkFailed reports whether a check should exit with a failure status.
+// failOn is "error" (fail on errors), "warning" (fail on errors or warnings)
+// or "never".
+func checkFailed(output *schema.CheckOutput, failOn string) bool {
+	switch failOn {
+	case "error":
+		return output.Summary.Errors > 0
+	case "warning":
+		return output.Summary.Errors > 0 || output.Summary.Warnings > 0
+	}
+	return false
+}
+
+// loadCheckOptions builds the lint options from the lockplane.toml at
+// configPath or, if configPath is empty, the one found by searching upward
+// from schemaPath. A missing config file isn't an error unless it was named
+// explicitly; the defaults are used instead.
+func loadCheckOptions(schemaPath string, configPath string) (schema.CheckOptions, error) {
+	var cfg *config.Config
+	var err error
+	if configPath != "" {
+		cfg, err = config.LoadConfigFile(configPath)
+	} else {
+		startDir := schemaPath
+		if info, statErr := os.Stat(schemaPath); statErr == nil && !info.IsDir() {
+			startDir = filepath.Dir(schemaPath)
+		}
+		cfg, err = config.LoadConfigFrom(startDir)
+		if errors.Is(err, config.ErrConfigNotFound) {
+			return schema.CheckOptions{}, nil
+		}
+	}
+	if err != nil {
+		config.PrintLoadConfigErrorDetails(err, nil)
+		return schema.CheckOptions{}, err
+	}
+
+	namingPolicy, err := schema.NewNamingPolicy(cfg.Lint.NamingPolicy.Tables, cfg.Lint.NamingPolicy.Columns)
+	if err != nil {
+		return schema.CheckOptions{}, err
+	}
+	if err := schema.ValidateRuleSeverities(cfg.Lint.Rules); err != nil {
+		return schema.CheckOptions{}, fmt.Errorf("%s: %w", cfg.ConfigFilePath, err)
+	}
+	opts := schema.CheckOptions{NamingPolicy: namingPolicy, RuleSeverities: cfg.Lint.Rules, ConfigFile: cfg.ConfigFilePath}
+	if pattern := cfg.Lint.ColumnTypes.Columns; pattern != "" {
+		if opts.ConsistentColumns, err = regexp.Compile(pattern); err != nil {
+			return schema.CheckOptions{}, fmt.Errorf("invalid column_types pattern %q: %w", pattern, err)
+		}
+	}
+	if pattern := cfg.Lint.FlagColumns.Columns; pattern != "" {
+		if opts.FlagColumns, err = regexp.Compile(pattern); err != nil {
+			return schema.CheckOptions{}, fmt.Errorf("invalid flag_columns pattern %q: %w", pattern, err)
+		}
+	}
+	return opts, nil
+}
+
+// printExternalTablesText prints each external table with the foreign keys
+// that reference it
+func printExternalTablesText(externals []schema.ExternalTable) {
+	if len(externals) == 0 {
+		fmt.Println("No external tables referenced")
+		return
+	}
+
+	for _, external := range externals {
+		fmt.Println(external.Name)
+		for _, ref := range external.ReferencedBy {
+			line := fmt.Sprintf("  referenced by %s (%s)", ref.Table, ref.ForeignKey)
+			if ref.SourceLocation != nil {
+				line += " at " + ref.SourceLocation.String()
+			}
+			fmt.Println(line)
+		}
+	}
+	fmt.Printf("%d external table(s)\n", len(externals))
+}
+
+// printObjectsByFileText prints each schema file with the tables and views it
+// defines
+func printObjectsByFileText(files []schema.FileObjects) {
+	for _, file := range files {
+		fmt.Println(cmp.Or(file.File, "(unknown file)"))
+		for _, table := range file.Tables {
+			fmt.Printf("  table %s\n", table)
+		}
+		for _, view := range file.Views {
+			fmt.Printf("  view %s\n", view)
+		}
+	}
+}
+
+// printCheckText prints one line per diagnostic followed by a summary
+func printCheckText(output *schema.CheckOutput) {
+	for _, d := range output.Diagnostics {
+		line := fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+		if d.Code != "" {
+			line += fmt.Sprintf(" [%s]", d.Code)
+		}
+		fmt.Println(line)
+	}
+
+	if c := output.Coverage; c != nil {
+		fmt.Printf("Coverage: %d of %d statement(s) modeled (%.1f%%)\n", c.Modeled, c.Statements, c.Percent)
+		if c.Tracked > 0 {
+			fmt.Printf("%d statement(s) recorded as other objects\n", c.Tracked)
+		}
+	}
+
+	if len(output.Diagnostics) == 0 {
+		fmt.Println("No problems found")
+		return
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n", output.Summary.Errors, output.Summary.Warnings)
 }
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestCheckFailed(t *testing.T) {
+	clean := schema.NewCheckOutput()
+
+	warnings := schema.NewCheckOutput()
+	warnings.AddWarning(schema.Diagnostic{Code: schema.CodeMissingPrimaryKey})
+
+	errors := schema.NewCheckOutput()
+	errors.AddError(schema.Diagnostic{Code: schema.CodeParseError})
+
+	tests := []struct {
+		name     string
+		output   *schema.CheckOutput
+		failOn   string
+		expected bool
+	}{
+		{"clean, fail on error", clean, "error", false},
+		{"clean, fail on warning", clean, "warning", false},
+		{"warnings, fail on error", warnings, "error", false},
+		{"warnings, fail on warning", warnings, "warning", true},
+		{"warnings, never fail", warnings, "never", false},
+		{"errors, fail on error", errors, "error", true},
+		{"errors, fail on warning", errors, "warning", true},
+		{"errors, never fail", errors, "never", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkFailed(tt.output, tt.failOn); got != tt.expected {
+				t.Errorf("checkFailed(%q) = %v, expected %v", tt.failOn, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadCheckOptions(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(projectDir, ".git"), 0o755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "lockplane.toml"), []byte("[lint.rules]\nLP001 = \"off\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	schemaDir := filepath.Join(projectDir, "schema")
+	if err := os.Mkdir(schemaDir, 0o755); err != nil {
+		t.Fatalf("Failed to create schema dir: %v", err)
+	}
+	schemaFile := filepath.Join(schemaDir, "users.lp.sql")
+	if err := os.WriteFile(schemaFile, []byte("CREATE TABLE users (id INTEGER);"), 0o600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	// Found by searching upward from a schema directory or file
+	for _, path := range []string{schemaDir, schemaFile} {
+		opts, err := loadCheckOptions(path, "")
+		if err != nil {
+			t.Fatalf("loadCheckOptions(%s) failed: %v", path, err)
+		}
+		if opts.RuleSeverities[schema.CodeMissingPrimaryKey] != schema.RuleOff {
+			t.Errorf("Expected LP001 to be off for %s, got %v", path, opts.RuleSeverities)
+		}
+	}
+
+	// An explicit config wins over the search
+	explicit := filepath.Join(t.TempDir(), "ci.toml")
+	if err := os.WriteFile(explicit, []byte("[lint.rules]\nLP001 = \"error\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	opts, err := loadCheckOptions(schemaDir, explicit)
+	if err != nil {
+		t.Fatalf("loadCheckOptions failed: %v", err)
+	}
+	if opts.RuleSeverities[schema.CodeMissingPrimaryKey] != schema.SeverityError {
+		t.Errorf("Expected LP001 to be an error, got %v", opts.RuleSeverities)
+	}
+
+	// The LP260 flag names are configurable
+	if err := os.WriteFile(explicit, []byte("[lint.flag_columns]\ncolumns = \"^(is|has|can)_\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if opts, err = loadCheckOptions(schemaDir, explicit); err != nil {
+		t.Fatalf("loadCheckOptions failed: %v", err)
+	}
+	if opts.FlagColumns == nil || !opts.FlagColumns.MatchString("can_edit") {
+		t.Errorf("Expected the configured flag pattern, got %v", opts.FlagColumns)
+	}
+	if err := os.WriteFile(explicit, []byte("[lint.flag_columns]\ncolumns = \"(\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := loadCheckOptions(schemaDir, explicit); err == nil || !strings.Contains(err.Error(), "invalid flag_columns pattern") {
+		t.Errorf("Expected an invalid pattern error, got %v", err)
+	}
+
+	// A missing explicit config is an error
+	if _, err := loadCheckOptions(schemaDir, filepath.Join(projectDir, "missing.toml")); err == nil {
+		t.Error("Expected an error for a missing --config file")
+	}
+
+	// Invalid rules are reported with the config path
+	if err := os.WriteFile(explicit, []byte("[lint.rules]\nLP000 = \"off\"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := loadCheckOptions(schemaDir, explicit); err == nil || !strings.Contains(err.Error(), explicit) {
+		t.Errorf("Expected an error mentioning %s, got %v", explicit, err)
+	}
+}
+
+func TestCheckOutputIncludesAppliedConfig(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(projectDir, ".git"), 0o755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	configPath := filepath.Join(projectDir, "lockplane.toml")
+	config := "[lint.rules]\nLP001 = \"off\"\nLP202 = \"error\"\n\n[lint.naming_policy]\ntables = \"^[a-z_]+$\"\n"
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	schemaFile := filepath.Join(projectDir, "users.lp.sql")
+	if err := os.WriteFile(schemaFile, []byte("CREATE TABLE users (id INTEGER);"), 0o600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	opts, err := loadCheckOptions(schemaFile, "")
+	if err != nil {
+		t.Fatalf("loadCheckOptions failed: %v", err)
+	}
+	output, err := schema.CheckSchemaWithOptions(schemaFile, opts)
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("Failed to marshal output: %v", err)
+	}
+	var report struct {
+		Config struct {
+			File         string            `json:"file"`
+			Rules        map[string]string `json:"rules"`
+			NamingPolicy struct {
+				Tables string `json:"tables"`
+			} `json:"naming_policy"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to unmarshal output: %v", err)
+	}
+
+	if report.Config.File != configPath {
+		t.Errorf("Expected config file %s, got %q", configPath, report.Config.File)
+	}
+	expected := map[string]string{
+		schema.CodeMissingPrimaryKey:      schema.RuleOff,
+		schema.CodeUnindexedForeignKey:    schema.SeverityError,
+		schema.CodeForeignKeyNotUnique:    schema.SeverityWarning,
+		schema.CodeInconsistentColumnType: schema.RuleOff,
+	}
+	for code, severity := range expected {
+		if report.Config.Rules[code] != severity {
+			t.Errorf("Expected rule %s to be %q, got %q", code, severity, report.Config.Rules[code])
+		}
+	}
+	if report.Config.NamingPolicy.Tables != "^[a-z_]+$" {
+		t.Errorf("Expected the table naming pattern, got %q", report.Config.NamingPolicy.Tables)
+	}
+
+	// Without a config file, no config is reported
+	output, err = schema.CheckSchemaWithOptions(schemaFile, schema.CheckOptions{})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if output.Config != nil {
+		t.Errorf("Expected no config without a config file, got %+v", output.Config)
+	}
+}
+
+func TestCheckSchemaRoots(t *testing.T) {
+	repoDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repoDir, ".git"), 0o755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+
+	// Both services have a table without a primary key, which only service
+	// a's config turns off
+	configs := []struct {
+		service string
+		config  string
+	}{
+		{"a", "[lint.rules]\nLP001 = \"off\"\n"},
+		{"b", "[lint.rules]\nLP001 = \"error\"\n"},
+	}
+	var roots []string
+	for _, c := range configs {
+		serviceDir := filepath.Join(repoDir, "services", c.service)
+		root := filepath.Join(serviceDir, "schema")
+		if err := os.MkdirAll(root, 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", root, err)
+		}
+		if err := os.WriteFile(filepath.Join(serviceDir, "lockplane.toml"), []byte(c.config), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "events.lp.sql"), []byte("CREATE TABLE events (payload TEXT);"), 0o600); err != nil {
+			t.Fatalf("Failed to write schema: %v", err)
+		}
+		roots = append(roots, root)
+	}
+
+	output, err := checkSchemaRoots(roots, schema.CheckOptions{})
+	if err != nil {
+		t.Fatalf("checkSchemaRoots failed: %v", err)
+	}
+	if len(output.Roots) != 2 {
+		t.Fatalf("Expected a report per root, got %v", output.Roots)
+	}
+
+	a, b := output.Roots[roots[0]], output.Roots[roots[1]]
+
+	for _, d := range a.Diagnostics {
+		if d.Code == schema.CodeMissingPrimaryKey {
+			t.Errorf("Expected LP001 to be off for service a, got %+v", d)
+		}
+	}
+	if a.Config == nil || !strings.HasSuffix(a.Config.File, filepath.Join("a", "lockplane.toml")) {
+		t.Errorf("Expected service a to be checked with its own config, got %+v", a.Config)
+	}
+	if b.Summary.Errors != 1 || b.Diagnostics[0].Code != schema.CodeMissingPrimaryKey {
+		t.Errorf("Expected LP001 to be an error for service b, got %+v", b.Diagnostics)
+	}
+	if output.Summary.Errors != 1 || output.Summary.Valid {
+		t.Errorf("Expected the combined summary to count service b's error, got %+v", output.Summary)
+	}
+
+	// The JSON report is keyed by root
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("Failed to marshal report: %v", err)
+	}
+	var report struct {
+		Roots map[string]json.RawMessage `json:"roots"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to unmarshal report: %v", err)
+	}
+	if _, ok := report.Roots[roots[0]]; !ok || len(report.Roots) != 2 {
+		t.Errorf("Expected the report to be keyed by root, got %s", data)
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lockplane/lockplane/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(configSchemaCmd)
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "config-schema",
+	Short: "Print the JSON Schema for lockplane.toml",
+	Long: `Print a JSON Schema describing every key in lockplane.toml. Editors with
+TOML schema support can use it to validate and complete the config file.
+
+Examples:
+lockplane config-schema > lockplane.schema.json
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := config.JSONSchema()
+		if err != nil {
+			log.Fatalf("Failed to generate config schema: %v", err)
+		}
+		fmt.Println(string(schema))
+	},
+}
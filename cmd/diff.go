@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/database"
+	"github.com/lockplane/lockplane/internal/driver"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old schema> <new schema>",
+	Short: "Print the SQL to migrate between two schemas",
+	Long: `Compare two schemas and print the PostgreSQL DDL that migrates the old schema
+to the new one. Each schema may be a directory or a single .lp.sql file. No
+database connection is needed.
+
+Examples:
+lockplane diff old-schema/ schema/
+lockplane diff before.lp.sql after.lp.sql > migration.sql
+`,
+	Args: cobra.ExactArgs(2),
+	Run:  runDiff,
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	diff, migration, err := diffSchemaPaths(args[0], args[1])
+	if err != nil {
+		log.Fatalf("Failed to diff schemas: %v", err)
+	}
+
+	if diff.IsEmpty() {
+		fmt.Fprintln(os.Stderr, "No changes detected")
+		return
+	}
+	fmt.Println(migration)
+}
+
+// diffSchemaPaths loads the schemas at oldPath and newPath and returns the
+// differences between them, along with the PostgreSQL DDL that migrates the
+// old schema to the new one
+func diffSchemaPaths(oldPath string, newPath string) (*schema.SchemaDiff, string, error) {
+	oldSchema, err := schema.LoadSchema(oldPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load %s: %w", oldPath, err)
+	}
+	newSchema, err := schema.LoadSchema(newPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load %s: %w", newPath, err)
+	}
+
+	diff, err := schema.DiffSchemas(oldSchema, newSchema)
+	if err != nil {
+		return nil, "", err
+	}
+	driver, err := driver.NewDriver(database.DatabaseTypePostgres)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create database driver: %w", err)
+	}
+	return diff, driver.GenerateMigration(diff), nil
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSchemaDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestDiffSchemaPaths(t *testing.T) {
+	oldDir := writeSchemaDir(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(100), age INTEGER);`,
+		"posts.lp.sql": `CREATE TABLE posts (id INTEGER PRIMARY KEY);`,
+	})
+	newDir := writeSchemaDir(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT);`,
+		"tags.lp.sql":  `CREATE TABLE auth.tags (id INTEGER PRIMARY KEY);`,
+	})
+
+	_, migration, err := diffSchemaPaths(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("diffSchemaPaths failed: %v", err)
+	}
+
+	expected := `CREATE TABLE auth.tags (
+  id integer NOT NULL PRIMARY KEY
+);
+
+ALTER TABLE users ADD COLUMN email text;
+
+ALTER TABLE users DROP COLUMN age;
+
+ALTER TABLE users ALTER COLUMN name TYPE text;
+
+ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+
+DROP TABLE posts CASCADE;`
+	if migration != expected {
+		t.Errorf("Unexpected migration.\nExpected:\n%s\n\nGot:\n%s", expected, migration)
+	}
+}
+
+func TestDiffSchemaPathsForeignKeys(t *testing.T) {
+	oldDir := writeSchemaDir(t, map[string]string{
+		"schema.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES users, editor_id INTEGER REFERENCES users);`,
+	})
+	newDir := writeSchemaDir(t, map[string]string{
+		"schema.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, author_id INTEGER REFERENCES users ON DELETE CASCADE, editor_id INTEGER, reviewer_id INTEGER);
+ALTER TABLE posts ADD CONSTRAINT posts_reviewer_fkey FOREIGN KEY (reviewer_id) REFERENCES users (id) DEFERRABLE;
+CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES posts);`,
+	})
+
+	diff, migration, err := diffSchemaPaths(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("diffSchemaPaths failed: %v", err)
+	}
+	if diff.IsEmpty() {
+		t.Fatal("Expected changes")
+	}
+
+	expected := `CREATE TABLE comments (
+  id integer NOT NULL PRIMARY KEY,
+  post_id integer
+);
+
+ALTER TABLE posts DROP CONSTRAINT posts_editor_id_fkey;
+
+ALTER TABLE posts DROP CONSTRAINT posts_author_id_fkey;
+
+ALTER TABLE posts ADD COLUMN reviewer_id integer;
+
+ALTER TABLE comments ADD CONSTRAINT comments_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts;
+
+ALTER TABLE posts ADD CONSTRAINT posts_reviewer_fkey FOREIGN KEY (reviewer_id) REFERENCES users (id) DEFERRABLE;
+
+ALTER TABLE posts ADD CONSTRAINT posts_author_id_fkey FOREIGN KEY (author_id) REFERENCES users ON DELETE CASCADE;`
+	if migration != expected {
+		t.Errorf("Unexpected migration.\nExpected:\n%s\n\nGot:\n%s", expected, migration)
+	}
+}
+
+func TestDiffSchemaPathsNoChanges(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+	})
+
+	diff, migration, err := diffSchemaPaths(dir, dir)
+	if err != nil {
+		t.Fatalf("diffSchemaPaths failed: %v", err)
+	}
+	if !diff.IsEmpty() || migration != "" {
+		t.Errorf("Expected no changes, got %+v and %q", diff, migration)
+	}
+}
+
+func TestDiffSchemaPathsDomains(t *testing.T) {
+	oldDir := writeSchemaDir(t, map[string]string{
+		"types.lp.sql": `CREATE DOMAIN email AS VARCHAR(100) CHECK (VALUE LIKE '%@%');
+CREATE DOMAIN code AS TEXT;`,
+	})
+	newDir := writeSchemaDir(t, map[string]string{
+		"types.lp.sql": `CREATE DOMAIN email AS VARCHAR(255) NOT NULL CHECK (VALUE LIKE '%@%');
+CREATE DOMAIN auth.token AS TEXT CHECK (length(VALUE) = 32);`,
+	})
+
+	_, migration, err := diffSchemaPaths(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("diffSchemaPaths failed: %v", err)
+	}
+
+	expected := `CREATE DOMAIN auth.token AS text CONSTRAINT token_check CHECK (length(value) = 32);
+
+-- The base type of domain email changed from varchar(100) to varchar(255), which ALTER DOMAIN can't do.
+-- Recreate the domain and the columns that use it instead.
+
+ALTER DOMAIN email SET NOT NULL;
+
+DROP DOMAIN code;`
+	if migration != expected {
+		t.Errorf("Unexpected migration.\nExpected:\n%s\n\nGot:\n%s", expected, migration)
+	}
+}
+
+func TestDiffSchemaPathsInvalidSchema(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+	})
+
+	_, _, err := diffSchemaPaths(dir, filepath.Join(dir, "missing"))
+	if err == nil || !strings.Contains(err.Error(), "failed to load") {
+		t.Errorf("Expected load error, got %v", err)
+	}
+}
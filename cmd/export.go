@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/database"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var exportFormat string
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "json", "Output format: json or jsonschema")
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [schema dir or .lp.sql file]",
+	Short: "Print the parsed schema as JSON, or the JSON Schema describing it",
+	Long: `Print the schema lockplane parses from .lp.sql files as JSON, for tools that
+generate code from it. --format jsonschema prints the JSON Schema (draft
+2020-12) the JSON output follows instead, and takes no schema path. Its $id
+carries a version that changes when the output changes incompatibly.
+
+Examples:
+lockplane export schema/ > schema.json
+lockplane export --format jsonschema > lockplane-schema.json
+`,
+	Args: cobra.RangeArgs(0, 1),
+	Run:  runExport,
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	path := ""
+	switch exportFormat {
+	case "json":
+		if len(args) != 1 {
+			log.Fatalf("export --format json needs a schema dir or .lp.sql file")
+		}
+		path = args[0]
+	case "jsonschema":
+		if len(args) != 0 {
+			log.Fatalf("export --format jsonschema doesn't take a schema path")
+		}
+	default:
+		log.Fatalf("Invalid --format %q: must be json or jsonschema", exportFormat)
+	}
+
+	if err := printExport(path, exportFormat, os.Stdout); err != nil {
+		log.Fatalf("Failed to export schema: %v", err)
+	}
+}
+
+// printExport prints the schema at path as JSON, or with format jsonschema the
+// JSON Schema of that output, in which case path is unused
+func printExport(path string, format string, w io.Writer) error {
+	var data []byte
+	var err error
+	if format == "jsonschema" {
+		data, err = database.JSONSchema()
+	} else {
+		var loaded *database.Schema
+		if loaded, err = schema.LoadSchema(path); err != nil {
+			return err
+		}
+		data, err = json.MarshalIndent(loaded, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
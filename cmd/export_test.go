@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPrintExport(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"a.lp.sql": `CREATE SCHEMA auth;
+CREATE TYPE status AS ENUM ('active', 'banned');
+CREATE DOMAIN email AS TEXT;
+CREATE TABLE auth.users (
+  id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  email email NOT NULL UNIQUE,
+  status status DEFAULT 'active',
+  tags TEXT[],
+  CHECK (id > 0)
+);
+CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  author_id BIGINT REFERENCES auth.users (id) ON DELETE CASCADE
+);
+CREATE INDEX posts_author_idx ON posts (author_id);
+CREATE VIEW recent_posts AS SELECT id FROM posts;`,
+	})
+
+	var out bytes.Buffer
+	if err := printExport(dir, "json", &out); err != nil {
+		t.Fatalf("printExport failed: %v", err)
+	}
+	var exported any
+	if err := json.Unmarshal(out.Bytes(), &exported); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+
+	out.Reset()
+	if err := printExport("", "jsonschema", &out); err != nil {
+		t.Fatalf("printExport failed: %v", err)
+	}
+	var jsonSchema map[string]any
+	if err := json.Unmarshal(out.Bytes(), &jsonSchema); err != nil {
+		t.Fatalf("JSON Schema is not valid JSON: %v", err)
+	}
+
+	// The export follows the JSON Schema
+	if err := validateJSON(jsonSchema, jsonSchema, exported, "$"); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(out.String(), `"$id": "urn:lockplane:schema:v1"`) {
+		t.Errorf("Expected a versioned JSON Schema, got:\n%s", out.String())
+	}
+}
+
+// validateJSON checks value against the subset of JSON Schema used by
+// database.JSONSchema: $ref, anyOf, type, properties, required, items and
+// additionalProperties
+func validateJSON(root map[string]any, s map[string]any, value any, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		def, ok := root["$defs"].(map[string]any)[name].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: unknown $ref %s", path, ref)
+		}
+		return validateJSON(root, def, value, path)
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		var errs []string
+		for _, option := range anyOf {
+			err := validateJSON(root, option.(map[string]any), value, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s: matches no option: %s", path, strings.Join(errs, "; "))
+	}
+
+	switch s["type"] {
+	case "null":
+		if value != nil {
+			return fmt.Errorf("%s: expected null, got %v", path, value)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %v", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %v", path, value)
+		}
+	case "integer", "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected a number, got %v", path, value)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %v", path, value)
+		}
+		for i, item := range items {
+			if err := validateJSON(root, s["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %v", path, value)
+		}
+		if required, ok := s["required"].([]any); ok {
+			for _, name := range required {
+				if _, ok := object[name.(string)]; !ok {
+					return fmt.Errorf("%s: missing required property %s", path, name)
+				}
+			}
+		}
+		properties, _ := s["properties"].(map[string]any)
+		for name, v := range object {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				property, ok = s["additionalProperties"].(map[string]any)
+			}
+			if !ok {
+				return fmt.Errorf("%s: property %s isn't in the JSON Schema", path, name)
+			}
+			if err := validateJSON(root, property, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var fmtCheck bool
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "List files that aren't formatted instead of rewriting them, and exit with status 1 if there are any")
+}
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [schema dir or .lp.sql file]",
+	Short: "Rewrite .lp.sql files in a canonical style",
+	Long: `Rewrite .lp.sql schema files in a canonical style: one column per line,
+normalized type names, and constraints sorted by name. The names of the
+rewritten files are printed.
+
+Files are rebuilt from the parsed schema, so files with comments, statements
+lockplane doesn't model, or details it doesn't track (such as partial indexes)
+are skipped with a message rather than rewritten.
+
+Examples:
+lockplane fmt schema/
+lockplane fmt --check schema/  # Fail if any file isn't formatted, like gofmt -l
+`,
+	Args: cobra.ExactArgs(1),
+	Run:  runFmt,
+}
+
+func runFmt(cmd *cobra.Command, args []string) {
+	ok, err := formatSchemaPath(args[0], fmtCheck, os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatalf("Failed to format schema: %v", err)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// formatSchemaPath formats the schema files at path, printing the name of
+// each file that was rewritten or, with check, that needs to be. Files that
+// can't be formatted are reported to stderr. It returns false if a file
+// failed to parse or, with check, isn't formatted.
+func formatSchemaPath(path string, check bool, stdout io.Writer, stderr io.Writer) (bool, error) {
+	files, err := schema.FormatSchemaFiles(path)
+	if err != nil {
+		return false, err
+	}
+
+	ok := true
+	for _, file := range files {
+		switch {
+		case errors.Is(file.Err, schema.ErrCannotFormat):
+			fmt.Fprintf(stderr, "%s: skipped: %v\n", file.Path, file.Err)
+		case file.Err != nil:
+			fmt.Fprintf(stderr, "%v\n", file.Err)
+			ok = false
+		case !file.Changed:
+		case check:
+			fmt.Fprintln(stdout, file.Path)
+			ok = false
+		default:
+			info, err := os.Stat(file.Path)
+			if err != nil {
+				return false, err
+			}
+			if err := os.WriteFile(file.Path, []byte(file.Formatted), info.Mode().Perm()); err != nil {
+				return false, fmt.Errorf("failed to write %s: %w", file.Path, err)
+			}
+			fmt.Fprintln(stdout, file.Path)
+		}
+	}
+	return ok, nil
+}
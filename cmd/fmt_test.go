@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatSchemaPath(t *testing.T) {
+	unformatted := "create table users (id bigint primary key, email text not null);"
+	dir := writeSchemaDir(t, map[string]string{
+		"users.lp.sql": unformatted,
+		"notes.lp.sql": "-- kept as written\nCREATE TABLE notes (id BIGINT PRIMARY KEY);",
+	})
+	usersPath := filepath.Join(dir, "users.lp.sql")
+
+	// --check lists the file without touching it
+	var stdout, stderr bytes.Buffer
+	ok, err := formatSchemaPath(dir, true, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("formatSchemaPath failed: %v", err)
+	}
+	if ok || stdout.String() != usersPath+"\n" {
+		t.Errorf("Expected --check to fail listing %s, got ok=%v output %q", usersPath, ok, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "notes.lp.sql: skipped: can't be formatted: formatting would remove its comments") {
+		t.Errorf("Expected notes.lp.sql to be reported as skipped, got %q", stderr.String())
+	}
+	if data, _ := os.ReadFile(usersPath); string(data) != unformatted {
+		t.Errorf("Expected --check to leave the file alone, got %q", data)
+	}
+
+	// Without --check the file is rewritten
+	stdout.Reset()
+	stderr.Reset()
+	ok, err = formatSchemaPath(dir, false, &stdout, &stderr)
+	if err != nil || !ok {
+		t.Fatalf("Expected formatting to succeed, got ok=%v err=%v", ok, err)
+	}
+	expected := "CREATE TABLE users (\n  id bigint,\n  email text NOT NULL,\n  PRIMARY KEY (id)\n);\n"
+	if data, _ := os.ReadFile(usersPath); string(data) != expected {
+		t.Errorf("Expected the file to be formatted, got %q", data)
+	}
+
+	// After which --check passes
+	stdout.Reset()
+	ok, err = formatSchemaPath(dir, true, &stdout, &stderr)
+	if err != nil || !ok || stdout.Len() != 0 {
+		t.Errorf("Expected --check to pass, got ok=%v err=%v output %q", ok, err, stdout.String())
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var lsFormat string
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+	lsCmd.Flags().StringVar(&lsFormat, "format", "text", "Output format: text or json")
+}
+
+var lsCmd = &cobra.Command{
+	Use:   "ls [schema dir or .lp.sql file]",
+	Short: "List the tables and columns of a schema",
+	Long: `List each table of the schema as schema.table, sorted by schema and name,
+with its columns in order: their normalized types, NOT NULL for columns that
+can't be null and PK for primary key columns. It is meant to be skimmed in
+code review; use lockplane export for everything lockplane parses.
+
+Examples:
+lockplane ls schema/
+lockplane ls --format json schema/
+`,
+	Args: cobra.ExactArgs(1),
+	Run:  runLs,
+}
+
+func runLs(cmd *cobra.Command, args []string) {
+	if lsFormat != "text" && lsFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", lsFormat)
+	}
+	if err := printTableList(args[0], lsFormat, os.Stdout); err != nil {
+		log.Fatalf("Failed to load schema: %v", err)
+	}
+}
+
+// listedTable is a table in the output of ls --format json
+type listedTable struct {
+	Schema  string         `json:"schema"`
+	Name    string         `json:"name"`
+	Columns []listedColumn `json:"columns"`
+}
+
+// listedColumn is a column in the output of ls --format json
+type listedColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	NotNull    bool   `json:"not_null"`
+	PrimaryKey bool   `json:"primary_key"`
+}
+
+// printTableList prints the tables and columns of the schema at path as text
+// or JSON
+func printTableList(path string, format string, w io.Writer) error {
+	loaded, err := schema.LoadSchemaWithOptions(path, schema.LoadSchemaOptions{Sort: true})
+	if err != nil {
+		return err
+	}
+	tables := listTables(loaded)
+
+	if format == "json" {
+		data, err := json.MarshalIndent(tables, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := fmt.Fprintf(w, "%s.%s\n", table.Schema, table.Name); err != nil {
+			return err
+		}
+		nameWidth, typeWidth := 0, 0
+		for _, column := range table.Columns {
+			nameWidth = max(nameWidth, len(column.Name))
+			typeWidth = max(typeWidth, len(column.Type))
+		}
+		for _, column := range table.Columns {
+			line := fmt.Sprintf("  %-*s  %-*s", nameWidth, column.Name, typeWidth, column.Type)
+			// Primary key columns are always NOT NULL
+			if column.NotNull {
+				line += "  NOT NULL"
+			}
+			if column.PrimaryKey {
+				line += "  PK"
+			}
+			if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listTables returns the tables of a sorted schema with their columns
+func listTables(loaded *database.Schema) []listedTable {
+	tables := make([]listedTable, 0, len(loaded.Tables))
+	for _, table := range loaded.Tables {
+		listed := listedTable{Schema: table.Schema, Name: table.Name, Columns: []listedColumn{}}
+		if listed.Schema == "" {
+			listed.Schema = "public"
+		}
+		for _, column := range table.Columns {
+			listed.Columns = append(listed.Columns, listedColumn{
+				Name:       column.Name,
+				Type:       column.Type,
+				NotNull:    !column.Nullable,
+				PrimaryKey: column.IsPrimaryKey,
+			})
+		}
+		tables = append(tables, listed)
+	}
+	return tables
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPrintTableList(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY, email VARCHAR(255) NOT NULL, bio TEXT);
+CREATE TABLE auth.sessions (user_id INT8, token TEXT, PRIMARY KEY (user_id, token));`,
+		"b.lp.sql": `CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users);
+CREATE TABLE audit_log ();`,
+	})
+
+	var out bytes.Buffer
+	if err := printTableList(dir, "text", &out); err != nil {
+		t.Fatalf("printTableList failed: %v", err)
+	}
+	expected := `auth.sessions
+  user_id  bigint  NOT NULL  PK
+  token    text    NOT NULL  PK
+public.audit_log
+public.posts
+  id         bigint  NOT NULL  PK
+  author_id  bigint
+public.users
+  id     bigint        NOT NULL  PK
+  email  varchar(255)  NOT NULL
+  bio    text
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := printTableList(dir, "json", &out); err != nil {
+		t.Fatalf("printTableList failed: %v", err)
+	}
+	var tables []listedTable
+	if err := json.Unmarshal(out.Bytes(), &tables); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if len(tables) != 4 || tables[0].Schema != "auth" || tables[3].Name != "users" {
+		t.Fatalf("Expected 4 tables sorted by schema and name, got %+v", tables)
+	}
+	want := []listedColumn{
+		{Name: "id", Type: "bigint", NotNull: true, PrimaryKey: true},
+		{Name: "email", Type: "varchar(255)", NotNull: true},
+		{Name: "bio", Type: "text"},
+	}
+	if !reflect.DeepEqual(tables[3].Columns, want) {
+		t.Errorf("Expected users columns %+v, got %+v", want, tables[3].Columns)
+	}
+	if tables[1].Columns == nil {
+		t.Errorf("Expected a table without columns to list an empty array")
+	}
+}
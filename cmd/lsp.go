@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/lsp"
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server that checks .lp.sql files as they are edited",
+	Long: `Run a Language Server Protocol server over stdio for editor integration.
+
+Open .lp.sql buffers are checked shortly after they stop changing, and the
+diagnostics are published to the editor. Lint rules are configured by the
+lockplane.toml nearest to each file, as with lockplane check.
+`,
+	Args: cobra.NoArgs,
+	Run:  runLSP,
+}
+
+func runLSP(cmd *cobra.Command, args []string) {
+	// stdout carries the protocol, so anything else printed goes to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	log.SetOutput(os.Stderr)
+
+	server := lsp.NewServer(out)
+	server.Options = func(path string) schema.CheckOptions {
+		opts, err := loadCheckOptions(path, "")
+		if err != nil {
+			log.Printf("Failed to load lockplane.toml for %s: %v", path, err)
+		}
+		return opts
+	}
+	if err := server.Serve(os.Stdin); err != nil {
+		log.Fatalf("Language server failed: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var mergeReportsOutput string
+
+func init() {
+	rootCmd.AddCommand(mergeReportsCmd)
+	mergeReportsCmd.Flags().StringVarP(&mergeReportsOutput, "output", "o", "", "Write the combined report to this file instead of stdout")
+}
+
+var mergeReportsCmd = &cobra.Command{
+	Use:   "merge-reports <report.json>...",
+	Short: "Combine JSON reports from lockplane check into one",
+	Long: `Combine reports written by lockplane check --output json into a single
+report. Diagnostics are concatenated and sorted by location, and the summary
+counts are summed. This is useful when schema directories are checked
+separately, e.g. one per team in a monorepo.
+
+Examples:
+lockplane merge-reports a.json b.json
+lockplane merge-reports a.json b.json -o combined.json
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runMergeReports,
+}
+
+func runMergeReports(cmd *cobra.Command, args []string) {
+	merged, err := mergeReportFiles(args)
+	if err != nil {
+		log.Fatalf("Failed to merge reports: %v", err)
+	}
+
+	reportJson, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal merged report to JSON: %v", err)
+	}
+
+	if mergeReportsOutput == "" {
+		fmt.Println(string(reportJson))
+		return
+	}
+	if err := os.WriteFile(mergeReportsOutput, append(reportJson, '\n'), 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", mergeReportsOutput, err)
+	}
+}
+
+// mergeReportFiles reads the check reports at paths and merges them
+func mergeReportFiles(paths []string) (*schema.CheckOutput, error) {
+	var outputs []*schema.CheckOutput
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var output schema.CheckOutput
+		if err := json.Unmarshal(data, &output); err != nil {
+			return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+		}
+		outputs = append(outputs, &output)
+	}
+
+	return schema.MergeCheckOutputs(outputs...), nil
+}
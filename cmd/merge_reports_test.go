@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeReportFiles(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"a.json": `{
+  "diagnostics": [
+    {"severity": "warning", "code": "LP001", "message": "table \"posts\" has no primary key", "file": "team-b/posts.lp.sql", "line": 1, "column": 1},
+    {"severity": "error", "code": "LP000", "message": "syntax error", "file": "team-a/users.lp.sql", "line": 3, "column": 5}
+  ],
+  "summary": {"errors": 1, "warnings": 1, "valid": false, "by_code": {"LP000": 1, "LP001": 1}}
+}`,
+		"b.json": `{
+  "diagnostics": [
+    {"severity": "warning", "code": "naming-policy", "message": "bad name", "file": "team-a/users.lp.sql", "line": 1, "column": 1}
+  ],
+  "summary": {"errors": 0, "warnings": 1, "valid": true}
+}`,
+	})
+
+	merged, err := mergeReportFiles([]string{filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")})
+	if err != nil {
+		t.Fatalf("mergeReportFiles failed: %v", err)
+	}
+
+	if merged.Summary.Errors != 1 || merged.Summary.Warnings != 2 || merged.Summary.Valid {
+		t.Errorf("Expected 1 error, 2 warnings and an invalid report, got %+v", merged.Summary)
+	}
+	// b.json predates by_code, so its diagnostics are counted instead
+	if want := map[string]int{"LP000": 1, "LP001": 1, "naming-policy": 1}; !reflect.DeepEqual(merged.Summary.ByCode, want) {
+		t.Errorf("Expected counts by code %v, got %v", want, merged.Summary.ByCode)
+	}
+	if len(merged.Diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d", len(merged.Diagnostics))
+	}
+
+	expected := []string{"naming-policy", "LP000", "LP001"}
+	for i, code := range expected {
+		if merged.Diagnostics[i].Code != code {
+			t.Errorf("Expected diagnostic %d to be %s, got %s", i, code, merged.Diagnostics[i].Code)
+		}
+	}
+}
+
+func TestMergeReportFilesInvalidJSON(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"bad.json": `not json`,
+	})
+
+	if _, err := mergeReportFiles([]string{filepath.Join(dir, "bad.json")}); err == nil {
+		t.Error("Expected an error for a report that isn't JSON")
+	}
+}
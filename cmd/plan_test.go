@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+	"github.com/lockplane/lockplane/internal/driver"
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestPlanMigration(t *testing.T) {
+	current, err := schema.ParseSQLSchemaWithDialect(`CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(100), age BIGINT, bio VARCHAR(50));
+CREATE TABLE legacy (id INTEGER PRIMARY KEY);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse current schema: %v", err)
+	}
+	desired, err := schema.ParseSQLSchemaWithDialect(`CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(200) NOT NULL, age INTEGER, email TEXT);
+CREATE DOMAIN email AS TEXT;
+CREATE TABLE events (id INTEGER, at DATE) PARTITION BY RANGE (at);
+CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse desired schema: %v", err)
+	}
+	gen, err := driver.NewDriver(database.DatabaseTypePostgres)
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+
+	diff := func() *schema.SchemaDiff {
+		diff, err := schema.DiffSchemas(current, desired)
+		if err != nil {
+			t.Fatalf("DiffSchemas failed: %v", err)
+		}
+		return diff
+	}
+
+	plan, withheld := planMigration(diff(), gen, false)
+	expected := `CREATE TABLE events (
+  id integer,
+  at date
+) PARTITION BY RANGE (at);
+
+ALTER TABLE users ADD COLUMN email text;
+
+ALTER TABLE users ALTER COLUMN name TYPE varchar(200);
+
+ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+
+-- Withheld, destructive: drops column users.bio
+-- ALTER TABLE users DROP COLUMN bio;
+
+-- Withheld, destructive: changes the type of users.age from bigint to integer, a narrowing change
+-- ALTER TABLE users ALTER COLUMN age TYPE integer;
+
+-- Withheld, destructive: drops table legacy and its rows
+-- DROP TABLE legacy CASCADE;`
+	if plan != expected || withheld != 3 {
+		t.Errorf("Unexpected plan with %d withheld.\nExpected:\n%s\n\nGot:\n%s", withheld, expected, plan)
+	}
+
+	plan, withheld = planMigration(diff(), gen, true)
+	expected = `CREATE TABLE events (
+  id integer,
+  at date
+) PARTITION BY RANGE (at);
+
+ALTER TABLE users ADD COLUMN email text;
+
+ALTER TABLE users ALTER COLUMN name TYPE varchar(200);
+
+ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+
+-- Destructive: drops column users.bio
+ALTER TABLE users DROP COLUMN bio;
+
+-- Destructive: changes the type of users.age from bigint to integer, a narrowing change
+ALTER TABLE users ALTER COLUMN age TYPE integer;
+
+-- Destructive: drops table legacy and its rows
+DROP TABLE legacy CASCADE;`
+	if plan != expected || withheld != 0 {
+		t.Errorf("Unexpected plan with %d withheld.\nExpected:\n%s\n\nGot:\n%s", withheld, expected, plan)
+	}
+
+	if plan, _ := planMigration(&schema.SchemaDiff{}, gen, false); plan != "" {
+		t.Errorf("Expected an empty plan, got:\n%s", plan)
+	}
+
+	// Foreign keys aren't introspected, so their changes are left out
+	withoutKey, err := schema.ParseSQLSchemaWithDialect(`CREATE TABLE users (id INTEGER PRIMARY KEY, manager_id INTEGER);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	withKey, err := schema.ParseSQLSchemaWithDialect(`CREATE TABLE users (id INTEGER PRIMARY KEY, manager_id INTEGER REFERENCES users);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	keyDiff, err := schema.DiffSchemas(withoutKey, withKey)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
+	if plan, _ := planMigration(keyDiff, gen, false); plan != "" || !keyDiff.IsEmpty() {
+		t.Errorf("Expected the foreign key to be left out, got %+v and plan:\n%s", keyDiff, plan)
+	}
+}
+
+func TestIntrospectionScope(t *testing.T) {
+	desired, err := schema.ParseSQLSchemaWithDialect(`CREATE TABLE users (id INTEGER);
+CREATE TABLE billing.invoices (id INTEGER);
+CREATE TABLE auth.sessions (id INTEGER);
+CREATE TABLE billing.payments (id INTEGER);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse desired schema: %v", err)
+	}
+
+	expected := []string{"auth", "billing", "public"}
+	if scope := introspectionScope(desired); !reflect.DeepEqual(scope, expected) {
+		t.Errorf("Expected scope %v, got %v", expected, scope)
+	}
+	if scope := introspectionScope(&database.Schema{}); !reflect.DeepEqual(scope, []string{"public"}) {
+		t.Errorf("Expected only public for an empty schema, got %v", scope)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var rulesList bool
+var rulesFormat string
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.Flags().BoolVar(&rulesList, "list", false, "List every diagnostic code with its default severity")
+	rulesCmd.Flags().StringVar(&rulesFormat, "format", "text", "Output format: text or json")
+}
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules --list",
+	Short: "List the diagnostic codes lockplane check reports",
+	Long: `List the diagnostic codes lockplane check reports
+
+Every diagnostic carries a stable code, such as LP100 for a table defined
+more than once. Lint rules can be configured by code in the [lint.rules]
+section of lockplane.toml; parse and validation errors are always reported.
+
+Examples:
+lockplane rules --list
+lockplane rules --list --format json
+`,
+	Run: runRules,
+}
+
+func runRules(cmd *cobra.Command, args []string) {
+	if !rulesList {
+		_ = cmd.Help()
+		return
+	}
+
+	rules := schema.Rules()
+	switch rulesFormat {
+	case "json":
+		printJSON(rules)
+	case "text":
+		printRulesText(rules)
+	default:
+		log.Fatalf("Unknown output format %q: expected text or json", rulesFormat)
+	}
+}
+
+// printRulesText prints one rule per line: code, default severity and
+// description. Codes that can't be configured are marked.
+func printRulesText(rules []schema.Rule) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tSEVERITY\tDESCRIPTION")
+	for _, rule := range rules {
+		description := rule.Description
+		if !rule.Configurable {
+			description += " (always reported)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rule.Code, rule.Severity, description)
+	}
+	_ = w.Flush()
+}
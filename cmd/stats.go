@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lockplane/lockplane/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var statsFormat string
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().StringVar(&statsFormat, "format", "text", "Output format: text or json")
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [schema dir or .lp.sql file]",
+	Short: "Count the kinds of statements in schema files",
+	Long: `Count the statements in .lp.sql schema files by kind, as PostgreSQL's parser
+names them (CreateStmt, AlterTableStmt, IndexStmt, ...), most common first.
+Every statement is counted, including those lockplane doesn't model, which
+shows what a schema is made of. Use lockplane check --coverage to see how much
+of it lockplane models.
+
+Examples:
+lockplane stats schema/
+lockplane stats --format json schema/
+`,
+	Args: cobra.ExactArgs(1),
+	Run:  runStats,
+}
+
+func runStats(cmd *cobra.Command, args []string) {
+	if statsFormat != "text" && statsFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", statsFormat)
+	}
+	if err := printStats(args[0], statsFormat, os.Stdout); err != nil {
+		log.Fatalf("Failed to count statements: %v", err)
+	}
+}
+
+// printStats prints the statement kinds of the schema files at path as a
+// text histogram or JSON
+func printStats(path string, format string, w io.Writer) error {
+	stats, err := schema.CountStatementKinds(path)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	width := len("Total")
+	for _, kind := range stats.Kinds {
+		width = max(width, len(kind.Kind))
+	}
+	for _, kind := range stats.Kinds {
+		if _, err := fmt.Fprintf(w, "%-*s  %d\n", width, kind.Kind, kind.Count); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "%-*s  %d\n", width, "Total", stats.Statements)
+	return err
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+func TestPrintStats(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);
+CREATE INDEX ON users (email);
+CREATE FUNCTION now_utc() RETURNS timestamp AS 'SELECT now()' LANGUAGE sql;`,
+		"b.lp.sql": `CREATE TABLE posts (id BIGINT PRIMARY KEY);
+ALTER TABLE posts ADD COLUMN author_id BIGINT;
+CREATE INDEX ON posts (author_id);
+GRANT SELECT ON posts TO reader;`,
+	})
+
+	var out bytes.Buffer
+	if err := printStats(dir, "text", &out); err != nil {
+		t.Fatalf("printStats failed: %v", err)
+	}
+	expected := `CreateStmt          2
+IndexStmt           2
+AlterTableStmt      1
+CreateFunctionStmt  1
+GrantStmt           1
+Total               7
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := printStats(dir, "json", &out); err != nil {
+		t.Fatalf("printStats failed: %v", err)
+	}
+	var stats schema.StatementStats
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if stats.Statements != 7 || len(stats.Kinds) != 5 {
+		t.Fatalf("Expected 7 statements of 5 kinds, got %+v", stats)
+	}
+	if !reflect.DeepEqual(stats.Kinds[0], schema.StatementKindCount{Kind: "CreateStmt", Count: 2}) {
+		t.Errorf("Expected CreateStmt to be listed first, got %+v", stats.Kinds[0])
+	}
+}
+
+func TestPrintStatsParseError(t *testing.T) {
+	dir := writeSchemaDir(t, map[string]string{
+		"a.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\nCREATE TABLE;",
+	})
+
+	var out bytes.Buffer
+	if err := printStats(dir, "text", &out); err == nil {
+		t.Error("Expected a parse error")
+	}
+}
@@ -10,13 +10,49 @@ import (
 	"github.com/pelletier/go-toml/v2"
 )
 
+// ErrConfigNotFound is returned when no lockplane.toml is found in the current
+// directory or its parents.
+var ErrConfigNotFound = errors.New("lockplane.toml not found")
+
 // EnvironmentConfig describes a single named environment from lockplane.toml.
 type EnvironmentConfig struct {
-	PostgresURL string `toml:"postgres_url"`
+	PostgresURL string `toml:"postgres_url" description:"PostgreSQL connection URL"`
+}
+
+// LintConfig configures the lint rules run by lockplane check.
+type LintConfig struct {
+	NamingPolicy NamingPolicyConfig `toml:"naming_policy" description:"Patterns that table and column names must match"`
+	ColumnTypes  ColumnTypesConfig  `toml:"column_types" description:"Options for the inconsistent-column-type rule"`
+	FlagColumns  FlagColumnsConfig  `toml:"flag_columns" description:"Options for the LP260 nullable flag rule"`
+	// Rules maps lint rule codes (e.g. "LP001" or "naming-policy") to a
+	// severity of "off", "warning" or "error"
+	Rules map[string]string `toml:"rules" description:"Severity of each lint rule by code: off, warning or error"`
+}
+
+// NamingPolicyConfig holds the regular expressions that table and column names
+// must match. An empty pattern disables the check for that kind of object.
+type NamingPolicyConfig struct {
+	Tables  string `toml:"tables" description:"Regular expression table names must match"`
+	Columns string `toml:"columns" description:"Regular expression column names must match"`
 }
 
+// ColumnTypesConfig configures the inconsistent-column-type rule, which is off
+// unless enabled in [lint.rules]
+type ColumnTypesConfig struct {
+	Columns string `toml:"columns" description:"Regular expression selecting the column names whose types must match across tables. Empty compares every column."`
+}
+
+// FlagColumnsConfig configures the LP260 rule, which is off unless enabled in
+// [lint.rules]
+type FlagColumnsConfig struct {
+	Columns string `toml:"columns" description:"Regular expression selecting the column names that are flags, besides boolean columns. Empty uses ^(is|has)_."`
+}
+
+// Config is the contents of lockplane.toml. The description tags document
+// each key in the JSON Schema printed by lockplane config-schema.
 type Config struct {
-	Environments   map[string]EnvironmentConfig `toml:"environments"`
+	Environments   map[string]EnvironmentConfig `toml:"environments" description:"Database environments by name. Only \"local\" is used for now."`
+	Lint           LintConfig                   `toml:"lint" description:"Configuration for the lint rules run by lockplane check"`
 	ConfigFilePath string                       `toml:"-"`
 }
 
@@ -36,12 +72,32 @@ func PrintLoadConfigErrorDetails(err error, t *testing.T) {
 	}
 }
 
+// LoadConfig loads the lockplane.toml found in the current directory or its
+// parents
 func LoadConfig() (*Config, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return nil, err
 	}
+	return LoadConfigFile(configPath)
+}
 
+// LoadConfigFrom loads the lockplane.toml found in startDir or its parents,
+// stopping at the project root
+func LoadConfigFrom(startDir string) (*Config, error) {
+	startDir, err := filepath.Abs(startDir)
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := findConfigPath(startDir)
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfigFile(configPath)
+}
+
+// LoadConfigFile loads the config file at configPath
+func LoadConfigFile(configPath string) (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, err
@@ -61,7 +117,11 @@ func getConfigPath() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return findConfigPath(startDir)
+}
 
+// findConfigPath searches startDir and its parents for lockplane.toml
+func findConfigPath(startDir string) (string, error) {
 	dir := startDir
 	for {
 		// Check if lockplane.toml exists in current directory
@@ -84,7 +144,7 @@ func getConfigPath() (string, error) {
 		dir = parent
 	}
 
-	return "", fmt.Errorf("lockplane.toml not found")
+	return "", ErrConfigNotFound
 }
 
 // isProjectRoot checks if the directory is a project root based on common markers
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -135,6 +136,90 @@ func TestLoadConfigNoFileReturnsError(t *testing.T) {
 	if err == nil {
 		t.Fatalf("LoadConfig returned nil error, expected error")
 	}
+	if !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
+
+func TestLoadConfigLintNamingPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	configContent := exampleConfig + `
+
+[lint.naming_policy]
+tables = "^tbl_"
+columns = "^[a-z_]+$"
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "lockplane.toml"), []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cleanup := changeToDir(t, tempDir)
+	defer cleanup()
+
+	config, err := LoadConfig()
+	if err != nil {
+		PrintLoadConfigErrorDetails(err, t)
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	policy := config.Lint.NamingPolicy
+	if policy.Tables != "^tbl_" {
+		t.Errorf("Expected tables pattern ^tbl_, got %q", policy.Tables)
+	}
+	if policy.Columns != "^[a-z_]+$" {
+		t.Errorf("Expected columns pattern ^[a-z_]+$, got %q", policy.Columns)
+	}
+}
+
+func TestLoadConfigLintRules(t *testing.T) {
+	tempDir := t.TempDir()
+	configContent := exampleConfig + `
+
+[lint.rules]
+LP001 = "off"
+LP202 = "error"
+`
+	configPath := filepath.Join(tempDir, "lockplane.toml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := LoadConfigFile(configPath)
+	if err != nil {
+		PrintLoadConfigErrorDetails(err, t)
+		t.Fatalf("LoadConfigFile returned error: %v", err)
+	}
+
+	if config.Lint.Rules["LP001"] != "off" || config.Lint.Rules["LP202"] != "error" {
+		t.Errorf("Unexpected lint rules %v", config.Lint.Rules)
+	}
+	if config.ConfigFilePath != configPath {
+		t.Errorf("Expected ConfigFilePath=%q, got %q", configPath, config.ConfigFilePath)
+	}
+}
+
+func TestLoadConfigFromSchemaDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "lockplane.toml"), []byte(exampleConfig), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tempDir, ".git"), 0o755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	schemaDir := filepath.Join(tempDir, "db", "schema")
+	if err := os.MkdirAll(schemaDir, 0o755); err != nil {
+		t.Fatalf("Failed to create schema dir: %v", err)
+	}
+
+	config, err := LoadConfigFrom(schemaDir)
+	if err != nil {
+		t.Fatalf("LoadConfigFrom returned error: %v", err)
+	}
+	compareConfigPaths(t, filepath.Join(tempDir, "lockplane.toml"), config.ConfigFilePath)
+
+	if _, err := LoadConfigFrom(t.TempDir()); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound outside the project, got %v", err)
+	}
 }
 
 func TestLoadConfigStopsAtGitRoot(t *testing.T) {
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("JSONSchema output is not valid JSON: %v", err)
+	}
+
+	if schema["$schema"] != "http://json-schema.org/draft-07/schema#" {
+		t.Errorf("Expected a draft-07 $schema, got %v", schema["$schema"])
+	}
+
+	// property walks nested object schemas: property(s, "lint", "naming_policy")
+	property := func(s map[string]any, path ...string) map[string]any {
+		t.Helper()
+		for _, name := range path {
+			if s["type"] != "object" {
+				t.Fatalf("Expected an object schema containing %q, got %v", name, s)
+			}
+			properties, _ := s["properties"].(map[string]any)
+			next, ok := properties[name].(map[string]any)
+			if !ok {
+				t.Fatalf("Expected property %q in %v", name, s)
+			}
+			s = next
+		}
+		return s
+	}
+
+	environment, ok := property(schema, "environments")["additionalProperties"].(map[string]any)
+	if !ok {
+		t.Fatal("Expected environments to allow any environment name")
+	}
+	if url := property(environment, "postgres_url"); url["type"] != "string" || url["description"] == nil {
+		t.Errorf("Expected a described string postgres_url, got %v", url)
+	}
+
+	for _, key := range []string{"tables", "columns"} {
+		if p := property(schema, "lint", "naming_policy", key); p["type"] != "string" {
+			t.Errorf("Expected lint.naming_policy.%s to be a string, got %v", key, p)
+		}
+	}
+
+	if properties := schema["properties"].(map[string]any); properties["ConfigFilePath"] != nil {
+		t.Error("Expected fields not read from the config file to be omitted")
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("Expected unknown top-level keys to be rejected")
+	}
+}
@@ -1,14 +1,52 @@
 package database
 
+import "fmt"
+
 // Dialect represents the database dialect associated with a schema
 type Dialect string
 
-const DialectPostgres Dialect = "postgres"
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+	DialectMySQL    Dialect = "mysql"
+)
 
 // Schema represents a database schema
 type Schema struct {
-	Tables  []Table `json:"tables"`
-	Dialect Dialect `json:"dialect,omitempty"`
+	Tables  []Table  `json:"tables"`
+	Domains []Domain `json:"domains,omitempty"`
+	Enums   []Enum   `json:"enums,omitempty"`
+	Views   []View   `json:"views,omitempty"`
+	// Schemas lists the schemas created with CREATE SCHEMA, in order, and
+	// SchemaLocations where each was created
+	Schemas         []string                   `json:"schemas,omitempty"`
+	SchemaLocations map[string]*SourceLocation `json:"schema_locations,omitempty"`
+	// Extensions lists the extensions created with CREATE EXTENSION, in order
+	Extensions []Extension `json:"extensions,omitempty"`
+	// Sequences lists the sequences created with CREATE SEQUENCE. Sequences
+	// created implicitly for serial and identity columns aren't listed.
+	Sequences []Sequence `json:"sequences,omitempty"`
+	// OtherObjects records objects lockplane recognizes but doesn't model,
+	// such as custom aggregates and operators
+	OtherObjects []ObjectRef `json:"other_objects,omitempty"`
+	Dialect      Dialect     `json:"dialect,omitempty"`
+}
+
+// SourceLocation identifies where an object was defined in the schema files.
+// Line and Column are 1-based.
+type SourceLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// EndLine and EndColumn locate the position just past the located text,
+	// such as a table's name or a whole CREATE INDEX statement. They are zero
+	// when only the start is known.
+	EndLine   int `json:"end_line,omitempty"`
+	EndColumn int `json:"end_column,omitempty"`
+}
+
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
 }
 
 // Table represents a database table
@@ -16,12 +54,98 @@ type Table struct {
 	Name    string   `json:"name"`
 	Schema  string   `json:"schema,omitempty"` // Schema name (e.g., "public", "storage")
 	Columns []Column `json:"columns"`
-	// Indexes     []Index      `json:"indexes"`
-	// ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
-	RLSEnabled bool `json:"rls_enabled"`
-	// Policies    []Policy     `json:"policies,omitempty"` // Row Level Security policies
+	// PrimaryKey lists the primary key columns in declaration order, whether
+	// the key was declared inline on a column or as a table constraint.
+	PrimaryKey []string `json:"primary_key,omitempty"`
+	// PrimaryKeyName is the name of the primary key constraint, <table>_pkey
+	// unless the constraint was named
+	PrimaryKeyName string `json:"primary_key_name,omitempty"`
+	// PrimaryKeyIndex names the existing index the primary key was added with
+	// (ADD PRIMARY KEY USING INDEX)
+	PrimaryKeyIndex string  `json:"primary_key_index,omitempty"`
+	Indexes         []Index `json:"indexes,omitempty"`
+	// CheckConstraints holds both table and column CHECK constraints
+	CheckConstraints []CheckConstraint `json:"check_constraints,omitempty"`
+	// UniqueConstraints holds both table and column UNIQUE constraints
+	UniqueConstraints []UniqueConstraint `json:"unique_constraints,omitempty"`
+	ForeignKeys       []ForeignKey       `json:"foreign_keys,omitempty"`
+	// ExclusionConstraints holds the EXCLUDE constraints, which can only be
+	// declared as table constraints
+	ExclusionConstraints []ExclusionConstraint `json:"exclusion_constraints,omitempty"`
+	RLSEnabled           bool                  `json:"rls_enabled"`
+	// WithOids is set for tables created WITH (OIDS), which PostgreSQL 12 and
+	// later reject
+	WithOids bool `json:"with_oids,omitempty"`
+	// Options holds the storage parameters set with WITH (...) or ALTER TABLE
+	// ... SET (...), e.g. "fillfactor": "70". Parameters in a namespace are
+	// keyed with it, as in "toast.autovacuum_enabled".
+	Options map[string]string `json:"options,omitempty"`
+	// Persistence is set for tables created UNLOGGED or TEMPORARY, and is
+	// empty for ordinary tables
+	Persistence TablePersistence `json:"persistence,omitempty"`
+	// Comment is the text set with COMMENT ON TABLE
+	Comment string `json:"comment,omitempty"`
+	// RawName is the name as written in CREATE TABLE, like Column.RawName,
+	// without the schema
+	RawName string `json:"raw_name,omitempty"`
+	// RawSchema is the schema as written in CREATE TABLE, with its quotes if
+	// it was quoted, or empty if the name wasn't schema-qualified
+	RawSchema string `json:"raw_schema,omitempty"`
+	// Policies lists the row level security policies created with CREATE
+	// POLICY
+	Policies []Policy `json:"policies,omitempty"`
+	// LikeClauses records the LIKE clauses the table was created with. Their
+	// columns have already been copied into Columns.
+	LikeClauses []LikeClause `json:"like_clauses,omitempty"`
+	// Inherits lists the parents named in an INHERITS clause
+	Inherits []TableRef `json:"inherits,omitempty"`
+	// PartitionBy is set for partitioned tables, created with PARTITION BY
+	PartitionBy *PartitionSpec `json:"partition_by,omitempty"`
+	// PartitionOf is set for partitions, created with PARTITION OF or
+	// attached with ALTER TABLE ... ATTACH PARTITION
+	PartitionOf *TableRef `json:"partition_of,omitempty"`
+	// Partitions lists the partitions of a partitioned table
+	Partitions     []TableRef      `json:"partitions,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// TableRef names a table by schema and name. An empty schema means the public
+// schema.
+type TableRef struct {
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table"`
 }
 
+// PartitionSpec is the PARTITION BY clause of a partitioned table
+type PartitionSpec struct {
+	Strategy PartitionStrategy `json:"strategy"`
+	// Columns lists the partition key in order. A key that is an expression
+	// rather than a column is recorded as its SQL, as in "lower(email)".
+	Columns []string `json:"columns"`
+}
+
+// PartitionStrategy is how a partitioned table's rows are divided between its
+// partitions
+type PartitionStrategy string
+
+const (
+	PartitionStrategyRange PartitionStrategy = "RANGE"
+	PartitionStrategyList  PartitionStrategy = "LIST"
+	PartitionStrategyHash  PartitionStrategy = "HASH"
+)
+
+// TablePersistence is whether a table's rows are written to the WAL and
+// outlive the session that created them
+type TablePersistence string
+
+const (
+	// TablePersistenceUnlogged tables aren't written to the WAL, so they are
+	// truncated after a crash and aren't replicated
+	TablePersistenceUnlogged TablePersistence = "unlogged"
+	// TablePersistenceTemporary tables are dropped at the end of the session
+	TablePersistenceTemporary TablePersistence = "temporary"
+)
+
 // Column represents a table column
 type Column struct {
 	Name         string  `json:"name"`
@@ -29,6 +153,286 @@ type Column struct {
 	Nullable     bool    `json:"nullable"`
 	Default      *string `json:"default,omitempty"`
 	IsPrimaryKey bool    `json:"is_primary_key"`
+	// NotNullExplicit is set when the column was declared NOT NULL, as
+	// opposed to being NOT NULL only because it is part of the primary key
+	// or an identity column. Introspected columns don't record it.
+	NotNullExplicit bool `json:"not_null_explicit,omitempty"`
+	// Precision and Scale are the modifiers of a numeric/decimal column, and
+	// Length that of a varchar/char column. They mirror the modifiers in Type.
+	Precision *int `json:"precision,omitempty"`
+	Scale     *int `json:"scale,omitempty"`
+	Length    *int `json:"length,omitempty"`
+	// ArrayDims lists the dimensions of an array column, with the declared
+	// size of each or -1 when it has none. It mirrors the brackets in Type.
+	ArrayDims []int         `json:"array_dims,omitempty"`
+	Identity  *IdentitySpec `json:"identity,omitempty"`
+	// Generated is set for GENERATED ALWAYS AS (...) columns, whose values
+	// are computed and which have no writable default
+	Generated *GeneratedColumn `json:"generated,omitempty"`
+	// Comment is the text set with COMMENT ON COLUMN
+	Comment string `json:"comment,omitempty"`
+	// RawName is the name as written in the schema file, with its quotes if it
+	// was quoted. PostgreSQL folds unquoted names to lowercase, so Name is
+	// "orders" for both Orders and orders, but "Orders" for "Orders". Empty
+	// for columns that weren't parsed from SQL.
+	RawName        string          `json:"raw_name,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// IdentitySpec describes a GENERATED ... AS IDENTITY column
+type IdentitySpec struct {
+	// Always is set for GENERATED ALWAYS, and unset for GENERATED BY DEFAULT
+	Always bool `json:"always"`
+	// Sequence is the sequence that generates the column's values. Unless
+	// SEQUENCE NAME is given, PostgreSQL creates <table>_<column>_seq in the
+	// table's schema.
+	Sequence string `json:"sequence,omitempty"`
+	// The sequence options given in the column definition. Options that
+	// weren't given are nil (or false for Cycle) and take PostgreSQL's
+	// defaults.
+	Start     *int64 `json:"start,omitempty"`
+	Increment *int64 `json:"increment,omitempty"`
+	MinValue  *int64 `json:"min_value,omitempty"`
+	MaxValue  *int64 `json:"max_value,omitempty"`
+	Cache     *int64 `json:"cache,omitempty"`
+	Cycle     bool   `json:"cycle,omitempty"`
+}
+
+// Storage kinds of a generated column
+const (
+	GeneratedStored  = "STORED"
+	GeneratedVirtual = "VIRTUAL"
+)
+
+// GeneratedColumn describes a GENERATED ALWAYS AS (expression) column
+type GeneratedColumn struct {
+	Expression string `json:"expression"`
+	// Storage is GeneratedStored or GeneratedVirtual
+	Storage string `json:"storage"`
+}
+
+// Index represents an index on a table
+type Index struct {
+	Name   string `json:"name"`
+	Unique bool   `json:"unique,omitempty"`
+	Method string `json:"method,omitempty"` // Access method (e.g., "btree", "gin")
+	// Columns lists the indexed columns in order. Expression elements (e.g.
+	// lower(email)) are listed separately in Expressions.
+	Columns     []string `json:"columns,omitempty"`
+	Expressions []string `json:"expressions,omitempty"`
+	// Include lists the non-key columns of a covering index (INCLUDE (...))
+	Include []string `json:"include,omitempty"`
+	// Where is the predicate of a partial index rendered as SQL, or empty for
+	// an index on every row
+	Where string `json:"where,omitempty"`
+	// WhereExpr is the predicate as an expression tree, for analysis
+	WhereExpr      *Expr           `json:"where_expr,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// CheckConstraint represents a CHECK constraint on a table
+type CheckConstraint struct {
+	Name string `json:"name"`
+	// Expression is the condition rendered as SQL, for display
+	Expression string `json:"expression"`
+	// Expr is the condition as an expression tree, for analysis
+	Expr           *Expr           `json:"expr,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// UniqueConstraint represents a UNIQUE constraint on a table
+type UniqueConstraint struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	// Index names the existing index the constraint was added with (ADD
+	// CONSTRAINT ... UNIQUE USING INDEX). PostgreSQL renames the index to the
+	// constraint's name.
+	Index          string          `json:"index,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// ExclusionConstraint represents an EXCLUDE constraint, which rejects a row if
+// any existing row matches it on every element's operator
+type ExclusionConstraint struct {
+	Name string `json:"name"`
+	// Method is the index access method that enforces the constraint, e.g.
+	// "gist"
+	Method   string             `json:"method"`
+	Elements []ExclusionElement `json:"elements"`
+	// Where is the predicate of a partial constraint rendered as SQL, or
+	// empty if the constraint applies to every row
+	Where          string          `json:"where,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// ExclusionElement is one element of an EXCLUDE constraint: a column or
+// expression and the operator rows are compared with
+type ExclusionElement struct {
+	// Column is the compared column, or empty for an expression element
+	Column     string `json:"column,omitempty"`
+	Expression string `json:"expression,omitempty"`
+	// OpClass is the element's operator class, e.g. "gist_int4_ops", or empty
+	// for the type's default. A schema-qualified name is kept as
+	// "schema.name".
+	OpClass string `json:"opclass,omitempty"`
+	// Operator is the comparison operator, e.g. "=" or "&&", qualified as
+	// "schema.op" when written OPERATOR(schema.op)
+	Operator string `json:"operator"`
+}
+
+// ForeignKey represents a FOREIGN KEY (or column REFERENCES) constraint
+type ForeignKey struct {
+	Name string `json:"name"`
+	// Columns lists the referencing columns of the table the key is declared on
+	Columns          []string `json:"columns"`
+	ReferencedSchema string   `json:"referenced_schema,omitempty"`
+	ReferencedTable  string   `json:"referenced_table"`
+	// ReferencedColumns is empty when the key references the primary key of
+	// the referenced table implicitly, e.g. REFERENCES users
+	ReferencedColumns []string `json:"referenced_columns,omitempty"`
+	// OnDelete is the ON DELETE action, empty for the default NO ACTION
+	OnDelete FKAction `json:"on_delete,omitempty"`
+	// OnDeleteColumns lists the columns ON DELETE SET NULL (columns) or SET
+	// DEFAULT (columns) applies to. Empty means every column of the key.
+	OnDeleteColumns []string `json:"on_delete_columns,omitempty"`
+	// OnUpdate is the ON UPDATE action, empty for the default NO ACTION
+	OnUpdate FKAction `json:"on_update,omitempty"`
+	// Match is the MATCH type, empty for the default MATCH SIMPLE
+	Match FKMatch `json:"match,omitempty"`
+	// Deferrable is set for DEFERRABLE keys, and InitiallyDeferred for those
+	// checked at the end of the transaction by default (INITIALLY DEFERRED)
+	Deferrable        bool            `json:"deferrable,omitempty"`
+	InitiallyDeferred bool            `json:"initially_deferred,omitempty"`
+	SourceLocation    *SourceLocation `json:"source_location,omitempty"`
+}
+
+// FKAction is the referential action a foreign key takes when the row it
+// references is deleted or has its key updated
+type FKAction string
+
+const (
+	FKActionNoAction   FKAction = "NO ACTION"
+	FKActionRestrict   FKAction = "RESTRICT"
+	FKActionCascade    FKAction = "CASCADE"
+	FKActionSetNull    FKAction = "SET NULL"
+	FKActionSetDefault FKAction = "SET DEFAULT"
+)
+
+// FKMatch is how a foreign key matches multicolumn keys with null columns
+type FKMatch string
+
+const (
+	// FKMatchSimple lets any column of the key be null, in which case the row
+	// isn't checked
+	FKMatchSimple FKMatch = "SIMPLE"
+	// FKMatchFull requires the columns to be all null or all non-null
+	FKMatchFull FKMatch = "FULL"
+	// FKMatchPartial is accepted by PostgreSQL's grammar but not implemented
+	FKMatchPartial FKMatch = "PARTIAL"
+)
+
+// LikeClause records a CREATE TABLE ... (LIKE source INCLUDING ...) clause
+type LikeClause struct {
+	Schema string `json:"schema,omitempty"`
+	Table  string `json:"table"`
+	// Including lists the copied attributes (e.g. "DEFAULTS", "INDEXES"), or
+	// just "ALL" when everything was included
+	Including      []string        `json:"including,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// Policy represents a row level security policy (CREATE POLICY)
+type Policy struct {
+	Name string `json:"name"`
+	// Command is the command the policy applies to: "ALL", "SELECT",
+	// "INSERT", "UPDATE" or "DELETE"
+	Command string `json:"command"`
+	// Permissive is unset for AS RESTRICTIVE policies
+	Permissive bool `json:"permissive"`
+	// Roles lists the roles the policy applies to, "public" for PUBLIC
+	Roles []string `json:"roles,omitempty"`
+	// Using and WithCheck are the USING and WITH CHECK expressions rendered
+	// as SQL, or empty when not given
+	Using          string          `json:"using,omitempty"`
+	WithCheck      string          `json:"with_check,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// Domain represents a user-defined domain (CREATE DOMAIN), a named data type
+// layered over a base type with optional constraints
+type Domain struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema,omitempty"`
+	// BaseType is the type the domain was declared over, which may itself be
+	// another domain
+	BaseType string  `json:"base_type"`
+	NotNull  bool    `json:"not_null,omitempty"`
+	Default  *string `json:"default,omitempty"`
+	// CheckConstraints are the domain's CHECK constraints, whose expressions
+	// refer to the value being checked as value
+	CheckConstraints []CheckConstraint `json:"check_constraints,omitempty"`
+	SourceLocation   *SourceLocation   `json:"source_location,omitempty"`
+}
+
+// Extension represents an extension the schema installs (CREATE EXTENSION)
+type Extension struct {
+	Name string `json:"name"`
+	// Schema is the schema named with WITH SCHEMA, which the extension's
+	// objects are created in. It is empty if none was named, in which case
+	// they are created in the first schema on the search path.
+	Schema         string          `json:"schema,omitempty"`
+	IfNotExists    bool            `json:"if_not_exists,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// Sequence is a sequence created with CREATE SEQUENCE
+type Sequence struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema,omitempty"`
+	// Start and Increment are nil when not given, in which case the sequence
+	// starts at 1 and counts up by 1
+	Start          *int64          `json:"start,omitempty"`
+	Increment      *int64          `json:"increment,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// Enum represents an enumerated type (CREATE TYPE ... AS ENUM)
+type Enum struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema,omitempty"`
+	// Values lists the enum's labels in declaration order, which is also their
+	// sort order
+	Values         []string        `json:"values"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// View represents a view (CREATE VIEW) or materialized view (CREATE
+// MATERIALIZED VIEW)
+type View struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema,omitempty"`
+	// Query is the view's defining query, deparsed
+	Query string `json:"query"`
+	// Materialized is set for materialized views, which store their rows and
+	// can be indexed
+	Materialized bool `json:"materialized,omitempty"`
+	// WithData is set for materialized views populated when they are
+	// created, i.e. without WITH NO DATA. A view created WITH NO DATA can't
+	// be queried until it is refreshed.
+	WithData bool `json:"with_data,omitempty"`
+	// Indexes are the indexes on a materialized view
+	Indexes        []Index         `json:"indexes,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// ObjectRef records a schema object that lockplane tracks without modeling
+// its definition
+type ObjectRef struct {
+	// Kind is the type of object, e.g. "aggregate" or "operator"
+	Kind           string          `json:"kind"`
+	Name           string          `json:"name"`
+	Schema         string          `json:"schema,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
 }
 
 // represent the type of database for a connection
@@ -0,0 +1,111 @@
+package database
+
+import "strings"
+
+// ExprKind identifies the kind of node in an Expr tree
+type ExprKind string
+
+const (
+	// ExprOperator applies Op to Args, e.g. price > 0, a AND b, NOT a,
+	// x IS NULL, x IN (1, 2) or x BETWEEN 1 AND 2
+	ExprOperator ExprKind = "operator"
+	// ExprColumn references the column Name
+	ExprColumn ExprKind = "column"
+	// ExprLiteral is a constant Value of type LiteralType
+	ExprLiteral ExprKind = "literal"
+	// ExprFunction calls the function Name with Args
+	ExprFunction ExprKind = "function"
+	// ExprCast casts Args[0] to Type
+	ExprCast ExprKind = "cast"
+	// ExprOther is any expression lockplane doesn't break down. Only Text is
+	// set.
+	ExprOther ExprKind = "other"
+)
+
+// Literal types of an ExprLiteral
+const (
+	LiteralInteger   = "integer"
+	LiteralFloat     = "float"
+	LiteralString    = "string"
+	LiteralBoolean   = "boolean"
+	LiteralBitString = "bitstring"
+	LiteralNull      = "null"
+)
+
+// Expr is a simplified expression tree, covering the operators, column
+// references, literals and function calls that appear in constraints
+type Expr struct {
+	Kind ExprKind `json:"kind"`
+	Op   string   `json:"op,omitempty"`
+	// Name is the column or function name
+	Name        string  `json:"name,omitempty"`
+	Value       string  `json:"value,omitempty"`
+	LiteralType string  `json:"literal_type,omitempty"`
+	Type        string  `json:"type,omitempty"`
+	Args        []*Expr `json:"args,omitempty"`
+	Text        string  `json:"text,omitempty"`
+}
+
+// String renders the expression as SQL. Nested operators are parenthesized,
+// so the result doesn't depend on operator precedence.
+func (e *Expr) String() string {
+	switch e.Kind {
+	case ExprColumn:
+		return e.Name
+
+	case ExprLiteral:
+		switch e.LiteralType {
+		case LiteralString:
+			return "'" + strings.ReplaceAll(e.Value, "'", "''") + "'"
+		case LiteralNull:
+			return "NULL"
+		}
+		return e.Value
+
+	case ExprFunction:
+		return e.Name + "(" + joinExprs(e.Args, ", ") + ")"
+
+	case ExprCast:
+		return e.Args[0].operand() + "::" + e.Type
+
+	case ExprOperator:
+		switch e.Op {
+		case "IS NULL", "IS NOT NULL":
+			return e.Args[0].operand() + " " + e.Op
+		case "IN", "NOT IN":
+			return e.Args[0].operand() + " " + e.Op + " (" + joinExprs(e.Args[1:], ", ") + ")"
+		case "BETWEEN", "NOT BETWEEN":
+			return e.Args[0].operand() + " " + e.Op + " " + e.Args[1].operand() + " AND " + e.Args[2].operand()
+		}
+
+		if len(e.Args) == 1 {
+			if e.Op == "NOT" {
+				return "NOT " + e.Args[0].operand()
+			}
+			return e.Op + e.Args[0].operand()
+		}
+		operands := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			operands[i] = arg.operand()
+		}
+		return strings.Join(operands, " "+e.Op+" ")
+	}
+
+	return e.Text
+}
+
+// operand renders e for use inside another expression
+func (e *Expr) operand() string {
+	if e.Kind == ExprOperator {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+func joinExprs(exprs []*Expr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = expr.String()
+	}
+	return strings.Join(parts, sep)
+}
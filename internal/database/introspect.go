@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Introspect reads the tables of the named schemas in a PostgreSQL database,
+// or of every user schema when none are named. The result has the same shape
+// the .lp.sql parser produces, with column types normalized the same way, so
+// the two can be diffed directly.
+func Introspect(ctx context.Context, db *sql.DB, schemaNames ...string) (*Schema, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, c.relrowsecurity, COALESCE(obj_description(c.oid, 'pg_class'), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('r', 'p')
+		  AND NOT c.relispartition
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_toast%'
+		  AND n.nspname NOT LIKE 'pg_temp%'
+		  AND (cardinality($1::text[]) = 0 OR n.nspname = ANY($1))
+		ORDER BY n.nspname, c.relname
+	`, pq.Array(schemaNames))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+
+	tables := []Table{}
+	for rows.Next() {
+		var table Table
+		if err := rows.Scan(&table.Schema, &table.Name, &table.RLSEnabled, &table.Comment); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+	_ = rows.Close()
+
+	for i := range tables {
+		table := &tables[i]
+
+		table.Columns, err = introspectColumns(ctx, db, table.Schema, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns for table %s.%s: %w", table.Schema, table.Name, err)
+		}
+
+		table.PrimaryKey, err = introspectPrimaryKey(ctx, db, table.Schema, table.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get primary key for table %s.%s: %w", table.Schema, table.Name, err)
+		}
+		for _, name := range table.PrimaryKey {
+			for j := range table.Columns {
+				if table.Columns[j].Name == name {
+					table.Columns[j].IsPrimaryKey = true
+				}
+			}
+		}
+	}
+
+	return &Schema{
+		Tables:  tables,
+		Dialect: DialectPostgres,
+	}, nil
+}
+
+// introspectColumns returns the columns of a table in declaration order
+func introspectColumns(ctx context.Context, db *sql.DB, schemaName string, tableName string) ([]Column, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			a.attname,
+			format_type(a.atttypid, a.atttypmod),
+			NOT a.attnotnull,
+			pg_get_expr(d.adbin, d.adrelid),
+			pg_get_serial_sequence(quote_ident(n.nspname) || '.' || quote_ident(c.relname), a.attname),
+			a.attidentity,
+			a.attgenerated,
+			COALESCE(col_description(c.oid, a.attnum), '')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = $1
+		  AND c.relname = $2
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns := []Column{}
+	for rows.Next() {
+		var col Column
+		var formattedType string
+		var defaultVal sql.NullString
+		var ownedSequence sql.NullString
+		var identity string
+		var generated string
+
+		if err := rows.Scan(&col.Name, &formattedType, &col.Nullable, &defaultVal, &ownedSequence, &identity, &generated, &col.Comment); err != nil {
+			return nil, err
+		}
+
+		col.Type = normalizeIntrospectedType(formattedType)
+		col.Precision, col.Scale, col.Length = TypeModifiers(col.Type)
+		col.ArrayDims = ArrayDims(col.Type)
+		if defaultVal.Valid {
+			col.Default = &defaultVal.String
+		}
+
+		// The expression of a generated column is stored as its default
+		if generated != "" && col.Default != nil {
+			col.Generated = &GeneratedColumn{Expression: *col.Default, Storage: GeneratedStored}
+			if generated == "v" {
+				col.Generated.Storage = GeneratedVirtual
+			}
+			col.Default = nil
+		}
+
+		// A serial column is an integer column whose default draws from a
+		// sequence it owns. Report it the way it would be declared.
+		if ownedSequence.Valid && !col.Nullable && col.Default != nil && strings.HasPrefix(*col.Default, "nextval(") {
+			for serial, integer := range serialTypes {
+				if col.Type == integer {
+					col.Type = serial
+					col.Default = nil
+					break
+				}
+			}
+		}
+
+		// Identity columns own their sequence too, but have no default
+		if identity != "" {
+			col.Identity = &IdentitySpec{
+				Always:   identity == "a",
+				Sequence: unqualifiedSequenceName(ownedSequence.String, schemaName),
+			}
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+// unqualifiedSequenceName strips the schema from a sequence name reported by
+// pg_get_serial_sequence when it is the table's own schema, matching how the
+// parser names the sequences of identity columns
+func unqualifiedSequenceName(sequence string, tableSchema string) string {
+	if rest, ok := strings.CutPrefix(sequence, tableSchema+"."); ok {
+		return strings.Trim(rest, `"`)
+	}
+	return sequence
+}
+
+// introspectPrimaryKey returns the primary key columns of a table in key order
+func introspectPrimaryKey(ctx context.Context, db *sql.DB, schemaName string, tableName string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT a.attname
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+		WHERE i.indisprimary
+		  AND n.nspname = $1
+		  AND c.relname = $2
+		ORDER BY k.ord
+	`, schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var primaryKey []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		primaryKey = append(primaryKey, name)
+	}
+
+	return primaryKey, rows.Err()
+}
+
+// normalizeIntrospectedType normalizes a type as reported by format_type (e.g.
+// "character varying(255)[]") to the spelling the parser uses ("varchar(255)[]").
+// The modifiers of time types come before the time zone ("timestamp(3) without
+// time zone"), so they are taken out wherever they are and put back the way
+// the parser places them.
+func normalizeIntrospectedType(formatted string) string {
+	base := formatted
+
+	arraySuffix := ""
+	for strings.HasSuffix(base, "[]") {
+		base = strings.TrimSuffix(base, "[]")
+		arraySuffix += "[]"
+	}
+
+	modifiers := ""
+	if open := strings.Index(base, "("); open != -1 {
+		if end := strings.Index(base[open:], ")"); end != -1 {
+			modifiers = base[open : open+end+1]
+			base = base[:open] + base[open+end+1:]
+		}
+	}
+
+	return WithTypeModifiers(NormalizePostgreSQLType(base), modifiers) + arraySuffix
+}
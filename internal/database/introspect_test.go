@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+func TestNormalizeIntrospectedType(t *testing.T) {
+	tests := []struct {
+		formatted string
+		expected  string
+	}{
+		{"integer", "integer"},
+		{"bigint", "bigint"},
+		{"boolean", "boolean"},
+		{"text", "text"},
+		{"character varying(255)", "varchar(255)"},
+		{"character varying", "varchar"},
+		{"character(2)", "char(2)"},
+		{"numeric(10,2)", "numeric(10,2)"},
+		{"timestamp with time zone", "timestamp with time zone"},
+		{"timestamp(3) without time zone", "timestamp(3) without time zone"},
+		{"time(0) with time zone[]", "time(0) with time zone[]"},
+		{"bit varying(8)", "varbit(8)"},
+		{"text[]", "text[]"},
+		{"character varying(50)[]", "varchar(50)[]"},
+		{"jsonb", "jsonb"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeIntrospectedType(tt.formatted); got != tt.expected {
+			t.Errorf("normalizeIntrospectedType(%q) = %q, expected %q", tt.formatted, got, tt.expected)
+		}
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	// Skip integration tests when running with -short flag
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dbUrl := os.Getenv("POSTGRES_URL")
+	if dbUrl == "" {
+		t.Skip("Skipping test: POSTGRES_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dbUrl+"?sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	if err := db.PingContext(ctx); err != nil {
+		t.Skipf("Skipping test: cannot connect to database: %v", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE SCHEMA test_introspect;
+		CREATE TABLE test_introspect.memberships (
+			id SERIAL,
+			org_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			role VARCHAR(20) NOT NULL DEFAULT 'member',
+			tags TEXT[],
+			PRIMARY KEY (org_id, user_id)
+		);
+		ALTER TABLE test_introspect.memberships ENABLE ROW LEVEL SECURITY;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	defer func() { _, _ = db.ExecContext(ctx, "DROP SCHEMA test_introspect CASCADE") }()
+
+	schema, err := Introspect(ctx, db)
+	if err != nil {
+		t.Fatalf("Introspect failed: %v", err)
+	}
+
+	var table *Table
+	for i := range schema.Tables {
+		if schema.Tables[i].Schema == "test_introspect" && schema.Tables[i].Name == "memberships" {
+			table = &schema.Tables[i]
+		}
+	}
+	if table == nil {
+		t.Fatal("Expected to find table test_introspect.memberships")
+	}
+
+	if !table.RLSEnabled {
+		t.Error("Expected RLS to be enabled")
+	}
+	if !reflect.DeepEqual(table.PrimaryKey, []string{"org_id", "user_id"}) {
+		t.Errorf("Expected primary key (org_id, user_id), got %v", table.PrimaryKey)
+	}
+
+	expectedTypes := map[string]string{
+		"id":      "serial",
+		"org_id":  "bigint",
+		"user_id": "bigint",
+		"role":    "varchar(20)",
+		"tags":    "text[]",
+	}
+	for _, col := range table.Columns {
+		if col.Type != expectedTypes[col.Name] {
+			t.Errorf("Expected column %s to have type %q, got %q", col.Name, expectedTypes[col.Name], col.Type)
+		}
+	}
+
+	role := table.Columns[3]
+	if role.Nullable || role.Default == nil {
+		t.Errorf("Expected role to be NOT NULL with a default, got %+v", role)
+	}
+	if !table.Columns[1].IsPrimaryKey || table.Columns[0].IsPrimaryKey {
+		t.Error("Expected only the primary key columns to be marked IsPrimaryKey")
+	}
+	if table.Columns[0].Default != nil {
+		t.Errorf("Expected the serial column's sequence default to be dropped, got %q", *table.Columns[0].Default)
+	}
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// JSONSchemaVersion is the version of the JSON Schema returned by JSONSchema.
+// It is increased when the JSON encoding of Schema changes in a way that can
+// break consumers, such as a property being removed or changing type. New
+// optional properties don't change it, so objects allow properties the schema
+// doesn't list.
+const JSONSchemaVersion = 1
+
+// typeDescriptions describes the model types in the JSON Schema
+var typeDescriptions = map[reflect.Type]string{
+	reflect.TypeFor[Schema]():              "A database schema: the tables, types and views defined by a set of schema files",
+	reflect.TypeFor[SourceLocation]():      "Where an object was defined in the schema files. Line and column are 1-based.",
+	reflect.TypeFor[Table]():               "A table, with its columns, indexes and constraints",
+	reflect.TypeFor[TableRef]():            "A table named by schema and name. An empty schema means the public schema.",
+	reflect.TypeFor[PartitionSpec]():       "The PARTITION BY clause of a partitioned table. An expression key is recorded as its SQL.",
+	reflect.TypeFor[Column]():              "A table column. type is the normalized PostgreSQL type name, with any modifiers and array brackets.",
+	reflect.TypeFor[IdentitySpec]():        "A GENERATED ... AS IDENTITY column's sequence",
+	reflect.TypeFor[GeneratedColumn]():     "A GENERATED ALWAYS AS (expression) column",
+	reflect.TypeFor[Index]():               "An index on a table or materialized view",
+	reflect.TypeFor[CheckConstraint]():     "A CHECK constraint",
+	reflect.TypeFor[UniqueConstraint]():    "A UNIQUE constraint",
+	reflect.TypeFor[ExclusionConstraint](): "An EXCLUDE constraint",
+	reflect.TypeFor[ExclusionElement]():    "An element of an EXCLUDE constraint and the operator rows are compared with",
+	reflect.TypeFor[ForeignKey]():          "A FOREIGN KEY or column REFERENCES constraint",
+	reflect.TypeFor[LikeClause]():          "A CREATE TABLE ... (LIKE source INCLUDING ...) clause",
+	reflect.TypeFor[Policy]():              "A row level security policy",
+	reflect.TypeFor[Domain]():              "A domain, a named data type over a base type with optional constraints",
+	reflect.TypeFor[Extension]():           "An extension installed with CREATE EXTENSION",
+	reflect.TypeFor[Enum]():                "An enumerated type",
+	reflect.TypeFor[View]():                "A view or materialized view",
+	reflect.TypeFor[ObjectRef]():           "An object lockplane recognizes without modeling its definition",
+	reflect.TypeFor[Expr]():                "A simplified expression tree. kind is operator, column, literal, function, cast or other.",
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the JSON
+// encoding of Schema, for tools that consume lockplane's parsed model. It is
+// generated from the json tags of the model, so properties tagged omitempty
+// are optional and the rest required. Each struct type is described once
+// under $defs.
+func JSONSchema() ([]byte, error) {
+	defs := map[string]any{}
+	root, err := modelSchemaFor(reflect.TypeFor[Schema](), defs)
+	if err != nil {
+		return nil, err
+	}
+	schema := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     fmt.Sprintf("urn:lockplane:schema:v%d", JSONSchemaVersion),
+		"title":   "lockplane schema",
+		"$ref":    root["$ref"],
+		"$defs":   defs,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// modelSchemaFor describes a Go type as encoding/json encodes it. Struct types
+// are added to defs and referenced, so recursive types such as Expr work.
+func modelSchemaFor(t reflect.Type, defs map[string]any) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Pointer:
+		return modelSchemaFor(t.Elem(), defs)
+
+	case reflect.Slice:
+		items, err := modelSchemaFor(t.Elem(), defs)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := modelSchemaFor(t.Elem(), defs)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok {
+			return ref, nil
+		}
+		// Reserve the name before describing the fields, which may refer back
+		// to this type
+		defs[t.Name()] = nil
+
+		properties := map[string]any{}
+		required := []string{}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			property, err := modelSchemaFor(field.Type, defs)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+			}
+			omitEmpty := strings.Contains(","+options+",", ",omitempty,")
+			if !omitEmpty {
+				required = append(required, name)
+				// encoding/json writes nil pointers, slices and maps as null
+				switch field.Type.Kind() {
+				case reflect.Pointer, reflect.Slice, reflect.Map:
+					property = map[string]any{"anyOf": []any{property, map[string]any{"type": "null"}}}
+				}
+			}
+			properties[name] = property
+		}
+
+		def := map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+		if description, ok := typeDescriptions[t]; ok {
+			def["description"] = description
+		}
+		defs[t.Name()] = def
+		return ref, nil
+	}
+
+	return nil, fmt.Errorf("unsupported model type %s", t)
+}
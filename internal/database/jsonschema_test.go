@@ -0,0 +1,86 @@
+package database
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("JSONSchema output is not valid JSON: %v", err)
+	}
+
+	if schema["$schema"] != "https://json-schema.org/draft/2020-12/schema" {
+		t.Errorf("Expected a draft 2020-12 $schema, got %v", schema["$schema"])
+	}
+	if schema["$id"] != "urn:lockplane:schema:v1" {
+		t.Errorf("Expected a versioned $id, got %v", schema["$id"])
+	}
+	if schema["$ref"] != "#/$defs/Schema" {
+		t.Errorf("Expected the root to be a Schema, got %v", schema["$ref"])
+	}
+
+	defs := schema["$defs"].(map[string]any)
+	def := func(name string) map[string]any {
+		t.Helper()
+		d, ok := defs[name].(map[string]any)
+		if !ok {
+			t.Fatalf("Expected a definition for %s", name)
+		}
+		return d
+	}
+	property := func(d map[string]any, name string) map[string]any {
+		t.Helper()
+		p, ok := d["properties"].(map[string]any)[name].(map[string]any)
+		if !ok {
+			t.Fatalf("Expected property %q in %v", name, d)
+		}
+		return p
+	}
+	required := func(d map[string]any) []string {
+		var names []string
+		for _, name := range d["required"].([]any) {
+			names = append(names, name.(string))
+		}
+		return names
+	}
+
+	table := def("Table")
+	if table["description"] == nil {
+		t.Error("Expected Table to be described")
+	}
+	if names := required(table); !slices.Contains(names, "name") || !slices.Contains(names, "columns") || slices.Contains(names, "schema") {
+		t.Errorf("Expected name and columns to be required and schema optional, got %v", names)
+	}
+	// Columns without omitempty can be null
+	columns := property(table, "columns")["anyOf"].([]any)
+	if items := columns[0].(map[string]any)["items"].(map[string]any); items["$ref"] != "#/$defs/Column" {
+		t.Errorf("Expected columns to be Column items, got %v", columns)
+	}
+
+	column := def("Column")
+	if p := property(column, "nullable"); p["type"] != "boolean" {
+		t.Errorf("Expected nullable to be a boolean, got %v", p)
+	}
+	if p := property(column, "array_dims"); p["type"] != "array" || p["items"].(map[string]any)["type"] != "integer" {
+		t.Errorf("Expected array_dims to be an integer array, got %v", p)
+	}
+	if slices.Contains(required(column), "default") {
+		t.Error("Expected default to be optional")
+	}
+
+	// Expr refers to itself
+	if items := property(def("Expr"), "args")["items"].(map[string]any); items["$ref"] != "#/$defs/Expr" {
+		t.Errorf("Expected args to be Expr items, got %v", items)
+	}
+	if p := property(def("Schema"), "schema_locations"); p["additionalProperties"].(map[string]any)["$ref"] != "#/$defs/SourceLocation" {
+		t.Errorf("Expected schema_locations to map names to SourceLocations, got %v", p)
+	}
+}
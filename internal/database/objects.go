@@ -0,0 +1,115 @@
+package database
+
+import "strings"
+
+// ObjectGraph lists the objects of a schema with stable IDs, and the foreign
+// keys between them by those IDs, for tools that build graphs of a schema
+type ObjectGraph struct {
+	Objects     []SchemaObject   `json:"objects"`
+	ForeignKeys []ForeignKeyEdge `json:"foreign_keys"`
+}
+
+// SchemaObject is an object in an ObjectGraph. Parent is the ID of the table
+// a column, index or constraint belongs to.
+type SchemaObject struct {
+	ID             string          `json:"id"`
+	Kind           string          `json:"kind"`
+	Schema         string          `json:"schema"`
+	Name           string          `json:"name"`
+	Parent         string          `json:"parent,omitempty"`
+	SourceLocation *SourceLocation `json:"source_location,omitempty"`
+}
+
+// ForeignKeyEdge links the columns of a foreign key to the columns they
+// reference, by object ID
+type ForeignKeyEdge struct {
+	ID                string   `json:"id"`
+	From              string   `json:"from"`
+	To                string   `json:"to"`
+	Columns           []string `json:"columns"`
+	ReferencedColumns []string `json:"referenced_columns"`
+}
+
+// ObjectID returns the stable ID of an object: its kind followed by its
+// schema-qualified name, e.g. "table:public.users" or
+// "column:public.users.email". The same name always gives the same ID.
+func ObjectID(kind string, schema string, names ...string) string {
+	return kind + ":" + schemaOrPublic(schema) + "." + strings.Join(names, ".")
+}
+
+// ObjectGraph returns the tables, columns, indexes, constraints, domains and
+// enums of the schema with their IDs. A foreign key that implicitly
+// references the primary key is linked to the primary key columns when the
+// referenced table is in the schema.
+func (s *Schema) ObjectGraph() ObjectGraph {
+	graph := ObjectGraph{Objects: []SchemaObject{}, ForeignKeys: []ForeignKeyEdge{}}
+	add := func(kind, schema, parent string, loc *SourceLocation, names ...string) {
+		graph.Objects = append(graph.Objects, SchemaObject{
+			ID:             ObjectID(kind, schema, names...),
+			Kind:           kind,
+			Schema:         schemaOrPublic(schema),
+			Name:           names[len(names)-1],
+			Parent:         parent,
+			SourceLocation: loc,
+		})
+	}
+
+	for _, enum := range s.Enums {
+		add("enum", enum.Schema, "", enum.SourceLocation, enum.Name)
+	}
+	for _, domain := range s.Domains {
+		add("domain", domain.Schema, "", domain.SourceLocation, domain.Name)
+	}
+
+	for i := range s.Tables {
+		table := &s.Tables[i]
+		tableID := ObjectID("table", table.Schema, table.Name)
+		add("table", table.Schema, "", table.SourceLocation, table.Name)
+		for _, col := range table.Columns {
+			add("column", table.Schema, tableID, col.SourceLocation, table.Name, col.Name)
+		}
+		for _, index := range table.Indexes {
+			add("index", table.Schema, tableID, index.SourceLocation, index.Name)
+		}
+		for _, unique := range table.UniqueConstraints {
+			add("constraint", table.Schema, tableID, unique.SourceLocation, table.Name, unique.Name)
+		}
+		for _, check := range table.CheckConstraints {
+			add("constraint", table.Schema, tableID, check.SourceLocation, table.Name, check.Name)
+		}
+		for _, fk := range table.ForeignKeys {
+			add("constraint", table.Schema, tableID, fk.SourceLocation, table.Name, fk.Name)
+			graph.ForeignKeys = append(graph.ForeignKeys, s.foreignKeyEdge(table, fk))
+		}
+	}
+	return graph
+}
+
+// foreignKeyEdge converts a foreign key of table to an edge between column IDs
+func (s *Schema) foreignKeyEdge(table *Table, fk ForeignKey) ForeignKeyEdge {
+	edge := ForeignKeyEdge{
+		ID:                ObjectID("constraint", table.Schema, table.Name, fk.Name),
+		From:              ObjectID("table", table.Schema, table.Name),
+		To:                ObjectID("table", fk.ReferencedSchema, fk.ReferencedTable),
+		Columns:           []string{},
+		ReferencedColumns: []string{},
+	}
+	for _, col := range fk.Columns {
+		edge.Columns = append(edge.Columns, ObjectID("column", table.Schema, table.Name, col))
+	}
+
+	referenced := fk.ReferencedColumns
+	if len(referenced) == 0 {
+		for i := range s.Tables {
+			target := &s.Tables[i]
+			if target.Name == fk.ReferencedTable && schemaOrPublic(target.Schema) == schemaOrPublic(fk.ReferencedSchema) {
+				referenced = target.PrimaryKey
+				break
+			}
+		}
+	}
+	for _, col := range referenced {
+		edge.ReferencedColumns = append(edge.ReferencedColumns, ObjectID("column", fk.ReferencedSchema, fk.ReferencedTable, col))
+	}
+	return edge
+}
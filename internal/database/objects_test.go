@@ -0,0 +1,72 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestObjectGraph(t *testing.T) {
+	if ObjectID("table", "", "users") != ObjectID("table", "public", "users") {
+		t.Errorf("Expected an empty schema to give the same ID as public")
+	}
+	if got := ObjectID("column", "auth", "users", "email"); got != "column:auth.users.email" {
+		t.Errorf("Expected column:auth.users.email, got %s", got)
+	}
+
+	schema := &Schema{
+		Tables: []Table{
+			{
+				Name:       "users",
+				Columns:    []Column{{Name: "id"}},
+				PrimaryKey: []string{"id"},
+			},
+			{
+				Name:    "posts",
+				Columns: []Column{{Name: "id"}, {Name: "author_id"}},
+				ForeignKeys: []ForeignKey{
+					{Name: "posts_author_id_fkey", Columns: []string{"author_id"}, ReferencedTable: "users"},
+				},
+			},
+		},
+	}
+
+	graph := schema.ObjectGraph()
+	if !reflect.DeepEqual(graph, schema.ObjectGraph()) {
+		t.Errorf("Expected the same IDs from the same schema")
+	}
+
+	ids := map[string]bool{}
+	for _, object := range graph.Objects {
+		ids[object.ID] = true
+	}
+	for _, id := range []string{
+		"table:public.users",
+		"column:public.users.id",
+		"table:public.posts",
+		"column:public.posts.author_id",
+		"constraint:public.posts.posts_author_id_fkey",
+	} {
+		if !ids[id] {
+			t.Errorf("Expected object %s in %v", id, ids)
+		}
+	}
+
+	if len(graph.ForeignKeys) != 1 {
+		t.Fatalf("Expected 1 foreign key, got %d", len(graph.ForeignKeys))
+	}
+	expected := ForeignKeyEdge{
+		ID:                "constraint:public.posts.posts_author_id_fkey",
+		From:              "table:public.posts",
+		To:                "table:public.users",
+		Columns:           []string{"column:public.posts.author_id"},
+		ReferencedColumns: []string{"column:public.users.id"},
+	}
+	if !reflect.DeepEqual(graph.ForeignKeys[0], expected) {
+		t.Errorf("Expected foreign key %+v, got %+v", expected, graph.ForeignKeys[0])
+	}
+	for _, id := range append([]string{expected.From, expected.To}, expected.ReferencedColumns...) {
+		if !ids[id] {
+			t.Errorf("Foreign key references %s, which is not an object", id)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Sort puts the schema in a canonical order, independent of the order of the
+// files it was loaded from: tables, domains, enums and sequences are sorted by
+// schema and name, with an empty schema sorting as "public". Columns keep their
+// declaration order, which is significant.
+func (s *Schema) Sort() {
+	slices.SortStableFunc(s.Tables, func(a, b Table) int {
+		return compareQualifiedNames(a.Schema, a.Name, b.Schema, b.Name)
+	})
+	slices.SortStableFunc(s.Domains, func(a, b Domain) int {
+		return compareQualifiedNames(a.Schema, a.Name, b.Schema, b.Name)
+	})
+	slices.SortStableFunc(s.Enums, func(a, b Enum) int {
+		return compareQualifiedNames(a.Schema, a.Name, b.Schema, b.Name)
+	})
+	slices.SortStableFunc(s.Sequences, func(a, b Sequence) int {
+		return compareQualifiedNames(a.Schema, a.Name, b.Schema, b.Name)
+	})
+}
+
+func compareQualifiedNames(aSchema, aName, bSchema, bName string) int {
+	return cmp.Or(
+		cmp.Compare(schemaOrPublic(aSchema), schemaOrPublic(bSchema)),
+		cmp.Compare(aName, bName),
+	)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaSort(t *testing.T) {
+	schema := &Schema{
+		Tables: []Table{
+			{Name: "users", Columns: []Column{{Name: "id"}, {Name: "email"}, {Name: "created_at"}}},
+			{Name: "sessions", Schema: "auth"},
+			{Name: "accounts", Schema: "public"},
+			{Name: "users", Schema: "auth"},
+		},
+		Domains: []Domain{{Name: "email"}, {Name: "email", Schema: "auth"}},
+		Enums:   []Enum{{Name: "status"}, {Name: "mood"}},
+	}
+
+	schema.Sort()
+
+	var tables []string
+	for _, table := range schema.Tables {
+		tables = append(tables, schemaOrPublic(table.Schema)+"."+table.Name)
+	}
+	expected := []string{"auth.sessions", "auth.users", "public.accounts", "public.users"}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Expected tables %v, got %v", expected, tables)
+	}
+
+	// Columns keep their declaration order
+	var columns []string
+	for _, col := range schema.Tables[3].Columns {
+		columns = append(columns, col.Name)
+	}
+	if !reflect.DeepEqual(columns, []string{"id", "email", "created_at"}) {
+		t.Errorf("Expected columns in declaration order, got %v", columns)
+	}
+
+	if schema.Domains[0].Schema != "auth" || schema.Enums[0].Name != "mood" {
+		t.Errorf("Expected domains and enums to be sorted, got %+v and %+v", schema.Domains, schema.Enums)
+	}
+}
@@ -0,0 +1,450 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var typeMap = map[string]string{
+	// Integer types
+	"int2":    "smallint",
+	"int4":    "integer",
+	"int8":    "bigint",
+	"serial":  "serial",
+	"serial2": "smallserial",
+	"serial4": "serial",
+	"serial8": "bigserial",
+
+	// Boolean
+	"bool": "boolean",
+
+	// Character types. The long spellings are what the database reports for
+	// introspected columns.
+	"varchar":           "varchar",
+	"character varying": "varchar",
+	"bpchar":            "char",
+	"character":         "char",
+
+	// Bit strings
+	"bit varying": "varbit",
+
+	// Floating point
+	"float4": "real",
+	"float8": "double precision",
+
+	// Timestamp types
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+
+	// Text (keep as-is, but explicitly map)
+	"text": "text",
+
+	// Numeric
+	"numeric": "numeric",
+	"decimal": "decimal",
+}
+
+// NormalizePostgreSQLType converts PostgreSQL internal type names to standard SQL types
+// This is necessary because we use pg_query (PostgreSQL parser) for all SQL parsing,
+// and it normalizes types to PostgreSQL internal names like "int4", "int8", "bool", etc.
+// Introspected types are normalized the same way so they compare equal to parsed ones.
+func NormalizePostgreSQLType(pgType string) string {
+	// Map PostgreSQL internal types to standard SQL types
+	if normalized, ok := typeMap[strings.ToLower(pgType)]; ok {
+		return normalized
+	}
+
+	return pgType
+}
+
+// mysqlTypeMap maps MySQL type names that PostgreSQL doesn't have to their
+// PostgreSQL equivalents
+var mysqlTypeMap = map[string]string{
+	"tinyint":    "smallint",
+	"mediumint":  "integer",
+	"datetime":   "timestamp without time zone",
+	"tinytext":   "text",
+	"mediumtext": "text",
+	"longtext":   "text",
+	"tinyblob":   "bytea",
+	"blob":       "bytea",
+	"mediumblob": "bytea",
+	"longblob":   "bytea",
+	"binary":     "bytea",
+	"varbinary":  "bytea",
+}
+
+// NormalizeMySQLType converts a MySQL type name, as parsed, to the standard
+// SQL type used in the shared model, keeping modifiers and array bounds.
+// Binary types drop their length, since bytea has none.
+func NormalizeMySQLType(typ string) string {
+	return normalizeMappedType(typ, mysqlTypeMap)
+}
+
+// sqliteTypeMap maps the type names SQLite schemas commonly use that
+// PostgreSQL doesn't have to their PostgreSQL equivalents. SQLite accepts any
+// type name, so names it doesn't list are kept.
+var sqliteTypeMap = map[string]string{
+	"tinyint":   "smallint",
+	"mediumint": "integer",
+	"double":    "double precision",
+	"datetime":  "timestamp without time zone",
+	"nvarchar":  "varchar",
+	"clob":      "text",
+	"blob":      "bytea",
+}
+
+// NormalizeSQLiteType converts a SQLite type name, as parsed, to the standard
+// SQL type used in the shared model, as NormalizeMySQLType does for MySQL
+func NormalizeSQLiteType(typ string) string {
+	return normalizeMappedType(typ, sqliteTypeMap)
+}
+
+// normalizeMappedType replaces the base name of typ with its entry in types,
+// keeping modifiers and array bounds, except that bytea drops its length
+func normalizeMappedType(typ string, types map[string]string) string {
+	base, suffix := typ, ""
+	if i := strings.IndexAny(typ, "(["); i != -1 {
+		base, suffix = typ[:i], typ[i:]
+	}
+	normalized, ok := types[strings.ToLower(base)]
+	if !ok {
+		return typ
+	}
+	if normalized == "bytea" {
+		suffix = suffix[strings.Index(suffix, ")")+1:]
+	}
+	modifiers, brackets := suffix, ""
+	if i := strings.Index(suffix, "["); i != -1 {
+		modifiers, brackets = suffix[:i], suffix[i:]
+	}
+	return WithTypeModifiers(normalized, modifiers) + brackets
+}
+
+// WithTypeModifiers returns typ, a normalized type name, with modifiers such
+// as "(3)" added. As PostgreSQL spells them, the precision of a time or
+// timestamp type comes before "with time zone" or "without time zone".
+func WithTypeModifiers(typ string, modifiers string) string {
+	if i := strings.Index(typ, " with"); i != -1 && strings.HasSuffix(typ, " time zone") {
+		return typ[:i] + modifiers + typ[i:]
+	}
+	return typ + modifiers
+}
+
+// splitArrayType splits a normalized type into its element type and its array
+// brackets, e.g. "integer[3][]" into "integer" and "[3][]"
+func splitArrayType(typ string) (elem string, brackets string) {
+	if open := strings.Index(typ, "["); open != -1 {
+		return typ[:open], typ[open:]
+	}
+	return typ, ""
+}
+
+// ArrayDims returns the dimensions of a normalized array type such as
+// "integer[3][]", with the declared size of each or -1 when it has none. It
+// returns nil for types that aren't arrays.
+func ArrayDims(typ string) []int {
+	_, brackets := splitArrayType(typ)
+	var dims []int
+	for brackets != "" {
+		end := strings.Index(brackets, "]")
+		if brackets[0] != '[' || end == -1 {
+			return nil
+		}
+		size, err := strconv.Atoi(brackets[1:end])
+		if err != nil {
+			size = -1
+		}
+		dims = append(dims, size)
+		brackets = brackets[end+1:]
+	}
+	return dims
+}
+
+// TypeModifiers extracts the structured modifiers from a normalized type such
+// as "numeric(10,2)" or "varchar(255)[]". Precision and scale are returned for
+// numeric and decimal types, with a precision-only numeric(p) having scale 0;
+// length is returned for varchar and char. Other types return nil for all three.
+func TypeModifiers(typ string) (precision *int, scale *int, length *int) {
+	base, _ := splitArrayType(typ)
+
+	open := strings.Index(base, "(")
+	if open == -1 || !strings.HasSuffix(base, ")") {
+		return nil, nil, nil
+	}
+
+	var mods []int
+	for _, mod := range strings.Split(base[open+1:len(base)-1], ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(mod))
+		if err != nil {
+			return nil, nil, nil
+		}
+		mods = append(mods, n)
+	}
+
+	switch strings.ToLower(base[:open]) {
+	case "numeric", "decimal":
+		precision = &mods[0]
+		zero := 0
+		scale = &zero
+		if len(mods) > 1 {
+			scale = &mods[1]
+		}
+	case "varchar", "char":
+		length = &mods[0]
+	}
+	return precision, scale, length
+}
+
+// serialTypes maps the serial pseudo-types to the integer type of the column
+// they create
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+}
+
+// IsSerialType reports whether a normalized column type is one of the serial
+// pseudo-types, which give the column a default drawn from a new sequence
+func IsSerialType(typ string) bool {
+	_, ok := serialTypes[typ]
+	return ok
+}
+
+// builtinTypes are the normalized names of the PostgreSQL types a column may
+// use without the schema defining them. A few common extension types are
+// included, since schemas use them without declaring them.
+var builtinTypes = map[string]bool{
+	"smallint": true, "integer": true, "bigint": true,
+	"smallserial": true, "serial": true, "bigserial": true,
+	"real": true, "double precision": true, "numeric": true, "decimal": true, "money": true,
+	"boolean": true, "text": true, "varchar": true, "char": true, "name": true,
+	"bytea": true, "uuid": true, "json": true, "jsonb": true, "jsonpath": true, "xml": true,
+	"date": true, "interval": true,
+	"timestamp without time zone": true, "timestamp with time zone": true,
+	"time without time zone": true, "time with time zone": true,
+	"bit": true, "varbit": true,
+	"inet": true, "cidr": true, "macaddr": true, "macaddr8": true,
+	"point": true, "line": true, "lseg": true, "box": true, "path": true, "polygon": true, "circle": true,
+	"tsvector": true, "tsquery": true, "pg_lsn": true, "pg_snapshot": true, "txid_snapshot": true,
+	"oid": true, "xid": true, "xid8": true, "cid": true, "tid": true,
+	"regclass": true, "regtype": true, "regproc": true, "regprocedure": true, "regoper": true, "regoperator": true,
+	"regnamespace": true, "regrole": true, "regconfig": true, "regdictionary": true, "regcollation": true,
+	"int4range": true, "int8range": true, "numrange": true, "tsrange": true, "tstzrange": true, "daterange": true,
+	"int4multirange": true, "int8multirange": true, "nummultirange": true,
+	"tsmultirange": true, "tstzmultirange": true, "datemultirange": true,
+
+	// Extension types
+	"citext": true, "hstore": true, "ltree": true, "vector": true, "geometry": true, "geography": true,
+}
+
+// extensionTypes lists the types installed by common extensions, by
+// extension name. They are known types in schemas that create the extension.
+var extensionTypes = map[string][]string{
+	"citext":  {"citext"},
+	"cube":    {"cube"},
+	"hstore":  {"hstore"},
+	"isn":     {"ean13", "isbn", "isbn13", "ismn", "ismn13", "issn", "issn13", "upc"},
+	"ltree":   {"ltree", "lquery", "ltxtquery"},
+	"postgis": {"geometry", "geography", "box2d", "box3d"},
+	"seg":     {"seg"},
+	"vector":  {"vector", "halfvec", "sparsevec"},
+}
+
+// BaseType returns a normalized column type without its modifiers and array
+// brackets, e.g. "varchar" for "varchar(20)[]", "timestamp with time zone"
+// for "timestamp(3) with time zone" and "interval" for "interval day to
+// second(3)"
+func BaseType(typ string) string {
+	base, _ := splitArrayType(typ)
+	if open := strings.Index(base, "("); open != -1 {
+		rest := ""
+		if end := strings.Index(base[open:], ")"); end != -1 {
+			rest = base[open+end+1:]
+		}
+		base = base[:open] + rest
+	}
+	// The fields of an interval restrict it like a modifier
+	if strings.HasPrefix(base, "interval ") {
+		base = "interval"
+	}
+	return base
+}
+
+// IsKnownType reports whether typ, a normalized column type, is a built-in
+// type, a domain or enum defined in the schema, or a type of an extension the
+// schema creates. Modifiers and array brackets are ignored.
+func (s *Schema) IsKnownType(typ string) bool {
+	base := BaseType(typ)
+	if strings.HasPrefix(base, "pg_catalog.") || builtinTypes[strings.ToLower(base)] {
+		return true
+	}
+	return s.findDomain(base) != nil || s.FindEnum(base) != nil || s.isExtensionType(base)
+}
+
+// isExtensionType reports whether typ is a type installed by an extension the
+// schema creates. A qualified type must be in the extension's schema, but an
+// unqualified one is accepted whichever schema the extension is in, since
+// that schema is usually on the search path.
+func (s *Schema) isExtensionType(typ string) bool {
+	typeSchema, typeName := splitQualifiedName(typ)
+	qualified := strings.Contains(typ, ".")
+	for _, extension := range s.Extensions {
+		if qualified && schemaOrPublic(extension.Schema) != typeSchema {
+			continue
+		}
+		if slices.Contains(extensionTypes[extension.Name], typeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindEnum returns the enum named by typ, which may be schema-qualified, or
+// nil if typ is not an enum defined in the schema
+func (s *Schema) FindEnum(typ string) *Enum {
+	elem, _ := splitArrayType(typ)
+	enumSchema, enumName := splitQualifiedName(elem)
+	for i := range s.Enums {
+		if s.Enums[i].Name == enumName && schemaOrPublic(s.Enums[i].Schema) == enumSchema {
+			return &s.Enums[i]
+		}
+	}
+	return nil
+}
+
+// EffectiveType is a column's type with domains and serial pseudo-types
+// resolved to the type actually stored by the database
+type EffectiveType struct {
+	// Type is the resolved base type, e.g. "integer" or "varchar(255)[]"
+	Type string `json:"type"`
+	// Domains lists the domains resolved through, outermost first
+	Domains []string `json:"domains,omitempty"`
+	// Serial is set when the column was declared with a serial pseudo-type,
+	// i.e. it is backed by an owned sequence
+	Serial bool `json:"serial,omitempty"`
+	// Array is set when the resolved type is an array type
+	Array bool `json:"array,omitempty"`
+	// NotNull is set when the column or any domain it uses rejects NULL
+	NotNull bool `json:"not_null,omitempty"`
+}
+
+// EffectiveColumnType resolves the type of a column in the schema. table may
+// be schema-qualified ("auth.users"); an unqualified name is looked up in the
+// public schema.
+func (s *Schema) EffectiveColumnType(table string, column string) (EffectiveType, error) {
+	tableSchema, tableName := splitQualifiedName(table)
+
+	var tbl *Table
+	for i := range s.Tables {
+		if s.Tables[i].Name == tableName && schemaOrPublic(s.Tables[i].Schema) == tableSchema {
+			tbl = &s.Tables[i]
+			break
+		}
+	}
+	if tbl == nil {
+		return EffectiveType{}, fmt.Errorf("table %q does not exist", table)
+	}
+
+	var col *Column
+	for i := range tbl.Columns {
+		if tbl.Columns[i].Name == column {
+			col = &tbl.Columns[i]
+			break
+		}
+	}
+	if col == nil {
+		return EffectiveType{}, fmt.Errorf("column %q does not exist in table %q", column, table)
+	}
+
+	resolved, err := s.resolveType(col.Type)
+	if err != nil {
+		return EffectiveType{}, fmt.Errorf("column %q in table %q: %w", column, table, err)
+	}
+	resolved.NotNull = resolved.NotNull || !col.Nullable
+	return resolved, nil
+}
+
+// resolveType expands domains and serial pseudo-types in typ
+func (s *Schema) resolveType(typ string) (EffectiveType, error) {
+	var result EffectiveType
+
+	base, brackets := splitArrayType(typ)
+	seen := map[string]bool{}
+	for {
+		domain := s.findDomain(base)
+		if domain == nil {
+			break
+		}
+
+		name := schemaOrPublic(domain.Schema) + "." + domain.Name
+		if seen[name] {
+			return EffectiveType{}, fmt.Errorf("domain %q is defined in terms of itself", name)
+		}
+		seen[name] = true
+
+		result.Domains = append(result.Domains, domain.Name)
+		result.NotNull = result.NotNull || domain.NotNull
+
+		// A domain may be declared over an array type
+		var baseBrackets string
+		base, baseBrackets = splitArrayType(domain.BaseType)
+		brackets += baseBrackets
+	}
+
+	if integer, ok := serialTypes[strings.ToLower(base)]; ok {
+		base = integer
+		result.Serial = true
+		result.NotNull = true
+	}
+
+	result.Type = base + brackets
+	result.Array = brackets != ""
+	return result, nil
+}
+
+// findDomain returns the domain named by typ, or nil if typ is not a domain
+func (s *Schema) findDomain(typ string) *Domain {
+	domainSchema, domainName := splitQualifiedName(typ)
+	for i := range s.Domains {
+		if s.Domains[i].Name == domainName && schemaOrPublic(s.Domains[i].Schema) == domainSchema {
+			return &s.Domains[i]
+		}
+	}
+	return nil
+}
+
+// splitQualifiedName splits "schema.name" into its parts, defaulting the schema
+// to "public". Quoted parts such as auth."Role" are unquoted, keeping their
+// case.
+func splitQualifiedName(name string) (string, string) {
+	inQuotes := false
+	for i, r := range name {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '.' && !inQuotes:
+			return unquoteIdent(name[:i]), unquoteIdent(name[i+1:])
+		}
+	}
+	return "public", unquoteIdent(name)
+}
+
+// unquoteIdent removes the double quotes around a quoted identifier
+func unquoteIdent(name string) string {
+	if len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"' {
+		return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+	}
+	return name
+}
+
+func schemaOrPublic(schema string) string {
+	if schema == "" {
+		return "public"
+	}
+	return schema
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEffectiveColumnTypeDomain(t *testing.T) {
+	schema := &Schema{
+		Domains: []Domain{
+			{Name: "email", BaseType: "varchar(255)", NotNull: true},
+			{Name: "work_email", Schema: "auth", BaseType: "email"},
+		},
+		Tables: []Table{
+			{
+				Name:   "users",
+				Schema: "auth",
+				Columns: []Column{
+					{Name: "email", Type: "auth.work_email", Nullable: true},
+					{Name: "aliases", Type: "email[]", Nullable: true},
+				},
+			},
+		},
+	}
+
+	got, err := schema.EffectiveColumnType("auth.users", "email")
+	if err != nil {
+		t.Fatalf("EffectiveColumnType failed: %v", err)
+	}
+	want := EffectiveType{
+		Type:    "varchar(255)",
+		Domains: []string{"work_email", "email"},
+		NotNull: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	got, err = schema.EffectiveColumnType("auth.users", "aliases")
+	if err != nil {
+		t.Fatalf("EffectiveColumnType failed: %v", err)
+	}
+	want = EffectiveType{
+		Type:    "varchar(255)[]",
+		Domains: []string{"email"},
+		Array:   true,
+		NotNull: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestEffectiveColumnTypeSerial(t *testing.T) {
+	schema := &Schema{
+		Tables: []Table{
+			{
+				Name: "events",
+				Columns: []Column{
+					{Name: "id", Type: "bigserial", Nullable: true},
+					{Name: "seq", Type: "smallserial", Nullable: true},
+					{Name: "payload", Type: "jsonb", Nullable: true},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		column string
+		want   EffectiveType
+	}{
+		{"id", EffectiveType{Type: "bigint", Serial: true, NotNull: true}},
+		{"seq", EffectiveType{Type: "smallint", Serial: true, NotNull: true}},
+		{"payload", EffectiveType{Type: "jsonb"}},
+	}
+
+	for _, tt := range tests {
+		got, err := schema.EffectiveColumnType("events", tt.column)
+		if err != nil {
+			t.Fatalf("EffectiveColumnType(%q) failed: %v", tt.column, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EffectiveColumnType(%q): expected %+v, got %+v", tt.column, tt.want, got)
+		}
+	}
+}
+
+func TestEffectiveColumnTypeDomainCycle(t *testing.T) {
+	schema := &Schema{
+		Domains: []Domain{
+			{Name: "a", BaseType: "b"},
+			{Name: "b", BaseType: "a"},
+		},
+		Tables: []Table{
+			{Name: "t", Columns: []Column{{Name: "c", Type: "a", Nullable: true}}},
+		},
+	}
+
+	if _, err := schema.EffectiveColumnType("t", "c"); err == nil {
+		t.Error("Expected error for cyclic domain definitions")
+	}
+}
+
+func TestEffectiveColumnTypeMissing(t *testing.T) {
+	schema := &Schema{
+		Tables: []Table{
+			{Name: "users", Columns: []Column{{Name: "id", Type: "integer"}}},
+		},
+	}
+
+	if _, err := schema.EffectiveColumnType("posts", "id"); err == nil {
+		t.Error("Expected error for missing table")
+	}
+	if _, err := schema.EffectiveColumnType("users", "email"); err == nil {
+		t.Error("Expected error for missing column")
+	}
+	// Unqualified names resolve to the public schema only
+	if _, err := schema.EffectiveColumnType("auth.users", "id"); err == nil {
+		t.Error("Expected error for table in another schema")
+	}
+}
+
+func TestNormalizeMySQLType(t *testing.T) {
+	tests := map[string]string{
+		"tinyint":       "smallint",
+		"mediumint":     "integer",
+		"datetime":      "timestamp without time zone",
+		"longtext":      "text",
+		"varbinary(16)": "bytea",
+		"blob[]":        "bytea[]",
+		"varchar(255)":  "varchar(255)",
+		"datetime(3)":   "timestamp(3) without time zone",
+		"integer":       "integer",
+	}
+	for input, expected := range tests {
+		if got := NormalizeMySQLType(input); got != expected {
+			t.Errorf("NormalizeMySQLType(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestNormalizeSQLiteType(t *testing.T) {
+	tests := map[string]string{
+		"blob":          "bytea",
+		"BLOB":          "bytea",
+		"datetime":      "timestamp without time zone",
+		"double":        "double precision",
+		"nvarchar(100)": "varchar(100)",
+		"clob":          "text",
+		"integer":       "integer",
+		"numeric(10,2)": "numeric(10,2)",
+	}
+	for input, expected := range tests {
+		if got := NormalizeSQLiteType(input); got != expected {
+			t.Errorf("NormalizeSQLiteType(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestBaseType(t *testing.T) {
+	tests := map[string]string{
+		"varchar(20)[]":                  "varchar",
+		"numeric(10,2)":                  "numeric",
+		"timestamp(3) with time zone":    "timestamp with time zone",
+		"time(6) without time zone[]":    "time without time zone",
+		"interval day to second(3)":      "interval",
+		"interval year to month":         "interval",
+		"auth.email":                     "auth.email",
+		"timestamp without time zone[3]": "timestamp without time zone",
+	}
+	for input, expected := range tests {
+		if got := BaseType(input); got != expected {
+			t.Errorf("BaseType(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestArrayDims(t *testing.T) {
+	tests := map[string][]int{
+		"text[]":          {-1},
+		"integer[][]":     {-1, -1},
+		"integer[3][3]":   {3, 3},
+		"numeric(10,2)[]": {-1},
+		"integer":         nil,
+	}
+	for input, expected := range tests {
+		if got := ArrayDims(input); !reflect.DeepEqual(got, expected) {
+			t.Errorf("ArrayDims(%q) = %v, expected %v", input, got, expected)
+		}
+	}
+}
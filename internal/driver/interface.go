@@ -44,8 +44,9 @@ type Driver interface {
 	// TestConnection attempts to connect to the database
 	OpenConnection(cfg database.ConnectionConfig) (*sql.DB, error)
 
-	// IntrospectSchema reads the entire database schema
-	IntrospectSchema(ctx context.Context, db *sql.DB, schemaName string) (*database.Schema, error)
+	// IntrospectSchema reads the tables of the named schemas, or of every user
+	// schema when none are named
+	IntrospectSchema(ctx context.Context, db *sql.DB, schemaNames ...string) (*database.Schema, error)
 
 	// Shortcut for Generator.GenerateMigration
 	GenerateMigration(diff *schema.SchemaDiff) string
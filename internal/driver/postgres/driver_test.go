@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -43,7 +44,7 @@ func TestDriver_Name(t *testing.T) {
 	}
 }
 
-func TestDriver_GetTables(t *testing.T) {
+func TestDriver_IntrospectSchema(t *testing.T) {
 	db, _ := getTestDb(t)
 	defer func() { _ = db.Close() }()
 
@@ -62,9 +63,9 @@ func TestDriver_GetTables(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE test_introspect_tables") }()
 
 	// Get tables
-	tables, err := GetTables(ctx, db, defaultSchema)
+	tables, err := introspectTables(ctx, db)
 	if err != nil {
-		t.Fatalf("GetTables failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	// Should have at least our test table
@@ -97,7 +98,45 @@ func findColumn(columns []database.Column, name string) *database.Column {
 	return nil
 }
 
-func TestIntrospector_GetColumns(t *testing.T) {
+// introspectTables introspects the tables of the default schema
+func introspectTables(ctx context.Context, db *sql.DB) ([]database.Table, error) {
+	schema, err := NewDriver().IntrospectSchema(ctx, db, defaultSchema)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Tables, nil
+}
+
+// introspectColumns introspects the columns of a table in the default schema
+func introspectColumns(ctx context.Context, db *sql.DB, tableName string) ([]database.Column, error) {
+	tables, err := introspectTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		if table.Name == tableName {
+			return table.Columns, nil
+		}
+	}
+	return nil, fmt.Errorf("table %s not found", tableName)
+}
+
+// tableRLSEnabled introspects whether row level security is enabled for a
+// table in the default schema
+func tableRLSEnabled(ctx context.Context, db *sql.DB, tableName string) (bool, error) {
+	tables, err := introspectTables(ctx, db)
+	if err != nil {
+		return false, err
+	}
+	for _, table := range tables {
+		if table.Name == tableName {
+			return table.RLSEnabled, nil
+		}
+	}
+	return false, fmt.Errorf("table %s not found", tableName)
+}
+
+func TestIntrospector_Columns(t *testing.T) {
 	db, _ := getTestDb(t)
 	defer func() { _ = db.Close() }()
 	ctx := context.Background()
@@ -121,9 +160,9 @@ func TestIntrospector_GetColumns(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS test_introspect_columns") }()
 
 	// Get columns
-	columns, err := GetColumns(ctx, db, defaultSchema, "test_introspect_columns")
+	columns, err := introspectColumns(ctx, db, "test_introspect_columns")
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	if len(columns) != 4 {
@@ -211,9 +250,9 @@ func TestIntrospector_IntegerTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -261,9 +300,9 @@ func TestIntrospector_SerialTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -310,9 +349,9 @@ func TestIntrospector_ManualSequence(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS "+tableName) }()
 
 	// Introspect
-	columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err := introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	if len(columns) != 1 {
@@ -352,9 +391,9 @@ func TestIntrospector_DroppedSequence(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE IF EXISTS "+tableName) }()
 
 	// Introspect initially - should see serial
-	columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err := introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 	if len(columns) != 1 {
 		t.Fatalf("Expected 1 column, got %d", len(columns))
@@ -370,9 +409,9 @@ func TestIntrospector_DroppedSequence(t *testing.T) {
 	}
 
 	// Introspect again - should now see integer with no default
-	columns, err = GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err = introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed after dropping sequence: %v", err)
+		t.Fatalf("IntrospectSchema failed after dropping sequence: %v", err)
 	}
 
 	if len(columns) != 1 {
@@ -391,72 +430,6 @@ func TestIntrospector_DroppedSequence(t *testing.T) {
 	}
 }
 
-// TestIsSerialColumn_EdgeCases tests edge cases for the isSerialColumn function
-func TestIsSerialColumn_EdgeCases(t *testing.T) {
-	db, _ := getTestDb(t)
-	defer func() { _ = db.Close() }()
-	ctx := context.Background()
-
-	tests := []struct {
-		name           string
-		columnType     string
-		nullable       bool
-		defaultVal     sql.NullString
-		expectedSerial bool
-		description    string
-	}{
-		{
-			name:           "nullable_integer_with_nextval",
-			columnType:     "integer",
-			nullable:       true,
-			defaultVal:     sql.NullString{String: "nextval('seq'::regclass)", Valid: true},
-			expectedSerial: false,
-			description:    "Nullable columns cannot be SERIAL",
-		},
-		{
-			name:           "integer_without_default",
-			columnType:     "integer",
-			nullable:       false,
-			defaultVal:     sql.NullString{Valid: false},
-			expectedSerial: false,
-			description:    "Columns without default cannot be SERIAL",
-		},
-		{
-			name:           "text_with_nextval",
-			columnType:     "text",
-			nullable:       false,
-			defaultVal:     sql.NullString{String: "nextval('seq'::regclass)", Valid: true},
-			expectedSerial: false,
-			description:    "Non-integer types cannot be SERIAL",
-		},
-		{
-			name:           "integer_with_non_nextval_default",
-			columnType:     "integer",
-			nullable:       false,
-			defaultVal:     sql.NullString{String: "42", Valid: true},
-			expectedSerial: false,
-			description:    "Defaults without nextval() are not SERIAL",
-		},
-		{
-			name:           "integer_with_malformed_nextval",
-			columnType:     "integer",
-			nullable:       false,
-			defaultVal:     sql.NullString{String: "nextval('seq')", Valid: true},
-			expectedSerial: false,
-			description:    "Malformed nextval (missing ::regclass) should not match",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := isSerialColumn(ctx, db, defaultSchema, "dummy_table", "dummy_col", tt.columnType, tt.nullable, tt.defaultVal)
-			if result != tt.expectedSerial {
-				t.Errorf("%s: expected %v, got %v", tt.description, tt.expectedSerial, result)
-			}
-		})
-	}
-}
-
 // TestIntrospector_SerialWithSchemaQualifiedSequence tests that SERIAL detection works
 // with schema-qualified sequence names in various formats (quoted and unquoted).
 func TestIntrospector_SerialWithSchemaQualifiedSequence(t *testing.T) {
@@ -542,9 +515,9 @@ func TestIntrospector_SerialWithSchemaQualifiedSequence(t *testing.T) {
 			}()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -593,9 +566,9 @@ func TestIntrospector_FloatingPointTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -621,10 +594,10 @@ func TestIntrospector_CharacterTypes(t *testing.T) {
 		expectedType string
 	}{
 		{"TEXT", "TEXT", "text"},
-		{"VARCHAR", "VARCHAR", "character varying"},
-		{"VARCHAR_50", "VARCHAR(50)", "character varying"},
-		{"CHAR", "CHAR", "character"},
-		{"CHAR_10", "CHAR(10)", "character"},
+		{"VARCHAR", "VARCHAR", "varchar"},
+		{"VARCHAR_50", "VARCHAR(50)", "varchar(50)"},
+		{"CHAR", "CHAR", "char(1)"},
+		{"CHAR_10", "CHAR(10)", "char(10)"},
 	}
 
 	for _, tt := range tests {
@@ -639,9 +612,9 @@ func TestIntrospector_CharacterTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -687,9 +660,9 @@ func TestIntrospector_TimestampTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -715,9 +688,9 @@ func TestIntrospector_NumericTypes(t *testing.T) {
 		expectedType string
 	}{
 		{"NUMERIC", "NUMERIC", "numeric"},
-		{"NUMERIC_10_2", "NUMERIC(10,2)", "numeric"},
+		{"NUMERIC_10_2", "NUMERIC(10,2)", "numeric(10,2)"},
 		{"DECIMAL", "DECIMAL", "numeric"},
-		{"DECIMAL_8_4", "DECIMAL(8,4)", "numeric"},
+		{"DECIMAL_8_4", "DECIMAL(8,4)", "numeric(8,4)"},
 	}
 
 	for _, tt := range tests {
@@ -732,9 +705,9 @@ func TestIntrospector_NumericTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -775,9 +748,9 @@ func TestIntrospector_BooleanType(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -820,9 +793,9 @@ func TestIntrospector_SpecialTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -847,9 +820,9 @@ func TestIntrospector_ArrayTypes(t *testing.T) {
 		sqlType      string
 		expectedType string
 	}{
-		{"INTEGER_ARRAY", "INTEGER[]", "ARRAY"},
-		{"TEXT_ARRAY", "TEXT[]", "ARRAY"},
-		{"VARCHAR_ARRAY", "VARCHAR(50)[]", "ARRAY"},
+		{"INTEGER_ARRAY", "INTEGER[]", "integer[]"},
+		{"TEXT_ARRAY", "TEXT[]", "text[]"},
+		{"VARCHAR_ARRAY", "VARCHAR(50)[]", "varchar(50)[]"},
 	}
 
 	for _, tt := range tests {
@@ -864,9 +837,9 @@ func TestIntrospector_ArrayTypes(t *testing.T) {
 			defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 			// Introspect
-			columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+			columns, err := introspectColumns(ctx, db, tableName)
 			if err != nil {
-				t.Fatalf("GetColumns failed: %v", err)
+				t.Fatalf("IntrospectSchema failed: %v", err)
 			}
 
 			if len(columns) != 1 {
@@ -905,9 +878,9 @@ func TestIntrospector_ConstraintsAndDefaults(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 	// Introspect
-	columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err := introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	if len(columns) != 6 {
@@ -1002,9 +975,9 @@ func TestIntrospector_ComplexRealWorldTable(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE "+tableName) }()
 
 	// Introspect
-	columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err := introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	if len(columns) != 12 {
@@ -1031,8 +1004,8 @@ func TestIntrospector_ComplexRealWorldTable(t *testing.T) {
 	if username == nil {
 		t.Fatal("Expected to find 'username' column")
 	}
-	if username.Type != "character varying" {
-		t.Errorf("Expected username type 'character varying', got %q", username.Type)
+	if username.Type != "varchar(50)" {
+		t.Errorf("Expected username type 'varchar(50)', got %q", username.Type)
 	}
 	if username.Nullable {
 		t.Error("Expected username to be NOT NULL")
@@ -1043,8 +1016,8 @@ func TestIntrospector_ComplexRealWorldTable(t *testing.T) {
 	if tags == nil {
 		t.Fatal("Expected to find 'tags' column")
 	}
-	if tags.Type != "ARRAY" {
-		t.Errorf("Expected tags type 'ARRAY', got %q", tags.Type)
+	if tags.Type != "text[]" {
+		t.Errorf("Expected tags type 'text[]', got %q", tags.Type)
 	}
 
 	// Verify metadata column (JSONB)
@@ -1069,7 +1042,7 @@ func TestIntrospector_ComplexRealWorldTable(t *testing.T) {
 	}
 }
 
-func TestGetRLSEnabled(t *testing.T) {
+func TestIntrospector_RLSEnabled(t *testing.T) {
 	db, _ := getTestDb(t)
 	defer func() { _ = db.Close() }()
 	ctx := context.Background()
@@ -1086,9 +1059,9 @@ func TestGetRLSEnabled(t *testing.T) {
 	defer func() { _, _ = db.ExecContext(ctx, "DROP TABLE test_rls_disabled") }()
 
 	// Test that RLS is disabled by default
-	rlsEnabled, err := GetRLSEnabled(ctx, db, defaultSchema, "test_rls_disabled")
+	rlsEnabled, err := tableRLSEnabled(ctx, db, "test_rls_disabled")
 	if err != nil {
-		t.Fatalf("GetRLSEnabled failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 	if rlsEnabled {
 		t.Error("Expected RLS to be disabled by default, but it was enabled")
@@ -1101,9 +1074,9 @@ func TestGetRLSEnabled(t *testing.T) {
 	}
 
 	// Test that RLS is now enabled
-	rlsEnabled, err = GetRLSEnabled(ctx, db, defaultSchema, "test_rls_disabled")
+	rlsEnabled, err = tableRLSEnabled(ctx, db, "test_rls_disabled")
 	if err != nil {
-		t.Fatalf("GetRLSEnabled failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 	if !rlsEnabled {
 		t.Error("Expected RLS to be enabled, but it was disabled")
@@ -1116,16 +1089,16 @@ func TestGetRLSEnabled(t *testing.T) {
 	}
 
 	// Test that RLS is now disabled again
-	rlsEnabled, err = GetRLSEnabled(ctx, db, defaultSchema, "test_rls_disabled")
+	rlsEnabled, err = tableRLSEnabled(ctx, db, "test_rls_disabled")
 	if err != nil {
-		t.Fatalf("GetRLSEnabled failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 	if rlsEnabled {
 		t.Error("Expected RLS to be disabled, but it was enabled")
 	}
 }
 
-func TestGetRLSEnabledIntegrationWithGetTables(t *testing.T) {
+func TestIntrospector_RLSEnabledPerTable(t *testing.T) {
 	db, _ := getTestDb(t)
 	defer func() { _ = db.Close() }()
 	ctx := context.Background()
@@ -1150,9 +1123,9 @@ func TestGetRLSEnabledIntegrationWithGetTables(t *testing.T) {
 	}
 
 	// Get tables and verify RLS status
-	tables, err := GetTables(ctx, db, defaultSchema)
+	tables, err := introspectTables(ctx, db)
 	if err != nil {
-		t.Fatalf("GetTables failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	// Find our test tables
@@ -1224,9 +1197,9 @@ func TestApplyMigration_Success(t *testing.T) {
 	}
 
 	// Verify table structure
-	columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err := introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	if len(columns) != 3 {
@@ -1364,9 +1337,9 @@ func TestApplyMigration_AlterTable(t *testing.T) {
 	}
 
 	// Verify column was added
-	columns, err := GetColumns(ctx, db, defaultSchema, tableName)
+	columns, err := introspectColumns(ctx, db, tableName)
 	if err != nil {
-		t.Fatalf("GetColumns failed: %v", err)
+		t.Fatalf("IntrospectSchema failed: %v", err)
 	}
 
 	if len(columns) != 3 {
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/lockplane/lockplane/internal/database"
@@ -18,11 +19,28 @@ func NewGenerator() *Generator {
 
 func (g *Generator) GenerateMigration(diff *schema.SchemaDiff) string {
 	migration := ""
+	// Domains are created and altered first, since tables may use them
+	for _, domain := range diff.AddedDomains {
+		migration += g.CreateDomain(domain) + "\n\n"
+	}
+	for _, domainDiff := range diff.ModifiedDomains {
+		migration += g.ModifyDomain(domainDiff) + "\n\n"
+	}
 	for _, table := range diff.AddedTables {
 		migration += g.CreateTable(table) + "\n\n"
 		// Add RLS if enabled for new table
-		if table.RLSEnabled {
-			migration += fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;\n\n", table.Name)
+		if table.RLSEnabled && table.PartitionOf == nil {
+			migration += fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;\n\n", tableName(table))
+		}
+	}
+	// Foreign keys that are removed or changed are dropped before the columns
+	// they use change
+	for _, tableDiff := range diff.ModifiedTables {
+		for _, fk := range tableDiff.RemovedForeignKeys {
+			migration += g.DropForeignKey(tableDiff.TableName, fk) + "\n\n"
+		}
+		for _, fkDiff := range tableDiff.ModifiedForeignKeys {
+			migration += g.DropForeignKey(tableDiff.TableName, fkDiff.Old) + "\n\n"
 		}
 	}
 	for _, tableDiff := range diff.ModifiedTables {
@@ -36,7 +54,10 @@ func (g *Generator) GenerateMigration(diff *schema.SchemaDiff) string {
 		}
 		// Handle modified columns
 		for _, columnDiff := range tableDiff.ModifiedColumns {
-			migration += g.ModifyColumn(tableDiff.TableName, columnDiff) + "\n\n"
+			// Some changes (e.g. to the primary key) have no column-level DDL
+			if sql := g.ModifyColumn(tableDiff.TableName, columnDiff); sql != "" {
+				migration += sql + "\n\n"
+			}
 		}
 		// Handle RLS changes
 		if tableDiff.RLSChanged {
@@ -47,36 +68,214 @@ func (g *Generator) GenerateMigration(diff *schema.SchemaDiff) string {
 			}
 		}
 	}
+	// Foreign keys are added once every table and column they use exists
+	for _, table := range diff.AddedTables {
+		if table.PartitionOf != nil {
+			continue
+		}
+		for _, fk := range table.ForeignKeys {
+			migration += g.AddForeignKey(tableName(table), fk) + "\n\n"
+		}
+	}
+	for _, tableDiff := range diff.ModifiedTables {
+		for _, fk := range tableDiff.AddedForeignKeys {
+			migration += g.AddForeignKey(tableDiff.TableName, fk) + "\n\n"
+		}
+		for _, fkDiff := range tableDiff.ModifiedForeignKeys {
+			migration += g.AddForeignKey(tableDiff.TableName, fkDiff.New) + "\n\n"
+		}
+	}
 	for _, table := range diff.RemovedTables {
 		migration += g.DropTable(table) + "\n\n"
 	}
+	for _, domain := range diff.RemovedDomains {
+		migration += g.DropDomain(domain) + "\n\n"
+	}
 	return strings.TrimSpace(migration)
 }
 
-// CreateTable generates PostgreSQL SQL to create a table
+// CreateDomain generates PostgreSQL SQL to create a domain
+func (g *Generator) CreateDomain(domain database.Domain) string {
+	sql := fmt.Sprintf("CREATE DOMAIN %s AS %s", domainName(domain), domain.BaseType)
+	if domain.Default != nil {
+		sql += " DEFAULT " + *domain.Default
+	}
+	if domain.NotNull {
+		sql += " NOT NULL"
+	}
+	for _, check := range domain.CheckConstraints {
+		sql += fmt.Sprintf(" CONSTRAINT %s CHECK (%s)", check.Name, check.Expression)
+	}
+	return sql + ";"
+}
+
+// DropDomain generates PostgreSQL SQL to drop a domain
+func (g *Generator) DropDomain(domain database.Domain) string {
+	return fmt.Sprintf("DROP DOMAIN %s;", domainName(domain))
+}
+
+// ModifyDomain generates PostgreSQL SQL to alter a domain. PostgreSQL can't
+// change a domain's base type, so a base type change is only reported, in a
+// comment.
+func (g *Generator) ModifyDomain(diff schema.DomainDiff) string {
+	sql := ""
+	name := domainName(diff.New)
+
+	if contains(diff.Changes, "base_type") {
+		sql += fmt.Sprintf("-- The base type of domain %s changed from %s to %s, which ALTER DOMAIN can't do.\n-- Recreate the domain and the columns that use it instead.\n\n",
+			name, diff.Old.BaseType, diff.New.BaseType)
+	}
+
+	if contains(diff.Changes, "not_null") {
+		if diff.New.NotNull {
+			sql += fmt.Sprintf("ALTER DOMAIN %s SET NOT NULL;\n\n", name)
+		} else {
+			sql += fmt.Sprintf("ALTER DOMAIN %s DROP NOT NULL;\n\n", name)
+		}
+	}
+
+	if contains(diff.Changes, "default") {
+		if diff.New.Default == nil {
+			sql += fmt.Sprintf("ALTER DOMAIN %s DROP DEFAULT;\n\n", name)
+		} else {
+			sql += fmt.Sprintf("ALTER DOMAIN %s SET DEFAULT %s;\n\n", name, *diff.New.Default)
+		}
+	}
+
+	// A check constraint whose expression changed is dropped and added again
+	if contains(diff.Changes, "check_constraints") {
+		for _, check := range diff.Old.CheckConstraints {
+			if !hasCheck(diff.New.CheckConstraints, check) {
+				sql += fmt.Sprintf("ALTER DOMAIN %s DROP CONSTRAINT %s;\n\n", name, check.Name)
+			}
+		}
+		for _, check := range diff.New.CheckConstraints {
+			if !hasCheck(diff.Old.CheckConstraints, check) {
+				sql += fmt.Sprintf("ALTER DOMAIN %s ADD CONSTRAINT %s CHECK (%s);\n\n", name, check.Name, check.Expression)
+			}
+		}
+	}
+
+	return strings.TrimSpace(sql)
+}
+
+// hasCheck reports whether checks has a constraint with the name and
+// expression of check
+func hasCheck(checks []database.CheckConstraint, check database.CheckConstraint) bool {
+	return slices.ContainsFunc(checks, func(c database.CheckConstraint) bool {
+		return c.Name == check.Name && c.Expression == check.Expression
+	})
+}
+
+// domainName returns the name to use for a domain in DDL, qualified with its
+// schema unless it is in the public schema
+func domainName(domain database.Domain) string {
+	if domain.Schema == "" || domain.Schema == "public" {
+		return domain.Name
+	}
+	return domain.Schema + "." + domain.Name
+}
+
+// CreateTable generates PostgreSQL SQL to create a table. The bounds of a
+// partition aren't modeled, so a partition isn't created; a comment says so
+// instead.
 func (g *Generator) CreateTable(table database.Table) string {
+	if table.PartitionOf != nil {
+		parent := database.Table{Schema: table.PartitionOf.Schema, Name: table.PartitionOf.Table}
+		return fmt.Sprintf("-- %s is a partition of %s, which can't be created without its bounds. Create it by hand.",
+			tableName(table), tableName(parent))
+	}
+
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", table.Name))
+	sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", tableName(table)))
+
+	// A composite primary key can't be declared inline, so it is emitted as a
+	// table constraint after the columns instead.
+	compositePK := len(table.PrimaryKey) > 1
 
 	// Add columns
 	for i, col := range table.Columns {
+		if compositePK {
+			col.IsPrimaryKey = false
+		}
 		sb.WriteString("  ")
 		sb.WriteString(g.FormatColumnDefinition(col))
-		if i < len(table.Columns)-1 {
+		if i < len(table.Columns)-1 || compositePK {
 			sb.WriteString(",")
 		}
 		sb.WriteString("\n")
 	}
 
-	sb.WriteString(");")
+	if compositePK {
+		sb.WriteString(fmt.Sprintf("  PRIMARY KEY (%s)\n", strings.Join(table.PrimaryKey, ", ")))
+	}
+
+	sb.WriteString(")")
+	if table.PartitionBy != nil {
+		// A partition key that is an expression must be parenthesized
+		keys := make([]string, len(table.PartitionBy.Columns))
+		for i, key := range table.PartitionBy.Columns {
+			keys[i] = key
+			if strings.ContainsAny(key, "( ") {
+				keys[i] = "(" + key + ")"
+			}
+		}
+		sb.WriteString(fmt.Sprintf(" PARTITION BY %s (%s)", table.PartitionBy.Strategy, strings.Join(keys, ", ")))
+	}
+	sb.WriteString(";")
 
 	return sb.String()
 }
 
 // DropTable generates PostgreSQL SQL to drop a table
 func (g *Generator) DropTable(table database.Table) string {
-	return fmt.Sprintf("DROP TABLE %s CASCADE;", table.Name)
+	return fmt.Sprintf("DROP TABLE %s CASCADE;", tableName(table))
+}
+
+// AddForeignKey generates PostgreSQL SQL to add a foreign key constraint
+func (g *Generator) AddForeignKey(tableName string, fk database.ForeignKey) string {
+	referenced := fk.ReferencedTable
+	if fk.ReferencedSchema != "" && fk.ReferencedSchema != "public" {
+		referenced = fk.ReferencedSchema + "." + fk.ReferencedTable
+	}
+	sql := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s",
+		tableName, fk.Name, strings.Join(fk.Columns, ", "), referenced)
+	if len(fk.ReferencedColumns) > 0 {
+		sql += fmt.Sprintf(" (%s)", strings.Join(fk.ReferencedColumns, ", "))
+	}
+	if fk.Match != "" {
+		sql += " MATCH " + string(fk.Match)
+	}
+	if fk.OnDelete != "" {
+		sql += " ON DELETE " + string(fk.OnDelete)
+		if len(fk.OnDeleteColumns) > 0 {
+			sql += fmt.Sprintf(" (%s)", strings.Join(fk.OnDeleteColumns, ", "))
+		}
+	}
+	if fk.OnUpdate != "" {
+		sql += " ON UPDATE " + string(fk.OnUpdate)
+	}
+	if fk.InitiallyDeferred {
+		sql += " DEFERRABLE INITIALLY DEFERRED"
+	} else if fk.Deferrable {
+		sql += " DEFERRABLE"
+	}
+	return sql + ";"
+}
+
+// DropForeignKey generates PostgreSQL SQL to drop a foreign key constraint
+func (g *Generator) DropForeignKey(tableName string, fk database.ForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", tableName, fk.Name)
+}
+
+// tableName returns the name to use for a table in DDL, qualified with its
+// schema unless it is in the public schema
+func tableName(table database.Table) string {
+	if table.Schema == "" || table.Schema == "public" {
+		return table.Name
+	}
+	return table.Schema + "." + table.Name
 }
 
 func (g *Generator) FormatColumnDefinition(col database.Column) string {
@@ -90,8 +289,13 @@ func (g *Generator) FormatColumnDefinition(col database.Column) string {
 		sb.WriteString(" NOT NULL")
 	}
 
-	// Default value
-	if col.Default != nil {
+	// Default value, or the expression of a generated column, which can't
+	// have a default. Identity columns have no default either.
+	if col.Identity != nil {
+		sb.WriteString(" " + identityClause(col.Identity))
+	} else if col.Generated != nil {
+		sb.WriteString(fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", col.Generated.Expression, col.Generated.Storage))
+	} else if col.Default != nil {
 		sb.WriteString(fmt.Sprintf(" DEFAULT %s", *col.Default))
 	}
 
@@ -103,6 +307,38 @@ func (g *Generator) FormatColumnDefinition(col database.Column) string {
 	return sb.String()
 }
 
+// identityClause returns the GENERATED ... AS IDENTITY clause for an identity
+// column, with the sequence options that were given
+func identityClause(identity *database.IdentitySpec) string {
+	clause := "GENERATED BY DEFAULT AS IDENTITY"
+	if identity.Always {
+		clause = "GENERATED ALWAYS AS IDENTITY"
+	}
+
+	var options []string
+	for _, option := range []struct {
+		name  string
+		value *int64
+	}{
+		{"START WITH", identity.Start},
+		{"INCREMENT BY", identity.Increment},
+		{"MINVALUE", identity.MinValue},
+		{"MAXVALUE", identity.MaxValue},
+		{"CACHE", identity.Cache},
+	} {
+		if option.value != nil {
+			options = append(options, fmt.Sprintf("%s %d", option.name, *option.value))
+		}
+	}
+	if identity.Cycle {
+		options = append(options, "CYCLE")
+	}
+	if len(options) > 0 {
+		clause += " (" + strings.Join(options, " ") + ")"
+	}
+	return clause
+}
+
 func (g *Generator) AddColumn(tableName string, col database.Column) string {
 	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", tableName, g.FormatColumnDefinition(col))
 }
@@ -152,5 +388,35 @@ func (g *Generator) ModifyColumn(tableName string, diff schema.ColumnDiff) strin
 		}
 	}
 
+	// Handle identity changes
+	if contains(diff.Changes, "identity") {
+		switch {
+		case diff.New.Identity == nil:
+			sql += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP IDENTITY;\n\n",
+				tableName, diff.ColumnName)
+		case diff.Old.Identity == nil:
+			sql += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ADD %s;\n\n",
+				tableName, diff.ColumnName, identityClause(diff.New.Identity))
+		case diff.New.Identity.Always:
+			sql += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET GENERATED ALWAYS;\n\n",
+				tableName, diff.ColumnName)
+		default:
+			sql += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET GENERATED BY DEFAULT;\n\n",
+				tableName, diff.ColumnName)
+		}
+	}
+
+	// Handle generation expression changes. SET EXPRESSION needs PostgreSQL 17
+	// or later.
+	if contains(diff.Changes, "generated") {
+		if diff.New.Generated == nil {
+			sql += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP EXPRESSION;\n\n",
+				tableName, diff.ColumnName)
+		} else {
+			sql += fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET EXPRESSION AS (%s);\n\n",
+				tableName, diff.ColumnName, diff.New.Generated.Expression)
+		}
+	}
+
 	return strings.TrimSpace(sql)
 }
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -66,6 +67,11 @@ func TestGenerator_FormatColumnDefinition(t *testing.T) {
 			column:   database.Column{Name: "balance", Type: "numeric", Nullable: false, Default: strPtr("0.00")},
 			expected: "balance numeric NOT NULL DEFAULT 0.00",
 		},
+		{
+			name:     "generated column",
+			column:   database.Column{Name: "amount", Type: "numeric", Nullable: true, Generated: &database.GeneratedColumn{Expression: "price * qty", Storage: database.GeneratedStored}},
+			expected: "amount numeric GENERATED ALWAYS AS (price * qty) STORED",
+		},
 	}
 
 	for _, tt := range tests {
@@ -162,6 +168,53 @@ func TestGenerator_CreateTable_SingleColumn(t *testing.T) {
 	}
 }
 
+func TestGenerator_CreateTable_CompositePrimaryKey(t *testing.T) {
+	gen := NewGenerator()
+
+	table := database.Table{
+		Name: "memberships",
+		Columns: []database.Column{
+			{Name: "user_id", Type: "integer", Nullable: false, IsPrimaryKey: true},
+			{Name: "group_id", Type: "integer", Nullable: false, IsPrimaryKey: true},
+		},
+		PrimaryKey: []string{"user_id", "group_id"},
+	}
+
+	sql := gen.CreateTable(table)
+	expected := "CREATE TABLE memberships (\n  user_id integer NOT NULL,\n  group_id integer NOT NULL,\n  PRIMARY KEY (user_id, group_id)\n);"
+
+	if sql != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestGenerator_CreateTable_Partitions(t *testing.T) {
+	gen := NewGenerator()
+
+	parent := database.Table{
+		Name: "events",
+		Columns: []database.Column{
+			{Name: "id", Type: "integer", Nullable: true},
+			{Name: "email", Type: "text", Nullable: true},
+		},
+		PartitionBy: &database.PartitionSpec{Strategy: database.PartitionStrategyList, Columns: []string{"id", "lower(email)"}},
+	}
+	expected := `CREATE TABLE events (
+  id integer,
+  email text
+) PARTITION BY LIST (id, (lower(email)));`
+	if sql := gen.CreateTable(parent); sql != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, sql)
+	}
+
+	partition := database.Table{Schema: "app", Name: "events_1", PartitionOf: &database.TableRef{Schema: "app", Table: "events"}, RLSEnabled: true}
+	sql := gen.GenerateMigration(&schema.SchemaDiff{AddedTables: []database.Table{partition}})
+	expected = "-- app.events_1 is a partition of app.events, which can't be created without its bounds. Create it by hand."
+	if sql != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, sql)
+	}
+}
+
 func TestGenerator_DropTable(t *testing.T) {
 	gen := NewGenerator()
 
@@ -180,6 +233,16 @@ func TestGenerator_DropTable(t *testing.T) {
 			table:    database.Table{Name: "user_sessions"},
 			expected: "DROP TABLE user_sessions CASCADE;",
 		},
+		{
+			name:     "public schema",
+			table:    database.Table{Name: "users", Schema: "public"},
+			expected: "DROP TABLE users CASCADE;",
+		},
+		{
+			name:     "other schema",
+			table:    database.Table{Name: "users", Schema: "auth"},
+			expected: "DROP TABLE auth.users CASCADE;",
+		},
 	}
 
 	for _, tt := range tests {
@@ -378,6 +441,70 @@ func TestGenerator_ModifyColumn_DefaultChange(t *testing.T) {
 	}
 }
 
+func TestGenerator_ModifyColumn_IdentityChange(t *testing.T) {
+	gen := NewGenerator()
+	always := &database.IdentitySpec{Always: true}
+	byDefault := &database.IdentitySpec{Always: false}
+
+	tests := []struct {
+		name     string
+		old      *database.IdentitySpec
+		new      *database.IdentitySpec
+		expected string
+	}{
+		{"add identity", nil, always, "ALTER TABLE users ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY;"},
+		{"drop identity", byDefault, nil, "ALTER TABLE users ALTER COLUMN id DROP IDENTITY;"},
+		{"set generated always", byDefault, always, "ALTER TABLE users ALTER COLUMN id SET GENERATED ALWAYS;"},
+		{"set generated by default", always, byDefault, "ALTER TABLE users ALTER COLUMN id SET GENERATED BY DEFAULT;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := gen.ModifyColumn("users", schema.ColumnDiff{
+				ColumnName: "id",
+				Old:        database.Column{Name: "id", Type: "bigint", Identity: tt.old},
+				New:        database.Column{Name: "id", Type: "bigint", Identity: tt.new},
+				Changes:    []string{"identity"},
+			})
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestGenerator_IdentityRoundTrip checks that identity columns, including
+// their sequence options, survive being generated and parsed again
+func TestGenerator_IdentityRoundTrip(t *testing.T) {
+	gen := NewGenerator()
+	sql := `CREATE TABLE events (
+  id BIGINT GENERATED ALWAYS AS IDENTITY (START WITH 1000 INCREMENT BY 10 MAXVALUE 9223372036854775807 CACHE 20 CYCLE),
+  legacy_id INTEGER GENERATED BY DEFAULT AS IDENTITY (MINVALUE -5 NO MAXVALUE),
+  seq INTEGER GENERATED BY DEFAULT AS IDENTITY
+);`
+
+	parsed, err := schema.ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	generated := gen.CreateTable(parsed.Tables[0])
+	reparsed, err := schema.ParseSQLSchemaWithDialect(generated, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse generated SQL %q: %v", generated, err)
+	}
+
+	for i, col := range parsed.Tables[0].Columns {
+		again := reparsed.Tables[0].Columns[i]
+		if !reflect.DeepEqual(col.Identity, again.Identity) {
+			t.Errorf("Column %s: identity %+v became %+v after generating %q", col.Name, col.Identity, again.Identity, generated)
+		}
+		if again.Nullable {
+			t.Errorf("Column %s: expected identity column to stay NOT NULL", col.Name)
+		}
+	}
+}
+
 func TestGenerator_ModifyColumn_MultipleChanges(t *testing.T) {
 	gen := NewGenerator()
 
@@ -546,6 +673,41 @@ func TestGenerator_GenerateMigration_Empty(t *testing.T) {
 		t.Errorf("Expected empty string for empty diff, got: %q", sql)
 	}
 }
+func TestGenerator_ModifyDomain(t *testing.T) {
+	gen := NewGenerator()
+	oldDefault := "''"
+
+	diff := schema.DomainDiff{
+		Name: "auth.email",
+		Old: database.Domain{
+			Name: "email", Schema: "auth", BaseType: "text", Default: &oldDefault,
+			CheckConstraints: []database.CheckConstraint{
+				{Name: "email_check", Expression: "value LIKE '%@%'"},
+				{Name: "email_check1", Expression: "length(value) < 100"},
+			},
+		},
+		New: database.Domain{
+			Name: "email", Schema: "auth", BaseType: "text",
+			CheckConstraints: []database.CheckConstraint{
+				{Name: "email_check", Expression: "value LIKE '%@%'"},
+				{Name: "email_check1", Expression: "length(value) < 255"},
+			},
+		},
+		Changes: []string{"default", "check_constraints"},
+	}
+
+	sql := gen.ModifyDomain(diff)
+	expected := `ALTER DOMAIN auth.email DROP DEFAULT;
+
+ALTER DOMAIN auth.email DROP CONSTRAINT email_check1;
+
+ALTER DOMAIN auth.email ADD CONSTRAINT email_check1 CHECK (length(value) < 255);`
+
+	if sql != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, sql)
+	}
+}
+
 func TestGenerator_GenerateMigration_EnableRLS(t *testing.T) {
 	gen := NewGenerator()
 
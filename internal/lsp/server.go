@@ -0,0 +1,443 @@
+// Package lsp implements a minimal Language Server Protocol server that
+// checks .lp.sql buffers as they are edited and publishes the diagnostics.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/lockplane/lockplane/internal/schema"
+)
+
+// DefaultDebounce is how long a buffer must go unchanged before it is checked
+const DefaultDebounce = 300 * time.Millisecond
+
+// LSP diagnostic severities
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+// Server checks .lp.sql documents opened in an editor. Requests are read from
+// one stream and responses and notifications written to another, as with
+// stdio.
+type Server struct {
+	// Debounce is how long to wait after a change before checking a document,
+	// so a burst of keystrokes is only checked once
+	Debounce time.Duration
+	// Options returns the check options for a document path, e.g. from the
+	// nearest lockplane.toml. Nil uses the defaults.
+	Options func(path string) schema.CheckOptions
+
+	out     io.Writer
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	documents map[string]*document
+	shutdown  bool
+	// checks counts the buffers actually parsed and checked, rather than
+	// answered from a document's cache
+	checks int
+}
+
+// maxCachedResults is how many distinct texts of a document have their
+// diagnostics kept. Undoing an edit, or typing and deleting a character,
+// returns to a text that was just checked.
+const maxCachedResults = 16
+
+// document is an open buffer. version counts changes, so a check scheduled
+// for an older version can tell it has been superseded. results caches the
+// diagnostics of texts the document has had while open.
+type document struct {
+	text    string
+	version int
+	timer   *time.Timer
+	results map[string][]lspDiagnostic
+}
+
+// NewServer returns a server that writes to out
+func NewServer(out io.Writer) *Server {
+	return &Server{
+		Debounce:  DefaultDebounce,
+		out:       out,
+		documents: make(map[string]*document),
+	}
+}
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errMethodNotFound is the JSON-RPC error code for an unknown method
+const errMethodNotFound = -32601
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range           lspRange         `json:"range"`
+	Severity        int              `json:"severity"`
+	Code            string           `json:"code,omitempty"`
+	CodeDescription *codeDescription `json:"codeDescription,omitempty"`
+	Source          string           `json:"source"`
+	Message         string           `json:"message"`
+}
+
+type codeDescription struct {
+	Href string `json:"href"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+// Serve reads messages from in until the client sends exit or closes the
+// stream
+func (s *Server) Serve(in io.Reader) error {
+	reader := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle responds to a request or applies a notification
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.respond(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				// Full document sync: every change sends the whole buffer
+				"textDocumentSync": 1,
+			},
+			"serverInfo": map[string]string{"name": "lockplane"},
+		})
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		for _, doc := range s.documents {
+			if doc.timer != nil {
+				doc.timer.Stop()
+			}
+		}
+		s.mu.Unlock()
+		return s.respond(msg.ID, nil)
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.close(params.TextDocument.URI)
+	default:
+		// Unknown notifications are ignored; unknown requests need an answer
+		if msg.ID != nil {
+			return s.respondError(msg.ID, errMethodNotFound, fmt.Sprintf("method %q not supported", msg.Method))
+		}
+	}
+	return nil
+}
+
+// update records the new text of a document and schedules a check once the
+// document stops changing. Documents other than .lp.sql files are ignored.
+func (s *Server) update(uri string, text string) {
+	if !strings.HasSuffix(uri, ".lp.sql") {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return
+	}
+
+	doc, ok := s.documents[uri]
+	if !ok {
+		doc = &document{results: make(map[string][]lspDiagnostic)}
+		s.documents[uri] = doc
+	}
+	doc.text = text
+	doc.version++
+	if doc.timer != nil {
+		doc.timer.Stop()
+	}
+	version := doc.version
+	doc.timer = time.AfterFunc(s.Debounce, func() { s.check(uri, version) })
+}
+
+// close forgets a document and clears its diagnostics
+func (s *Server) close(uri string) {
+	s.mu.Lock()
+	doc, ok := s.documents[uri]
+	if ok {
+		if doc.timer != nil {
+			doc.timer.Stop()
+		}
+		delete(s.documents, uri)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		_ = s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: []lspDiagnostic{}})
+	}
+}
+
+// check checks a document and publishes its diagnostics, unless the document
+// changed again since the check was scheduled. A text the document has
+// already had while open isn't parsed again.
+func (s *Server) check(uri string, version int) {
+	s.mu.Lock()
+	doc, ok := s.documents[uri]
+	if !ok || doc.version != version || s.shutdown {
+		s.mu.Unlock()
+		return
+	}
+	text := doc.text
+	diagnostics, cached := doc.results[text]
+	s.mu.Unlock()
+
+	if !cached {
+		diagnostics = s.diagnose(uri, text)
+	}
+
+	s.mu.Lock()
+	current := s.documents[uri] == doc && doc.version == version
+	if !cached {
+		s.checks++
+		if len(doc.results) >= maxCachedResults {
+			clear(doc.results)
+		}
+		doc.results[text] = diagnostics
+	}
+	s.mu.Unlock()
+	if current {
+		_ = s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	}
+}
+
+// diagnose checks the text of a document with the options for its path
+func (s *Server) diagnose(uri string, text string) []lspDiagnostic {
+	path := uriToPath(uri)
+	var opts schema.CheckOptions
+	if s.Options != nil {
+		opts = s.Options(path)
+	}
+	output := schema.CheckSQL(text, path, opts)
+
+	lines := strings.Split(text, "\n")
+	diagnostics := make([]lspDiagnostic, 0, len(output.Diagnostics))
+	for _, d := range output.Diagnostics {
+		diagnostics = append(diagnostics, toLSPDiagnostic(d, lines))
+	}
+	return diagnostics
+}
+
+// toLSPDiagnostic converts a diagnostic to the LSP form. LSP positions are
+// 0-based; the range is the diagnostic's own when it has an end, and
+// otherwise covers the word the diagnostic points at, so editors have
+// something to underline.
+func toLSPDiagnostic(d schema.Diagnostic, lines []string) lspDiagnostic {
+	start := position{Line: max(d.Line-1, 0), Character: max(d.Column-1, 0)}
+	end := start
+	switch {
+	case d.EndLine > 0:
+		end = position{Line: d.EndLine - 1, Character: max(d.EndColumn-1, 0)}
+	case start.Line < len(lines):
+		end.Character = wordEnd(lines[start.Line], start.Character)
+	}
+
+	diagnostic := lspDiagnostic{
+		Range:    lspRange{Start: start, End: end},
+		Severity: severityWarning,
+		Code:     d.Code,
+		Source:   "lockplane",
+		Message:  d.Message,
+	}
+	if d.Severity == schema.SeverityError {
+		diagnostic.Severity = severityError
+	}
+	if d.HelpURI != "" {
+		diagnostic.CodeDescription = &codeDescription{Href: d.HelpURI}
+	}
+	return diagnostic
+}
+
+// wordEnd returns the character after the identifier or quoted name starting
+// at character start of line, or start+1 when none starts there
+func wordEnd(line string, start int) int {
+	runes := []rune(strings.TrimRight(line, "\r"))
+	if start >= len(runes) {
+		return start
+	}
+	end := start
+	if runes[start] == '"' {
+		for end++; end < len(runes) && runes[end] != '"'; end++ {
+		}
+		return min(end+1, len(runes))
+	}
+	for end < len(runes) && (runes[end] == '_' || unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+		end++
+	}
+	if end == start {
+		end++
+	}
+	return end
+}
+
+// uriToPath converts a file:// URI to a path. Other URIs are returned as is.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+func (s *Server) respond(id *json.RawMessage, result any) error {
+	if result == nil {
+		// A null result must still be sent
+		result = json.RawMessage("null")
+	}
+	return s.write(message{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) respondError(id *json.RawMessage, code int, text string) error {
+	return s.write(message{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: text}})
+}
+
+func (s *Server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+// write sends a message with its Content-Length header
+func (s *Server) write(msg message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(data)
+	return err
+}
+
+// readMessage reads one message framed by a Content-Length header
+func readMessage(reader *bufio.Reader) (*message, error) {
+	length := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length == -1 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	// A malformed message is skipped rather than ending the session
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &message{}, nil
+	}
+	return &msg, nil
+}
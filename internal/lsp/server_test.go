@@ -0,0 +1,196 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// client drives a Server over pipes, as an editor would over stdio
+type client struct {
+	t      *testing.T
+	server *Server
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	nextID int
+}
+
+func newClient(t *testing.T, debounce time.Duration) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	server := NewServer(serverOut)
+	server.Debounce = debounce
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(serverIn) }()
+
+	t.Cleanup(func() {
+		clientOut.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+		serverOut.Close()
+	})
+	return &client{t: t, server: server, in: clientOut, out: bufio.NewReader(clientIn)}
+}
+
+func (c *client) send(method string, id *int, params any) {
+	c.t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != nil {
+		msg["id"] = *id
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) request(method string, params any) {
+	c.nextID++
+	id := c.nextID
+	c.send(method, &id, params)
+}
+
+func (c *client) notify(method string, params any) {
+	c.send(method, nil, params)
+}
+
+// receive reads the next message from the server
+func (c *client) receive() *message {
+	c.t.Helper()
+	msg, err := readMessage(c.out)
+	if err != nil {
+		c.t.Fatalf("Failed to read message: %v", err)
+	}
+	return msg
+}
+
+func (c *client) receiveDiagnostics() publishDiagnosticsParams {
+	c.t.Helper()
+	msg := c.receive()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("Expected publishDiagnostics, got %+v", msg)
+	}
+	var params publishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params
+}
+
+func TestServerPublishesDiagnostics(t *testing.T) {
+	c := newClient(t, 50*time.Millisecond)
+
+	c.request("initialize", map[string]any{})
+	if msg := c.receive(); msg.ID == nil || msg.Error != nil {
+		t.Fatalf("Expected an initialize result, got %+v", msg)
+	}
+	c.notify("initialized", map[string]any{})
+
+	uri := "file:///project/schema/users.lp.sql"
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "sql", "version": 1, "text": "CREATE TABLE users (id BIGINT);\n"},
+	})
+	// Rapid changes are checked once, after the last one
+	for i, text := range []string{
+		"CREATE TABLE users (id BIGINT PRIMARY KEY);\n",
+		"CREATE TABLE users (id BIGINT PRIMARY KEY);\nALTER TABLE users DROP COLUMN nope;\n",
+	} {
+		c.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": i + 2},
+			"contentChanges": []map[string]any{{"text": text}},
+		})
+	}
+
+	params := c.receiveDiagnostics()
+	if params.URI != uri || len(params.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic for %s, got %+v", uri, params)
+	}
+	d := params.Diagnostics[0]
+	expected := lspRange{Start: position{Line: 1, Character: 0}, End: position{Line: 1, Character: 5}}
+	if d.Range != expected || d.Severity != severityError || d.Code != "LP000" || d.Source != "lockplane" {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+	if d.CodeDescription == nil || d.CodeDescription.Href == "" {
+		t.Errorf("Expected a link to the rule docs, got %+v", d.CodeDescription)
+	}
+
+	// Fixing the buffer clears the diagnostics, apart from lint warnings
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 4},
+		"contentChanges": []map[string]any{{"text": "CREATE TABLE users (id BIGINT);\n"}},
+	})
+	params = c.receiveDiagnostics()
+	if len(params.Diagnostics) != 1 || params.Diagnostics[0].Severity != severityWarning || params.Diagnostics[0].Code != "LP001" {
+		t.Fatalf("Expected a missing primary key warning, got %+v", params.Diagnostics)
+	}
+	if r := params.Diagnostics[0].Range; r.Start.Character != 13 || r.End.Character != 18 {
+		t.Errorf("Expected the table name to be underlined, got %+v", r)
+	}
+
+	c.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	if params := c.receiveDiagnostics(); len(params.Diagnostics) != 0 {
+		t.Errorf("Expected diagnostics to be cleared on close, got %+v", params.Diagnostics)
+	}
+
+	c.request("shutdown", nil)
+	if msg := c.receive(); msg.Error != nil {
+		t.Errorf("Unexpected shutdown error: %+v", msg.Error)
+	}
+	c.notify("exit", nil)
+}
+
+func TestServerCachesResults(t *testing.T) {
+	c := newClient(t, time.Millisecond)
+
+	uri := "file:///project/schema/users.lp.sql"
+	broken := "CREATE TABLE users (id BIGINT PRIMARY KEY);\nALTER TABLE users DROP COLUMN nope;\n"
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "sql", "version": 1, "text": broken},
+	})
+	first := c.receiveDiagnostics()
+	if len(first.Diagnostics) != 1 || first.Diagnostics[0].Code != "LP000" {
+		t.Fatalf("Expected a parse error, got %+v", first.Diagnostics)
+	}
+
+	// Fixing the buffer and then undoing the fix only checks the new text
+	for i, text := range []string{"CREATE TABLE users (id BIGINT PRIMARY KEY);\n", broken} {
+		c.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": i + 2},
+			"contentChanges": []map[string]any{{"text": text}},
+		})
+		params := c.receiveDiagnostics()
+		if i == 0 && len(params.Diagnostics) != 0 {
+			t.Fatalf("Expected no diagnostics for the fixed buffer, got %+v", params.Diagnostics)
+		}
+		if i == 1 && !reflect.DeepEqual(params.Diagnostics, first.Diagnostics) {
+			t.Fatalf("Expected the cached diagnostics %+v, got %+v", first.Diagnostics, params.Diagnostics)
+		}
+	}
+
+	c.server.mu.Lock()
+	checks := c.server.checks
+	c.server.mu.Unlock()
+	if checks != 2 {
+		t.Errorf("Expected 2 checks, got %d", checks)
+	}
+}
+
+func TestServerUnknownRequest(t *testing.T) {
+	c := newClient(t, time.Millisecond)
+
+	c.notify("$/cancelRequest", map[string]any{"id": 1})
+	c.request("textDocument/hover", map[string]any{})
+	msg := c.receive()
+	if msg.Error == nil || msg.Error.Code != errMethodNotFound {
+		t.Errorf("Expected a method not found error, got %+v", msg)
+	}
+}
@@ -0,0 +1,53 @@
+package schema
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// FileObjects lists the tables and views defined in one schema file
+type FileObjects struct {
+	File string `json:"file"`
+	// Tables and Views hold schema-qualified names, in the order the file
+	// defines them. Views include materialized views.
+	Tables []string `json:"tables"`
+	Views  []string `json:"views"`
+}
+
+// ObjectsByFile groups the tables and views of a schema by the file that
+// defines them, using their source locations, sorted by file. Objects without
+// a source location are listed under an empty file name.
+func ObjectsByFile(schema *database.Schema) []FileObjects {
+	byFile := make(map[string]*FileObjects)
+	objects := func(loc *database.SourceLocation) *FileObjects {
+		var file string
+		if loc != nil {
+			file = loc.File
+		}
+		if byFile[file] == nil {
+			byFile[file] = &FileObjects{File: file, Tables: []string{}, Views: []string{}}
+		}
+		return byFile[file]
+	}
+
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		file := objects(table.SourceLocation)
+		file.Tables = append(file.Tables, qualifiedTableName(table))
+	}
+	for _, view := range schema.Views {
+		file := objects(view.SourceLocation)
+		file.Views = append(file.Views, qualifiedTableName(&database.Table{Schema: view.Schema, Name: view.Name}))
+	}
+
+	files := make([]FileObjects, 0, len(byFile))
+	for _, file := range byFile {
+		files = append(files, *file)
+	}
+	slices.SortFunc(files, func(a, b FileObjects) int {
+		return cmp.Compare(a.File, b.File)
+	})
+	return files
+}
@@ -0,0 +1,38 @@
+package schema
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestObjectsByFile(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE auth.sessions (id BIGINT PRIMARY KEY);
+CREATE VIEW active_users AS SELECT id FROM users;`,
+		"posts.lp.sql": `CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users);
+CREATE MATERIALIZED VIEW post_counts AS SELECT author_id, count(*) FROM posts GROUP BY author_id;`,
+	})
+
+	loaded, err := LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	expected := []FileObjects{
+		{File: filepath.Join(dir, "posts.lp.sql"), Tables: []string{"public.posts"}, Views: []string{"public.post_counts"}},
+		{File: filepath.Join(dir, "users.lp.sql"), Tables: []string{"public.users", "auth.sessions"}, Views: []string{"public.active_users"}},
+	}
+	if files := ObjectsByFile(loaded); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, files)
+	}
+
+	// A single file is grouped the same way
+	single, err := LoadSchema(filepath.Join(dir, "posts.lp.sql"))
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	if files := ObjectsByFile(single); !reflect.DeepEqual(files, expected[:1]) {
+		t.Errorf("Expected %+v, got %+v", expected[:1], files)
+	}
+}
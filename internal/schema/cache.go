@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultCacheDir is where lockplane check caches the parse trees of schema
+// files, relative to the working directory
+const DefaultCacheDir = ".lockplane/cache"
+
+// parseCacheVersion is part of the tag of every cache entry. Increase it when
+// the entry format changes, so older entries are ignored.
+const parseCacheVersion = 1
+
+// parseCache stores the pg_query parse tree of each schema file on disk, so
+// files that haven't changed since the last run skip pg_query.Parse. There is
+// an entry per file path, holding the SHA-256 of the SQL it was parsed from;
+// an entry whose hash doesn't match the file is stale, and the file is parsed
+// again and the entry replaced.
+//
+// Only parse trees are cached, not the schema a file produces: applying a
+// file's statements depends on the files before it, as when an ALTER TABLE
+// alters a table created in an earlier file.
+//
+// A parseCache also holds the trees of the files parsed ahead of time by
+// prepare, in memory. With an empty dir nothing is cached on disk, and a nil
+// *parseCache parses without caching at all. The disk cache is best-effort,
+// so entries that can't be read or written are ignored.
+type parseCache struct {
+	dir string
+
+	mu sync.Mutex
+	// prepared holds the results of prepare by file name
+	prepared map[string]preparedTree
+}
+
+// preparedTree is the result of parsing a file's SQL ahead of time
+type preparedTree struct {
+	sql  string
+	tree *pg_query.ParseResult
+	err  error
+}
+
+// prepare parses the SQL of files, in dialect, with a pool of a worker per
+// CPU, so that parse returns their trees without waiting. pg_query parses on
+// the calling thread without a global lock, so the files parse in parallel.
+func (c *parseCache) prepare(files []string, sources [][]byte, dialect database.Dialect) {
+	c.mu.Lock()
+	c.prepared = make(map[string]preparedTree, len(files))
+	c.mu.Unlock()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(files)) {
+		wg.Go(func() {
+			for i := range jobs {
+				sql := postgresSQL(string(sources[i]), dialect)
+				tree, err := c.parse(files[i], sql)
+				c.mu.Lock()
+				c.prepared[files[i]] = preparedTree{sql: sql, tree: tree, err: err}
+				c.mu.Unlock()
+			}
+		})
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// parse returns the parse tree of sql, the contents of filename after any
+// rewriting. The tree is taken from prepare's results, or the disk cache, if
+// either has one for that file and SQL.
+func (c *parseCache) parse(filename string, sql string) (*pg_query.ParseResult, error) {
+	if c == nil || filename == "" {
+		return pg_query.Parse(sql)
+	}
+	c.mu.Lock()
+	prepared, ok := c.prepared[filename]
+	c.mu.Unlock()
+	if ok && prepared.sql == sql {
+		return prepared.tree, prepared.err
+	}
+	if c.dir == "" {
+		return pg_query.Parse(sql)
+	}
+
+	entryPath := c.entryPath(filename)
+	header := c.header(sql)
+	if data, err := os.ReadFile(entryPath); err == nil && bytes.HasPrefix(data, header) {
+		tree := &pg_query.ParseResult{}
+		if proto.Unmarshal(data[len(header):], tree) == nil {
+			return tree, nil
+		}
+	}
+
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := proto.Marshal(tree); err == nil {
+		c.write(entryPath, append(header, data...))
+	}
+	return tree, nil
+}
+
+// entryPath returns the cache file of a schema file, named by the hash of its
+// absolute path
+func (c *parseCache) entryPath(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	sum := sha256.Sum256([]byte(filename))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".pb")
+}
+
+// header returns the first line of the entry for sql: the cache version, the
+// PostgreSQL version of the parser, and the SHA-256 of sql
+func (c *parseCache) header(sql string) []byte {
+	sum := sha256.Sum256([]byte(sql))
+	return fmt.Appendf(nil, "lockplane-parse-cache v%d pg%d %s\n", parseCacheVersion, parserVersion(), hex.EncodeToString(sum[:]))
+}
+
+// write replaces the entry at entryPath. The entry is written to a temporary
+// file and renamed into place, so a concurrent run never reads half an entry.
+func (c *parseCache) write(entryPath string, data []byte) {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), entryPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// parserVersion returns the PostgreSQL version pg_query parses, as in 170004.
+// It is part of each cache entry's tag, so upgrading the parser invalidates
+// the cache.
+var parserVersion = sync.OnceValue(func() int32 {
+	tree, err := pg_query.Parse("")
+	if err != nil {
+		return 0
+	}
+	return tree.Version
+})
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLoadSchemaCachesParseTrees(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\n",
+	})
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	opts := LoadSchemaOptions{CacheDir: cacheDir}
+
+	uncached, err := LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	first, err := LoadSchemaWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	second, err := LoadSchemaWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	if !reflect.DeepEqual(first, uncached) || !reflect.DeepEqual(second, uncached) {
+		t.Errorf("Expected the cached loads to match the uncached one:\n%+v\n%+v\n%+v", uncached, first, second)
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to read the cache: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 cache entry, got %d", len(entries))
+	}
+
+	// An entry for the same SQL is used instead of parsing the file, so a
+	// tree swapped into it shows up in the loaded schema
+	cache := &parseCache{dir: cacheDir}
+	file := filepath.Join(dir, "users.lp.sql")
+	sql := "CREATE TABLE users (id BIGINT PRIMARY KEY);\n"
+	tree, err := pg_query.Parse("CREATE TABLE cached (id BIGINT PRIMARY KEY);\n")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data, err := proto.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := os.WriteFile(cache.entryPath(file), append(cache.header(sql), data...), 0o644); err != nil {
+		t.Fatalf("Failed to write the cache entry: %v", err)
+	}
+	cached, err := LoadSchemaWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	if cached.Tables[0].Name != "cached" {
+		t.Errorf("Expected the cached parse tree to be used, got table %q", cached.Tables[0].Name)
+	}
+
+	// Once the file changes the entry is stale, and the file is parsed again
+	if err := os.WriteFile(file, []byte("CREATE TABLE accounts (id BIGINT PRIMARY KEY);\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write schema file: %v", err)
+	}
+	changed, err := LoadSchemaWithOptions(dir, opts)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	if changed.Tables[0].Name != "accounts" {
+		t.Errorf("Expected the changed file to be parsed again, got table %q", changed.Tables[0].Name)
+	}
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 1 {
+		t.Errorf("Expected the stale entry to be replaced, got %d entries", len(entries))
+	}
+}
+
+func TestLoadSchemaIgnoresCorruptCacheEntries(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": "CREATE TABLE users (id BIGINT PRIMARY KEY);\n",
+	})
+	cacheDir := t.TempDir()
+	cache := &parseCache{dir: cacheDir}
+	sql := "CREATE TABLE users (id BIGINT PRIMARY KEY);\n"
+	entry := append(cache.header(sql), "not a parse tree"...)
+	if err := os.WriteFile(cache.entryPath(filepath.Join(dir, "users.lp.sql")), entry, 0o644); err != nil {
+		t.Fatalf("Failed to write the cache entry: %v", err)
+	}
+
+	schema, err := LoadSchemaWithOptions(dir, LoadSchemaOptions{CacheDir: cacheDir})
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "users" {
+		t.Errorf("Expected the file to be parsed again, got %+v", schema.Tables)
+	}
+}
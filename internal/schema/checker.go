@@ -1,20 +1,488 @@
 package schema
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
 )
 
-func CheckSchema(path string) (reportJson string, err error) {
-	// step 1, no db, parse the sql
-	_, err = LoadSchema(path)
+// Diagnostic describes a single problem found while checking a schema.
+// Line and Column are 1-based.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	// EndLine and EndColumn locate the position just past the text the
+	// diagnostic is about, such as a table's name, when it is known. They
+	// are zero when the diagnostic points at Line and Column alone.
+	EndLine   int `json:"end_line,omitempty"`
+	EndColumn int `json:"end_column,omitempty"`
+	// HelpURI links to the documentation of the rule that reported the
+	// diagnostic. It is filled in from Code when the diagnostic is added to a
+	// CheckOutput.
+	HelpURI string `json:"help_uri,omitempty"`
+}
+
+// Summary totals the diagnostics in a CheckOutput. A schema is valid when it
+// has no errors; warnings don't affect validity.
+type Summary struct {
+	Errors   int  `json:"errors"`
+	Warnings int  `json:"warnings"`
+	Valid    bool `json:"valid"`
+	// ByCode counts the diagnostics of each rule code, errors and warnings
+	// alike
+	ByCode map[string]int `json:"by_code,omitempty"`
+}
+
+// DiagnosticSink receives diagnostics as a check finds them, so embedders can
+// stream them into their own systems instead of waiting for the CheckOutput
+type DiagnosticSink interface {
+	Report(d Diagnostic)
+}
+
+// CheckOutput is the report produced by CheckSchema. It is itself a
+// DiagnosticSink that collects the diagnostics reported to it.
+type CheckOutput struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Summary     Summary      `json:"summary"`
+	// Coverage is only reported when requested with CheckOptions.Coverage
+	Coverage *Coverage `json:"coverage,omitempty"`
+	// Config is the configuration the check ran with. It is only reported
+	// when the options were loaded from a config file, and isn't merged by
+	// MergeCheckOutputs.
+	Config *AppliedConfig `json:"config,omitempty"`
+
+	// sink is sent each diagnostic as it is added
+	sink DiagnosticSink
+}
+
+// NewCheckOutput returns an empty, valid report
+func NewCheckOutput() *CheckOutput {
+	return &CheckOutput{
+		Diagnostics: []Diagnostic{},
+		Summary:     Summary{Valid: true},
+	}
+}
+
+// newCheckOutput returns an empty report that forwards its diagnostics to
+// opts.Sink
+func newCheckOutput(opts CheckOptions) *CheckOutput {
+	output := NewCheckOutput()
+	output.sink = opts.Sink
+	return output
+}
+
+// AddError records an error diagnostic, marking the schema invalid
+func (o *CheckOutput) AddError(d Diagnostic) {
+	d.Severity = SeverityError
+	d.HelpURI = cmp.Or(d.HelpURI, helpURI(d.Code))
+	o.Diagnostics = append(o.Diagnostics, d)
+	o.Summary.Errors++
+	o.Summary.Valid = false
+	o.Summary.countCode(d.Code, 1)
+	if o.sink != nil {
+		o.sink.Report(d)
+	}
+}
+
+// AddWarning records a warning diagnostic
+func (o *CheckOutput) AddWarning(d Diagnostic) {
+	d.Severity = SeverityWarning
+	d.HelpURI = cmp.Or(d.HelpURI, helpURI(d.Code))
+	o.Diagnostics = append(o.Diagnostics, d)
+	o.Summary.Warnings++
+	o.Summary.countCode(d.Code, 1)
+	if o.sink != nil {
+		o.sink.Report(d)
+	}
+}
+
+// countCode adds n diagnostics with code to ByCode
+func (s *Summary) countCode(code string, n int) {
+	if s.ByCode == nil {
+		s.ByCode = make(map[string]int)
+	}
+	s.ByCode[code] += n
+}
+
+// Report records a diagnostic, making CheckOutput a collecting
+// DiagnosticSink
+func (o *CheckOutput) Report(d Diagnostic) {
+	o.Add(d)
+}
+
+// Add records a diagnostic according to its severity
+func (o *CheckOutput) Add(d Diagnostic) {
+	if d.Severity == SeverityError {
+		o.AddError(d)
+	} else {
+		o.AddWarning(d)
+	}
+}
+
+// MergeCheckOutputs combines reports from separate check runs into one. The
+// diagnostics are concatenated and sorted by location, the summary counts
+// summed, and the result is valid only if every report was. Coverage is
+// summed over the reports that include it.
+func MergeCheckOutputs(outputs ...*CheckOutput) *CheckOutput {
+	merged := NewCheckOutput()
+	for _, output := range outputs {
+		merged.Diagnostics = append(merged.Diagnostics, output.Diagnostics...)
+		merged.Summary.Errors += output.Summary.Errors
+		merged.Summary.Warnings += output.Summary.Warnings
+		merged.Summary.Valid = merged.Summary.Valid && output.Summary.Valid
+		for code, count := range output.Summary.ByCode {
+			merged.Summary.countCode(code, count)
+		}
+		// Reports written before ByCode existed are counted from their
+		// diagnostics
+		if output.Summary.ByCode == nil {
+			for _, d := range output.Diagnostics {
+				merged.Summary.countCode(d.Code, 1)
+			}
+		}
+
+		if c := output.Coverage; c != nil {
+			if merged.Coverage == nil {
+				merged.Coverage = &Coverage{}
+			}
+			merged.Coverage.Statements += c.Statements
+			merged.Coverage.Modeled += c.Modeled
+			merged.Coverage.Tracked += c.Tracked
+			merged.Coverage.Ignored += c.Ignored
+			for kind, count := range c.IgnoredByKind {
+				if merged.Coverage.IgnoredByKind == nil {
+					merged.Coverage.IgnoredByKind = make(map[string]int)
+				}
+				merged.Coverage.IgnoredByKind[kind] += count
+			}
+		}
+	}
+
+	if c := merged.Coverage; c != nil {
+		c.Percent = 100
+		if c.Statements > 0 {
+			c.Percent = float64(c.Modeled) * 100 / float64(c.Statements)
+		}
+	}
+
+	sort.SliceStable(merged.Diagnostics, func(i, j int) bool {
+		a, b := merged.Diagnostics[i], merged.Diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Message < b.Message
+	})
+
+	return merged
+}
+
+// CheckOptions configures the lint rules run by CheckSchemaWithOptions
+type CheckOptions struct {
+	NamingPolicy NamingPolicy
+	// Coverage reports how many statements were modeled in the output
+	Coverage bool
+	// FailFast stops checking at the first error, which is then the only
+	// diagnostic reported
+	FailFast bool
+	// RuleSeverities overrides the severity of lint rules by code. RuleOff
+	// disables a rule. Rules not listed keep their default severity.
+	RuleSeverities map[string]string
+	// ConsistentColumns restricts the inconsistent-column-type rule to column
+	// names that match. Nil compares every column name.
+	ConsistentColumns *regexp.Regexp
+	// FlagColumns selects the column names the LP260 rule treats as flags,
+	// besides boolean columns. Nil uses DefaultFlagColumns.
+	FlagColumns *regexp.Regexp
+	// ConfigFile is the lockplane.toml the options were loaded from, if any.
+	// When set, the applied configuration is echoed in CheckOutput.Config.
+	ConfigFile string
+	// Sink, if set, is sent each diagnostic as soon as it is found, as well
+	// as it being collected in the CheckOutput. With FailFast, diagnostics
+	// found before the first error have already been sent when the output is
+	// trimmed to that error.
+	Sink DiagnosticSink
+	// SkipGenerated suppresses lint diagnostics in files whose first line is
+	// "-- lockplane:generated". The files are still parsed, so other files
+	// can refer to their tables, and errors such as parse errors in them are
+	// still reported.
+	SkipGenerated bool
+	// StripMetaCommands ignores psql meta-commands such as \connect or \i,
+	// lines starting with a backslash, which would otherwise fail to parse.
+	// Each one is reported as a warning.
+	StripMetaCommands bool
+	// CacheDir, if set, caches the parse tree of each schema file, as
+	// LoadSchemaOptions.CacheDir does
+	CacheDir string
+	// Include and Exclude filter the files of a schema directory by base
+	// name, as LoadSchemaOptions.Include and Exclude do. Duplicates and
+	// references are only checked among the files that are kept.
+	Include []string
+	Exclude []string
+
+	// generated holds the file names lint diagnostics are suppressed for
+	generated map[string]bool
+}
+
+// AppliedConfig is the effective configuration of a check: the settings from
+// the config file, with every lint rule's severity resolved
+type AppliedConfig struct {
+	File string `json:"file"`
+	// Rules maps every lint rule's code to the severity it ran with, "off"
+	// for disabled rules
+	Rules        map[string]string `json:"rules"`
+	NamingPolicy struct {
+		Tables  string `json:"tables,omitempty"`
+		Columns string `json:"columns,omitempty"`
+	} `json:"naming_policy"`
+	// ConsistentColumns is the pattern limiting the inconsistent-column-type
+	// rule, empty when every column is compared
+	ConsistentColumns string `json:"consistent_columns,omitempty"`
+	// FlagColumns is the configured pattern of the LP260 rule's flag names,
+	// empty when the default is used
+	FlagColumns       string `json:"flag_columns,omitempty"`
+	FailFast          bool   `json:"fail_fast,omitempty"`
+	SkipGenerated     bool   `json:"skip_generated,omitempty"`
+	StripMetaCommands bool   `json:"strip_meta_commands,omitempty"`
+}
+
+// AppliedConfig returns the effective configuration described by opts
+func (opts CheckOptions) AppliedConfig() *AppliedConfig {
+	applied := &AppliedConfig{
+		File:              opts.ConfigFile,
+		Rules:             make(map[string]string),
+		FailFast:          opts.FailFast,
+		SkipGenerated:     opts.SkipGenerated,
+		StripMetaCommands: opts.StripMetaCommands,
+	}
+	for _, rule := range lintRules {
+		applied.Rules[rule.Code] = cmp.Or(opts.RuleSeverities[rule.Code], rule.Severity)
+	}
+	if opts.NamingPolicy.Tables != nil {
+		applied.NamingPolicy.Tables = opts.NamingPolicy.Tables.String()
+	}
+	if opts.NamingPolicy.Columns != nil {
+		applied.NamingPolicy.Columns = opts.NamingPolicy.Columns.String()
+	}
+	if opts.ConsistentColumns != nil {
+		applied.ConsistentColumns = opts.ConsistentColumns.String()
+	}
+	if opts.FlagColumns != nil {
+		applied.FlagColumns = opts.FlagColumns.String()
+	}
+	return applied
+}
+
+// CheckSchema loads the schema at path and reports any problems with it as
+// diagnostics. An error is returned only when path doesn't lead to any schema
+// files; problems within the files are reported in the output.
+func CheckSchema(path string) (*CheckOutput, error) {
+	return CheckSchemaWithOptions(path, CheckOptions{})
+}
+
+// CheckSchemaWithOptions is like CheckSchema, with lint rules configured by
+// opts.
+func CheckSchemaWithOptions(path string, opts CheckOptions) (*CheckOutput, error) {
+	files, err := findSchemaFiles(osFS{}, path, LoadSchemaOptions{Include: opts.Include, Exclude: opts.Exclude})
 	if err != nil {
-		return "", fmt.Errorf("could not load schema: %v", err)
+		return nil, err
+	}
+
+	output := newCheckOutput(opts)
+	if opts.ConfigFile != "" {
+		output.Config = opts.AppliedConfig()
+	}
+
+	var coverage *Coverage
+	if opts.Coverage {
+		coverage = &Coverage{Percent: 100}
+	}
+
+	if opts.StripMetaCommands {
+		warnings, err := findMetaCommands(files)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range warnings {
+			output.AddWarning(d)
+		}
+	}
+
+	// step 1, no db, parse the sql. Every file that fails to parse is
+	// reported, and the schema isn't linted.
+	loadOpts := LoadSchemaOptions{StripMetaCommands: opts.StripMetaCommands, CacheDir: opts.CacheDir}
+	schema, errs := parseSchemaFileErrors(osFS{}, files, loadOpts, coverage, opts.FailFast)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			output.AddError(parseErrorToDiagnostic(err, files[0]))
+		}
+		return output, nil
+	}
+
+	for _, d := range ValidateDuplicateTablesAsDiagnostics(schema) {
+		output.AddError(d)
 	}
+	for _, d := range ValidateDuplicateIndexesAsDiagnostics(schema) {
+		output.AddError(d)
+	}
+	if stopOnError(output, opts) {
+		return output, nil
+	}
+
+	output.Coverage = coverage
 
-	// step 2, enrich the parser output
+	// step 2, enrich the parser output with lint results
+	if opts.SkipGenerated {
+		if opts.generated, err = findGeneratedFiles(files); err != nil {
+			return nil, err
+		}
+	}
+	runLintRules(schema, opts, output)
 
 	// step 3, with db, run a diff and validate the results
 	// if db is not available, include a warning
 	// TODO surface the warning in vscode
-	return "", nil
+	return output, nil
+}
+
+// CheckSQL checks the SQL of a single schema file, such as an unsaved editor
+// buffer, locating diagnostics in filename. Unlike CheckSchema, a statement
+// that fails to parse is reported and skipped, so every problem in the file is
+// reported at once.
+func CheckSQL(sql string, filename string, opts CheckOptions) *CheckOutput {
+	output := newCheckOutput(opts)
+
+	if opts.StripMetaCommands {
+		for _, d := range metaCommandDiagnostics(sql, filename) {
+			output.AddWarning(d)
+		}
+		sql, _ = stripMetaCommands(sql)
+	}
+
+	schema, diagnostics := ParseWithDiagnostics(sql, database.DialectPostgres, filename)
+	for _, d := range diagnostics {
+		output.AddError(d)
+	}
+	for _, d := range ValidateDuplicateTablesAsDiagnostics(schema) {
+		output.AddError(d)
+	}
+	for _, d := range ValidateDuplicateIndexesAsDiagnostics(schema) {
+		output.AddError(d)
+	}
+	if stopOnError(output, opts) {
+		return output
+	}
+
+	if opts.SkipGenerated {
+		opts.generated = make(map[string]bool)
+		for _, name := range generatedFileNames(sql, filename) {
+			opts.generated[name] = true
+		}
+	}
+	runLintRules(schema, opts, output)
+	return output
+}
+
+// findGeneratedFiles returns the names diagnostics in the generated files
+// among files are reported against
+func findGeneratedFiles(files []string) (map[string]bool, error) {
+	generated := make(map[string]bool)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file %s: %w", file, err)
+		}
+		for _, name := range generatedFileNames(string(data), file) {
+			generated[name] = true
+		}
+	}
+	return generated, nil
+}
+
+// stopOnError reports whether checking should stop because opts.FailFast is
+// set and output has an error. When it does, output is trimmed to that first
+// error.
+func stopOnError(output *CheckOutput, opts CheckOptions) bool {
+	if !opts.FailFast || output.Summary.Errors == 0 {
+		return false
+	}
+
+	for _, d := range output.Diagnostics {
+		if d.Severity == SeverityError {
+			output.Diagnostics = []Diagnostic{d}
+			output.Summary = Summary{Errors: 1, Valid: false, ByCode: map[string]int{d.Code: 1}}
+			break
+		}
+	}
+	return true
+}
+
+// parseErrorToDiagnostic converts an error from loading schema files into an
+// error diagnostic, using the location carried by a ParseError when there is
+// one. Otherwise the diagnostic points at the start of defaultFile.
+func parseErrorToDiagnostic(err error, defaultFile string) Diagnostic {
+	d := Diagnostic{
+		Severity: SeverityError,
+		Code:     CodeParseError,
+		Message:  err.Error(),
+		File:     defaultFile,
+		Line:     1,
+		Column:   1,
+	}
+
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		d.Message = parseErr.Err.Error()
+		if parseErr.File != "" {
+			d.File = parseErr.File
+		}
+		if parseErr.Line > 0 {
+			d.Line = parseErr.Line
+			d.Column = parseErr.Column
+		}
+	}
+
+	return d
+}
+
+// diagnosticAt builds a diagnostic located at loc. A nil location points at the
+// start of an unknown file.
+func diagnosticAt(loc *database.SourceLocation, code string, message string) Diagnostic {
+	d := Diagnostic{
+		Code:    code,
+		Message: message,
+		Line:    1,
+		Column:  1,
+	}
+	if loc != nil {
+		d.File = loc.File
+		d.Line = loc.Line
+		d.Column = loc.Column
+		d.EndLine = loc.EndLine
+		d.EndColumn = loc.EndColumn
+	}
+	return d
 }
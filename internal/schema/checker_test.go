@@ -0,0 +1,1502 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeSchemaFiles writes the given files into a new temp dir and returns it
+func writeSchemaFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	for filename, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", filename, err)
+		}
+	}
+	return tempDir
+}
+
+func TestCheckSchemaValid(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if !output.Summary.Valid {
+		t.Error("Expected schema to be valid")
+	}
+	if len(output.Diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %+v", output.Diagnostics)
+	}
+}
+
+func TestCheckOutputSummaryByCode(t *testing.T) {
+	output := NewCheckOutput()
+	output.AddError(Diagnostic{Code: CodeDuplicateTable})
+	output.AddWarning(Diagnostic{Code: CodeMissingPrimaryKey})
+	output.Add(Diagnostic{Code: CodeMissingPrimaryKey, Severity: SeverityWarning})
+
+	expected := map[string]int{CodeDuplicateTable: 1, CodeMissingPrimaryKey: 2}
+	if !reflect.DeepEqual(output.Summary.ByCode, expected) {
+		t.Errorf("Expected counts by code %v, got %v", expected, output.Summary.ByCode)
+	}
+	if output.Summary.Errors != 1 || output.Summary.Warnings != 2 {
+		t.Errorf("Expected 1 error and 2 warnings, got %+v", output.Summary)
+	}
+
+	merged := MergeCheckOutputs(output, output)
+	expected = map[string]int{CodeDuplicateTable: 2, CodeMissingPrimaryKey: 4}
+	if !reflect.DeepEqual(merged.Summary.ByCode, expected) {
+		t.Errorf("Expected merged counts by code %v, got %v", expected, merged.Summary.ByCode)
+	}
+}
+
+func TestCheckSchemaMissingPath(t *testing.T) {
+	_, err := CheckSchema("/nonexistent/path/file.lp.sql")
+	if err == nil {
+		t.Fatal("Expected error for non-existent path, got nil")
+	}
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got %v", err)
+	}
+}
+
+func TestCheckSchemaParseError(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE a (id INTEGER);`,
+		"b.lp.sql": "\n\nCREATE TABLE b (x INT, PRIMARY KEY (y));",
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if output.Summary.Valid || output.Summary.Errors != 1 {
+		t.Fatalf("Expected 1 error, got summary %+v", output.Summary)
+	}
+
+	d := output.Diagnostics[0]
+	if d.Severity != SeverityError {
+		t.Errorf("Expected error severity, got %q", d.Severity)
+	}
+	if d.Code != CodeParseError {
+		t.Errorf("Expected code %s, got %q", CodeParseError, d.Code)
+	}
+	if d.File != filepath.Join(dir, "b.lp.sql") || d.Line != 3 || d.Column != 1 {
+		t.Errorf("Expected diagnostic at b.lp.sql:3:1, got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if d.EndLine != 0 || d.EndColumn != 0 {
+		t.Errorf("Expected a parse error to stay a single point, got a range to %d:%d", d.EndLine, d.EndColumn)
+	}
+	if !strings.Contains(d.Message, `primary key column "y" does not exist`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaParseErrorsInEveryFile(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.lp.sql": "CREATE TABLE a (id INTEGER PRIMARY KEY);",
+		"b.lp.sql": "CREATE TABLE b id INTEGER);",
+		"c.lp.sql": "CREATE TABLE c (id INTEGER PRIMARY KEY);\nALTER TABLE missing ADD COLUMN x INT;",
+		"d.lp.sql": "ALTER TABLE a ADD COLUMN name TEXT;\nCREATE TABLE d (x INT, PRIMARY KEY (y));",
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	expected := []struct {
+		file string
+		line int
+	}{{"b.lp.sql", 1}, {"c.lp.sql", 2}, {"d.lp.sql", 2}}
+	if len(output.Diagnostics) != len(expected) || output.Summary.Errors != len(expected) {
+		t.Fatalf("Expected a parse error in each broken file, got %+v", output.Diagnostics)
+	}
+	for i, want := range expected {
+		d := output.Diagnostics[i]
+		if d.Code != CodeParseError || d.File != filepath.Join(dir, want.file) || d.Line != want.line {
+			t.Errorf("Expected a parse error at %s:%d, got %s at %s:%d: %s", want.file, want.line, d.Code, d.File, d.Line, d.Message)
+		}
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{FailFast: true})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if len(output.Diagnostics) != 1 || output.Diagnostics[0].File != filepath.Join(dir, "b.lp.sql") {
+		t.Errorf("Expected only the error in b.lp.sql with fail-fast, got %+v", output.Diagnostics)
+	}
+}
+
+func TestCheckSchemaDuplicateTables(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+		"b.lp.sql": "\nCREATE TABLE users (id BIGINT PRIMARY KEY);",
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if output.Summary.Errors != 1 {
+		t.Fatalf("Expected 1 error, got %+v", output.Diagnostics)
+	}
+
+	d := output.Diagnostics[0]
+	if d.Code != CodeDuplicateTable || d.Severity != SeverityError {
+		t.Errorf("Expected %s error, got %s %s", CodeDuplicateTable, d.Severity, d.Code)
+	}
+	if d.File != filepath.Join(dir, "b.lp.sql") || d.Line != 2 || d.Column != 14 {
+		t.Errorf("Expected diagnostic at b.lp.sql:2:14, got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if d.EndLine != 2 || d.EndColumn != 19 {
+		t.Errorf("Expected the range to end after the table name at 2:19, got %d:%d", d.EndLine, d.EndColumn)
+	}
+	if !strings.Contains(d.Message, `table "public.users" is defined multiple times`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+	if !strings.Contains(d.Message, "a.lp.sql:1:14") {
+		t.Errorf("Expected message to mention the first definition, got %q", d.Message)
+	}
+}
+
+func TestCheckSchemaIncludeExclude(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"auth_users.lp.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+		"legacy_users.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY);`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if len(diagnosticsWithCode(output, CodeDuplicateTable)) != 1 {
+		t.Fatalf("Expected the duplicate table to be reported, got %+v", output.Diagnostics)
+	}
+
+	// Duplicates are only found among the files that are checked
+	for _, opts := range []CheckOptions{{Include: []string{"auth_*"}}, {Exclude: []string{"legacy_*"}}} {
+		output, err := CheckSchemaWithOptions(dir, opts)
+		if err != nil {
+			t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+		}
+		if len(output.Diagnostics) != 0 {
+			t.Errorf("Expected no diagnostics with include %v and exclude %v, got %+v", opts.Include, opts.Exclude, output.Diagnostics)
+		}
+	}
+}
+
+func TestCheckSchemaDuplicateIndexNames(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);
+CREATE INDEX idx_email ON users (email);`,
+		"b.lp.sql": `CREATE TABLE accounts (id INTEGER PRIMARY KEY, email TEXT);
+CREATE INDEX idx_email ON accounts (email);`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	diagnostics := diagnosticsWithCode(output, CodeDuplicateIndexName)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 %s diagnostic, got %+v", CodeDuplicateIndexName, output.Diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Severity != SeverityError || d.File != filepath.Join(dir, "b.lp.sql") || d.Line != 2 {
+		t.Errorf("Expected an error at b.lp.sql:2, got %+v", d)
+	}
+	if !strings.Contains(d.Message, `index "public.idx_email" is defined multiple times`) || !strings.Contains(d.Message, "a.lp.sql:2:") {
+		t.Errorf("Expected the message to mention the first definition, got %q", d.Message)
+	}
+}
+
+func TestCheckSchemaIndexNamesInDifferentSchemas(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE public.users (id INTEGER PRIMARY KEY, email TEXT);
+CREATE INDEX idx_email ON public.users (email);
+CREATE TABLE auth.users (id INTEGER PRIMARY KEY, email TEXT);
+CREATE INDEX idx_email ON auth.users (email);
+CREATE INDEX idx_users_id ON public.users (id);`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if diagnostics := diagnosticsWithCode(output, CodeDuplicateIndexName); len(diagnostics) != 0 {
+		t.Errorf("Expected no %s diagnostics, got %+v", CodeDuplicateIndexName, diagnostics)
+	}
+}
+
+func TestCheckSchemaSkipGenerated(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"generated.lp.sql": `-- lockplane:generated
+CREATE TABLE events (id BIGINT);
+CREATE TABLE audit (id BIGINT);
+`,
+		"users.lp.sql": `CREATE TABLE users (id BIGINT);
+CREATE TABLE logins (id BIGINT PRIMARY KEY, event_id BIGINT REFERENCES events (id));
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if missing := diagnosticsWithCode(output, CodeMissingPrimaryKey); len(missing) != 3 {
+		t.Fatalf("Expected 3 missing primary key warnings without the option, got %+v", missing)
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{SkipGenerated: true})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	// The generated file is still parsed, so the foreign key to events
+	// resolves, but only users is reported
+	for _, d := range output.Diagnostics {
+		if d.File == filepath.Join(dir, "generated.lp.sql") {
+			t.Errorf("Expected diagnostics in the generated file to be suppressed, got %+v", d)
+		}
+	}
+	missing := diagnosticsWithCode(output, CodeMissingPrimaryKey)
+	if len(missing) != 1 || !strings.Contains(missing[0].Message, `"public.users"`) {
+		t.Errorf("Expected only users to be reported, got %+v", output.Diagnostics)
+	}
+	if len(diagnosticsWithCode(output, CodeForeignKeyNotUnique)) != 1 {
+		t.Errorf("Expected the foreign key to the generated table to be checked, got %+v", output.Diagnostics)
+	}
+}
+
+func TestCheckSchemaFailFast(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"a.lp.sql": `CREATE TABLE events (id INTEGER);
+CREATE TABLE users (id INTEGER PRIMARY KEY);
+CREATE TABLE posts (id INTEGER PRIMARY KEY);`,
+		"b.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users (uid));`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if output.Summary.Errors < 2 || output.Summary.Warnings == 0 {
+		t.Fatalf("Expected several errors and a warning without fail-fast, got %+v", output.Summary)
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{FailFast: true})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if len(output.Diagnostics) != 1 {
+		t.Fatalf("Expected only the first error with fail-fast, got %+v", output.Diagnostics)
+	}
+	if !reflect.DeepEqual(output.Summary, Summary{Errors: 1, Valid: false, ByCode: map[string]int{CodeDuplicateTable: 1}}) {
+		t.Errorf("Expected a summary of 1 error, got %+v", output.Summary)
+	}
+	if d := output.Diagnostics[0]; d.Code != CodeDuplicateTable || !strings.Contains(d.Message, `"public.users"`) {
+		t.Errorf("Expected the first duplicate table error, got %+v", d)
+	}
+}
+
+func TestCheckSchemaIndexOnMissingColumn(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);
+CREATE INDEX users_email_idx ON users (email);
+ALTER TABLE users DROP COLUMN email;
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if !output.Summary.Valid {
+		t.Error("Expected warnings not to make the schema invalid")
+	}
+	if output.Summary.Warnings != 1 || len(output.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 warning, got %+v", output.Diagnostics)
+	}
+
+	d := output.Diagnostics[0]
+	if d.Code != CodeIndexOnMissingColumn || d.Severity != SeverityWarning {
+		t.Errorf("Expected %s warning, got %s %s", CodeIndexOnMissingColumn, d.Severity, d.Code)
+	}
+	if d.File != filepath.Join(dir, "users.lp.sql") || d.Line != 2 || d.Column != 1 {
+		t.Errorf("Expected diagnostic at the index (users.lp.sql:2:1), got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if d.EndLine != 2 || d.EndColumn != 46 {
+		t.Errorf("Expected the range to span the CREATE INDEX statement to 2:46, got %d:%d", d.EndLine, d.EndColumn)
+	}
+	if !strings.Contains(d.Message, `missing column "email"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaIndexClauseMissingColumn(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, active BOOLEAN);
+CREATE INDEX users_email_idx ON users (email) INCLUDE (id) WHERE active;
+CREATE INDEX users_email_name_idx ON users (email) INCLUDE (name);
+CREATE INDEX users_deleted_idx ON users (id) WHERE deleted_at IS NULL AND users.archived;
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if output.Summary.Valid {
+		t.Error("Expected the missing columns to make the schema invalid")
+	}
+	diagnostics := diagnosticsWithCode(output, CodeIndexClauseMissingColumn)
+	if len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 %s errors, got %+v", CodeIndexClauseMissingColumn, output.Diagnostics)
+	}
+
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{3, `includes missing column "name"`},
+		{4, `WHERE predicate referencing missing column "deleted_at"`},
+		{4, `WHERE predicate referencing missing column "archived"`},
+	}
+	for i, want := range expected {
+		d := diagnostics[i]
+		if d.Severity != SeverityError || d.Line != want.line || d.Column != 1 {
+			t.Errorf("Expected an error at the index (line %d), got %s at %d:%d", want.line, d.Severity, d.Line, d.Column)
+		}
+		if !strings.Contains(d.Message, want.message) {
+			t.Errorf("Expected message containing %q, got %q", want.message, d.Message)
+		}
+	}
+}
+
+func TestCheckSchemaTrivialCheck(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"products.lp.sql": `CREATE TABLE products (
+  id INTEGER PRIMARY KEY,
+  price NUMERIC CHECK (price > 0),
+  CHECK (1 = 1),
+  CONSTRAINT never CHECK (false)
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if !output.Summary.Valid {
+		t.Error("Expected warnings not to make the schema invalid")
+	}
+	if len(output.Diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", output.Diagnostics)
+	}
+
+	tests := []struct {
+		line    int
+		message string
+	}{
+		{4, `"products_check" on table "public.products" is always true`},
+		{5, `"never" on table "public.products" is always false`},
+	}
+	for i, tt := range tests {
+		d := output.Diagnostics[i]
+		if d.Code != CodeTrivialCheck || d.Severity != SeverityWarning {
+			t.Errorf("Expected %s warning, got %s %s", CodeTrivialCheck, d.Severity, d.Code)
+		}
+		if d.Line != tt.line || d.Column != 3 {
+			t.Errorf("Expected diagnostic at %d:3, got %d:%d", tt.line, d.Line, d.Column)
+		}
+		if !strings.Contains(d.Message, tt.message) {
+			t.Errorf("Expected message to contain %q, got %q", tt.message, d.Message)
+		}
+	}
+}
+
+func TestCheckSchemaEmptyTable(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE placeholder ();
+CREATE TABLE events (id BIGINT PRIMARY KEY, payload JSONB);
+CREATE TABLE archived_events () INHERITS (events);
+CREATE TABLE measurements (id BIGINT, taken_at DATE) PARTITION BY RANGE (taken_at);
+CREATE TABLE measurements_2024 PARTITION OF measurements FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	empty := diagnosticsWithCode(output, CodeEmptyTable)
+	if len(empty) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeEmptyTable, empty)
+	}
+	d := empty[0]
+	if d.Severity != SeverityWarning || d.Line != 1 || d.Column != 14 {
+		t.Errorf("Expected a warning at the table name (1:14), got %s at %d:%d", d.Severity, d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, `"public.placeholder"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaUndefinedType(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"types.lp.sql": `CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE DOMAIN email AS TEXT;
+CREATE TYPE auth.role AS ENUM ('admin', 'member');
+`,
+		"people.lp.sql": `CREATE TABLE people (
+  id BIGINT PRIMARY KEY,
+  current_mood mood,
+  contact email,
+  status auth.status,
+  role auth.role,
+  tags mood[],
+  price NUMERIC(10,2)
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	undefined := diagnosticsWithCode(output, CodeUndefinedType)
+	if len(undefined) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeUndefinedType, undefined)
+	}
+	d := undefined[0]
+	if d.Severity != SeverityWarning || d.Line != 5 || d.Column != 3 {
+		t.Errorf("Expected a warning at the column (5:3), got %s at %d:%d", d.Severity, d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, `"auth.status"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+	if unknown := diagnosticsWithCode(output, CodeUnknownType); len(unknown) != 0 {
+		t.Errorf("Expected no %s warnings, got %+v", CodeUnknownType, unknown)
+	}
+}
+
+func TestCheckSchemaUnknownType(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"people.lp.sql": `CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE TABLE people (
+  id integar PRIMARY KEY,
+  name varchr(100),
+  tags txt[],
+  created_at TIMESTAMP(3) WITH TIME ZONE,
+  moods mood[],
+  location public.geo_point,
+  oid_ref regtype,
+  ip inet
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	unknown := diagnosticsWithCode(output, CodeUnknownType)
+	expected := []struct {
+		line int
+		typ  string
+	}{{3, `"integar"`}, {4, `"varchr"`}, {5, `"txt"`}}
+	if len(unknown) != len(expected) {
+		t.Fatalf("Expected %d %s warnings, got %+v", len(expected), CodeUnknownType, unknown)
+	}
+	for i, want := range expected {
+		d := unknown[i]
+		if d.Severity != SeverityWarning || d.Line != want.line || d.Column != 3 || !strings.Contains(d.Message, want.typ) {
+			t.Errorf("Expected a warning about %s at %d:3, got %s at %d:%d: %s", want.typ, want.line, d.Severity, d.Line, d.Column, d.Message)
+		}
+	}
+}
+
+func TestCheckSchemaExtensionTypes(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tags.lp.sql": `CREATE EXTENSION IF NOT EXISTS ltree;
+CREATE EXTENSION vector WITH SCHEMA extensions;
+CREATE TABLE tags (
+  id BIGINT PRIMARY KEY,
+  path ltree,
+  pattern lquery,
+  embedding extensions.halfvec(3),
+  sparse public.sparsevec,
+  code isbn
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	// isbn needs the isn extension, which the schema doesn't create
+	unknown := diagnosticsWithCode(output, CodeUnknownType)
+	if len(unknown) != 1 || unknown[0].Line != 9 {
+		t.Errorf("Expected a %s warning for code only, got %+v", CodeUnknownType, unknown)
+	}
+	// vector is installed in the extensions schema, not public
+	undefined := diagnosticsWithCode(output, CodeUndefinedType)
+	if len(undefined) != 1 || undefined[0].Line != 8 {
+		t.Errorf("Expected a %s warning for sparse only, got %+v", CodeUndefinedType, undefined)
+	}
+}
+
+func TestCheckSchemaWithOids(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE legacy (id BIGINT PRIMARY KEY) WITH (OIDS);
+CREATE TABLE modern (id BIGINT PRIMARY KEY) WITH (oids = false, fillfactor = 70);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	oids := diagnosticsWithCode(output, CodeWithOidsDeprecated)
+	if len(oids) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeWithOidsDeprecated, oids)
+	}
+	d := oids[0]
+	if d.Severity != SeverityWarning || d.Line != 1 || d.Column != 14 {
+		t.Errorf("Expected a warning at the table name (1:14), got %s at %d:%d", d.Severity, d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, `"public.legacy"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaRLSWithoutPolicy(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE notes (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY);
+CREATE TABLE tags (id BIGINT PRIMARY KEY);
+ALTER TABLE notes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE posts ENABLE ROW LEVEL SECURITY;
+CREATE POLICY posts_read ON posts FOR SELECT USING (true);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	rls := diagnosticsWithCode(output, CodeRLSWithoutPolicy)
+	if len(rls) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeRLSWithoutPolicy, rls)
+	}
+	d := rls[0]
+	if d.Severity != SeverityWarning || d.Line != 1 || d.Column != 14 {
+		t.Errorf("Expected a warning at the table name (1:14), got %s at %d:%d", d.Severity, d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, `"public.notes"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaPartitionParentMissing(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"events.lp.sql": `CREATE TABLE events (id BIGINT, created_at DATE, PRIMARY KEY (id, created_at)) PARTITION BY RANGE (created_at);
+CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+CREATE TABLE logs_2024 PARTITION OF audit.logs FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	missing := diagnosticsWithCode(output, CodePartitionParentMissing)
+	if len(missing) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodePartitionParentMissing, missing)
+	}
+	d := missing[0]
+	if d.Severity != SeverityWarning || d.Line != 3 || d.Column != 14 {
+		t.Errorf("Expected a warning at the partition name (3:14), got %s at %d:%d", d.Severity, d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, `"public.logs_2024"`) || !strings.Contains(d.Message, `"audit.logs"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaUndeclaredSchema(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schemas.lp.sql": "CREATE SCHEMA auth;\nCREATE SCHEMA IF NOT EXISTS auth;\n",
+		"tables.lp.sql": `CREATE TABLE auth.users (id BIGINT PRIMARY KEY);
+CREATE TABLE aut.sessions (id BIGINT PRIMARY KEY);
+CREATE TABLE public.notes (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY);
+CREATE TYPE billing.plan AS ENUM ('free', 'paid');
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	undeclared := diagnosticsWithCode(output, CodeUndeclaredSchema)
+	if len(undeclared) != 2 {
+		t.Fatalf("Expected 2 %s warnings, got %+v", CodeUndeclaredSchema, undeclared)
+	}
+	if d := undeclared[0]; d.Severity != SeverityWarning || d.Line != 2 || d.Column != 14 || !strings.Contains(d.Message, `table "aut.sessions" is in schema "aut"`) {
+		t.Errorf("Expected a warning at aut.sessions (2:14), got %+v", d)
+	}
+	if d := undeclared[1]; !strings.Contains(d.Message, `type "billing.plan"`) {
+		t.Errorf("Expected a warning for billing.plan, got %+v", d)
+	}
+
+	// Teams that create schemas out-of-band can turn the rule off
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: map[string]string{CodeUndeclaredSchema: RuleOff}})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if undeclared := diagnosticsWithCode(output, CodeUndeclaredSchema); len(undeclared) != 0 {
+		t.Errorf("Expected the rule to be off, got %+v", undeclared)
+	}
+}
+
+func TestCheckSchemaStripMetaCommands(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"dump.lp.sql": `\connect app
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+`,
+	})
+
+	// Without the option the meta-command fails to parse
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if output.Summary.Valid {
+		t.Fatalf("Expected a parse error for the meta-command, got %+v", output.Diagnostics)
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{StripMetaCommands: true})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if !output.Summary.Valid {
+		t.Fatalf("Expected the schema to parse, got %+v", output.Diagnostics)
+	}
+	warnings := diagnosticsWithCode(output, CodeMetaCommand)
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeMetaCommand, output.Diagnostics)
+	}
+	if d := warnings[0]; d.Severity != SeverityWarning || d.Line != 1 || d.Column != 1 || !strings.Contains(d.Message, `"\\connect app"`) {
+		t.Errorf("Expected a warning for \\connect at 1:1, got %+v", d)
+	}
+
+	schema, err := LoadSchemaWithOptions(dir, LoadSchemaOptions{StripMetaCommands: true})
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].SourceLocation.Line != 2 {
+		t.Errorf("Expected users on line 2, got %+v", schema.Tables)
+	}
+
+	sqlOutput := CheckSQL("\\connect app\nCREATE TABLE users (id BIGINT PRIMARY KEY);\n", "buffer.lp.sql", CheckOptions{StripMetaCommands: true})
+	if !sqlOutput.Summary.Valid || len(diagnosticsWithCode(sqlOutput, CodeMetaCommand)) != 1 {
+		t.Errorf("Expected CheckSQL to strip the meta-command with a warning, got %+v", sqlOutput.Diagnostics)
+	}
+}
+
+func TestCheckSchemaUnquotedUppercase(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE SCHEMA app;
+CREATE TABLE app.Orders (id BIGINT PRIMARY KEY, "CustomerId" BIGINT, Total NUMERIC);
+CREATE TABLE "Invoices" (id BIGINT PRIMARY KEY, amount NUMERIC);
+ALTER TABLE "Invoices" ADD COLUMN DueDate DATE;
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	uppercase := diagnosticsWithCode(output, CodeUnquotedUppercase)
+	if len(uppercase) != 3 {
+		t.Fatalf("Expected 3 %s warnings, got %+v", CodeUnquotedUppercase, uppercase)
+	}
+	if d := uppercase[0]; d.Severity != SeverityWarning || d.Line != 2 || d.Column != 14 || !strings.Contains(d.Message, `table Orders is unquoted, so PostgreSQL names it "app.orders"`) {
+		t.Errorf("Expected a warning at app.Orders (2:14), got %+v", d)
+	}
+	if d := uppercase[1]; d.Line != 2 || d.Column != 70 || !strings.Contains(d.Message, `column Total in table "app.orders" is unquoted, so PostgreSQL names it "total"`) {
+		t.Errorf("Expected a warning at Total (2:70), got %+v", d)
+	}
+	if d := uppercase[2]; d.Line != 4 || d.Column != 35 || !strings.Contains(d.Message, `column DueDate in table "public.Invoices"`) {
+		t.Errorf("Expected a warning at DueDate (4:35), got %+v", d)
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: map[string]string{CodeUnquotedUppercase: RuleOff}})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if uppercase := diagnosticsWithCode(output, CodeUnquotedUppercase); len(uppercase) != 0 {
+		t.Errorf("Expected the rule to be off, got %+v", uppercase)
+	}
+}
+
+func TestCheckSchemaReservedKeyword(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE events (
+  id BIGINT PRIMARY KEY,
+  time TIMESTAMPTZ,
+  "position" INTEGER,
+  name TEXT,
+  type TEXT
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	// name and type are unreserved, and "position" is quoted
+	reserved := diagnosticsWithCode(output, CodeReservedKeyword)
+	if len(reserved) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeReservedKeyword, reserved)
+	}
+	if d := reserved[0]; d.Severity != SeverityWarning || d.Line != 3 || d.Column != 3 || !strings.Contains(d.Message, `column "time" in table "public.events" is named with the keyword TIME`) {
+		t.Errorf("Expected a warning at time (3:3), got %+v", d)
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: map[string]string{CodeReservedKeyword: SeverityError}})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if reserved := diagnosticsWithCode(output, CodeReservedKeyword); len(reserved) != 1 || reserved[0].Severity != SeverityError {
+		t.Errorf("Expected the rule to report an error, got %+v", reserved)
+	}
+}
+
+func TestCheckSchemaAutoIncrementDefault(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE orders (
+  id SERIAL DEFAULT 0 PRIMARY KEY,
+  number BIGINT GENERATED BY DEFAULT AS IDENTITY DEFAULT 1,
+  ref BIGSERIAL,
+  status TEXT DEFAULT 'new',
+  seq BIGINT GENERATED ALWAYS AS IDENTITY
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	// Serial and identity columns without defaults, and ordinary defaulted
+	// columns, are fine
+	conflicts := diagnosticsWithCode(output, CodeAutoIncrementDefault)
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 %s errors, got %+v", CodeAutoIncrementDefault, conflicts)
+	}
+	if d := conflicts[0]; d.Severity != SeverityError || d.Line != 2 || d.Column != 3 || !strings.Contains(d.Message, `column "id" in table "public.orders" is a serial column`) {
+		t.Errorf("Expected an error at id (2:3), got %+v", d)
+	}
+	if d := conflicts[1]; d.Line != 3 || !strings.Contains(d.Message, `column "number" in table "public.orders" is an identity column`) || !strings.Contains(d.Message, "DEFAULT 1") {
+		t.Errorf("Expected an error at number (3:3), got %+v", d)
+	}
+}
+
+func TestCheckSchemaUndeclaredSequence(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE SCHEMA app;
+CREATE SEQUENCE app.invoice_numbers;
+CREATE TABLE orders (
+  id SERIAL PRIMARY KEY,
+  number BIGINT GENERATED BY DEFAULT AS IDENTITY (SEQUENCE NAME app.order_numbers),
+  invoice BIGINT DEFAULT nextval('invoice_numbers'),
+  copy BIGINT DEFAULT nextval('orders_id_seq'::regclass),
+  renumbered BIGINT DEFAULT nextval('app.order_numbers'),
+  legacy BIGINT DEFAULT nextval('Legacy_Seq'),
+  quoted BIGINT DEFAULT nextval('app."Invoice_Numbers"')
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	// Declared sequences, and the sequences of serial and identity columns,
+	// are fine. Unquoted names fold to lowercase; quoted names keep their case.
+	undeclared := diagnosticsWithCode(output, CodeUndeclaredSequence)
+	if len(undeclared) != 2 {
+		t.Fatalf("Expected 2 %s warnings, got %+v", CodeUndeclaredSequence, undeclared)
+	}
+	if d := undeclared[0]; d.Severity != SeverityWarning || d.Line != 9 || d.Column != 3 ||
+		!strings.Contains(d.Message, `column "legacy" in table "public.orders" takes its default from sequence "legacy_seq"`) {
+		t.Errorf("Expected a warning at legacy (9:3), got %+v", d)
+	}
+	if d := undeclared[1]; d.Line != 10 || !strings.Contains(d.Message, `sequence "app.Invoice_Numbers"`) {
+		t.Errorf("Expected a warning at quoted (10:3), got %+v", d)
+	}
+}
+
+func TestCheckSchemaForeignKeySetNullNotNull(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  author_id BIGINT NOT NULL REFERENCES users ON DELETE SET NULL,
+  editor_id BIGINT REFERENCES users ON DELETE SET NULL,
+  owner_id BIGINT REFERENCES users ON DELETE SET DEFAULT,
+  reviewer_id BIGINT NOT NULL DEFAULT 0 REFERENCES users ON DELETE SET DEFAULT,
+  tenant_id BIGINT NOT NULL,
+  parent_id BIGINT,
+  FOREIGN KEY (tenant_id, parent_id) REFERENCES posts (id, id) ON DELETE SET NULL (parent_id),
+  FOREIGN KEY (tenant_id) REFERENCES users ON DELETE CASCADE
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	actions := diagnosticsWithCode(output, CodeForeignKeySetNullNotNull)
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 %s warnings, got %+v", CodeForeignKeySetNullNotNull, actions)
+	}
+	if d := actions[0]; d.Severity != SeverityWarning || d.Line != 4 || d.Column != 29 ||
+		!strings.Contains(d.Message, `"author_id" is NOT NULL`) {
+		t.Errorf("Expected a warning for author_id at the foreign key (4:29), got %+v", d)
+	}
+	if d := actions[1]; d.Line != 6 || !strings.Contains(d.Message, `"owner_id" has no default`) {
+		t.Errorf("Expected a warning for owner_id on line 6, got %+v", d)
+	}
+}
+
+func TestCheckSchemaNullableFlag(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE DOMAIN required_bool AS BOOLEAN NOT NULL;
+CREATE TABLE users (
+  id BIGINT PRIMARY KEY,
+  is_admin BOOLEAN,
+  verified BOOLEAN DEFAULT false,
+  has_avatar INTEGER,
+  can_edit INTEGER,
+  is_active BOOLEAN NOT NULL DEFAULT true,
+  is_deleted required_bool,
+  is_owner BOOLEAN GENERATED ALWAYS AS (id = 1) STORED
+);
+`,
+	})
+	enabled := map[string]string{CodeNullableFlag: SeverityWarning}
+
+	// Off unless enabled
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if d := diagnosticsWithCode(output, CodeNullableFlag); len(d) != 0 {
+		t.Errorf("Expected the rule to be off by default, got %+v", d)
+	}
+
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: enabled})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	flags := diagnosticsWithCode(output, CodeNullableFlag)
+	if len(flags) != 3 {
+		t.Fatalf("Expected is_admin, verified and has_avatar to be reported, got %+v", flags)
+	}
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{4, `column "is_admin" in table "public.users" looks like a flag but is nullable; consider making it NOT NULL DEFAULT`},
+		{5, `column "verified" in table "public.users" looks like a flag but is nullable; consider making it NOT NULL`},
+		{6, `column "has_avatar" in table "public.users" looks like a flag but is nullable; consider making it NOT NULL DEFAULT`},
+	}
+	for i, want := range expected {
+		if d := flags[i]; d.Line != want.line || d.Column != 3 || d.Message != want.message || d.Severity != SeverityWarning {
+			t.Errorf("Expected a warning at %d:3 %q, got %+v", want.line, want.message, d)
+		}
+	}
+
+	// The flag names are configurable
+	output, err = CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: enabled, FlagColumns: regexp.MustCompile(`^can_`)})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	var names []string
+	for _, d := range diagnosticsWithCode(output, CodeNullableFlag) {
+		names = append(names, d.Message[len(`column "`):strings.Index(d.Message, `" in`)])
+	}
+	if !reflect.DeepEqual(names, []string{"is_admin", "verified", "can_edit"}) {
+		t.Errorf("Expected the boolean columns and can_edit to be reported, got %v", names)
+	}
+}
+
+func TestCheckSchemaInconsistentColumnType(t *testing.T) {
+	enabled := map[string]string{CodeInconsistentColumnType: SeverityWarning}
+
+	t.Run("consistent", func(t *testing.T) {
+		dir := writeSchemaFiles(t, map[string]string{
+			"tables.lp.sql": `CREATE DOMAIN tenant AS BIGINT;
+CREATE TABLE users (id BIGSERIAL PRIMARY KEY, tenant_id BIGINT);
+CREATE TABLE orders (id BIGINT PRIMARY KEY, tenant_id tenant);
+`,
+		})
+
+		output, err := CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: enabled})
+		if err != nil {
+			t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+		}
+		if d := diagnosticsWithCode(output, CodeInconsistentColumnType); len(d) != 0 {
+			t.Errorf("Expected no %s warnings, got %+v", CodeInconsistentColumnType, d)
+		}
+	})
+
+	t.Run("inconsistent", func(t *testing.T) {
+		dir := writeSchemaFiles(t, map[string]string{
+			"tables.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY, tenant_id BIGINT);
+CREATE TABLE orders (id INTEGER PRIMARY KEY, tenant_id INTEGER);
+`,
+		})
+
+		// Off unless enabled
+		output, err := CheckSchema(dir)
+		if err != nil {
+			t.Fatalf("CheckSchema failed: %v", err)
+		}
+		if d := diagnosticsWithCode(output, CodeInconsistentColumnType); len(d) != 0 {
+			t.Errorf("Expected the rule to be off by default, got %+v", d)
+		}
+
+		output, err = CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: enabled})
+		if err != nil {
+			t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+		}
+		inconsistent := diagnosticsWithCode(output, CodeInconsistentColumnType)
+		if len(inconsistent) != 4 {
+			t.Fatalf("Expected id and tenant_id to be reported in both tables, got %+v", inconsistent)
+		}
+		users, orders := inconsistent[2], inconsistent[3]
+		if users.Line != 1 || users.Column != 44 || orders.Line != 2 || orders.Column != 46 {
+			t.Errorf("Expected tenant_id to be reported at 1:44 and 2:46, got %d:%d and %d:%d", users.Line, users.Column, orders.Line, orders.Column)
+		}
+		expected := `column "tenant_id" in table "public.users" has type "bigint", but in table "public.orders" it has type "integer"`
+		if users.Message != expected {
+			t.Errorf("Expected message %q, got %q", expected, users.Message)
+		}
+
+		// Restricted to tenant_id
+		output, err = CheckSchemaWithOptions(dir, CheckOptions{
+			RuleSeverities:    enabled,
+			ConsistentColumns: regexp.MustCompile(`^tenant_id$`),
+		})
+		if err != nil {
+			t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+		}
+		inconsistent = diagnosticsWithCode(output, CodeInconsistentColumnType)
+		if len(inconsistent) != 2 {
+			t.Fatalf("Expected only tenant_id to be reported, got %+v", inconsistent)
+		}
+		for _, d := range inconsistent {
+			if !strings.Contains(d.Message, `"tenant_id"`) {
+				t.Errorf("Unexpected message: %q", d.Message)
+			}
+		}
+	})
+}
+
+// recordingSink is a DiagnosticSink that records the diagnostics it receives
+type recordingSink struct {
+	diagnostics []Diagnostic
+}
+
+func (s *recordingSink) Report(d Diagnostic) {
+	s.diagnostics = append(s.diagnostics, d)
+}
+
+func TestCheckSchemaSink(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"tables.lp.sql": `CREATE TABLE users (id BIGINT);
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users (missing));
+`,
+	})
+
+	sink := &recordingSink{}
+	output, err := CheckSchemaWithOptions(dir, CheckOptions{Sink: sink})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+	if len(sink.diagnostics) == 0 {
+		t.Fatal("Expected the sink to receive diagnostics")
+	}
+	if !reflect.DeepEqual(sink.diagnostics, output.Diagnostics) {
+		t.Errorf("Expected the sink to receive the collected diagnostics %+v, got %+v", output.Diagnostics, sink.diagnostics)
+	}
+	// Diagnostics arrive as they are found: validation errors before lints
+	if sink.diagnostics[0].Code != CodeDuplicateTable {
+		t.Errorf("Expected %s first, got %+v", CodeDuplicateTable, sink.diagnostics[0])
+	}
+
+	sink = &recordingSink{}
+	output = CheckSQL("CREATE TABLE users (id BIGINT);", "users.lp.sql", CheckOptions{Sink: sink})
+	if len(sink.diagnostics) != 1 || !reflect.DeepEqual(sink.diagnostics, output.Diagnostics) {
+		t.Errorf("Expected the sink to receive %+v, got %+v", output.Diagnostics, sink.diagnostics)
+	}
+
+	// CheckOutput collects what is reported to it
+	collected := NewCheckOutput()
+	var _ DiagnosticSink = collected
+	collected.Report(Diagnostic{Severity: SeverityWarning, Code: CodeMissingPrimaryKey})
+	if collected.Summary.Warnings != 1 || len(collected.Diagnostics) != 1 {
+		t.Errorf("Expected CheckOutput to collect the diagnostic, got %+v", collected)
+	}
+}
+
+func TestDiagnosticHelpURI(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"events.lp.sql": "CREATE TABLE events (id BIGINT);\n",
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	missing := diagnosticsWithCode(output, CodeMissingPrimaryKey)
+	if len(missing) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeMissingPrimaryKey, missing)
+	}
+	expected := "https://github.com/lockplane/lockplane/blob/main/docs/rules.md#lp001"
+	if missing[0].HelpURI != expected {
+		t.Errorf("Expected help URI %q, got %q", expected, missing[0].HelpURI)
+	}
+
+	output = NewCheckOutput()
+	output.AddWarning(Diagnostic{Code: "unknown-code"})
+	if output.Diagnostics[0].HelpURI != "" {
+		t.Errorf("Expected no help URI for an unknown code, got %q", output.Diagnostics[0].HelpURI)
+	}
+}
+
+// TestRuleDocs checks that every code has a section in docs/rules.md, which
+// the help URIs link to
+func TestRuleDocs(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "docs", "rules.md"))
+	if err != nil {
+		t.Fatalf("Failed to read rule docs: %v", err)
+	}
+	docs := string(data)
+
+	for _, rule := range Rules() {
+		if !strings.Contains(docs, "\n## "+rule.Code+"\n") {
+			t.Errorf("docs/rules.md has no section for %s", rule.Code)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules := Rules()
+	if len(rules) != len(validationCodeDescriptions)+len(lintRules) {
+		t.Fatalf("Expected every code to be listed once, got %d rules", len(rules))
+	}
+	if !slices.IsSortedFunc(rules, func(a, b Rule) int { return strings.Compare(a.Code, b.Code) }) {
+		t.Errorf("Expected rules sorted by code, got %v", rules)
+	}
+	for _, rule := range rules {
+		if rule.Description == "" || rule.HelpURI == "" {
+			t.Errorf("Expected %s to have a description and help link, got %+v", rule.Code, rule)
+		}
+	}
+
+	// The duplicate table diagnostic carries a listed, unconfigurable code
+	output := CheckSQL(`CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE users (id BIGINT PRIMARY KEY);`, "users.lp.sql", CheckOptions{})
+	if len(output.Diagnostics) != 1 || output.Diagnostics[0].Code != CodeDuplicateTable {
+		t.Fatalf("Expected a %s diagnostic, got %+v", CodeDuplicateTable, output.Diagnostics)
+	}
+	i := slices.IndexFunc(rules, func(rule Rule) bool { return rule.Code == CodeDuplicateTable })
+	if i == -1 || rules[i].Severity != SeverityError || rules[i].Configurable {
+		t.Errorf("Expected %s to be listed as an unconfigurable error, got %+v", CodeDuplicateTable, rules)
+	}
+}
+
+// diagnosticsWithCode returns the diagnostics in output with the given code
+func diagnosticsWithCode(output *CheckOutput, code string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, d := range output.Diagnostics {
+		if d.Code == code {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+func TestCheckSchemaForeignKeyReferences(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT UNIQUE, name TEXT);
+CREATE TABLE tags (name TEXT);
+`,
+		"posts.lp.sql": `CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  author_id BIGINT REFERENCES users,
+  author_email TEXT REFERENCES users (email),
+  author_name TEXT REFERENCES users (name),
+  editor_id BIGINT REFERENCES users (uid),
+  tag TEXT REFERENCES tags,
+  org_id BIGINT REFERENCES orgs (id)
+);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	missing := diagnosticsWithCode(output, CodeForeignKeyMissingColumn)
+	if len(missing) != 2 {
+		t.Fatalf("Expected 2 %s errors, got %+v", CodeForeignKeyMissingColumn, missing)
+	}
+	if output.Summary.Valid {
+		t.Error("Expected foreign keys to missing columns to make the schema invalid")
+	}
+
+	tests := []struct {
+		d       Diagnostic
+		line    int
+		column  int
+		message string
+	}{
+		{missing[0], 6, 20, `references missing column public.users(uid)`},
+		{missing[1], 7, 12, `references public.tags, which has no primary key`},
+	}
+	for _, tt := range tests {
+		if tt.d.Severity != SeverityError {
+			t.Errorf("Expected an error, got %s", tt.d.Severity)
+		}
+		if tt.d.File != filepath.Join(dir, "posts.lp.sql") || tt.d.Line != tt.line || tt.d.Column != tt.column {
+			t.Errorf("Expected diagnostic at posts.lp.sql:%d:%d, got %s:%d:%d", tt.line, tt.column, tt.d.File, tt.d.Line, tt.d.Column)
+		}
+		if !strings.Contains(tt.d.Message, tt.message) {
+			t.Errorf("Expected message to contain %q, got %q", tt.message, tt.d.Message)
+		}
+	}
+
+	notUnique := diagnosticsWithCode(output, CodeForeignKeyNotUnique)
+	if len(notUnique) != 1 {
+		t.Fatalf("Expected 1 %s warning, got %+v", CodeForeignKeyNotUnique, notUnique)
+	}
+	if d := notUnique[0]; d.Severity != SeverityWarning || d.Line != 5 || !strings.Contains(d.Message, `public.users(name)`) {
+		t.Errorf("Expected a warning about users(name) on line 5, got %+v", d)
+	}
+}
+
+func TestCheckSchemaUnindexedForeignKey(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schema.lp.sql": `CREATE TABLE orgs (id BIGINT PRIMARY KEY);
+CREATE TABLE users (id BIGINT PRIMARY KEY, org_id BIGINT REFERENCES orgs);
+CREATE TABLE memberships (
+  org_id BIGINT REFERENCES orgs,
+  user_id BIGINT REFERENCES users,
+  PRIMARY KEY (org_id, user_id)
+);
+CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  org_id BIGINT,
+  user_id BIGINT,
+  FOREIGN KEY (org_id, user_id) REFERENCES memberships (org_id, user_id),
+  FOREIGN KEY (user_id, org_id) REFERENCES memberships (user_id, org_id)
+);
+CREATE INDEX posts_org_user_idx ON posts (org_id, user_id, id);
+`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	unindexed := diagnosticsWithCode(output, CodeUnindexedForeignKey)
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{2, `foreign key "users_org_id_fkey" on table "public.users" has no index on (org_id)`},
+		{5, `foreign key "memberships_user_id_fkey" on table "public.memberships" has no index on (user_id)`},
+		{13, `foreign key "posts_user_id_org_id_fkey" on table "public.posts" has no index on (user_id, org_id)`},
+	}
+	if len(unindexed) != len(expected) {
+		t.Fatalf("Expected %d %s warnings, got %+v", len(expected), CodeUnindexedForeignKey, unindexed)
+	}
+	for i, tt := range expected {
+		d := unindexed[i]
+		if d.Severity != SeverityWarning || d.Line != tt.line {
+			t.Errorf("Expected a warning on line %d, got %s on line %d", tt.line, d.Severity, d.Line)
+		}
+		if !strings.Contains(d.Message, tt.message) {
+			t.Errorf("Expected message to contain %q, got %q", tt.message, d.Message)
+		}
+	}
+}
+
+func TestCheckSchemaRuleSeverities(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schema.lp.sql": `CREATE TABLE events (id BIGINT);
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users);
+`,
+	})
+
+	output, err := CheckSchemaWithOptions(dir, CheckOptions{RuleSeverities: map[string]string{
+		CodeMissingPrimaryKey:   RuleOff,
+		CodeUnindexedForeignKey: SeverityError,
+	}})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+
+	if len(diagnosticsWithCode(output, CodeMissingPrimaryKey)) != 0 {
+		t.Error("Expected the disabled rule not to report anything")
+	}
+	unindexed := diagnosticsWithCode(output, CodeUnindexedForeignKey)
+	if len(unindexed) != 1 || unindexed[0].Severity != SeverityError {
+		t.Fatalf("Expected 1 %s error, got %+v", CodeUnindexedForeignKey, unindexed)
+	}
+	if output.Summary.Valid || output.Summary.Errors != 1 || output.Summary.Warnings != 0 {
+		t.Errorf("Expected 1 error and no warnings, got %+v", output.Summary)
+	}
+}
+
+func TestValidateRuleSeverities(t *testing.T) {
+	tests := []struct {
+		rules map[string]string
+		err   string
+	}{
+		{map[string]string{CodeMissingPrimaryKey: RuleOff, CodeNamingPolicy: SeverityError}, ""},
+		{map[string]string{CodeMissingPrimaryKey: "info"}, `invalid severity "info" for rule LP001`},
+		{map[string]string{CodeParseError: RuleOff}, "rule LP000 reports schema errors and can't be configured"},
+		{map[string]string{CodeDuplicateTable: SeverityWarning}, "rule LP100 reports schema errors and can't be configured"},
+		{map[string]string{"LP999": RuleOff}, "unknown rule LP999"},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRuleSeverities(tt.rules)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("ValidateRuleSeverities(%v) failed: %v", tt.rules, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ValidateRuleSeverities(%v): expected error containing %q, got %v", tt.rules, tt.err, err)
+		}
+	}
+}
+
+func TestCheckSchemaMissingPrimaryKey(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"events.lp.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY);\n\nCREATE TABLE audit.events (id INTEGER, payload JSONB);",
+		"tags.lp.sql": `CREATE SCHEMA audit;
+CREATE TABLE tags (post_id INTEGER, name TEXT, PRIMARY KEY (post_id, name));`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if !output.Summary.Valid || output.Summary.Errors != 0 {
+		t.Errorf("Expected warnings not to make the schema invalid, got %+v", output.Summary)
+	}
+	if output.Summary.Warnings != 1 || len(output.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 warning, got %+v", output.Diagnostics)
+	}
+
+	d := output.Diagnostics[0]
+	if d.Code != CodeMissingPrimaryKey || d.Severity != SeverityWarning {
+		t.Errorf("Expected %s warning, got %s %s", CodeMissingPrimaryKey, d.Severity, d.Code)
+	}
+	if d.File != filepath.Join(dir, "events.lp.sql") || d.Line != 3 || d.Column != 14 {
+		t.Errorf("Expected diagnostic at the table name (events.lp.sql:3:14), got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if d.Message != `table "audit.events" has no primary key` {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaNamingPolicy(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schema.lp.sql": `CREATE TABLE tbl_users (id INTEGER PRIMARY KEY);
+CREATE TABLE posts (id INTEGER PRIMARY KEY);`,
+	})
+
+	policy, err := NewNamingPolicy("^tbl_", "")
+	if err != nil {
+		t.Fatalf("NewNamingPolicy failed: %v", err)
+	}
+
+	output, err := CheckSchemaWithOptions(dir, CheckOptions{NamingPolicy: policy})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+
+	if !output.Summary.Valid {
+		t.Error("Expected warnings not to make the schema invalid")
+	}
+	if output.Summary.Warnings != 1 || len(output.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 warning, got %+v", output.Diagnostics)
+	}
+
+	d := output.Diagnostics[0]
+	if d.Code != CodeNamingPolicy || d.Severity != SeverityWarning {
+		t.Errorf("Expected %s warning, got %s %s", CodeNamingPolicy, d.Severity, d.Code)
+	}
+	if d.File != filepath.Join(dir, "schema.lp.sql") || d.Line != 2 || d.Column != 14 {
+		t.Errorf("Expected diagnostic at the table name (schema.lp.sql:2:14), got %s:%d:%d", d.File, d.Line, d.Column)
+	}
+	if d.Message != `table "public.posts" does not match naming pattern "^tbl_"` {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestCheckSchemaNamingPolicyColumns(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, users_email TEXT);`,
+	})
+
+	policy, err := NewNamingPolicy("", "^[a-z]+$")
+	if err != nil {
+		t.Fatalf("NewNamingPolicy failed: %v", err)
+	}
+
+	output, err := CheckSchemaWithOptions(dir, CheckOptions{NamingPolicy: policy})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+
+	if len(output.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 warning, got %+v", output.Diagnostics)
+	}
+	d := output.Diagnostics[0]
+	if d.Line != 1 || d.Column != 45 {
+		t.Errorf("Expected diagnostic at the column (1:45), got %d:%d", d.Line, d.Column)
+	}
+	if !strings.Contains(d.Message, `column "users_email"`) {
+		t.Errorf("Unexpected message: %q", d.Message)
+	}
+}
+
+func TestNewNamingPolicyInvalidPattern(t *testing.T) {
+	if _, err := NewNamingPolicy("(", ""); err == nil {
+		t.Error("Expected error for invalid table pattern")
+	}
+	if _, err := NewNamingPolicy("", "["); err == nil {
+		t.Error("Expected error for invalid column pattern")
+	}
+}
+
+func TestCheckSchemaInvalidSQLCode(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY`,
+	})
+
+	output, err := CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	if len(output.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", output.Diagnostics)
+	}
+	if d := output.Diagnostics[0]; d.Code != CodeParseError {
+		t.Errorf("Expected code %s, got %q", CodeParseError, d.Code)
+	}
+}
+
+func TestCheckSchemaCoverage(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"schema.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);
+CREATE INDEX users_email_idx ON users (email);
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users OWNER TO app;
+CREATE FUNCTION touch() RETURNS trigger AS $$ BEGIN RETURN NEW; END $$ LANGUAGE plpgsql;
+CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch();
+GRANT SELECT ON users TO app;
+CREATE INDEX orders_idx ON orders (id);
+`,
+	})
+
+	output, err := CheckSchemaWithOptions(dir, CheckOptions{Coverage: true})
+	if err != nil {
+		t.Fatalf("CheckSchemaWithOptions failed: %v", err)
+	}
+
+	c := output.Coverage
+	if c == nil {
+		t.Fatal("Expected coverage to be reported")
+	}
+	if c.Statements != 8 || c.Modeled != 3 || c.Ignored != 5 {
+		t.Errorf("Expected 3 of 8 statements modeled, got %+v", c)
+	}
+	if c.Percent != 37.5 {
+		t.Errorf("Expected 37.5%% coverage, got %v", c.Percent)
+	}
+
+	expectedKinds := map[string]int{
+		"AlterTableStmt":     1,
+		"CreateFunctionStmt": 1,
+		"CreateTrigStmt":     1,
+		"GrantStmt":          1,
+		"IndexStmt":          1,
+	}
+	if !reflect.DeepEqual(c.IgnoredByKind, expectedKinds) {
+		t.Errorf("Expected ignored kinds %v, got %v", expectedKinds, c.IgnoredByKind)
+	}
+
+	// Coverage is opt-in
+	output, err = CheckSchema(dir)
+	if err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+	if output.Coverage != nil {
+		t.Errorf("Expected no coverage without the option, got %+v", output.Coverage)
+	}
+}
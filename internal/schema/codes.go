@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+)
+
+// Diagnostic codes identify the kind of problem a Diagnostic reports. They are
+// stable across releases, so tools can match on them and users can refer to
+// them when configuring rules.
+//
+// Codes are grouped by range:
+//
+//	LP000-LP099  schema files that fail to parse, and table design lints
+//	LP100-LP199  schema-level validation errors
+//	LP200-LP299  foreign keys and references between tables, row level
+//	             security, references to schemas, identifiers, column
+//	             defaults, and column types
+//
+// Some older lint rules use descriptive slugs instead of LPxxx codes.
+const (
+	// CodeParseError is reported when a schema file can't be parsed
+	CodeParseError = "LP000"
+	// CodeMissingPrimaryKey is reported for tables without a primary key
+	CodeMissingPrimaryKey = "LP001"
+
+	// CodeDuplicateTable is reported when a table is defined more than once
+	CodeDuplicateTable = "LP100"
+
+	// CodeForeignKeyMissingColumn is reported for foreign keys that reference
+	// a column the referenced table doesn't have
+	CodeForeignKeyMissingColumn = "LP200"
+	// CodeForeignKeyNotUnique is reported for foreign keys whose referenced
+	// columns aren't a primary key or unique constraint
+	CodeForeignKeyNotUnique = "LP201"
+	// CodeUnindexedForeignKey is reported for foreign keys whose columns
+	// aren't the leading columns of any index
+	CodeUnindexedForeignKey = "LP202"
+	// CodePartitionParentMissing is reported for partitions whose partitioned
+	// parent table isn't in the schema
+	CodePartitionParentMissing = "LP203"
+	// CodeIndexClauseMissingColumn is reported for indexes whose INCLUDE
+	// columns or WHERE predicate reference a column their table doesn't have
+	CodeIndexClauseMissingColumn = "LP204"
+	// CodeRLSWithoutPolicy is reported for tables with row level security
+	// enabled but no policies
+	CodeRLSWithoutPolicy = "LP210"
+	// CodeUndeclaredSchema is reported for objects in a schema other than
+	// public that no CREATE SCHEMA creates
+	CodeUndeclaredSchema = "LP220"
+	// CodeUnquotedUppercase is reported for table and column names written
+	// unquoted with uppercase letters, which PostgreSQL folds to lowercase
+	CodeUnquotedUppercase = "LP230"
+	// CodeReservedKeyword is reported for unquoted table and column names that
+	// are keywords PostgreSQL reserves in some contexts
+	CodeReservedKeyword = "LP231"
+	// CodeAutoIncrementDefault is reported for serial and identity columns
+	// that are also given a DEFAULT
+	CodeAutoIncrementDefault = "LP240"
+	// CodeUndeclaredSequence is reported for columns whose DEFAULT calls
+	// nextval on a sequence the schema doesn't create
+	CodeUndeclaredSequence = "LP241"
+	// CodeUnknownType is reported for columns whose type, without a schema,
+	// is neither built in nor an enum or domain defined in the schema
+	CodeUnknownType = "LP250"
+	// CodeNullableFlag is reported for nullable boolean columns and columns
+	// named like flags, such as is_active
+	CodeNullableFlag = "LP260"
+
+	// CodeIndexOnMissingColumn is reported for indexes that reference a column
+	// their table doesn't have
+	CodeIndexOnMissingColumn = "index-on-missing-column"
+	// CodeNamingPolicy is reported for table and column names that don't match
+	// the configured naming policy
+	CodeNamingPolicy = "naming-policy"
+	// CodeTrivialCheck is reported for CHECK constraints that are always true
+	// or always false
+	CodeTrivialCheck = "trivial-check"
+	// CodeEmptyTable is reported for tables with no columns that don't
+	// inherit any
+	CodeEmptyTable = "empty-table"
+	// CodeUndefinedType is reported for columns whose schema-qualified type
+	// is not a domain or enum defined in the schema
+	CodeUndefinedType = "undefined-type"
+	// CodeInconsistentColumnType is reported for columns that share a name
+	// with a column of a different type in another table
+	CodeInconsistentColumnType = "inconsistent-column-type"
+	// CodeWithOidsDeprecated is reported for tables created WITH (OIDS),
+	// which PostgreSQL no longer supports
+	CodeWithOidsDeprecated = "with-oids-deprecated"
+	// CodeForeignKeySetNullNotNull is reported for foreign keys whose ON
+	// DELETE action would set a NOT NULL column to null, or set a column
+	// without a default to its default
+	CodeForeignKeySetNullNotNull = "fk-setnull-notnull"
+	// CodeDuplicateIndexName is reported when an index name is used more than
+	// once in a schema
+	CodeDuplicateIndexName = "duplicate-index-name"
+	// CodeMetaCommand is reported for psql meta-commands, such as \connect,
+	// that were stripped from a schema file before parsing
+	CodeMetaCommand = "psql-meta-command"
+)
+
+// validationCodeDescriptions describes the codes reported outside the lint
+// rules, which carry their own descriptions
+var validationCodeDescriptions = map[string]string{
+	CodeParseError:         "A schema file can't be parsed",
+	CodeDuplicateTable:     "A table is defined more than once",
+	CodeDuplicateIndexName: "An index name is used more than once in a schema",
+	CodeMetaCommand:        "A psql meta-command was ignored",
+}
+
+// ruleDocsURL is the base of the documentation links in Diagnostic.HelpURI.
+// Each code has a section in docs/rules.md.
+const ruleDocsURL = "https://github.com/lockplane/lockplane/blob/main/docs/rules.md#"
+
+// helpURI returns the documentation link for a code, or "" for an unknown
+// code
+func helpURI(code string) string {
+	if codeDescription(code) == "" {
+		return ""
+	}
+	return ruleDocsURL + strings.ToLower(code)
+}
+
+// codeDescription returns a one-line description of the problem a code
+// reports, or "" for an unknown code
+func codeDescription(code string) string {
+	for _, rule := range lintRules {
+		if rule.Code == code {
+			return rule.Description
+		}
+	}
+	return validationCodeDescriptions[code]
+}
+
+// Rule describes a diagnostic code
+type Rule struct {
+	Code string `json:"code"`
+	// Severity is the severity the code is reported with by default: error,
+	// warning, or off for lint rules that must be turned on
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	// Configurable is set for lint rules, whose severity can be changed in
+	// lockplane.toml. Parse and validation errors are always reported.
+	Configurable bool   `json:"configurable"`
+	HelpURI      string `json:"help_uri"`
+}
+
+// Rules returns every code lockplane check can report, sorted by code
+func Rules() []Rule {
+	var rules []Rule
+	for code, description := range validationCodeDescriptions {
+		rules = append(rules, Rule{Code: code, Severity: SeverityError, Description: description, HelpURI: helpURI(code)})
+	}
+	for _, rule := range lintRules {
+		rules = append(rules, Rule{Code: rule.Code, Severity: rule.Severity, Description: rule.Description, Configurable: true, HelpURI: helpURI(rule.Code)})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Code < rules[j].Code
+	})
+	return rules
+}
@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// Coverage reports how many statements in the schema files lockplane fully
+// modeled. Ignored statements (functions, triggers, grants, ALTER TABLE
+// commands lockplane doesn't understand, etc.) are parsed but not validated.
+// Tracked statements, such as CREATE AGGREGATE, aren't validated either but
+// are recorded in Schema.OtherObjects.
+type Coverage struct {
+	Statements int     `json:"statements"`
+	Modeled    int     `json:"modeled"`
+	Tracked    int     `json:"tracked"`
+	Ignored    int     `json:"ignored"`
+	Percent    float64 `json:"percent"`
+	// IgnoredByKind counts the ignored statements by parse node type, e.g.
+	// "CreateFunctionStmt"
+	IgnoredByKind map[string]int `json:"ignored_by_kind,omitempty"`
+}
+
+// record tallies a statement. It does nothing on a nil Coverage, so parsing
+// without coverage doesn't need to check.
+func (c *Coverage) record(stmt *pg_query.Node, modeled bool) {
+	if c == nil {
+		return
+	}
+
+	c.Statements++
+	if modeled {
+		c.Modeled++
+	} else {
+		c.Ignored++
+		if c.IgnoredByKind == nil {
+			c.IgnoredByKind = make(map[string]int)
+		}
+		c.IgnoredByKind[statementKind(stmt)]++
+	}
+	c.Percent = float64(c.Modeled) * 100 / float64(c.Statements)
+}
+
+// recordTracked tallies a statement recorded in Schema.OtherObjects
+func (c *Coverage) recordTracked() {
+	if c == nil {
+		return
+	}
+
+	c.Statements++
+	c.Tracked++
+	c.Percent = float64(c.Modeled) * 100 / float64(c.Statements)
+}
+
+// statementKind names a statement by its parse node type
+func statementKind(stmt *pg_query.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", stmt.Node), "*pg_query.Node_")
+}
+
+// StatementStats counts the statements in a set of schema files by kind
+type StatementStats struct {
+	Statements int `json:"statements"`
+	// Kinds lists each kind of statement with its count, most common first
+	Kinds []StatementKindCount `json:"kinds"`
+}
+
+// StatementKindCount is the number of statements of one kind
+type StatementKindCount struct {
+	// Kind is the statement's parse node type, e.g. "CreateStmt"
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+// CountStatementKinds counts the statements in the schema files at path, a
+// directory or .lp.sql file as for LoadSchema, by parse node type. Every
+// statement is counted, whether or not lockplane models it; kinds with the
+// same count are sorted by name.
+func CountStatementKinds(path string) (*StatementStats, error) {
+	files, err := findSchemaFiles(osFS{}, path, LoadSchemaOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	stats := &StatementStats{Kinds: []StatementKindCount{}}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file %s: %w", file, err)
+		}
+		tree, err := pg_query.Parse(string(data))
+		if err != nil {
+			return nil, newLocator(string(data), file).syntaxError(err)
+		}
+		for _, stmt := range tree.Stmts {
+			counts[statementKind(stmt.Stmt)]++
+			stats.Statements++
+		}
+	}
+
+	for kind, count := range counts {
+		stats.Kinds = append(stats.Kinds, StatementKindCount{Kind: kind, Count: count})
+	}
+	sort.Slice(stats.Kinds, func(i, j int) bool {
+		a, b := stats.Kinds[i], stats.Kinds[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Kind < b.Kind
+	})
+	return stats, nil
+}
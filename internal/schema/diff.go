@@ -1,12 +1,23 @@
 package schema
 
-import "github.com/lockplane/lockplane/internal/database"
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
 
 // SchemaDiff represents all differences between two schemas
 type SchemaDiff struct {
 	AddedTables    []database.Table `json:"added_tables,omitempty"`
 	RemovedTables  []database.Table `json:"removed_tables,omitempty"`
 	ModifiedTables []TableDiff      `json:"modified_tables,omitempty"`
+	// Domains are matched by schema-qualified name
+	AddedDomains    []database.Domain `json:"added_domains,omitempty"`
+	RemovedDomains  []database.Domain `json:"removed_domains,omitempty"`
+	ModifiedDomains []DomainDiff      `json:"modified_domains,omitempty"`
 }
 
 // TableDiff represents changes to a single table
@@ -17,6 +28,10 @@ type TableDiff struct {
 	ModifiedColumns []ColumnDiff      `json:"modified_columns,omitempty"`
 	RLSChanged      bool              `json:"rls_changed,omitempty"`
 	RLSEnabled      bool              `json:"rls_enabled,omitempty"`
+	// Foreign keys are matched by constraint name
+	AddedForeignKeys    []database.ForeignKey `json:"added_foreign_keys,omitempty"`
+	RemovedForeignKeys  []database.ForeignKey `json:"removed_foreign_keys,omitempty"`
+	ModifiedForeignKeys []ForeignKeyDiff      `json:"modified_foreign_keys,omitempty"`
 }
 
 // ColumnDiff represents changes to a single column
@@ -25,26 +40,72 @@ type ColumnDiff struct {
 	Old        database.Column `json:"old"`
 	New        database.Column `json:"new"`
 	Changes    []string        `json:"changes"` // e.g. ["type", "nullable", "default"]
+	// TypeChange classifies the change when Changes includes "type"
+	TypeChange TypeChange `json:"type_change,omitempty"`
+}
+
+// ForeignKeyDiff represents changes to a single foreign key constraint
+type ForeignKeyDiff struct {
+	Name string              `json:"name"`
+	Old  database.ForeignKey `json:"old"`
+	New  database.ForeignKey `json:"new"`
+	// Changes lists what changed: "columns", "references", "on_delete",
+	// "on_update", "match" and "deferrable"
+	Changes []string `json:"changes"`
+}
+
+// DomainDiff represents changes to a single domain
+type DomainDiff struct {
+	// Name is the bare name for domains in the public schema, otherwise
+	// schema.name
+	Name string          `json:"name"`
+	Old  database.Domain `json:"old"`
+	New  database.Domain `json:"new"`
+	// Changes lists what changed: "base_type", "not_null", "default" and
+	// "check_constraints"
+	Changes []string `json:"changes"`
 }
 
-// DiffSchemas compares two schemas and returns their differences
-func DiffSchemas(current, desired *database.Schema) *SchemaDiff {
+// TypeChange classifies a change to a column's type by whether existing
+// values are sure to survive it
+type TypeChange string
+
+const (
+	// TypeChangeWidening is a change to a type that holds every value of the
+	// old one, such as integer to bigint or varchar(10) to varchar(20)
+	TypeChangeWidening TypeChange = "widening"
+	// TypeChangeNarrowing is a change to a related type that some values of
+	// the old one don't fit, such as bigint to integer, so the change may fail
+	// or lose data
+	TypeChangeNarrowing TypeChange = "narrowing"
+	// TypeChangeIncompatible is a change between unrelated types, such as
+	// text to integer, which PostgreSQL can only make with an explicit USING
+	TypeChangeIncompatible TypeChange = "incompatible"
+)
+
+// DiffSchemas compares two schemas and returns the changes needed to turn
+// current into desired. Tables are matched by schema-qualified name and
+// columns by name. Changes are listed in the order the tables and columns are
+// declared: additions and modifications follow desired, removals follow
+// current. An error is returned if either schema defines a table twice, since
+// the tables can't be matched up unambiguously.
+func DiffSchemas(current, desired *database.Schema) (*SchemaDiff, error) {
 	diff := &SchemaDiff{}
 
 	// Build maps for quick lookup
-	currentTables := make(map[string]*database.Table)
-	for i := range current.Tables {
-		currentTables[current.Tables[i].Name] = &current.Tables[i]
+	currentTables, err := tablesByName(current)
+	if err != nil {
+		return nil, fmt.Errorf("current schema: %w", err)
 	}
-
-	desiredTables := make(map[string]*database.Table)
-	for i := range desired.Tables {
-		desiredTables[desired.Tables[i].Name] = &desired.Tables[i]
+	desiredTables, err := tablesByName(desired)
+	if err != nil {
+		return nil, fmt.Errorf("desired schema: %w", err)
 	}
 
 	// Find added and modified tables
-	for name, desiredTable := range desiredTables {
-		currentTable, exists := currentTables[name]
+	for i := range desired.Tables {
+		desiredTable := &desired.Tables[i]
+		currentTable, exists := currentTables[qualifiedTableName(desiredTable)]
 		if !exists {
 			// Table added
 			diff.AddedTables = append(diff.AddedTables, *desiredTable)
@@ -58,19 +119,115 @@ func DiffSchemas(current, desired *database.Schema) *SchemaDiff {
 	}
 
 	// Find removed tables
-	for name, currentTable := range currentTables {
-		if _, exists := desiredTables[name]; !exists {
+	for i := range current.Tables {
+		currentTable := &current.Tables[i]
+		if _, exists := desiredTables[qualifiedTableName(currentTable)]; !exists {
 			diff.RemovedTables = append(diff.RemovedTables, *currentTable)
 		}
 	}
 
-	return diff
+	diffDomains(diff, current, desired)
+	return diff, nil
+}
+
+// diffDomains adds the domains added, removed and changed between two
+// schemas to diff
+func diffDomains(diff *SchemaDiff, current, desired *database.Schema) {
+	currentDomains := make(map[string]*database.Domain)
+	for i := range current.Domains {
+		currentDomains[qualifiedDomainName(&current.Domains[i])] = &current.Domains[i]
+	}
+	desiredDomains := make(map[string]bool)
+
+	for _, domain := range desired.Domains {
+		name := qualifiedDomainName(&domain)
+		desiredDomains[name] = true
+		currentDomain, exists := currentDomains[name]
+		if !exists {
+			diff.AddedDomains = append(diff.AddedDomains, domain)
+			continue
+		}
+
+		var changes []string
+		if !sameType(currentDomain.BaseType, domain.BaseType) {
+			changes = append(changes, "base_type")
+		}
+		if currentDomain.NotNull != domain.NotNull {
+			changes = append(changes, "not_null")
+		}
+		if !equalDefaults(currentDomain.Default, domain.Default) {
+			changes = append(changes, "default")
+		}
+		if !equalChecks(currentDomain.CheckConstraints, domain.CheckConstraints) {
+			changes = append(changes, "check_constraints")
+		}
+		if len(changes) > 0 {
+			diff.ModifiedDomains = append(diff.ModifiedDomains, DomainDiff{
+				Name:    strings.TrimPrefix(name, "public."),
+				Old:     *currentDomain,
+				New:     domain,
+				Changes: changes,
+			})
+		}
+	}
+
+	for _, domain := range current.Domains {
+		if !desiredDomains[qualifiedDomainName(&domain)] {
+			diff.RemovedDomains = append(diff.RemovedDomains, domain)
+		}
+	}
+}
+
+// qualifiedDomainName returns schema.name for a domain, using public for
+// domains without a schema
+func qualifiedDomainName(domain *database.Domain) string {
+	return cmp.Or(domain.Schema, "public") + "." + domain.Name
+}
+
+// equalChecks compares two sets of check constraints by name and expression,
+// ignoring their order
+func equalChecks(a, b []database.CheckConstraint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	expressions := make(map[string]string, len(a))
+	for _, check := range a {
+		expressions[check.Name] = check.Expression
+	}
+	for _, check := range b {
+		if expression, ok := expressions[check.Name]; !ok || expression != check.Expression {
+			return false
+		}
+	}
+	return true
+}
+
+// tablesByName indexes the tables of a schema by schema-qualified name
+func tablesByName(schema *database.Schema) (map[string]*database.Table, error) {
+	tables := make(map[string]*database.Table)
+	for i := range schema.Tables {
+		name := qualifiedTableName(&schema.Tables[i])
+		if _, exists := tables[name]; exists {
+			return nil, fmt.Errorf("table %q is defined multiple times", name)
+		}
+		tables[name] = &schema.Tables[i]
+	}
+	return tables, nil
+}
+
+// diffTableName is the name used to refer to a table in a TableDiff: the bare
+// name for tables in the public schema, otherwise schema.name
+func diffTableName(table *database.Table) string {
+	if table.Schema == "" || table.Schema == "public" {
+		return table.Name
+	}
+	return table.Schema + "." + table.Name
 }
 
 // diffTables compares two tables and returns their differences
 func diffTables(current, desired *database.Table) *TableDiff {
 	diff := &TableDiff{
-		TableName: current.Name,
+		TableName: diffTableName(current),
 	}
 
 	// Build maps for columns
@@ -85,8 +242,9 @@ func diffTables(current, desired *database.Table) *TableDiff {
 	}
 
 	// Find added and modified columns
-	for name, desiredCol := range desiredCols {
-		currentCol, exists := currentCols[name]
+	for i := range desired.Columns {
+		desiredCol := &desired.Columns[i]
+		currentCol, exists := currentCols[desiredCol.Name]
 		if !exists {
 			// Column added
 			diff.AddedColumns = append(diff.AddedColumns, *desiredCol)
@@ -100,8 +258,9 @@ func diffTables(current, desired *database.Table) *TableDiff {
 	}
 
 	// Find removed columns
-	for name, currentCol := range currentCols {
-		if _, exists := desiredCols[name]; !exists {
+	for i := range current.Columns {
+		currentCol := &current.Columns[i]
+		if _, exists := desiredCols[currentCol.Name]; !exists {
 			diff.RemovedColumns = append(diff.RemovedColumns, *currentCol)
 		}
 	}
@@ -112,14 +271,65 @@ func diffTables(current, desired *database.Table) *TableDiff {
 		diff.RLSEnabled = desired.RLSEnabled
 	}
 
+	diffForeignKeys(diff, current, desired)
 	return diff
 }
 
+// diffForeignKeys adds the foreign keys added, removed and changed between
+// two versions of a table to diff
+func diffForeignKeys(diff *TableDiff, current, desired *database.Table) {
+	currentKeys := make(map[string]*database.ForeignKey)
+	for i := range current.ForeignKeys {
+		currentKeys[current.ForeignKeys[i].Name] = &current.ForeignKeys[i]
+	}
+	desiredKeys := make(map[string]bool)
+
+	for _, fk := range desired.ForeignKeys {
+		desiredKeys[fk.Name] = true
+		currentKey, exists := currentKeys[fk.Name]
+		if !exists {
+			diff.AddedForeignKeys = append(diff.AddedForeignKeys, fk)
+			continue
+		}
+
+		var changes []string
+		if !slices.Equal(currentKey.Columns, fk.Columns) {
+			changes = append(changes, "columns")
+		}
+		if cmp.Or(currentKey.ReferencedSchema, "public") != cmp.Or(fk.ReferencedSchema, "public") ||
+			currentKey.ReferencedTable != fk.ReferencedTable || !slices.Equal(currentKey.ReferencedColumns, fk.ReferencedColumns) {
+			changes = append(changes, "references")
+		}
+		if cmp.Or(currentKey.OnDelete, database.FKActionNoAction) != cmp.Or(fk.OnDelete, database.FKActionNoAction) ||
+			!slices.Equal(currentKey.OnDeleteColumns, fk.OnDeleteColumns) {
+			changes = append(changes, "on_delete")
+		}
+		if cmp.Or(currentKey.OnUpdate, database.FKActionNoAction) != cmp.Or(fk.OnUpdate, database.FKActionNoAction) {
+			changes = append(changes, "on_update")
+		}
+		if cmp.Or(currentKey.Match, database.FKMatchSimple) != cmp.Or(fk.Match, database.FKMatchSimple) {
+			changes = append(changes, "match")
+		}
+		if currentKey.Deferrable != fk.Deferrable || currentKey.InitiallyDeferred != fk.InitiallyDeferred {
+			changes = append(changes, "deferrable")
+		}
+		if len(changes) > 0 {
+			diff.ModifiedForeignKeys = append(diff.ModifiedForeignKeys, ForeignKeyDiff{Name: fk.Name, Old: *currentKey, New: fk, Changes: changes})
+		}
+	}
+
+	for _, fk := range current.ForeignKeys {
+		if !desiredKeys[fk.Name] {
+			diff.RemovedForeignKeys = append(diff.RemovedForeignKeys, fk)
+		}
+	}
+}
+
 // diffColumns compares two columns and returns their differences
 func diffColumns(current, desired *database.Column) *ColumnDiff {
 	var changes []string
 
-	if current.Type != desired.Type {
+	if !sameType(current.Type, desired.Type) {
 		changes = append(changes, "type")
 	}
 	if current.Nullable != desired.Nullable {
@@ -128,6 +338,12 @@ func diffColumns(current, desired *database.Column) *ColumnDiff {
 	if !equalDefaults(current.Default, desired.Default) {
 		changes = append(changes, "default")
 	}
+	if !equalGenerated(current.Generated, desired.Generated) {
+		changes = append(changes, "generated")
+	}
+	if !equalIdentity(current.Identity, desired.Identity) {
+		changes = append(changes, "identity")
+	}
 	if current.IsPrimaryKey != desired.IsPrimaryKey {
 		changes = append(changes, "is_primary_key")
 	}
@@ -136,12 +352,164 @@ func diffColumns(current, desired *database.Column) *ColumnDiff {
 		return nil
 	}
 
-	return &ColumnDiff{
+	columnDiff := &ColumnDiff{
 		ColumnName: current.Name,
 		Old:        *current,
 		New:        *desired,
 		Changes:    changes,
 	}
+	if !sameType(current.Type, desired.Type) {
+		columnDiff.TypeChange = classifyTypeChange(current, desired)
+	}
+	return columnDiff
+}
+
+// sameType reports whether two normalized column types are the same.
+// PostgreSQL doesn't enforce the number or size of array dimensions, and
+// reports every array type with a single [], so array types are compared by
+// element type.
+func sameType(a, b string) bool {
+	return collapseArrayDims(a) == collapseArrayDims(b)
+}
+
+// collapseArrayDims replaces the brackets of an array type with a single []
+func collapseArrayDims(typ string) string {
+	if open := strings.Index(typ, "["); open != -1 {
+		return typ[:open] + "[]"
+	}
+	return typ
+}
+
+// integerDigits is the number of decimal digits every value of each integer
+// type fits in, for comparing integer types with each other and with numeric
+// and floating point types
+var integerDigits = map[string]int{
+	"smallint": 5,
+	"integer":  10,
+	"bigint":   19,
+}
+
+// floatDigits is the number of decimal digits each floating point type holds
+// exactly
+var floatDigits = map[string]int{
+	"real":             6,
+	"double precision": 15,
+}
+
+// classifyTypeChange classifies the change from the type of current to the
+// type of desired, using the structured length, precision and scale of the
+// columns. Changes it can't reason about, such as the precision of a
+// timestamp, are reported as narrowing rather than widening.
+func classifyTypeChange(current, desired *database.Column) TypeChange {
+	oldBase, oldArray := baseType(current.Type)
+	newBase, newArray := baseType(desired.Type)
+	if oldArray != newArray {
+		return TypeChangeIncompatible
+	}
+
+	oldInt, oldIsInt := integerDigits[oldBase]
+	newInt, newIsInt := integerDigits[newBase]
+	oldFloat, oldIsFloat := floatDigits[oldBase]
+	newFloat, newIsFloat := floatDigits[newBase]
+
+	switch {
+	case oldBase == newBase && oldBase == "numeric":
+		return numericChange(current, desired)
+	case isCharacterType(oldBase) && isCharacterType(newBase):
+		return limitChange(characterLength(oldBase, current.Length), characterLength(newBase, desired.Length))
+	case oldBase == newBase:
+		return TypeChangeNarrowing
+	case oldIsInt && newIsInt:
+		return widensIf(newInt >= oldInt)
+	case oldIsInt && newBase == "numeric":
+		return widensIf(desired.Precision == nil || *desired.Precision-scaleOf(desired) >= oldInt)
+	case oldIsInt && newIsFloat:
+		return widensIf(newFloat >= oldInt)
+	case oldIsFloat && newIsFloat:
+		return widensIf(newFloat >= oldFloat)
+	case (oldBase == "numeric" || oldIsFloat) && (newIsInt || newIsFloat || newBase == "numeric"):
+		// Fractions are rounded away, or digits lost, in the new type
+		return TypeChangeNarrowing
+	}
+	return TypeChangeIncompatible
+}
+
+// baseType strips the modifiers and array bounds from a normalized type,
+// returning serial types as their integer type and decimal as numeric
+func baseType(typ string) (base string, isArray bool) {
+	isArray = strings.Contains(typ, "[")
+	base = database.NormalizePostgreSQLType(strings.TrimSpace(database.BaseType(typ)))
+	switch base {
+	case "smallserial":
+		base = "smallint"
+	case "serial":
+		base = "integer"
+	case "bigserial":
+		base = "bigint"
+	case "decimal":
+		base = "numeric"
+	}
+	return base, isArray
+}
+
+// isCharacterType reports whether base is text, varchar or char
+func isCharacterType(base string) bool {
+	return base == "text" || base == "varchar" || base == "char"
+}
+
+// characterLength returns the length limit of a character type, or nil if
+// it has none. char without a length is char(1).
+func characterLength(base string, length *int) *int {
+	if base == "char" && length == nil {
+		one := 1
+		return &one
+	}
+	if base == "text" {
+		return nil
+	}
+	return length
+}
+
+// numericChange classifies a change between two numeric types by the digits
+// they allow before and after the decimal point
+func numericChange(current, desired *database.Column) TypeChange {
+	switch {
+	case desired.Precision == nil:
+		return TypeChangeWidening
+	case current.Precision == nil:
+		return TypeChangeNarrowing
+	}
+	oldScale, newScale := scaleOf(current), scaleOf(desired)
+	return widensIf(*desired.Precision-newScale >= *current.Precision-oldScale && newScale >= oldScale)
+}
+
+// scaleOf returns the scale of a numeric column, which is 0 when unset
+func scaleOf(col *database.Column) int {
+	if col.Scale == nil {
+		return 0
+	}
+	return *col.Scale
+}
+
+// limitChange classifies a change between two length limits, where nil is
+// no limit
+func limitChange(current, desired *int) TypeChange {
+	switch {
+	case desired == nil:
+		return TypeChangeWidening
+	case current == nil:
+		return TypeChangeNarrowing
+	}
+	return widensIf(*desired >= *current)
+}
+
+// widensIf returns TypeChangeWidening if widens is set, and otherwise
+// TypeChangeNarrowing
+func widensIf(widens bool) TypeChange {
+	if widens {
+		return TypeChangeWidening
+	}
+	return TypeChangeNarrowing
 }
 
 // equalDefaults compares two default values
@@ -155,17 +523,41 @@ func equalDefaults(a, b *string) bool {
 	return *a == *b
 }
 
+// equalGenerated compares the generation expressions of two columns
+func equalGenerated(a, b *database.GeneratedColumn) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// equalIdentity compares whether two columns are identity columns, and of the
+// same kind. Sequence names and options aren't compared, since introspected
+// columns report every option while parsed ones only report those given.
+func equalIdentity(a, b *database.IdentitySpec) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Always == b.Always
+}
+
 // IsEmpty returns true if there are no differences
 func (d *TableDiff) IsEmpty() bool {
 	return len(d.AddedColumns) == 0 &&
 		len(d.RemovedColumns) == 0 &&
 		len(d.ModifiedColumns) == 0 &&
-		!d.RLSChanged
+		!d.RLSChanged &&
+		len(d.AddedForeignKeys) == 0 &&
+		len(d.RemovedForeignKeys) == 0 &&
+		len(d.ModifiedForeignKeys) == 0
 }
 
 // IsEmpty returns true if there are no differences
 func (d *SchemaDiff) IsEmpty() bool {
 	return len(d.AddedTables) == 0 &&
 		len(d.RemovedTables) == 0 &&
-		len(d.ModifiedTables) == 0
+		len(d.ModifiedTables) == 0 &&
+		len(d.AddedDomains) == 0 &&
+		len(d.RemovedDomains) == 0 &&
+		len(d.ModifiedDomains) == 0
 }
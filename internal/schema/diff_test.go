@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/lockplane/lockplane/internal/database"
@@ -29,7 +30,10 @@ func TestDiffSchemas_AddedTables(t *testing.T) {
 		},
 	}
 
-	diff := DiffSchemas(current, desired)
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
 
 	if len(diff.AddedTables) != 1 {
 		t.Fatalf("Expected 1 added table, got %d", len(diff.AddedTables))
@@ -80,7 +84,10 @@ func TestDiffSchemas_RemovedTables(t *testing.T) {
 		},
 	}
 
-	diff := DiffSchemas(current, desired)
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
 
 	if len(diff.RemovedTables) != 1 {
 		t.Fatalf("Expected 1 removed table, got %d", len(diff.RemovedTables))
@@ -113,7 +120,10 @@ func TestDiffSchemas_NoChanges(t *testing.T) {
 		},
 	}
 
-	diff := DiffSchemas(schema, schema)
+	diff, err := DiffSchemas(schema, schema)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
 
 	if !diff.IsEmpty() {
 		t.Error("Expected no differences for identical schemas")
@@ -136,7 +146,10 @@ func TestDiffSchemas_EmptySchemas(t *testing.T) {
 	current := &database.Schema{Tables: []database.Table{}}
 	desired := &database.Schema{Tables: []database.Table{}}
 
-	diff := DiffSchemas(current, desired)
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
 
 	if !diff.IsEmpty() {
 		t.Error("Expected no differences for empty schemas")
@@ -251,6 +264,54 @@ func TestDiffTables_ModifiedColumns(t *testing.T) {
 	}
 }
 
+func TestDiffTables_ForeignKeys(t *testing.T) {
+	current := &database.Table{
+		Name: "posts",
+		ForeignKeys: []database.ForeignKey{
+			{Name: "posts_author_id_fkey", Columns: []string{"author_id"}, ReferencedTable: "users"},
+			{Name: "posts_editor_id_fkey", Columns: []string{"editor_id"}, ReferencedTable: "users", OnDelete: database.FKActionSetNull},
+			{Name: "posts_org_id_fkey", Columns: []string{"org_id"}, ReferencedTable: "orgs"},
+		},
+	}
+	desired := &database.Table{
+		Name: "posts",
+		ForeignKeys: []database.ForeignKey{
+			{Name: "posts_author_id_fkey", Columns: []string{"author_id"}, ReferencedTable: "users", OnDelete: database.FKActionCascade},
+			// An explicit public schema and NO ACTION match the defaults
+			{Name: "posts_editor_id_fkey", Columns: []string{"editor_id"}, ReferencedSchema: "public", ReferencedTable: "users", OnDelete: database.FKActionSetNull, OnUpdate: database.FKActionNoAction},
+			{Name: "posts_team_id_fkey", Columns: []string{"team_id"}, ReferencedTable: "teams", Match: database.FKMatchFull, Deferrable: true},
+		},
+	}
+
+	diff := diffTables(current, desired)
+	if diff.IsEmpty() {
+		t.Fatal("Expected foreign key changes")
+	}
+	if len(diff.ModifiedForeignKeys) != 1 {
+		t.Fatalf("Expected 1 modified foreign key, got %+v", diff.ModifiedForeignKeys)
+	}
+	modified := diff.ModifiedForeignKeys[0]
+	if modified.Name != "posts_author_id_fkey" || !reflect.DeepEqual(modified.Changes, []string{"on_delete"}) ||
+		modified.Old.OnDelete != "" || modified.New.OnDelete != database.FKActionCascade {
+		t.Errorf("Expected the author key's ON DELETE to change from NO ACTION to CASCADE, got %+v", modified)
+	}
+	if len(diff.AddedForeignKeys) != 1 || diff.AddedForeignKeys[0].Name != "posts_team_id_fkey" {
+		t.Errorf("Expected posts_team_id_fkey to be added, got %+v", diff.AddedForeignKeys)
+	}
+	if len(diff.RemovedForeignKeys) != 1 || diff.RemovedForeignKeys[0].Name != "posts_org_id_fkey" {
+		t.Errorf("Expected posts_org_id_fkey to be removed, got %+v", diff.RemovedForeignKeys)
+	}
+
+	desired.ForeignKeys = []database.ForeignKey{
+		{Name: "posts_author_id_fkey", Columns: []string{"author_id"}, ReferencedTable: "users", OnUpdate: database.FKActionRestrict, Match: database.FKMatchFull, Deferrable: true, InitiallyDeferred: true},
+	}
+	current.ForeignKeys = current.ForeignKeys[:1]
+	diff = diffTables(current, desired)
+	if len(diff.ModifiedForeignKeys) != 1 || !reflect.DeepEqual(diff.ModifiedForeignKeys[0].Changes, []string{"on_update", "match", "deferrable"}) {
+		t.Errorf("Expected on_update, match and deferrable changes, got %+v", diff.ModifiedForeignKeys)
+	}
+}
+
 func TestDiffColumns_TypeChange(t *testing.T) {
 	current := &database.Column{
 		Name: "age",
@@ -277,6 +338,73 @@ func TestDiffColumns_TypeChange(t *testing.T) {
 	}
 }
 
+func TestDiffColumns_ArrayDimensions(t *testing.T) {
+	current := &database.Column{Name: "grid", Type: "integer[]", Nullable: true}
+	desired := &database.Column{Name: "grid", Type: "integer[3][3]", Nullable: true}
+
+	// The database doesn't enforce array dimensions, so they aren't a change
+	if diff := diffColumns(current, desired); diff != nil {
+		t.Errorf("Expected no diff, got %+v", diff)
+	}
+
+	desired.Type = "integer"
+	if diff := diffColumns(current, desired); diff == nil || diff.TypeChange != TypeChangeIncompatible {
+		t.Errorf("Expected an incompatible type change, got %+v", diff)
+	}
+}
+
+func TestDiffColumns_GeneratedChange(t *testing.T) {
+	current := &database.Column{
+		Name:      "amount",
+		Type:      "numeric",
+		Generated: &database.GeneratedColumn{Expression: "price * qty", Storage: database.GeneratedStored},
+	}
+
+	desired := &database.Column{
+		Name:      "amount",
+		Type:      "numeric",
+		Generated: &database.GeneratedColumn{Expression: "price * qty * 2", Storage: database.GeneratedStored},
+	}
+
+	diff := diffColumns(current, desired)
+	if diff == nil || len(diff.Changes) != 1 || diff.Changes[0] != "generated" {
+		t.Fatalf("Expected a 'generated' change, got %+v", diff)
+	}
+
+	desired.Generated = nil
+	diff = diffColumns(current, desired)
+	if diff == nil || len(diff.Changes) != 1 || diff.Changes[0] != "generated" {
+		t.Fatalf("Expected a 'generated' change when the expression is dropped, got %+v", diff)
+	}
+
+	desired.Generated = &database.GeneratedColumn{Expression: "price * qty", Storage: database.GeneratedStored}
+	if diff := diffColumns(current, desired); diff != nil {
+		t.Errorf("Expected no diff for the same expression, got %+v", diff)
+	}
+}
+
+func TestDiffColumns_IdentityChange(t *testing.T) {
+	serial := &database.Column{Name: "id", Type: "serial"}
+	identity := &database.Column{Name: "id", Type: "integer", Identity: &database.IdentitySpec{Always: true, Sequence: "users_id_seq"}}
+
+	diff := diffColumns(serial, identity)
+	if diff == nil || !reflect.DeepEqual(diff.Changes, []string{"type", "identity"}) {
+		t.Fatalf("Expected type and identity changes from serial, got %+v", diff)
+	}
+
+	byDefault := &database.Column{Name: "id", Type: "integer", Identity: &database.IdentitySpec{Always: false}}
+	if diff := diffColumns(identity, byDefault); diff == nil || !reflect.DeepEqual(diff.Changes, []string{"identity"}) {
+		t.Errorf("Expected an identity change from ALWAYS to BY DEFAULT, got %+v", diff)
+	}
+
+	// Sequence names and options aren't compared
+	start := int64(100)
+	renamed := &database.Column{Name: "id", Type: "integer", Identity: &database.IdentitySpec{Always: true, Sequence: "other_seq", Start: &start}}
+	if diff := diffColumns(identity, renamed); diff != nil {
+		t.Errorf("Expected no diff, got %+v", diff)
+	}
+}
+
 func TestDiffColumns_NullableChange(t *testing.T) {
 	current := &database.Column{
 		Name:     "email",
@@ -698,7 +826,10 @@ func TestDiffSchemas_ComplexScenario(t *testing.T) {
 		},
 	}
 
-	diff := DiffSchemas(current, desired)
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
 
 	// Check added tables
 	if len(diff.AddedTables) != 1 {
@@ -863,7 +994,10 @@ func TestDiffSchemas_RLSOnlyChange(t *testing.T) {
 		},
 	}
 
-	diff := DiffSchemas(current, desired)
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
 
 	if diff.IsEmpty() {
 		t.Error("Expected diff to NOT be empty when RLS changes")
@@ -884,3 +1018,198 @@ func TestDiffSchemas_RLSOnlyChange(t *testing.T) {
 		t.Error("Expected TableDiff to NOT be empty when RLS changes")
 	}
 }
+
+func TestDiffSchemas_DeterministicOrder(t *testing.T) {
+	current := &database.Schema{
+		Tables: []database.Table{
+			{Name: "z_removed", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+			{Name: "a_removed", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+			{
+				Name: "users",
+				Columns: []database.Column{
+					{Name: "id", Type: "integer"},
+					{Name: "z_old", Type: "text"},
+					{Name: "a_old", Type: "text"},
+				},
+			},
+		},
+	}
+	desired := &database.Schema{
+		Tables: []database.Table{
+			{Name: "z_added", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+			{
+				Name: "users",
+				Columns: []database.Column{
+					{Name: "id", Type: "bigint"},
+					{Name: "z_new", Type: "text"},
+					{Name: "a_new", Type: "text"},
+				},
+			},
+			{Name: "a_added", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		},
+	}
+
+	// Run several times, since map iteration order would vary between runs
+	for i := 0; i < 10; i++ {
+		diff, err := DiffSchemas(current, desired)
+		if err != nil {
+			t.Fatalf("DiffSchemas failed: %v", err)
+		}
+
+		if diff.AddedTables[0].Name != "z_added" || diff.AddedTables[1].Name != "a_added" {
+			t.Errorf("Expected added tables in declaration order, got %s, %s", diff.AddedTables[0].Name, diff.AddedTables[1].Name)
+		}
+		if diff.RemovedTables[0].Name != "z_removed" || diff.RemovedTables[1].Name != "a_removed" {
+			t.Errorf("Expected removed tables in declaration order, got %s, %s", diff.RemovedTables[0].Name, diff.RemovedTables[1].Name)
+		}
+
+		users := diff.ModifiedTables[0]
+		if users.AddedColumns[0].Name != "z_new" || users.AddedColumns[1].Name != "a_new" {
+			t.Errorf("Expected added columns in declaration order, got %+v", users.AddedColumns)
+		}
+		if users.RemovedColumns[0].Name != "z_old" || users.RemovedColumns[1].Name != "a_old" {
+			t.Errorf("Expected removed columns in declaration order, got %+v", users.RemovedColumns)
+		}
+	}
+}
+
+func TestDiffSchemas_MatchesTablesBySchema(t *testing.T) {
+	current := &database.Schema{
+		Tables: []database.Table{
+			{Name: "users", Schema: "public", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		},
+	}
+	desired := &database.Schema{
+		Tables: []database.Table{
+			// Unqualified tables are in the public schema
+			{Name: "users", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+			{Name: "users", Schema: "auth", Columns: []database.Column{{Name: "id", Type: "integer"}}},
+		},
+	}
+
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
+
+	if len(diff.ModifiedTables) != 0 || len(diff.RemovedTables) != 0 {
+		t.Errorf("Expected public.users to be unchanged, got %+v", diff)
+	}
+	if len(diff.AddedTables) != 1 || diff.AddedTables[0].Schema != "auth" {
+		t.Errorf("Expected auth.users to be added, got %+v", diff.AddedTables)
+	}
+}
+
+func TestDiffSchemas_DuplicateTables(t *testing.T) {
+	current := &database.Schema{}
+	desired := &database.Schema{
+		Tables: []database.Table{
+			{Name: "users"},
+			{Name: "users", Schema: "public"},
+		},
+	}
+
+	_, err := DiffSchemas(current, desired)
+	if err == nil {
+		t.Fatal("Expected error for duplicate tables")
+	}
+	if err.Error() != `desired schema: table "public.users" is defined multiple times` {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDiffSchemas_Domains(t *testing.T) {
+	current, err := ParseSQLSchemaWithDialect(`CREATE DOMAIN email AS varchar(100) CHECK (VALUE LIKE '%@%');
+CREATE DOMAIN auth.token AS text;
+CREATE DOMAIN score AS integer;`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse current schema: %v", err)
+	}
+	desired, err := ParseSQLSchemaWithDialect(`CREATE DOMAIN public.email AS varchar(255) CHECK (VALUE LIKE '%@%');
+CREATE DOMAIN auth.token AS text;
+CREATE DOMAIN slug AS text NOT NULL;`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("Failed to parse desired schema: %v", err)
+	}
+
+	diff, err := DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
+	if diff.IsEmpty() {
+		t.Fatal("Expected the domain changes to make the diff non-empty")
+	}
+	if len(diff.ModifiedDomains) != 1 {
+		t.Fatalf("Expected 1 modified domain, got %+v", diff.ModifiedDomains)
+	}
+	modified := diff.ModifiedDomains[0]
+	if modified.Name != "email" || !reflect.DeepEqual(modified.Changes, []string{"base_type"}) {
+		t.Errorf("Expected email's base type to change, got %s %v", modified.Name, modified.Changes)
+	}
+	if modified.Old.BaseType != "varchar(100)" || modified.New.BaseType != "varchar(255)" {
+		t.Errorf("Expected varchar(100) to varchar(255), got %s to %s", modified.Old.BaseType, modified.New.BaseType)
+	}
+	if len(diff.AddedDomains) != 1 || diff.AddedDomains[0].Name != "slug" {
+		t.Errorf("Expected slug to be added, got %+v", diff.AddedDomains)
+	}
+	if len(diff.RemovedDomains) != 1 || diff.RemovedDomains[0].Name != "score" {
+		t.Errorf("Expected score to be removed, got %+v", diff.RemovedDomains)
+	}
+
+	// Renaming a domain's CHECK constraint changes it
+	desired.Domains[0].CheckConstraints[0].Name = "email_has_at"
+	diff, err = DiffSchemas(current, desired)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
+	if len(diff.ModifiedDomains) != 1 || !reflect.DeepEqual(diff.ModifiedDomains[0].Changes, []string{"base_type", "check_constraints"}) {
+		t.Errorf("Expected the base type and check constraints of email to change, got %+v", diff.ModifiedDomains)
+	}
+}
+
+func TestClassifyTypeChange(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     TypeChange
+	}{
+		{"integer", "bigint", TypeChangeWidening},
+		{"bigint", "integer", TypeChangeNarrowing},
+		{"smallint", "integer", TypeChangeWidening},
+		{"serial", "bigint", TypeChangeWidening},
+		{"varchar(10)", "varchar(20)", TypeChangeWidening},
+		{"varchar(20)", "varchar(10)", TypeChangeNarrowing},
+		{"varchar(255)", "text", TypeChangeWidening},
+		{"text", "varchar(255)", TypeChangeNarrowing},
+		{"varchar", "varchar(10)", TypeChangeNarrowing},
+		{"char", "varchar(5)", TypeChangeWidening},
+		{"numeric(10,2)", "numeric(12,2)", TypeChangeWidening},
+		{"numeric(10,2)", "numeric(10,4)", TypeChangeNarrowing},
+		{"numeric(10,2)", "numeric", TypeChangeWidening},
+		{"integer", "numeric(12,2)", TypeChangeWidening},
+		{"bigint", "numeric(12,2)", TypeChangeNarrowing},
+		{"numeric", "integer", TypeChangeNarrowing},
+		{"real", "double precision", TypeChangeWidening},
+		{"bigint", "double precision", TypeChangeNarrowing},
+		{"integer[]", "bigint[]", TypeChangeWidening},
+		{"integer", "integer[]", TypeChangeIncompatible},
+		{"text", "integer", TypeChangeIncompatible},
+		{"integer", "uuid", TypeChangeIncompatible},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.old+" to "+tt.new, func(t *testing.T) {
+			current := &database.Column{Name: "c", Type: tt.old}
+			current.Precision, current.Scale, current.Length = database.TypeModifiers(tt.old)
+			desired := &database.Column{Name: "c", Type: tt.new}
+			desired.Precision, desired.Scale, desired.Length = database.TypeModifiers(tt.new)
+
+			diff := diffColumns(current, desired)
+			if diff == nil {
+				t.Fatal("Expected diff, got nil")
+			}
+			if diff.TypeChange != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, diff.TypeChange)
+			}
+		})
+	}
+}
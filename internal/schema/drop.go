@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// parseDropTable applies DROP TABLE to the schema, so files can model an
+// ordered stream of DDL rather than only declarations. It reports whether the
+// statement was modeled; DROP statements for other kinds of objects are not.
+//
+// As in PostgreSQL, dropping a partitioned table drops its partitions, and
+// tables that other tables depend on (through foreign keys or inheritance)
+// can only be dropped with CASCADE, which drops the foreign keys and the
+// inheriting tables.
+func parseDropTable(schema *database.Schema, stmt *pg_query.DropStmt) (bool, error) {
+	if stmt.RemoveType != pg_query.ObjectType_OBJECT_TABLE {
+		return false, nil
+	}
+
+	dropped := make(map[string]bool)
+	for _, object := range stmt.Objects {
+		list := object.GetList()
+		if list == nil {
+			return false, fmt.Errorf("DROP TABLE missing table name")
+		}
+		names := constraintKeys(list.Items)
+		var tableSchema, tableName string
+		switch len(names) {
+		case 1:
+			tableName = names[0]
+		case 2:
+			tableSchema, tableName = names[0], names[1]
+		default:
+			return false, fmt.Errorf("unsupported table name %q", names)
+		}
+
+		i := findTableIndex(schema, tableSchema, tableName)
+		if i == -1 {
+			if stmt.MissingOk {
+				continue
+			}
+			return false, fmt.Errorf("table %q does not exist", qualifiedTableName(&database.Table{Schema: tableSchema, Name: tableName}))
+		}
+		dropPartitions(schema, &schema.Tables[i], dropped)
+	}
+
+	cascade := stmt.Behavior == pg_query.DropBehavior_DROP_CASCADE
+	if err := dropDependents(schema, dropped, cascade); err != nil {
+		return false, err
+	}
+
+	schema.Tables = slices.DeleteFunc(schema.Tables, func(table database.Table) bool {
+		return dropped[qualifiedTableName(&table)]
+	})
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		table.Partitions = slices.DeleteFunc(table.Partitions, func(ref database.TableRef) bool {
+			return dropped[qualifiedTableName(&database.Table{Schema: ref.Schema, Name: ref.Table})]
+		})
+	}
+	return true, nil
+}
+
+// dropPartitions marks table and, recursively, its partitions as dropped
+func dropPartitions(schema *database.Schema, table *database.Table, dropped map[string]bool) {
+	dropped[qualifiedTableName(table)] = true
+	for _, partition := range table.Partitions {
+		if i := findTableIndex(schema, partition.Schema, partition.Table); i != -1 {
+			dropPartitions(schema, &schema.Tables[i], dropped)
+		}
+	}
+}
+
+// dropDependents handles the tables that depend on the dropped tables but
+// aren't being dropped themselves. Without cascade the first one is an error.
+// With cascade, their foreign keys to dropped tables are removed and tables
+// inheriting from dropped tables are dropped too.
+func dropDependents(schema *database.Schema, dropped map[string]bool, cascade bool) error {
+	for changed := true; changed; {
+		changed = false
+		for i := range schema.Tables {
+			table := &schema.Tables[i]
+			name := qualifiedTableName(table)
+			if dropped[name] {
+				continue
+			}
+
+			for _, parent := range table.Inherits {
+				parentName := qualifiedTableName(&database.Table{Schema: parent.Schema, Name: parent.Table})
+				if !dropped[parentName] {
+					continue
+				}
+				if !cascade {
+					return fmt.Errorf("cannot drop table %q because table %q inherits from it; use DROP ... CASCADE", parentName, name)
+				}
+				dropPartitions(schema, table, dropped)
+				changed = true
+				break
+			}
+			if dropped[name] {
+				continue
+			}
+
+			for _, fk := range table.ForeignKeys {
+				target := qualifiedTableName(&database.Table{Schema: fk.ReferencedSchema, Name: fk.ReferencedTable})
+				if dropped[target] && !cascade {
+					return fmt.Errorf("cannot drop table %q because foreign key %q on table %q references it; use DROP ... CASCADE", target, fk.Name, name)
+				}
+			}
+			table.ForeignKeys = slices.DeleteFunc(table.ForeignKeys, func(fk database.ForeignKey) bool {
+				return dropped[qualifiedTableName(&database.Table{Schema: fk.ReferencedSchema, Name: fk.ReferencedTable})]
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+func tableNames(schema *database.Schema) []string {
+	var names []string
+	for i := range schema.Tables {
+		names = append(names, qualifiedTableName(&schema.Tables[i]))
+	}
+	return names
+}
+
+func TestParseDropTable(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE legacy (id BIGINT PRIMARY KEY);
+CREATE TABLE auth.legacy (id BIGINT PRIMARY KEY);
+DROP TABLE legacy;
+DROP TABLE IF EXISTS missing, auth.legacy;
+CREATE TABLE legacy (id BIGINT PRIMARY KEY, name TEXT);
+`
+
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	if names := tableNames(schema); !reflect.DeepEqual(names, []string{"public.users", "public.legacy"}) {
+		t.Fatalf("Unexpected tables %v", names)
+	}
+	if len(schema.Tables[1].Columns) != 2 {
+		t.Errorf("Expected the recreated legacy table, got %+v", schema.Tables[1])
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %+v", coverage)
+	}
+}
+
+func TestParseDropTableCascade(t *testing.T) {
+	base := `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users);
+CREATE TABLE admins (level INT) INHERITS (users);
+`
+
+	schema, err := ParseSQLSchemaWithDialect(base+"DROP TABLE users CASCADE;", database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if names := tableNames(schema); !reflect.DeepEqual(names, []string{"public.posts"}) {
+		t.Fatalf("Expected only posts to remain, got %v", names)
+	}
+	if len(schema.Tables[0].ForeignKeys) != 0 {
+		t.Errorf("Expected the foreign key to users to be dropped, got %+v", schema.Tables[0].ForeignKeys)
+	}
+
+	// Dropping every dependent in the same statement needs no CASCADE
+	schema, err = ParseSQLSchemaWithDialect(base+"DROP TABLE posts, admins, users;", database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if len(schema.Tables) != 0 {
+		t.Errorf("Expected no tables, got %v", tableNames(schema))
+	}
+}
+
+func TestParseDropTablePartitions(t *testing.T) {
+	sql := `CREATE TABLE events (id BIGINT, at DATE) PARTITION BY RANGE (at);
+CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+CREATE TABLE events_2025 PARTITION OF events FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+CREATE TABLE audit (id BIGINT) PARTITION BY RANGE (id);
+CREATE TABLE audit_1 PARTITION OF audit FOR VALUES FROM (0) TO (100);
+DROP TABLE events_2024;
+DROP TABLE audit;
+`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if names := tableNames(schema); !reflect.DeepEqual(names, []string{"public.events", "public.events_2025"}) {
+		t.Fatalf("Unexpected tables %v", names)
+	}
+	if partitions := schema.Tables[0].Partitions; !reflect.DeepEqual(partitions, []database.TableRef{{Table: "events_2025"}}) {
+		t.Errorf("Expected events_2024 to be removed from the partitions, got %+v", partitions)
+	}
+}
+
+func TestParseDropTableErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name:     "missing table",
+			sql:      `DROP TABLE accounts;`,
+			expected: `table "public.accounts" does not exist`,
+		},
+		{
+			name:     "referenced by a foreign key",
+			sql:      `DROP TABLE users;`,
+			expected: `cannot drop table "public.users" because foreign key "posts_author_id_fkey" on table "public.posts" references it`,
+		},
+		{
+			name:     "inherited",
+			sql:      `DROP TABLE posts;`,
+			expected: `cannot drop table "public.posts" because table "public.drafts" inherits from it`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT REFERENCES users);
+CREATE TABLE drafts () INHERITS (posts);
+` + tt.sql
+			_, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// Other kinds of objects aren't modeled
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, "DROP INDEX missing_idx;", "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+	if coverage.Modeled != 0 || coverage.Ignored != 1 {
+		t.Errorf("Expected DROP INDEX to be ignored, got %+v", coverage)
+	}
+}
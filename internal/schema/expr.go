@@ -0,0 +1,362 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// generationExpression renders the expression of a generated column. Casts of
+// string literals are dropped, since PostgreSQL stores every literal with one
+// ('y'::text), so a column renders the same whether it was parsed or
+// introspected.
+func generationExpression(node *pg_query.Node) string {
+	expr := buildExpr(node)
+	if expr == nil {
+		return ""
+	}
+	return withoutLiteralCasts(expr).String()
+}
+
+// withoutLiteralCasts returns expr with the casts of string literals in it
+// replaced by the literals
+func withoutLiteralCasts(expr *database.Expr) *database.Expr {
+	if expr.Kind == database.ExprCast && len(expr.Args) == 1 &&
+		expr.Args[0].Kind == database.ExprLiteral && expr.Args[0].LiteralType == database.LiteralString {
+		return expr.Args[0]
+	}
+	for i, arg := range expr.Args {
+		if arg != nil {
+			expr.Args[i] = withoutLiteralCasts(arg)
+		}
+	}
+	return expr
+}
+
+// buildExpr converts an expression AST to a database.Expr tree. Expressions
+// that aren't broken down become ExprOther nodes holding formatExpr's text.
+func buildExpr(node *pg_query.Node) *database.Expr {
+	if node == nil {
+		return nil
+	}
+
+	switch expr := node.Node.(type) {
+	case *pg_query.Node_AConst:
+		return buildConst(expr.AConst)
+
+	case *pg_query.Node_ColumnRef:
+		return &database.Expr{Kind: database.ExprColumn, Name: formatExpr(node)}
+
+	case *pg_query.Node_AExpr:
+		if built := buildAExpr(expr.AExpr); built != nil {
+			return built
+		}
+
+	case *pg_query.Node_BoolExpr:
+		op := map[pg_query.BoolExprType]string{
+			pg_query.BoolExprType_AND_EXPR: "AND",
+			pg_query.BoolExprType_OR_EXPR:  "OR",
+			pg_query.BoolExprType_NOT_EXPR: "NOT",
+		}[expr.BoolExpr.Boolop]
+		if op != "" {
+			return &database.Expr{Kind: database.ExprOperator, Op: op, Args: buildExprs(expr.BoolExpr.Args)}
+		}
+
+	case *pg_query.Node_NullTest:
+		op := "IS NULL"
+		if expr.NullTest.Nulltesttype == pg_query.NullTestType_IS_NOT_NULL {
+			op = "IS NOT NULL"
+		}
+		return &database.Expr{Kind: database.ExprOperator, Op: op, Args: []*database.Expr{buildExpr(expr.NullTest.Arg)}}
+
+	case *pg_query.Node_FuncCall:
+		var name []string
+		for _, part := range expr.FuncCall.Funcname {
+			if s, ok := part.Node.(*pg_query.Node_String_); ok {
+				name = append(name, s.String_.Sval)
+			}
+		}
+		// Skip the implicit pg_catalog qualification of functions such as
+		// EXTRACT or TRIM
+		if len(name) > 1 && name[0] == "pg_catalog" {
+			name = name[1:]
+		}
+		return &database.Expr{Kind: database.ExprFunction, Name: strings.Join(name, "."), Args: buildExprs(expr.FuncCall.Args)}
+
+	case *pg_query.Node_TypeCast:
+		if expr.TypeCast.Arg != nil && expr.TypeCast.TypeName != nil {
+			return &database.Expr{
+				Kind: database.ExprCast,
+				Type: formatTypeName(expr.TypeCast.TypeName),
+				Args: []*database.Expr{buildExpr(expr.TypeCast.Arg)},
+			}
+		}
+	}
+
+	return &database.Expr{Kind: database.ExprOther, Text: formatExpr(node)}
+}
+
+func buildExprs(nodes []*pg_query.Node) []*database.Expr {
+	exprs := make([]*database.Expr, 0, len(nodes))
+	for _, node := range nodes {
+		exprs = append(exprs, buildExpr(node))
+	}
+	return exprs
+}
+
+// buildConst converts a constant to a literal node
+func buildConst(c *pg_query.A_Const) *database.Expr {
+	literal := &database.Expr{Kind: database.ExprLiteral}
+	switch {
+	case c.GetIsnull():
+		literal.LiteralType = database.LiteralNull
+	case c.GetIval() != nil:
+		literal.LiteralType = database.LiteralInteger
+		literal.Value = fmt.Sprintf("%d", c.GetIval().Ival)
+	case c.GetFval() != nil:
+		literal.LiteralType = database.LiteralFloat
+		literal.Value = c.GetFval().Fval
+	case c.GetSval() != nil:
+		literal.LiteralType = database.LiteralString
+		literal.Value = c.GetSval().Sval
+	case c.GetBoolval() != nil:
+		literal.LiteralType = database.LiteralBoolean
+		literal.Value = fmt.Sprintf("%t", c.GetBoolval().Boolval)
+	case c.GetBsval() != nil:
+		literal.LiteralType = database.LiteralBitString
+		literal.Value = c.GetBsval().Bsval
+	default:
+		// An A_Const with no value set is the integer 0
+		literal.LiteralType = database.LiteralInteger
+		literal.Value = "0"
+	}
+	return literal
+}
+
+// buildAExpr converts operator expressions (a > b, -a, a IN (...),
+// a BETWEEN b AND c, a LIKE b). It returns nil for forms it doesn't model.
+func buildAExpr(expr *pg_query.A_Expr) *database.Expr {
+	var name string
+	if len(expr.Name) > 0 {
+		if s, ok := expr.Name[len(expr.Name)-1].Node.(*pg_query.Node_String_); ok {
+			name = s.String_.Sval
+		}
+	}
+
+	operator := func(op string, args ...*database.Expr) *database.Expr {
+		return &database.Expr{Kind: database.ExprOperator, Op: op, Args: args}
+	}
+
+	switch expr.Kind {
+	case pg_query.A_Expr_Kind_AEXPR_OP:
+		if name == "" || expr.Rexpr == nil {
+			return nil
+		}
+		if expr.Lexpr == nil {
+			return operator(name, buildExpr(expr.Rexpr))
+		}
+		return operator(name, buildExpr(expr.Lexpr), buildExpr(expr.Rexpr))
+
+	case pg_query.A_Expr_Kind_AEXPR_IN:
+		list, ok := expr.Rexpr.GetNode().(*pg_query.Node_List)
+		if !ok {
+			return nil
+		}
+		op := "IN"
+		if name == "<>" {
+			op = "NOT IN"
+		}
+		return operator(op, append([]*database.Expr{buildExpr(expr.Lexpr)}, buildExprs(list.List.Items)...)...)
+
+	case pg_query.A_Expr_Kind_AEXPR_BETWEEN, pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN:
+		list, ok := expr.Rexpr.GetNode().(*pg_query.Node_List)
+		if !ok || len(list.List.Items) != 2 {
+			return nil
+		}
+		op := "BETWEEN"
+		if expr.Kind == pg_query.A_Expr_Kind_AEXPR_NOT_BETWEEN {
+			op = "NOT BETWEEN"
+		}
+		return operator(op, buildExpr(expr.Lexpr), buildExpr(list.List.Items[0]), buildExpr(list.List.Items[1]))
+
+	case pg_query.A_Expr_Kind_AEXPR_LIKE, pg_query.A_Expr_Kind_AEXPR_ILIKE:
+		op := map[string]string{"~~": "LIKE", "!~~": "NOT LIKE", "~~*": "ILIKE", "!~~*": "NOT ILIKE"}[name]
+		if op == "" {
+			return nil
+		}
+		return operator(op, buildExpr(expr.Lexpr), buildExpr(expr.Rexpr))
+	}
+
+	return nil
+}
+
+// exprColumnRefs returns the columns expr references, in order and without
+// repeats
+func exprColumnRefs(expr *database.Expr) []string {
+	var columns []string
+	var walk func(*database.Expr)
+	walk = func(expr *database.Expr) {
+		if expr == nil {
+			return
+		}
+		if expr.Kind == database.ExprColumn && !slices.Contains(columns, expr.Name) {
+			columns = append(columns, expr.Name)
+		}
+		for _, arg := range expr.Args {
+			walk(arg)
+		}
+	}
+	walk(expr)
+	return columns
+}
+
+// exprReferencesColumn reports whether expr references column
+func exprReferencesColumn(expr *database.Expr, column string) bool {
+	if expr == nil {
+		return false
+	}
+	if expr.Kind == database.ExprColumn && expr.Name == column {
+		return true
+	}
+	for _, arg := range expr.Args {
+		if exprReferencesColumn(arg, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// evalConstant evaluates an expression that doesn't depend on any column. The
+// result is a float64, string, bool, or nil for NULL. ok is false when expr
+// references a column or uses something the evaluator doesn't understand.
+func evalConstant(expr *database.Expr) (value any, ok bool) {
+	if expr == nil {
+		return nil, false
+	}
+
+	switch expr.Kind {
+	case database.ExprLiteral:
+		switch expr.LiteralType {
+		case database.LiteralInteger, database.LiteralFloat:
+			n, err := strconv.ParseFloat(expr.Value, 64)
+			return n, err == nil
+		case database.LiteralBoolean:
+			return expr.Value == "true", true
+		case database.LiteralString:
+			return expr.Value, true
+		case database.LiteralNull:
+			return nil, true
+		}
+
+	case database.ExprOperator:
+		return evalOperator(expr)
+	}
+
+	return nil, false
+}
+
+func evalOperator(expr *database.Expr) (any, bool) {
+	switch expr.Op {
+	case "AND", "OR":
+		// A single false (AND) or true (OR) operand decides the result even
+		// when the other operands aren't constant
+		decisive := expr.Op == "OR"
+		allConstant := true
+		for _, arg := range expr.Args {
+			value, ok := evalConstant(arg)
+			b, isBool := value.(bool)
+			if ok && isBool && b == decisive {
+				return decisive, true
+			}
+			if !ok || !isBool {
+				allConstant = false
+			}
+		}
+		return !decisive, allConstant
+
+	case "NOT":
+		value, ok := evalConstant(expr.Args[0])
+		b, isBool := value.(bool)
+		return !b, ok && isBool
+
+	case "IS NULL", "IS NOT NULL":
+		value, ok := evalConstant(expr.Args[0])
+		return (value == nil) == (expr.Op == "IS NULL"), ok
+	}
+
+	if len(expr.Args) == 1 {
+		value, ok := evalConstant(expr.Args[0])
+		n, isNumber := value.(float64)
+		switch expr.Op {
+		case "-":
+			return -n, ok && isNumber
+		case "+":
+			return n, ok && isNumber
+		}
+		return nil, false
+	}
+	if len(expr.Args) != 2 {
+		return nil, false
+	}
+
+	left, ok := evalConstant(expr.Args[0])
+	if !ok {
+		return nil, false
+	}
+	right, ok := evalConstant(expr.Args[1])
+	if !ok {
+		return nil, false
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, isNumber := right.(float64)
+		if !isNumber {
+			return nil, false
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, isString := right.(string)
+		if !isString {
+			return nil, false
+		}
+		cmp = strings.Compare(l, r)
+	case bool:
+		r, isBool := right.(bool)
+		if !isBool || (expr.Op != "=" && expr.Op != "<>" && expr.Op != "!=") {
+			return nil, false
+		}
+		if l != r {
+			cmp = 1
+		}
+	default:
+		return nil, false
+	}
+
+	switch expr.Op {
+	case "=":
+		return cmp == 0, true
+	case "<>", "!=":
+		return cmp != 0, true
+	case "<":
+		return cmp < 0, true
+	case "<=":
+		return cmp <= 0, true
+	case ">":
+		return cmp > 0, true
+	case ">=":
+		return cmp >= 0, true
+	}
+	return nil, false
+}
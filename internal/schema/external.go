@@ -0,0 +1,59 @@
+package schema
+
+import (
+	"sort"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// ExternalTable is a table that foreign keys reference but the schema doesn't
+// define, i.e. one the schema assumes already exists in the database
+type ExternalTable struct {
+	// Name is the schema-qualified table name, e.g. "auth.users"
+	Name string `json:"name"`
+	// ReferencedBy lists the foreign keys referencing the table, in schema order
+	ReferencedBy []ExternalReference `json:"referenced_by"`
+}
+
+// ExternalReference is a foreign key referencing an external table
+type ExternalReference struct {
+	// Table is the schema-qualified name of the table the key is declared on
+	Table          string                   `json:"table"`
+	ForeignKey     string                   `json:"foreign_key"`
+	SourceLocation *database.SourceLocation `json:"source_location,omitempty"`
+}
+
+// ExternalTables returns the tables referenced by foreign keys anywhere in the
+// schema that the schema doesn't define, sorted by name
+func ExternalTables(schema *database.Schema) []ExternalTable {
+	byName := make(map[string]*ExternalTable)
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, fk := range table.ForeignKeys {
+			if findTableIndex(schema, fk.ReferencedSchema, fk.ReferencedTable) != -1 {
+				continue
+			}
+
+			name := qualifiedTableName(&database.Table{Schema: fk.ReferencedSchema, Name: fk.ReferencedTable})
+			external, ok := byName[name]
+			if !ok {
+				external = &ExternalTable{Name: name}
+				byName[name] = external
+			}
+			external.ReferencedBy = append(external.ReferencedBy, ExternalReference{
+				Table:          qualifiedTableName(table),
+				ForeignKey:     fk.Name,
+				SourceLocation: fk.SourceLocation,
+			})
+		}
+	}
+
+	externals := make([]ExternalTable, 0, len(byName))
+	for _, external := range byName {
+		externals = append(externals, *external)
+	}
+	sort.Slice(externals, func(i, j int) bool {
+		return externals[i].Name < externals[j].Name
+	})
+	return externals
+}
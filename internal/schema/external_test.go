@@ -0,0 +1,60 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+func TestExternalTables(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (
+    id BIGINT PRIMARY KEY,
+    author_id BIGINT REFERENCES users (id),
+    owner_id UUID REFERENCES auth.users (id),
+    org_id BIGINT REFERENCES orgs
+);
+CREATE TABLE comments (
+    id BIGINT PRIMARY KEY,
+    author_id UUID,
+    CONSTRAINT comments_author_fk FOREIGN KEY (author_id) REFERENCES auth.users (id)
+);
+`
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	externals := ExternalTables(schema)
+
+	var names []string
+	for _, external := range externals {
+		names = append(names, external.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"auth.users", "public.orgs"}) {
+		t.Fatalf("Expected external tables auth.users and public.orgs, got %v", names)
+	}
+
+	var referencedBy []string
+	for _, ref := range externals[0].ReferencedBy {
+		referencedBy = append(referencedBy, ref.Table+" "+ref.ForeignKey)
+	}
+	expected := []string{"public.posts posts_owner_id_fkey", "public.comments comments_author_fk"}
+	if !reflect.DeepEqual(referencedBy, expected) {
+		t.Errorf("Expected auth.users to be referenced by %v, got %v", expected, referencedBy)
+	}
+	if loc := externals[0].ReferencedBy[1].SourceLocation; loc == nil || loc.Line != 11 {
+		t.Errorf("Expected the comments foreign key on line 11, got %v", loc)
+	}
+}
+
+func TestExternalTablesNone(t *testing.T) {
+	schema, err := ParseSQLSchemaWithDialect(`CREATE TABLE users (id BIGINT PRIMARY KEY, manager_id BIGINT REFERENCES users);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if externals := ExternalTables(schema); len(externals) != 0 {
+		t.Errorf("Expected no external tables, got %+v", externals)
+	}
+}
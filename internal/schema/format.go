@@ -0,0 +1,332 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// ErrCannotFormat is returned for schema files that can't be rewritten from
+// the model without losing something, such as comments or statements
+// lockplane doesn't model
+var ErrCannotFormat = errors.New("can't be formatted")
+
+// FormattedFile is a schema file and its contents in the canonical style
+type FormattedFile struct {
+	Path      string
+	Formatted string
+	// Changed is set when Formatted differs from the file's contents
+	Changed bool
+	// Err is set when the file couldn't be formatted, in which case Formatted
+	// is empty
+	Err error
+}
+
+// FormatSchemaFiles formats each schema file at path, a directory or .lp.sql
+// file as for LoadSchema. Files are formatted on their own, so a file that
+// alters tables created in another file can't be formatted.
+func FormatSchemaFiles(path string) ([]FormattedFile, error) {
+	files, err := findSchemaFiles(osFS{}, path, LoadSchemaOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]FormattedFile, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+		}
+		formatted, err := FormatSQL(string(data), file)
+		results = append(results, FormattedFile{
+			Path:      file,
+			Formatted: formatted,
+			Changed:   err == nil && formatted != string(data),
+			Err:       err,
+		})
+	}
+	return results, nil
+}
+
+// FormatSQL rewrites the PostgreSQL DDL of one schema file in the canonical
+// style of WriteSchema. filename is only used to locate parse errors.
+//
+// Formatting rebuilds the DDL from the model, so files with anything the
+// model doesn't capture are left alone and ErrCannotFormat returned: comments,
+// statements lockplane doesn't model, and details such as index storage
+// parameters or deferrable unique constraints. As a last check the output is
+// parsed again and must describe the same schema.
+func FormatSQL(sql string, filename string) (string, error) {
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return "", &ParseError{File: filename, Err: fmt.Errorf("failed to parse SQL: %w", err)}
+	}
+	for _, token := range scan.Tokens {
+		if token.Token == pg_query.Token_SQL_COMMENT || token.Token == pg_query.Token_C_COMMENT {
+			return "", fmt.Errorf("%w: formatting would remove its comments", ErrCannotFormat)
+		}
+	}
+
+	schema := newSchema(database.DialectPostgres)
+	coverage := &Coverage{}
+	if errs := parsePostgresSQLSchemaWithFilename(schema, sql, filename, coverage, nil, true); len(errs) > 0 {
+		return "", errs[0]
+	}
+	if coverage.Modeled != coverage.Statements {
+		kinds := make([]string, 0, len(coverage.IgnoredByKind))
+		for kind := range coverage.IgnoredByKind {
+			kinds = append(kinds, kind)
+		}
+		slices.Sort(kinds)
+		if len(kinds) == 0 {
+			kinds = append(kinds, "objects lockplane only tracks")
+		}
+		return "", fmt.Errorf("%w: it has statements lockplane doesn't model (%s)", ErrCannotFormat, strings.Join(kinds, ", "))
+	}
+
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		return "", err
+	}
+	for _, stmt := range tree.Stmts {
+		if detail := unmodeledDetail(stmt.Stmt); detail != "" {
+			return "", fmt.Errorf("%w: lockplane doesn't model %s", ErrCannotFormat, detail)
+		}
+	}
+
+	var formatted bytes.Buffer
+	if err := WriteSchema(&formatted, schema); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrCannotFormat, err)
+	}
+
+	// The formatted SQL must parse to a schema that formats the same way
+	reparsed, err := ParseSQLSchemaWithDialect(formatted.String(), database.DialectPostgres)
+	if err != nil {
+		return "", fmt.Errorf("%w: the formatted SQL doesn't parse: %v", ErrCannotFormat, err)
+	}
+	var again bytes.Buffer
+	if err := WriteSchema(&again, reparsed); err != nil || again.String() != formatted.String() {
+		return "", fmt.Errorf("%w: formatting would change the schema", ErrCannotFormat)
+	}
+	return formatted.String(), nil
+}
+
+// unmodeledDetail describes the first part of a modeled statement that the
+// model doesn't capture, and so formatting would drop, or returns ""
+func unmodeledDetail(stmt *pg_query.Node) string {
+	switch node := stmt.GetNode().(type) {
+	case *pg_query.Node_CreateStmt:
+		create := node.CreateStmt
+		switch {
+		case create.Partspec != nil || create.Partbound != nil:
+			return "partitioning"
+		case create.OfTypename != nil:
+			return "typed tables"
+		case create.Tablespacename != "" || create.AccessMethod != "":
+			return "tablespaces and table access methods"
+		case create.Oncommit != pg_query.OnCommitAction_ONCOMMIT_NOOP:
+			return "ON COMMIT"
+		}
+		for _, elt := range create.TableElts {
+			if detail := tableElementDetail(elt); detail != "" {
+				return detail
+			}
+		}
+		for _, constraint := range create.Constraints {
+			if detail := constraintDetail(constraint.GetConstraint()); detail != "" {
+				return detail
+			}
+		}
+
+	case *pg_query.Node_AlterTableStmt:
+		for _, cmd := range node.AlterTableStmt.Cmds {
+			if def := cmd.GetAlterTableCmd().GetDef(); def != nil {
+				if detail := tableElementDetail(def); detail != "" {
+					return detail
+				}
+			}
+		}
+
+	case *pg_query.Node_IndexStmt:
+		index := node.IndexStmt
+		switch {
+		case len(index.Options) > 0 || index.TableSpace != "":
+			return "index storage parameters and tablespaces"
+		case index.NullsNotDistinct:
+			return "NULLS NOT DISTINCT"
+		}
+		for _, param := range index.IndexParams {
+			elem := param.GetIndexElem()
+			if elem == nil {
+				continue
+			}
+			if detail := indexElemDetail(elem); detail != "" {
+				return "index " + detail
+			}
+			if len(elem.Opclass) > 0 {
+				return "index operator classes"
+			}
+		}
+
+	case *pg_query.Node_ViewStmt:
+		view := node.ViewStmt
+		switch {
+		case view.Replace:
+			return "CREATE OR REPLACE"
+		case view.View.GetRelpersistence() != "p":
+			return "temporary views"
+		case len(view.Aliases) > 0:
+			return "view column names"
+		case len(view.Options) > 0 || view.WithCheckOption != pg_query.ViewCheckOption_NO_CHECK_OPTION:
+			return "view options"
+		}
+
+	case *pg_query.Node_CreateTableAsStmt:
+		into := node.CreateTableAsStmt.GetInto()
+		switch {
+		case node.CreateTableAsStmt.IfNotExists:
+			return "IF NOT EXISTS"
+		case len(into.GetColNames()) > 0:
+			return "view column names"
+		case len(into.GetOptions()) > 0 || into.GetTableSpaceName() != "" || into.GetAccessMethod() != "":
+			return "view options"
+		}
+
+	case *pg_query.Node_CreateExtensionStmt:
+		for _, option := range node.CreateExtensionStmt.Options {
+			if option.GetDefElem().GetDefname() != "schema" {
+				return "extension versions and CASCADE"
+			}
+		}
+
+	case *pg_query.Node_CreateSeqStmt:
+		seq := node.CreateSeqStmt
+		switch {
+		case seq.IfNotExists:
+			return "IF NOT EXISTS"
+		case seq.Sequence.GetRelpersistence() != "p":
+			return "temporary and unlogged sequences"
+		}
+		for _, option := range seq.Options {
+			if name := option.GetDefElem().GetDefname(); name != "start" && name != "increment" {
+				return "sequence options other than START and INCREMENT"
+			}
+		}
+
+	case *pg_query.Node_CreateDomainStmt:
+		if node.CreateDomainStmt.CollClause != nil {
+			return "collations"
+		}
+		for _, c := range node.CreateDomainStmt.Constraints {
+			if detail := constraintDetail(c.GetConstraint()); detail != "" {
+				return detail
+			}
+		}
+	}
+	return ""
+}
+
+// indexElemDetail describes the unmodeled sort order or collation of an index
+// or exclusion constraint element, or returns ""
+func indexElemDetail(elem *pg_query.IndexElem) string {
+	if elem.GetOrdering() != pg_query.SortByDir_SORTBY_DEFAULT || elem.GetNullsOrdering() != pg_query.SortByNulls_SORTBY_NULLS_DEFAULT ||
+		len(elem.GetCollation()) > 0 || len(elem.GetOpclassopts()) > 0 {
+		return "element sort orders and collations"
+	}
+	return ""
+}
+
+// tableElementDetail describes the unmodeled part of a column definition or
+// table constraint, or returns ""
+func tableElementDetail(elt *pg_query.Node) string {
+	if constraint := elt.GetConstraint(); constraint != nil {
+		return constraintDetail(constraint)
+	}
+	colDef := elt.GetColumnDef()
+	if colDef == nil {
+		return ""
+	}
+	if colDef.CollClause != nil {
+		return "collations"
+	}
+	if colDef.Compression != "" || colDef.StorageName != "" {
+		return "column storage and compression"
+	}
+	// DEFERRABLE and INITIALLY DEFERRED are reported as constraints of their
+	// own, and are modeled when they follow a foreign key
+	afterForeignKey := false
+	for _, constraint := range colDef.Constraints {
+		c := constraint.GetConstraint()
+		if isConstraintAttr(c) && afterForeignKey {
+			continue
+		}
+		afterForeignKey = c.GetContype() == pg_query.ConstrType_CONSTR_FOREIGN
+		if detail := constraintDetail(c); detail != "" {
+			return detail
+		}
+	}
+	return ""
+}
+
+// isConstraintAttr reports whether a column constraint is a DEFERRABLE, NOT
+// DEFERRABLE, INITIALLY DEFERRED or INITIALLY IMMEDIATE attribute of the
+// constraint before it
+func isConstraintAttr(constraint *pg_query.Constraint) bool {
+	switch constraint.GetContype() {
+	case pg_query.ConstrType_CONSTR_ATTR_DEFERRABLE, pg_query.ConstrType_CONSTR_ATTR_DEFERRED,
+		pg_query.ConstrType_CONSTR_ATTR_NOT_DEFERRABLE, pg_query.ConstrType_CONSTR_ATTR_IMMEDIATE:
+		return true
+	}
+	return false
+}
+
+// identityOptions are the sequence options of an identity column that
+// parseIdentity models
+var identityOptions = []string{"sequence_name", "start", "increment", "minvalue", "maxvalue", "cache", "cycle"}
+
+// constraintDetail describes the unmodeled part of a constraint, or returns ""
+func constraintDetail(constraint *pg_query.Constraint) string {
+	if constraint == nil {
+		return ""
+	}
+	switch constraint.Contype {
+	case pg_query.ConstrType_CONSTR_EXCLUSION:
+		for _, item := range constraint.Exclusions {
+			if items := item.GetList().GetItems(); len(items) > 0 {
+				if detail := indexElemDetail(items[0].GetIndexElem()); detail != "" {
+					return "exclusion constraint " + detail
+				}
+			}
+		}
+	case pg_query.ConstrType_CONSTR_ATTR_DEFERRABLE, pg_query.ConstrType_CONSTR_ATTR_DEFERRED,
+		pg_query.ConstrType_CONSTR_ATTR_NOT_DEFERRABLE, pg_query.ConstrType_CONSTR_ATTR_IMMEDIATE:
+		return "deferrable constraints other than foreign keys"
+	case pg_query.ConstrType_CONSTR_IDENTITY:
+		// The options of an identity column are its sequence's, not index
+		// options, and parseIdentity models all but these
+		for _, option := range constraint.Options {
+			if name := option.GetDefElem().GetDefname(); !slices.Contains(identityOptions, name) {
+				return "identity sequence option " + strings.ToUpper(strings.ReplaceAll(name, "_", " "))
+			}
+		}
+		return ""
+	}
+
+	switch {
+	case (constraint.Deferrable || constraint.Initdeferred) && constraint.Contype != pg_query.ConstrType_CONSTR_FOREIGN:
+		return "deferrable constraints other than foreign keys"
+	case constraint.SkipValidation:
+		return "NOT VALID constraints"
+	case constraint.IsNoInherit:
+		return "NO INHERIT constraints"
+	case len(constraint.Including) > 0 || len(constraint.Options) > 0 || constraint.Indexspace != "" || constraint.NullsNotDistinct:
+		return "constraint index options"
+	}
+	return ""
+}
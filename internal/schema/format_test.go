@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatSQL(t *testing.T) {
+	sql := `create table posts (author_id bigint not null references users, id bigint primary key, title text unique);
+alter table posts add column body text default '';
+create index on posts (author_id);
+`
+	formatted, err := FormatSQL(sql, "posts.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+
+	expected := `CREATE TABLE posts (
+  author_id bigint NOT NULL,
+  id bigint,
+  title text,
+  body text DEFAULT '',
+  PRIMARY KEY (id),
+  CONSTRAINT posts_title_key UNIQUE (title),
+  CONSTRAINT posts_author_id_fkey FOREIGN KEY (author_id) REFERENCES users
+);
+
+CREATE INDEX posts_author_id_idx ON posts (author_id);
+`
+	if formatted != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+
+	// Formatting is idempotent
+	again, err := FormatSQL(formatted, "posts.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL of formatted SQL failed: %v", err)
+	}
+	if again != formatted {
+		t.Errorf("Expected formatting to be idempotent, got:\n%s", again)
+	}
+}
+
+func TestFormatSQLForeignKeyOptions(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  author_id BIGINT REFERENCES users MATCH FULL ON UPDATE CASCADE ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED,
+  editor_id BIGINT,
+  FOREIGN KEY (editor_id) REFERENCES users (id) ON UPDATE RESTRICT DEFERRABLE
+);
+`
+	formatted, err := FormatSQL(sql, "posts.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"CONSTRAINT posts_author_id_fkey FOREIGN KEY (author_id) REFERENCES users MATCH FULL ON DELETE SET NULL ON UPDATE CASCADE DEFERRABLE INITIALLY DEFERRED",
+		"CONSTRAINT posts_editor_id_fkey FOREIGN KEY (editor_id) REFERENCES users (id) ON UPDATE RESTRICT DEFERRABLE\n",
+	} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("Expected the formatted SQL to contain %q, got:\n%s", expected, formatted)
+		}
+	}
+}
+
+func TestFormatSQLIdentityOptions(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT GENERATED BY DEFAULT AS IDENTITY (START WITH 100 INCREMENT BY 5) PRIMARY KEY);
+`
+	formatted, err := FormatSQL(sql, "users.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+
+	expected := "id bigint GENERATED BY DEFAULT AS IDENTITY (START WITH 100 INCREMENT BY 5)"
+	if !strings.Contains(formatted, expected) {
+		t.Errorf("Expected the formatted SQL to contain %q, got:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatSQLLikeClauses(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT NOT NULL DEFAULT '', CHECK (email <> 'root'));
+CREATE INDEX ON users (email);
+COMMENT ON COLUMN users.email IS 'Login';
+CREATE TABLE users_archive (archived_at TIMESTAMPTZ, LIKE users INCLUDING DEFAULTS INCLUDING INDEXES INCLUDING COMMENTS, reason TEXT);
+CREATE TABLE users_copy (LIKE users INCLUDING ALL);
+`
+	formatted, err := FormatSQL(sql, "users.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"CREATE TABLE users_archive (\n  archived_at timestamp with time zone,\n  LIKE users INCLUDING COMMENTS INCLUDING DEFAULTS INCLUDING INDEXES,\n  reason text\n);\n",
+		"CREATE TABLE users_copy (\n  LIKE users INCLUDING ALL\n);\n",
+	} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("Expected the formatted SQL to contain %q, got:\n%s", expected, formatted)
+		}
+	}
+	if strings.Count(formatted, "CREATE INDEX") != 1 || strings.Count(formatted, "COMMENT ON") != 1 {
+		t.Errorf("Expected the copied index and comment to be left to LIKE, got:\n%s", formatted)
+	}
+
+	// A copied column changed afterwards can't be written as LIKE
+	_, err = FormatSQL(`CREATE TABLE users (id BIGINT, email TEXT);
+CREATE TABLE users_copy (LIKE users);
+COMMENT ON COLUMN users_copy.email IS 'Login';`, "users.lp.sql")
+	if !errors.Is(err, ErrCannotFormat) || !strings.Contains(err.Error(), "what LIKE users copied has since changed") {
+		t.Errorf("Expected ErrCannotFormat for a changed copy, got %v", err)
+	}
+}
+
+func TestFormatSQLCannotFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{"comments", "-- users\nCREATE TABLE users (id BIGINT PRIMARY KEY);", "formatting would remove its comments"},
+		{"unmodeled statements", "CREATE TABLE users (id BIGINT PRIMARY KEY);\nGRANT SELECT ON users TO reader;", "statements lockplane doesn't model (GrantStmt)"},
+		{"view options", "CREATE TABLE users (id BIGINT PRIMARY KEY);\nCREATE VIEW v WITH (security_barrier) AS SELECT id FROM users;", "doesn't model view options"},
+		{"index storage parameters", "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);\nCREATE INDEX ON users (email) WITH (fillfactor = 70);", "doesn't model index storage parameters"},
+		{"deferrable unique constraint", "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT UNIQUE DEFERRABLE);", "doesn't model deferrable constraints other than foreign keys"},
+		{"deferrable table unique constraint", "CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT, UNIQUE (email) DEFERRABLE INITIALLY DEFERRED);", "doesn't model deferrable constraints other than foreign keys"},
+		{"exclusion sort order", "CREATE TABLE rooms (id BIGINT PRIMARY KEY, EXCLUDE (id DESC WITH =));", "doesn't model exclusion constraint element sort orders"},
+		{"identity sequence type", "CREATE TABLE users (id BIGINT GENERATED BY DEFAULT AS IDENTITY (AS integer));", "doesn't model identity sequence option AS"},
+		{"collation", `CREATE TABLE users (id BIGINT PRIMARY KEY, name TEXT COLLATE "C");`, "doesn't model collations"},
+		{"unquoted expression", `CREATE TABLE orders ("order" INT CHECK ("order" > 0));`, "the formatted SQL doesn't parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FormatSQL(tt.sql, "schema.lp.sql")
+			if !errors.Is(err, ErrCannotFormat) || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected ErrCannotFormat mentioning %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// Parse errors are reported as such
+	if _, err := FormatSQL("CREATE TABLE users id BIGINT);", "schema.lp.sql"); err == nil || errors.Is(err, ErrCannotFormat) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestFormatSchemaFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.lp.sql": "CREATE TABLE a (\n  id bigint,\n  PRIMARY KEY (id)\n);\n",
+		"b.lp.sql": "create table b (id bigint primary key);",
+		"c.lp.sql": "-- notes\nCREATE TABLE c (id BIGINT PRIMARY KEY);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	results, err := FormatSchemaFiles(dir)
+	if err != nil {
+		t.Fatalf("FormatSchemaFiles failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 files, got %+v", results)
+	}
+	if results[0].Err != nil || results[0].Changed {
+		t.Errorf("Expected a.lp.sql to be formatted already, got %+v", results[0])
+	}
+	if results[1].Err != nil || !results[1].Changed || results[1].Formatted != "CREATE TABLE b (\n  id bigint,\n  PRIMARY KEY (id)\n);\n" {
+		t.Errorf("Expected b.lp.sql to need formatting, got %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, ErrCannotFormat) || results[2].Changed {
+		t.Errorf("Expected c.lp.sql to be skipped, got %+v", results[2])
+	}
+}
@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"strconv"
+
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// NormalizeIntrospected rewrites the column defaults and generation
+// expressions of an introspected schema, as deparsed by pg_get_expr (e.g.
+// 'x'::text), the way the parser renders them from .lp.sql files ('x'), so the
+// two compare equal. Expressions that don't parse are left as they are.
+//
+// PostgreSQL stores a negative number as a string cast to the column's type
+// ('-1.5'::numeric), which is rendered as the number it was declared as.
+func NormalizeIntrospected(s *database.Schema) {
+	for i := range s.Tables {
+		for j := range s.Tables[i].Columns {
+			col := &s.Tables[i].Columns[j]
+			if col.Generated != nil {
+				if node := parseExpression(col.Generated.Expression); node != nil {
+					col.Generated.Expression = generationExpression(node)
+				}
+			}
+			if col.Default == nil {
+				continue
+			}
+			if node := parseExpression(*col.Default); node != nil {
+				normalized := formatExpr(node)
+				if number, ok := castNumber(node); ok {
+					normalized = number
+				}
+				if col.Type == "boolean" {
+					normalized = normalizeBooleanDefault(normalized)
+				}
+				col.Default = &normalized
+			}
+		}
+	}
+}
+
+// parseExpression parses a standalone SQL expression, returning nil when it
+// isn't one
+func parseExpression(expr string) *pg_query.Node {
+	tree, err := pg_query.Parse("SELECT " + expr)
+	if err != nil || len(tree.Stmts) != 1 {
+		return nil
+	}
+	sel := tree.Stmts[0].Stmt.GetSelectStmt()
+	if sel == nil || len(sel.TargetList) != 1 || sel.FromClause != nil {
+		return nil
+	}
+	return sel.TargetList[0].GetResTarget().GetVal()
+}
+
+// numericCastTypes are the types, as named in a parsed cast, that a quoted
+// number is cast to when PostgreSQL deparses a negative default
+var numericCastTypes = map[string]bool{
+	"int2": true, "int4": true, "int8": true, "numeric": true, "float4": true, "float8": true,
+}
+
+// castNumber returns the number of an expression such as '-1'::integer
+func castNumber(node *pg_query.Node) (string, bool) {
+	cast := node.GetTypeCast()
+	if cast == nil || cast.TypeName == nil || len(cast.TypeName.Names) == 0 {
+		return "", false
+	}
+	names := cast.TypeName.Names
+	if !numericCastTypes[names[len(names)-1].GetString_().GetSval()] {
+		return "", false
+	}
+	constant := cast.Arg.GetAConst()
+	if constant == nil || constant.GetSval() == nil {
+		return "", false
+	}
+	number := constant.GetSval().GetSval()
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return "", false
+	}
+	return number, true
+}
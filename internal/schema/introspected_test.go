@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+func TestNormalizeIntrospectedDefaults(t *testing.T) {
+	parsed, err := ParseSQLSchemaWithDialect(`CREATE TABLE t (
+		status text DEFAULT 'active',
+		code varchar(3) DEFAULT 'abc',
+		meta jsonb DEFAULT '{}'::jsonb,
+		created_at timestamp DEFAULT now(),
+		updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+		ratio numeric DEFAULT -1.5,
+		delta integer DEFAULT -1,
+		active boolean DEFAULT 't'
+	);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	// The defaults as pg_get_expr reports them for the same table
+	introspected := map[string]string{
+		"status":     "'active'::text",
+		"code":       "'abc'::character varying",
+		"meta":       "'{}'::jsonb",
+		"created_at": "now()",
+		"updated_at": "CURRENT_TIMESTAMP",
+		"ratio":      "'-1.5'::numeric",
+		"delta":      "'-1'::integer",
+		"active":     "true",
+	}
+	schema := &database.Schema{Tables: []database.Table{{Name: "t"}}}
+	for _, col := range parsed.Tables[0].Columns {
+		def := introspected[col.Name]
+		schema.Tables[0].Columns = append(schema.Tables[0].Columns, database.Column{Name: col.Name, Type: col.Type, Default: &def})
+	}
+
+	NormalizeIntrospected(schema)
+
+	for i, col := range schema.Tables[0].Columns {
+		expected := *parsed.Tables[0].Columns[i].Default
+		if *col.Default != expected {
+			t.Errorf("Expected introspected default of %s to normalize to %q, got %q", col.Name, expected, *col.Default)
+		}
+	}
+}
+
+func TestNormalizeIntrospectedGeneratedColumns(t *testing.T) {
+	parsed, err := ParseSQLSchemaWithDialect(`CREATE TABLE t (
+		a text,
+		b integer,
+		c text GENERATED ALWAYS AS (lower(a) || 'y') STORED,
+		d integer GENERATED ALWAYS AS (b * 2 + 1) STORED,
+		e boolean GENERATED ALWAYS AS (a IS NOT NULL AND b > 0) STORED
+	);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	// The expressions as pg_get_expr reports them for the same table
+	introspected := map[string]string{
+		"c": "(lower(a) || 'y'::text)",
+		"d": "((b * 2) + 1)",
+		"e": "((a IS NOT NULL) AND (b > 0))",
+	}
+	schema := &database.Schema{Tables: []database.Table{{Name: "t"}}}
+	for _, col := range parsed.Tables[0].Columns {
+		introspectedCol := database.Column{Name: col.Name, Type: col.Type, Nullable: col.Nullable}
+		if expr, ok := introspected[col.Name]; ok {
+			introspectedCol.Generated = &database.GeneratedColumn{Expression: expr, Storage: database.GeneratedStored}
+		}
+		schema.Tables[0].Columns = append(schema.Tables[0].Columns, introspectedCol)
+	}
+
+	NormalizeIntrospected(schema)
+
+	diff, err := DiffSchemas(schema, parsed)
+	if err != nil {
+		t.Fatalf("DiffSchemas failed: %v", err)
+	}
+	if !diff.IsEmpty() {
+		for _, col := range diff.ModifiedTables[0].ModifiedColumns {
+			t.Errorf("Expected introspected column %s to match the parsed one, got %q for %q", col.ColumnName, col.Old.Generated.Expression, col.New.Generated.Expression)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// likeOption is a bit in TableLikeClause.Options. The parser reports the
+// options as PostgreSQL's CREATE_TABLE_LIKE_* bitmask, where each option's bit
+// is one less than its TableLikeOption enum value.
+type likeOption uint32
+
+func likeOptionBit(option pg_query.TableLikeOption) likeOption {
+	return 1 << (uint32(option) - 1)
+}
+
+var (
+	likeComments    = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_COMMENTS)
+	likeCompression = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_COMPRESSION)
+	likeConstraints = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_CONSTRAINTS)
+	likeDefaults    = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_DEFAULTS)
+	likeGenerated   = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_GENERATED)
+	likeIdentity    = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_IDENTITY)
+	likeIndexes     = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_INDEXES)
+	likeStatistics  = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_STATISTICS)
+	likeStorage     = likeOptionBit(pg_query.TableLikeOption_CREATE_TABLE_LIKE_STORAGE)
+)
+
+// likeAll is the value PostgreSQL uses for INCLUDING ALL (PG_INT32_MAX)
+const likeAll likeOption = 0x7FFFFFFF
+
+// likeOptionNames lists the LIKE options in the order they are recorded
+var likeOptionNames = []struct {
+	option likeOption
+	name   string
+}{
+	{likeComments, "COMMENTS"},
+	{likeCompression, "COMPRESSION"},
+	{likeConstraints, "CONSTRAINTS"},
+	{likeDefaults, "DEFAULTS"},
+	{likeGenerated, "GENERATED"},
+	{likeIdentity, "IDENTITY"},
+	{likeIndexes, "INDEXES"},
+	{likeStatistics, "STATISTICS"},
+	{likeStorage, "STORAGE"},
+}
+
+// includingOptions returns the names of the options set in options
+func includingOptions(options likeOption) []string {
+	if options == likeAll {
+		return []string{"ALL"}
+	}
+
+	var names []string
+	for _, o := range likeOptionNames {
+		if options&o.option != 0 {
+			names = append(names, o.name)
+		}
+	}
+	return names
+}
+
+// likeOptions returns the options named in LikeClause.Including, undoing
+// includingOptions
+func likeOptions(names []string) likeOption {
+	var options likeOption
+	for _, name := range names {
+		if name == "ALL" {
+			return likeAll
+		}
+		for _, o := range likeOptionNames {
+			if o.name == name {
+				options |= o.option
+			}
+		}
+	}
+	return options
+}
+
+// applyLikeClause copies the columns of the LIKE source table into table,
+// along with the attributes selected by the INCLUDING options that lockplane
+// tracks.
+func applyLikeClause(schema *database.Schema, table *database.Table, clause *pg_query.TableLikeClause, locate *locator) error {
+	if clause.Relation == nil {
+		return fmt.Errorf("LIKE clause missing relation")
+	}
+
+	sourceIndex := findTableIndex(schema, clause.Relation.Schemaname, clause.Relation.Relname)
+	if sourceIndex == -1 {
+		return fmt.Errorf("LIKE source table %q does not exist", rangeVarName(clause.Relation))
+	}
+
+	options := likeOption(clause.Options)
+	loc := locate.at(clause.Relation.Location)
+
+	table.LikeClauses = append(table.LikeClauses, database.LikeClause{
+		Schema:         clause.Relation.Schemaname,
+		Table:          clause.Relation.Relname,
+		Including:      includingOptions(options),
+		SourceLocation: loc,
+	})
+
+	copied := likeCopy(&schema.Tables[sourceIndex], table.Name, options)
+	for _, col := range copied.Columns {
+		col.SourceLocation = loc
+		table.Columns = append(table.Columns, col)
+	}
+	for _, check := range copied.CheckConstraints {
+		check.SourceLocation = loc
+		table.CheckConstraints = append(table.CheckConstraints, check)
+	}
+	table.PrimaryKey = append(table.PrimaryKey, copied.PrimaryKey...)
+	if copied.PrimaryKeyName != "" {
+		table.PrimaryKeyName = copied.PrimaryKeyName
+	}
+	for _, index := range copied.Indexes {
+		index.SourceLocation = loc
+		table.Indexes = append(table.Indexes, index)
+	}
+	for _, unique := range copied.UniqueConstraints {
+		unique.SourceLocation = loc
+		table.UniqueConstraints = append(table.UniqueConstraints, unique)
+	}
+	return nil
+}
+
+// likeCopy returns what a LIKE clause with the given options copies from
+// source into the table named tableName, without source locations. As in
+// PostgreSQL, NOT NULL is always copied, defaults only with INCLUDING
+// DEFAULTS, generation expressions only with INCLUDING GENERATED, identity
+// only with INCLUDING IDENTITY, check constraints only with INCLUDING
+// CONSTRAINTS and the primary key and indexes only with INCLUDING INDEXES.
+func likeCopy(source *database.Table, tableName string, options likeOption) *database.Table {
+	copied := &database.Table{}
+	for _, sourceCol := range source.Columns {
+		col := database.Column{
+			Name:     sourceCol.Name,
+			Type:     sourceCol.Type,
+			Nullable: sourceCol.Nullable,
+			// Unless the primary key is copied too, a column NOT NULL for
+			// being part of it is NOT NULL in its own right in the copy
+			NotNullExplicit: !sourceCol.Nullable,
+			Precision:       sourceCol.Precision,
+			Scale:           sourceCol.Scale,
+			Length:          sourceCol.Length,
+		}
+		if options&likeDefaults != 0 {
+			col.Default = sourceCol.Default
+		}
+		if options&likeGenerated != 0 && sourceCol.Generated != nil {
+			generated := *sourceCol.Generated
+			col.Generated = &generated
+		}
+		if options&likeIdentity != 0 && sourceCol.Identity != nil {
+			// The new table gets its own sequence, with the same options
+			identity := *sourceCol.Identity
+			identity.Sequence = identitySequenceName(tableName, col.Name)
+			col.Identity = &identity
+		}
+		if options&likeIndexes != 0 {
+			col.IsPrimaryKey = sourceCol.IsPrimaryKey
+			col.NotNullExplicit = sourceCol.NotNullExplicit
+		}
+		if options&likeComments != 0 {
+			col.Comment = sourceCol.Comment
+		}
+		copied.Columns = append(copied.Columns, col)
+	}
+
+	// CHECK constraints keep their names
+	if options&likeConstraints != 0 {
+		for _, check := range source.CheckConstraints {
+			check.SourceLocation = nil
+			copied.CheckConstraints = append(copied.CheckConstraints, check)
+		}
+	}
+
+	if options&likeIndexes == 0 {
+		return copied
+	}
+
+	copied.PrimaryKey = slices.Clone(source.PrimaryKey)
+	if len(source.PrimaryKey) > 0 {
+		// Like the indexes, the copied primary key gets a fresh name
+		copied.PrimaryKeyName = tableName + "_pkey"
+	}
+	for _, sourceIndex := range source.Indexes {
+		index := sourceIndex
+		// PostgreSQL picks a fresh name for each copied index
+		index.Name = defaultIndexName(tableName, index)
+		index.SourceLocation = nil
+		copied.Indexes = append(copied.Indexes, index)
+	}
+	for _, sourceUnique := range source.UniqueConstraints {
+		copied.UniqueConstraints = append(copied.UniqueConstraints, database.UniqueConstraint{
+			Name:    uniqueConstraintName(tableName, sourceUnique.Columns),
+			Columns: slices.Clone(sourceUnique.Columns),
+		})
+	}
+	return copied
+}
+
+// rangeVarName returns the schema-qualified name of a table reference,
+// defaulting to the public schema
+func rangeVarName(rv *pg_query.RangeVar) string {
+	tableSchema := rv.Schemaname
+	if tableSchema == "" {
+		tableSchema = "public"
+	}
+	return tableSchema + "." + rv.Relname
+}
@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+const likeSourceSQL = `CREATE TABLE users (
+  id BIGINT PRIMARY KEY,
+  email TEXT NOT NULL DEFAULT '',
+  active BOOLEAN DEFAULT true
+);
+CREATE INDEX users_email_idx ON users (email);
+`
+
+func TestParseLikeIncludingDefaults(t *testing.T) {
+	sql := likeSourceSQL + `CREATE TABLE users_archive (LIKE users INCLUDING DEFAULTS, archived_at TIMESTAMPTZ);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[1]
+	var names []string
+	for _, col := range table.Columns {
+		names = append(names, col.Name)
+	}
+	if want := []string{"id", "email", "active", "archived_at"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected columns %v, got %v", want, names)
+	}
+
+	id, email, active := table.Columns[0], table.Columns[1], table.Columns[2]
+	if id.Type != "bigint" || id.Nullable {
+		t.Errorf("Expected id to be NOT NULL bigint, got %+v", id)
+	}
+	if id.IsPrimaryKey || len(table.PrimaryKey) != 0 {
+		t.Error("Expected primary key not to be copied without INCLUDING INDEXES")
+	}
+	if email.Nullable || email.Default == nil || *email.Default != "''" {
+		t.Errorf("Expected email to be NOT NULL with default '', got %+v", email)
+	}
+	if active.Default == nil || *active.Default != "true" {
+		t.Errorf("Expected active to default to true, got %v", active.Default)
+	}
+	if len(table.Indexes) != 0 {
+		t.Errorf("Expected indexes not to be copied, got %+v", table.Indexes)
+	}
+
+	if len(table.LikeClauses) != 1 {
+		t.Fatalf("Expected 1 LIKE clause, got %d", len(table.LikeClauses))
+	}
+	like := table.LikeClauses[0]
+	if like.Table != "users" || !reflect.DeepEqual(like.Including, []string{"DEFAULTS"}) {
+		t.Errorf("Expected LIKE users INCLUDING DEFAULTS, got %+v", like)
+	}
+	expectLocation(t, "LIKE clause", like.SourceLocation, "", 7, 34)
+	expectLocation(t, "copied column", email.SourceLocation, "", 7, 34)
+}
+
+func TestParseLikeIncludingAll(t *testing.T) {
+	sql := likeSourceSQL + `CREATE TABLE users_copy (LIKE users INCLUDING ALL);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[1]
+	if len(table.Columns) != 3 {
+		t.Fatalf("Expected 3 columns, got %d", len(table.Columns))
+	}
+	if !table.Columns[0].IsPrimaryKey || !reflect.DeepEqual(table.PrimaryKey, []string{"id"}) {
+		t.Errorf("Expected primary key (id) to be copied, got %v", table.PrimaryKey)
+	}
+	if table.PrimaryKeyName != "users_copy_pkey" {
+		t.Errorf("Expected the copied primary key to be named users_copy_pkey, got %q", table.PrimaryKeyName)
+	}
+	if table.Columns[1].Default == nil {
+		t.Error("Expected defaults to be copied")
+	}
+	if len(table.Indexes) != 1 || table.Indexes[0].Name != "users_copy_email_idx" {
+		t.Fatalf("Expected copied index users_copy_email_idx, got %+v", table.Indexes)
+	}
+	if !reflect.DeepEqual(table.LikeClauses[0].Including, []string{"ALL"}) {
+		t.Errorf("Expected INCLUDING ALL, got %v", table.LikeClauses[0].Including)
+	}
+
+	// The source table is left untouched
+	if schema.Tables[0].Indexes[0].Name != "users_email_idx" {
+		t.Errorf("Expected source index to keep its name, got %q", schema.Tables[0].Indexes[0].Name)
+	}
+}
+
+func TestParseLikeExcludingOptions(t *testing.T) {
+	tests := []struct {
+		clause string
+		want   []string
+	}{
+		{"LIKE users", nil},
+		{"LIKE users EXCLUDING ALL", nil},
+		{"LIKE users INCLUDING INDEXES INCLUDING COMMENTS", []string{"COMMENTS", "INDEXES"}},
+		{"LIKE users INCLUDING ALL EXCLUDING INDEXES EXCLUDING STORAGE",
+			[]string{"COMMENTS", "COMPRESSION", "CONSTRAINTS", "DEFAULTS", "GENERATED", "IDENTITY", "STATISTICS"}},
+	}
+
+	for _, tt := range tests {
+		sql := likeSourceSQL + "CREATE TABLE users_copy (" + tt.clause + ");"
+		schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+		if err != nil {
+			t.Fatalf("%s: ParseSQLSchemaWithDialect failed: %v", tt.clause, err)
+		}
+
+		table := schema.Tables[1]
+		if got := table.LikeClauses[0].Including; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected options %v, got %v", tt.clause, tt.want, got)
+		}
+
+		copiedIndexes := len(table.Indexes) > 0
+		if includesIndexes := strings.Contains(tt.clause, "INCLUDING INDEXES"); copiedIndexes != includesIndexes {
+			t.Errorf("%s: expected indexes copied=%v, got %+v", tt.clause, includesIndexes, table.Indexes)
+		}
+	}
+}
+
+func TestParseLikeUnknownTable(t *testing.T) {
+	_, err := ParseSQLSchemaWithDialect(`CREATE TABLE copy (LIKE auth.users);`, database.DialectPostgres)
+	if err == nil {
+		t.Fatal("Expected error for LIKE on an unknown table")
+	}
+	if !strings.Contains(err.Error(), `LIKE source table "auth.users" does not exist`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
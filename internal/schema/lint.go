@@ -0,0 +1,964 @@
+package schema
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// lintRule is a check run against a successfully parsed schema. Lint rules
+// report problems that don't stop the schema from loading.
+type lintRule struct {
+	Code        string
+	Severity    string
+	Description string
+	Check       func(schema *database.Schema, opts CheckOptions) []Diagnostic
+}
+
+// lintRules are the built-in lint rules, run in order
+var lintRules = []lintRule{
+	{
+		Code:        CodeMissingPrimaryKey,
+		Severity:    SeverityWarning,
+		Description: "A table has no primary key",
+		Check:       checkMissingPrimaryKey,
+	},
+	{
+		Code:        CodeForeignKeyMissingColumn,
+		Severity:    SeverityError,
+		Description: "A foreign key references a column that doesn't exist",
+		Check:       checkForeignKeyMissingColumn,
+	},
+	{
+		Code:        CodeForeignKeyNotUnique,
+		Severity:    SeverityWarning,
+		Description: "A foreign key references columns without a primary key or unique constraint",
+		Check:       checkForeignKeyNotUnique,
+	},
+	{
+		Code:        CodeUnindexedForeignKey,
+		Severity:    SeverityWarning,
+		Description: "A foreign key's columns are not the leading columns of an index",
+		Check:       checkUnindexedForeignKey,
+	},
+	{
+		Code:        CodePartitionParentMissing,
+		Severity:    SeverityWarning,
+		Description: "A partition's parent table isn't in the schema",
+		Check:       checkPartitionParentMissing,
+	},
+	{
+		Code:        CodeRLSWithoutPolicy,
+		Severity:    SeverityWarning,
+		Description: "A table has row level security enabled but no policies",
+		Check:       checkRLSWithoutPolicy,
+	},
+	{
+		Code:        CodeUndeclaredSchema,
+		Severity:    SeverityWarning,
+		Description: "An object is in a schema that is never created",
+		Check:       checkUndeclaredSchema,
+	},
+	{
+		Code:        CodeUnquotedUppercase,
+		Severity:    SeverityWarning,
+		Description: "A table or column name is written unquoted with uppercase letters, which PostgreSQL folds to lowercase",
+		Check:       checkUnquotedUppercase,
+	},
+	{
+		Code:        CodeReservedKeyword,
+		Severity:    SeverityWarning,
+		Description: "A table or column name is an unquoted keyword that PostgreSQL reserves in some contexts",
+		Check:       checkReservedKeyword,
+	},
+	{
+		Code:        CodeAutoIncrementDefault,
+		Severity:    SeverityError,
+		Description: "A serial or identity column also has a DEFAULT",
+		Check:       checkAutoIncrementDefault,
+	},
+	{
+		Code:        CodeUndeclaredSequence,
+		Severity:    SeverityWarning,
+		Description: "A column's DEFAULT takes values from a sequence the schema doesn't create",
+		Check:       checkUndeclaredSequence,
+	},
+	{
+		Code:        CodeUnknownType,
+		Severity:    SeverityWarning,
+		Description: "A column's type is not a known built-in type, enum or domain",
+		Check:       checkUnknownType,
+	},
+	{
+		// Opt-in, since it is a matter of taste whether a flag may be unset
+		Code:        CodeNullableFlag,
+		Severity:    RuleOff,
+		Description: "A boolean or flag-named column is nullable instead of NOT NULL with a DEFAULT",
+		Check:       checkNullableFlag,
+	},
+	{
+		Code:        CodeIndexOnMissingColumn,
+		Severity:    SeverityWarning,
+		Description: "An index references a column that is not present on its table",
+		Check:       checkIndexOnMissingColumn,
+	},
+	{
+		Code:        CodeIndexClauseMissingColumn,
+		Severity:    SeverityError,
+		Description: "An index's INCLUDE columns or WHERE predicate reference a column that is not present on its table",
+		Check:       checkIndexClauseMissingColumn,
+	},
+	{
+		Code:        CodeNamingPolicy,
+		Severity:    SeverityWarning,
+		Description: "A table or column name does not match the configured naming policy",
+		Check:       checkNamingPolicy,
+	},
+	{
+		Code:        CodeTrivialCheck,
+		Severity:    SeverityWarning,
+		Description: "A CHECK constraint is always true or always false",
+		Check:       checkTrivialCheck,
+	},
+	{
+		Code:        CodeEmptyTable,
+		Severity:    SeverityWarning,
+		Description: "A table has no columns and doesn't inherit or partition another table",
+		Check:       checkEmptyTable,
+	},
+	{
+		Code:        CodeUndefinedType,
+		Severity:    SeverityWarning,
+		Description: "A column uses a schema-qualified type that isn't defined in the schema",
+		Check:       checkUndefinedType,
+	},
+	{
+		// Opt-in, since columns sharing a name don't always hold the same data
+		Code:        CodeInconsistentColumnType,
+		Severity:    RuleOff,
+		Description: "Columns with the same name have different types in different tables",
+		Check:       checkInconsistentColumnType,
+	},
+	{
+		Code:        CodeWithOidsDeprecated,
+		Severity:    SeverityWarning,
+		Description: "A table is created WITH OIDS, which PostgreSQL 12 and later don't support",
+		Check:       checkWithOids,
+	},
+	{
+		Code:        CodeForeignKeySetNullNotNull,
+		Severity:    SeverityWarning,
+		Description: "A foreign key's ON DELETE SET NULL or SET DEFAULT action conflicts with its columns",
+		Check:       checkForeignKeySetNullNotNull,
+	},
+}
+
+// RuleOff disables a lint rule in CheckOptions.RuleSeverities
+const RuleOff = "off"
+
+// ValidateRuleSeverities checks a map of lint rule codes to severities, as
+// configured in lockplane.toml. Only lint rules can be configured; parse and
+// validation errors are always reported.
+func ValidateRuleSeverities(rules map[string]string) error {
+	for code, severity := range rules {
+		if severity != RuleOff && severity != SeverityWarning && severity != SeverityError {
+			return fmt.Errorf("invalid severity %q for rule %s: expected off, warning or error", severity, code)
+		}
+		if _, ok := validationCodeDescriptions[code]; ok {
+			return fmt.Errorf("rule %s reports schema errors and can't be configured", code)
+		}
+		if !slices.ContainsFunc(lintRules, func(rule lintRule) bool { return rule.Code == code }) {
+			return fmt.Errorf("unknown rule %s", code)
+		}
+	}
+	return nil
+}
+
+// runLintRules runs every enabled lint rule against schema, adding the
+// diagnostics they produce to output. With opts.FailFast, it stops after the
+// first rule that reports an error.
+func runLintRules(schema *database.Schema, opts CheckOptions, output *CheckOutput) {
+	for _, rule := range lintRules {
+		severity := rule.Severity
+		if configured, ok := opts.RuleSeverities[rule.Code]; ok {
+			severity = configured
+		}
+		if severity == RuleOff {
+			continue
+		}
+
+		for _, d := range rule.Check(schema, opts) {
+			if opts.generated[d.File] {
+				continue
+			}
+			d.Code = rule.Code
+			d.Severity = severity
+			output.Add(d)
+		}
+		if stopOnError(output, opts) {
+			return
+		}
+	}
+}
+
+// checkMissingPrimaryKey warns about tables without a primary key, declared
+// either inline on a column or as a table constraint
+func checkMissingPrimaryKey(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if hasPrimaryKey(table) {
+			continue
+		}
+		diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeMissingPrimaryKey,
+			fmt.Sprintf("table %q has no primary key", qualifiedTableName(table))))
+	}
+	return diagnostics
+}
+
+func hasPrimaryKey(table *database.Table) bool {
+	if len(table.PrimaryKey) > 0 {
+		return true
+	}
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPartitionParentMissing warns about tables created PARTITION OF a table
+// the schema files don't define, such as one in a file that wasn't loaded
+func checkPartitionParentMissing(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		bound := table.PartitionOf
+		if bound == nil || findTableIndex(schema, bound.Schema, bound.Table) != -1 {
+			continue
+		}
+		parent := &database.Table{Schema: bound.Schema, Name: bound.Table}
+		diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodePartitionParentMissing,
+			fmt.Sprintf("table %q is a partition of %q, which isn't in the schema", qualifiedTableName(table), qualifiedTableName(parent))))
+	}
+	return diagnostics
+}
+
+// checkRLSWithoutPolicy warns about tables with row level security enabled
+// and no policies, which denies every role but the table's owner access to
+// its rows
+func checkRLSWithoutPolicy(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if !table.RLSEnabled || len(table.Policies) > 0 {
+			continue
+		}
+		diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeRLSWithoutPolicy,
+			fmt.Sprintf("table %q has row level security enabled but no policies, so only its owner can access its rows", qualifiedTableName(table))))
+	}
+	return diagnostics
+}
+
+// implicitSchemas are the schemas every database has, which schema files
+// don't create
+var implicitSchemas = map[string]bool{
+	"public":             true,
+	"pg_catalog":         true,
+	"information_schema": true,
+}
+
+// checkUndeclaredSchema warns about tables, views, enums, domains and sequences
+// in a schema that no CREATE SCHEMA in the schema files creates, which is
+// often a typo such as aut.users
+func checkUndeclaredSchema(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	declared := make(map[string]bool, len(schema.Schemas))
+	for _, name := range schema.Schemas {
+		declared[name] = true
+	}
+
+	var diagnostics []Diagnostic
+	check := func(kind string, objectSchema string, name string, loc *database.SourceLocation) {
+		if objectSchema == "" || implicitSchemas[objectSchema] || declared[objectSchema] {
+			return
+		}
+		diagnostics = append(diagnostics, diagnosticAt(loc, CodeUndeclaredSchema,
+			fmt.Sprintf("%s %q is in schema %q, which is never created with CREATE SCHEMA", kind, objectSchema+"."+name, objectSchema)))
+	}
+
+	for _, table := range schema.Tables {
+		check("table", table.Schema, table.Name, table.SourceLocation)
+	}
+	for _, view := range schema.Views {
+		check("view", view.Schema, view.Name, view.SourceLocation)
+	}
+	for _, enum := range schema.Enums {
+		check("type", enum.Schema, enum.Name, enum.SourceLocation)
+	}
+	for _, domain := range schema.Domains {
+		check("domain", domain.Schema, domain.Name, domain.SourceLocation)
+	}
+	for _, sequence := range schema.Sequences {
+		check("sequence", sequence.Schema, sequence.Name, sequence.SourceLocation)
+	}
+	return diagnostics
+}
+
+// checkUnquotedUppercase warns about table and column names written unquoted
+// with uppercase letters. PostgreSQL folds them to lowercase, so CREATE TABLE
+// Orders creates orders, which surprises people used to other databases.
+func checkUnquotedUppercase(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if hasFoldedUppercase(table.RawName) {
+			diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeUnquotedUppercase,
+				fmt.Sprintf("table %s is unquoted, so PostgreSQL names it %q; write the name in lowercase, or quote it to keep its case", table.RawName, qualifiedTableName(table))))
+		}
+		for _, col := range table.Columns {
+			if hasFoldedUppercase(col.RawName) {
+				diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeUnquotedUppercase,
+					fmt.Sprintf("column %s in table %q is unquoted, so PostgreSQL names it %q; write the name in lowercase, or quote it to keep its case", col.RawName, qualifiedTableName(table), col.Name)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// hasFoldedUppercase reports whether an identifier as written is unquoted and
+// has uppercase letters. PostgreSQL only folds ASCII letters.
+func hasFoldedUppercase(raw string) bool {
+	if raw == "" || strings.HasSuffix(raw, `"`) {
+		return false
+	}
+	return strings.ContainsFunc(raw, func(r rune) bool { return 'A' <= r && r <= 'Z' })
+}
+
+// checkReservedKeyword warns about unquoted table and column names that are
+// keywords PostgreSQL reserves in some contexts, such as time or position.
+// They are accepted as names but not where a type or function name can
+// appear, and other databases and tools may reject them.
+func checkReservedKeyword(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if isReservedKeyword(table.RawName) {
+			diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeReservedKeyword,
+				fmt.Sprintf("table %q is named with the keyword %s, which PostgreSQL reserves in some contexts; quote it or choose another name", qualifiedTableName(table), strings.ToUpper(table.RawName))))
+		}
+		for _, col := range table.Columns {
+			if isReservedKeyword(col.RawName) {
+				diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeReservedKeyword,
+					fmt.Sprintf("column %q in table %q is named with the keyword %s, which PostgreSQL reserves in some contexts; quote it or choose another name", col.Name, qualifiedTableName(table), strings.ToUpper(col.RawName))))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// isReservedKeyword reports whether an identifier as written is an unquoted
+// keyword that isn't fully unreserved in PostgreSQL's grammar
+func isReservedKeyword(raw string) bool {
+	if raw == "" || strings.HasSuffix(raw, `"`) {
+		return false
+	}
+	scan, err := pg_query.Scan(raw)
+	if err != nil || len(scan.Tokens) != 1 {
+		return false
+	}
+	return scan.Tokens[0].KeywordKind > pg_query.KeywordKind_UNRESERVED_KEYWORD
+}
+
+// checkAutoIncrementDefault reports serial and identity columns that are also
+// given a DEFAULT. Their values already come from a sequence, and PostgreSQL
+// rejects the table.
+func checkAutoIncrementDefault(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, col := range table.Columns {
+			if col.Default == nil {
+				continue
+			}
+			kind := ""
+			switch {
+			case col.Identity != nil:
+				kind = "an identity column"
+			case database.IsSerialType(col.Type):
+				kind = "a " + col.Type + " column"
+			default:
+				continue
+			}
+			diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeAutoIncrementDefault,
+				fmt.Sprintf("column %q in table %q is %s, whose values come from a sequence, but also has DEFAULT %s", col.Name, qualifiedTableName(table), kind, *col.Default)))
+		}
+	}
+	return diagnostics
+}
+
+// nextvalDefault matches a DEFAULT that takes its value from a sequence, as
+// the parser renders it with any ::regclass cast dropped, capturing the
+// sequence name
+var nextvalDefault = regexp.MustCompile(`^nextval\('((?:[^']|'')+)'\)$`)
+
+// checkUndeclaredSequence reports columns whose DEFAULT calls nextval on a
+// sequence that no CREATE SEQUENCE creates, and that isn't created for a
+// serial or identity column either. An unqualified sequence name is accepted
+// whichever schema the sequence is in, since that schema is usually on the
+// search path.
+func checkUndeclaredSequence(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	declared := make(map[string]bool)
+	names := make(map[string]bool)
+	declare := func(sequenceSchema string, name string) {
+		declared[cmp.Or(sequenceSchema, "public")+"."+name] = true
+		names[name] = true
+	}
+	for _, sequence := range schema.Sequences {
+		declare(sequence.Schema, sequence.Name)
+	}
+	for _, table := range schema.Tables {
+		for _, col := range table.Columns {
+			switch {
+			case col.Identity != nil && col.Identity.Sequence != "":
+				parts := strings.Split(col.Identity.Sequence, ".")
+				if len(parts) == 1 {
+					parts = []string{table.Schema, parts[0]}
+				}
+				declare(parts[len(parts)-2], parts[len(parts)-1])
+			case database.IsSerialType(col.Type):
+				declare(table.Schema, identitySequenceName(table.Name, col.Name))
+			}
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, col := range table.Columns {
+			if col.Default == nil {
+				continue
+			}
+			match := nextvalDefault.FindStringSubmatch(*col.Default)
+			if match == nil {
+				continue
+			}
+			parts := regclassNameParts(strings.ReplaceAll(match[1], "''", "'"))
+			name := parts[len(parts)-1]
+			if len(parts) == 1 && names[name] || len(parts) > 1 && declared[parts[len(parts)-2]+"."+name] {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeUndeclaredSequence,
+				fmt.Sprintf("column %q in table %q takes its default from sequence %q, which the schema doesn't create", col.Name, qualifiedTableName(table), strings.Join(parts, "."))))
+		}
+	}
+	return diagnostics
+}
+
+// regclassNameParts splits a possibly qualified relation name written as
+// text, such as 'app."Orders_seq"', into its parts. Unquoted parts are folded
+// to lowercase, as PostgreSQL does when it resolves the name.
+func regclassNameParts(name string) []string {
+	var parts []string
+	var part strings.Builder
+	inQuotes := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '"' && inQuotes && i+1 < len(name) && name[i+1] == '"':
+			part.WriteByte('"')
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '.' && !inQuotes:
+			parts = append(parts, part.String())
+			part.Reset()
+		case inQuotes:
+			part.WriteByte(c)
+		default:
+			part.WriteString(strings.ToLower(string(c)))
+		}
+	}
+	return append(parts, part.String())
+}
+
+// checkUnknownType reports columns whose type isn't qualified with a schema
+// and, stripped of modifiers and array brackets, is neither a built-in type
+// nor an enum or domain the schema defines. That is usually a misspelling,
+// such as integar. Schema-qualified types are left to checkUndefinedType,
+// since extensions install types in their own schemas.
+func checkUnknownType(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, col := range table.Columns {
+			base := database.BaseType(col.Type)
+			if base == "" || strings.Contains(base, ".") || schema.IsKnownType(col.Type) {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeUnknownType,
+				fmt.Sprintf("column %q in table %q has unknown type %q", col.Name, qualifiedTableName(table), base)))
+		}
+	}
+	return diagnostics
+}
+
+// DefaultFlagColumns matches the column names the LP260 rule treats as flags
+// unless CheckOptions.FlagColumns is set
+var DefaultFlagColumns = regexp.MustCompile(`^(is|has)_`)
+
+// checkNullableFlag reports nullable boolean columns, and nullable columns
+// whose names match the flag pattern. A flag that may also be NULL has three
+// states, which queries testing it easily get wrong. Generated columns are
+// skipped, since they can't have a default.
+func checkNullableFlag(schema *database.Schema, opts CheckOptions) []Diagnostic {
+	flags := cmp.Or(opts.FlagColumns, DefaultFlagColumns)
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for j := range table.Columns {
+			col := &table.Columns[j]
+			if !col.Nullable || col.Generated != nil {
+				continue
+			}
+			typ := col.Type
+			if effective, err := schema.EffectiveColumnType(qualifiedTableName(table), col.Name); err == nil {
+				if effective.NotNull {
+					continue
+				}
+				typ = effective.Type
+			}
+			if typ != "boolean" && !flags.MatchString(col.Name) {
+				continue
+			}
+			suggestion := "NOT NULL DEFAULT"
+			if col.Default != nil {
+				suggestion = "NOT NULL"
+			}
+			diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeNullableFlag,
+				fmt.Sprintf("column %q in table %q looks like a flag but is nullable; consider making it %s", col.Name, qualifiedTableName(table), suggestion)))
+		}
+	}
+	return diagnostics
+}
+
+// checkIndexOnMissingColumn warns about indexes that reference a column the
+// table no longer has, e.g. because it was dropped after the index was created
+func checkIndexOnMissingColumn(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, index := range table.Indexes {
+			for _, column := range index.Columns {
+				if findColumn(table, column) != nil {
+					continue
+				}
+				diagnostics = append(diagnostics, diagnosticAt(index.SourceLocation, CodeIndexOnMissingColumn,
+					fmt.Sprintf("index %q on table %q references missing column %q", index.Name, qualifiedTableName(table), column)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// checkIndexClauseMissingColumn reports covering indexes that include, and
+// partial indexes whose predicate references, a column the table doesn't
+// have
+func checkIndexClauseMissingColumn(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, index := range table.Indexes {
+			for _, column := range index.Include {
+				if findColumn(table, column) == nil {
+					diagnostics = append(diagnostics, diagnosticAt(index.SourceLocation, CodeIndexClauseMissingColumn,
+						fmt.Sprintf("index %q on table %q includes missing column %q", index.Name, qualifiedTableName(table), column)))
+				}
+			}
+			for _, ref := range exprColumnRefs(index.WhereExpr) {
+				// A reference may be qualified with the table's name
+				column := ref[strings.LastIndex(ref, ".")+1:]
+				if findColumn(table, column) == nil {
+					diagnostics = append(diagnostics, diagnosticAt(index.SourceLocation, CodeIndexClauseMissingColumn,
+						fmt.Sprintf("index %q on table %q has a WHERE predicate referencing missing column %q", index.Name, qualifiedTableName(table), column)))
+				}
+			}
+		}
+	}
+	return diagnostics
+}
+
+// foreignKeyTarget returns the table a foreign key references, or nil if it
+// isn't in the schema, along with the referenced columns. A key declared
+// without columns references the primary key.
+func foreignKeyTarget(schema *database.Schema, fk *database.ForeignKey) (*database.Table, []string) {
+	i := findTableIndex(schema, fk.ReferencedSchema, fk.ReferencedTable)
+	if i == -1 {
+		return nil, nil
+	}
+	target := &schema.Tables[i]
+	if len(fk.ReferencedColumns) > 0 {
+		return target, fk.ReferencedColumns
+	}
+	return target, target.PrimaryKey
+}
+
+// referencedName formats a foreign key target as schema.table(columns)
+func referencedName(target *database.Table, columns []string) string {
+	return fmt.Sprintf("%s(%s)", qualifiedTableName(target), strings.Join(columns, ", "))
+}
+
+// checkForeignKeyMissingColumn reports foreign keys that reference columns
+// their target table doesn't have. Keys referencing tables outside the loaded
+// schema are skipped, since the table may exist in the database.
+func checkForeignKeyMissingColumn(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for j := range table.ForeignKeys {
+			fk := &table.ForeignKeys[j]
+			target, columns := foreignKeyTarget(schema, fk)
+			if target == nil {
+				continue
+			}
+
+			if len(columns) == 0 {
+				diagnostics = append(diagnostics, diagnosticAt(fk.SourceLocation, CodeForeignKeyMissingColumn,
+					fmt.Sprintf("foreign key %q on table %q references %s, which has no primary key", fk.Name, qualifiedTableName(table), qualifiedTableName(target))))
+				continue
+			}
+			for _, column := range columns {
+				if findColumn(target, column) != nil {
+					continue
+				}
+				diagnostics = append(diagnostics, diagnosticAt(fk.SourceLocation, CodeForeignKeyMissingColumn,
+					fmt.Sprintf("foreign key %q on table %q references missing column %s", fk.Name, qualifiedTableName(table), referencedName(target, []string{column}))))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// checkForeignKeyNotUnique warns about foreign keys whose referenced columns
+// aren't exactly the primary key, a unique constraint or a unique index of
+// the target table. PostgreSQL rejects such keys.
+func checkForeignKeyNotUnique(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for j := range table.ForeignKeys {
+			fk := &table.ForeignKeys[j]
+			target, columns := foreignKeyTarget(schema, fk)
+			if target == nil || len(columns) == 0 || isUniqueKey(target, columns) {
+				continue
+			}
+			// Missing columns are already reported by LP200
+			missing := false
+			for _, column := range columns {
+				missing = missing || findColumn(target, column) == nil
+			}
+			if missing {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnosticAt(fk.SourceLocation, CodeForeignKeyNotUnique,
+				fmt.Sprintf("foreign key %q on table %q references %s, which is not a primary key or unique constraint", fk.Name, qualifiedTableName(table), referencedName(target, columns))))
+		}
+	}
+	return diagnostics
+}
+
+// checkUnindexedForeignKey warns about foreign keys with no index on their
+// columns. Without one, deleting or updating a referenced row scans the
+// referencing table, which is slow and holds locks for longer.
+func checkUnindexedForeignKey(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, fk := range table.ForeignKeys {
+			if hasLeadingIndex(table, fk.Columns) {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnosticAt(fk.SourceLocation, CodeUnindexedForeignKey,
+				fmt.Sprintf("foreign key %q on table %q has no index on (%s); consider CREATE INDEX ON %s (%s)",
+					fk.Name, qualifiedTableName(table), strings.Join(fk.Columns, ", "), qualifiedTableName(table), strings.Join(fk.Columns, ", "))))
+		}
+	}
+	return diagnostics
+}
+
+// hasLeadingIndex reports whether columns, in order, are the leading columns of
+// an index on table. Primary keys and unique constraints count, since
+// PostgreSQL backs them with an index.
+func hasLeadingIndex(table *database.Table, columns []string) bool {
+	leads := func(key []string) bool {
+		return len(key) >= len(columns) && slices.Equal(key[:len(columns)], columns)
+	}
+
+	if leads(table.PrimaryKey) {
+		return true
+	}
+	for _, unique := range table.UniqueConstraints {
+		if leads(unique.Columns) {
+			return true
+		}
+	}
+	for _, index := range table.Indexes {
+		// Expression indexes list only their plain columns, so their column
+		// positions can't be trusted
+		if len(index.Expressions) == 0 && leads(index.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueKey reports whether columns, in any order, are the primary key, a
+// unique constraint or a plain unique index of table
+func isUniqueKey(table *database.Table, columns []string) bool {
+	sameColumns := func(key []string) bool {
+		if len(key) != len(columns) {
+			return false
+		}
+		for _, column := range columns {
+			if !slices.Contains(key, column) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if sameColumns(table.PrimaryKey) {
+		return true
+	}
+	for _, unique := range table.UniqueConstraints {
+		if sameColumns(unique.Columns) {
+			return true
+		}
+	}
+	for _, index := range table.Indexes {
+		if index.Unique && len(index.Expressions) == 0 && sameColumns(index.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// NamingPolicy holds the patterns that table and column names must match. A
+// nil pattern allows any name.
+type NamingPolicy struct {
+	Tables  *regexp.Regexp
+	Columns *regexp.Regexp
+}
+
+// NewNamingPolicy compiles table and column name patterns into a NamingPolicy.
+// An empty pattern allows any name.
+func NewNamingPolicy(tables string, columns string) (NamingPolicy, error) {
+	var policy NamingPolicy
+	var err error
+	if tables != "" {
+		if policy.Tables, err = regexp.Compile(tables); err != nil {
+			return NamingPolicy{}, fmt.Errorf("invalid table naming pattern %q: %w", tables, err)
+		}
+	}
+	if columns != "" {
+		if policy.Columns, err = regexp.Compile(columns); err != nil {
+			return NamingPolicy{}, fmt.Errorf("invalid column naming pattern %q: %w", columns, err)
+		}
+	}
+	return policy, nil
+}
+
+// checkNamingPolicy warns about table and column names that don't match the
+// configured naming policy
+func checkNamingPolicy(schema *database.Schema, opts CheckOptions) []Diagnostic {
+	policy := opts.NamingPolicy
+
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if policy.Tables != nil && !policy.Tables.MatchString(table.Name) {
+			diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeNamingPolicy,
+				fmt.Sprintf("table %q does not match naming pattern %q", qualifiedTableName(table), policy.Tables)))
+		}
+
+		if policy.Columns == nil {
+			continue
+		}
+		for _, col := range table.Columns {
+			if !policy.Columns.MatchString(col.Name) {
+				diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeNamingPolicy,
+					fmt.Sprintf("column %q in table %q does not match naming pattern %q", col.Name, qualifiedTableName(table), policy.Columns)))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// checkTrivialCheck warns about CHECK constraints whose expression doesn't
+// depend on any column. An always-true check is useless; an always-false one
+// rejects every row.
+func checkTrivialCheck(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, check := range table.CheckConstraints {
+			value, ok := evalConstant(check.Expr)
+			result, isBool := value.(bool)
+			if !ok || !isBool {
+				continue
+			}
+
+			message := fmt.Sprintf("check constraint %q on table %q is always true and has no effect", check.Name, qualifiedTableName(table))
+			if !result {
+				message = fmt.Sprintf("check constraint %q on table %q is always false, so no row can be inserted", check.Name, qualifiedTableName(table))
+			}
+			diagnostics = append(diagnostics, diagnosticAt(check.SourceLocation, CodeTrivialCheck, message))
+		}
+	}
+	return diagnostics
+}
+
+// checkEmptyTable warns about tables declared without columns. Inheritance
+// children and partitions get their columns from the parent, so they are
+// skipped.
+func checkEmptyTable(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if len(table.Columns) > 0 || len(table.Inherits) > 0 || table.PartitionOf != nil {
+			continue
+		}
+		diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeEmptyTable,
+			fmt.Sprintf("table %q has no columns", qualifiedTableName(table))))
+	}
+	return diagnostics
+}
+
+// checkWithOids warns about tables created WITH (OIDS). PostgreSQL 12 removed
+// oids from user tables, so such a table can't be created.
+func checkWithOids(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		if !table.WithOids {
+			continue
+		}
+		diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeWithOidsDeprecated,
+			fmt.Sprintf("table %q is created WITH OIDS, which is not supported since PostgreSQL 12", qualifiedTableName(table))))
+	}
+	return diagnostics
+}
+
+// checkForeignKeySetNullNotNull reports foreign keys whose ON DELETE action
+// can't be carried out: SET NULL on a NOT NULL column makes deleting the
+// referenced row fail, and SET DEFAULT on a column without a default sets it
+// to null.
+func checkForeignKeySetNullNotNull(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, fk := range table.ForeignKeys {
+			if fk.OnDelete != database.FKActionSetNull && fk.OnDelete != database.FKActionSetDefault {
+				continue
+			}
+			columns := fk.OnDeleteColumns
+			if len(columns) == 0 {
+				columns = fk.Columns
+			}
+			for _, name := range columns {
+				col := findColumn(table, name)
+				if col == nil {
+					continue
+				}
+				var message string
+				switch {
+				case fk.OnDelete == database.FKActionSetNull && !col.Nullable:
+					message = fmt.Sprintf("foreign key %q on table %q is ON DELETE SET NULL, but column %q is NOT NULL",
+						fk.Name, qualifiedTableName(table), col.Name)
+				case fk.OnDelete == database.FKActionSetDefault && col.Default == nil:
+					message = fmt.Sprintf("foreign key %q on table %q is ON DELETE SET DEFAULT, but column %q has no default",
+						fk.Name, qualifiedTableName(table), col.Name)
+				default:
+					continue
+				}
+				diagnostics = append(diagnostics, diagnosticAt(fk.SourceLocation, CodeForeignKeySetNullNotNull, message))
+			}
+		}
+	}
+	return diagnostics
+}
+
+// checkUndefinedType reports columns declared with a schema-qualified type,
+// such as auth.role, that the schema doesn't define as an enum or domain.
+// Unqualified types are left to checkUnknownType.
+func checkUndefinedType(schema *database.Schema, _ CheckOptions) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for _, col := range table.Columns {
+			if !strings.Contains(database.BaseType(col.Type), ".") || schema.IsKnownType(col.Type) {
+				continue
+			}
+			diagnostics = append(diagnostics, diagnosticAt(col.SourceLocation, CodeUndefinedType,
+				fmt.Sprintf("column %q in table %q uses type %q, which is not defined in the schema", col.Name, qualifiedTableName(table), col.Type)))
+		}
+	}
+	return diagnostics
+}
+
+// checkInconsistentColumnType reports columns whose type differs from that of
+// a like-named column declared earlier, e.g. a tenant_id that is an integer in
+// one table and a bigint in another. Types are compared after resolving
+// domains and serial types. Each column involved is reported, naming the other
+// table, so both locations are flagged.
+func checkInconsistentColumnType(schema *database.Schema, opts CheckOptions) []Diagnostic {
+	type typedColumn struct {
+		table *database.Table
+		col   *database.Column
+		typ   string
+	}
+
+	var names []string
+	byName := map[string][]typedColumn{}
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		for j := range table.Columns {
+			col := &table.Columns[j]
+			if opts.ConsistentColumns != nil && !opts.ConsistentColumns.MatchString(col.Name) {
+				continue
+			}
+			typ := col.Type
+			if effective, err := schema.EffectiveColumnType(qualifiedTableName(table), col.Name); err == nil {
+				typ = effective.Type
+			}
+			if _, ok := byName[col.Name]; !ok {
+				names = append(names, col.Name)
+			}
+			byName[col.Name] = append(byName[col.Name], typedColumn{table, col, typ})
+		}
+	}
+
+	var diagnostics []Diagnostic
+	for _, name := range names {
+		columns := byName[name]
+		for i, c := range columns {
+			// Compare against the first column of a different type, so that
+			// the first column of a group is reported too
+			for j, other := range columns {
+				if j == i || other.typ == c.typ {
+					continue
+				}
+				diagnostics = append(diagnostics, diagnosticAt(c.col.SourceLocation, CodeInconsistentColumnType,
+					fmt.Sprintf("column %q in table %q has type %q, but in table %q it has type %q",
+						name, qualifiedTableName(c.table), c.typ, qualifiedTableName(other.table), other.typ)))
+				break
+			}
+		}
+	}
+	return diagnostics
+}
@@ -1,32 +1,263 @@
 package schema
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/lockplane/lockplane/internal/database"
 )
 
+// LoadSchemaOptions configures how schema files are discovered and parsed
+type LoadSchemaOptions struct {
+	// Dialect is the SQL dialect of the schema files. Defaults to PostgreSQL.
+	Dialect database.Dialect
+
+	// Recursive searches subdirectories of a schema directory for .lp.sql
+	// files, instead of only the directory itself.
+	Recursive bool
+
+	// Sort puts the loaded schema in canonical order with Schema.Sort, so it
+	// doesn't depend on how the tables are split across files
+	Sort bool
+
+	// StripMetaCommands ignores psql meta-commands such as \connect or \i,
+	// lines starting with a backslash, so files exported from psql load.
+	// Use CheckSchema with CheckOptions.StripMetaCommands to be warned about
+	// each one.
+	StripMetaCommands bool
+
+	// CacheDir, if set, is a directory to cache the parse tree of each schema
+	// file in, so files that haven't changed since they were last loaded
+	// aren't parsed again. See DefaultCacheDir.
+	CacheDir string
+
+	// Include, if set, loads only the files of a schema directory whose base
+	// name matches one of its globs, in the syntax of path.Match. Files whose
+	// base name matches one of the Exclude globs are skipped. A schema path
+	// that is a single .lp.sql file is loaded either way.
+	Include []string
+	Exclude []string
+
+	// Strict runs the lint rules, with their default severities, on the
+	// loaded schema and checks it for duplicate index names. Any problem
+	// found, even a warning, fails the load with a *LintError.
+	Strict bool
+}
+
 // load a schema from SQL DDL (.lp.sql) files. Accepts a file (must be .lp.sql)
 // or a directory to perform a shallow search for .lp.sql files.
+//
+// Files are applied in name order. A statement on a table whose CREATE TABLE
+// comes later in the same file is deferred until just after it, but not across
+// files: an ALTER TABLE on a table created in a later file fails.
 func LoadSchema(path string) (*database.Schema, error) {
-	if info, err := os.Stat(path); err == nil && info.IsDir() {
-		return loadSchemaFromDir(path)
+	return LoadSchemaWithOptions(path, LoadSchemaOptions{})
+}
+
+// LoadSchemaWithDialect is like LoadSchema, but parses the .lp.sql files using
+// the given SQL dialect.
+func LoadSchemaWithDialect(path string, dialect database.Dialect) (*database.Schema, error) {
+	return LoadSchemaWithOptions(path, LoadSchemaOptions{Dialect: dialect})
+}
+
+// LoadSchemaStrict is like LoadSchema, but fails with a *LintError when the
+// lint rules report any problem with the schema, warnings included
+func LoadSchemaStrict(path string) (*database.Schema, error) {
+	return LoadSchemaWithOptions(path, LoadSchemaOptions{Strict: true})
+}
+
+// LoadSchemaWithOptions is like LoadSchema, with control over how schema files
+// are discovered and parsed.
+func LoadSchemaWithOptions(path string, opts LoadSchemaOptions) (*database.Schema, error) {
+	return loadSchema(osFS{}, path, opts)
+}
+
+// LoadSchemaFS is like LoadSchema, but reads the schema files from fsys, such
+// as an embed.FS or an fstest.MapFS. path is a slash-separated path within
+// fsys, either a .lp.sql file or a directory, with "." for the root.
+func LoadSchemaFS(fsys fs.FS, path string) (*database.Schema, error) {
+	return loadSchema(fsys, path, LoadSchemaOptions{})
+}
+
+// loadSchema finds, parses and validates the schema files at path in fsys
+func loadSchema(fsys fs.FS, path string, opts LoadSchemaOptions) (*database.Schema, error) {
+	files, err := findSchemaFiles(fsys, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := parseSchemaFiles(fsys, files, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate that there are no duplicate table definitions
+	if err := validateNoDuplicateTables(schema); err != nil {
+		return nil, err
+	}
+
+	if opts.Strict {
+		output := newCheckOutput(CheckOptions{})
+		for _, d := range ValidateDuplicateIndexesAsDiagnostics(schema) {
+			output.AddError(d)
+		}
+		runLintRules(schema, CheckOptions{}, output)
+		if len(output.Diagnostics) > 0 {
+			return nil, &LintError{Diagnostics: output.Diagnostics}
+		}
+	}
+
+	// Sort only once duplicates have been reported in file order
+	if opts.Sort {
+		schema.Sort()
+	}
+
+	return schema, nil
+}
+
+// LintError is returned by a strict load for a schema with problems the lint
+// rules report. Its message lists every diagnostic with its location.
+type LintError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *LintError) Error() string {
+	lines := []string{fmt.Sprintf("schema has %d problem(s):", len(e.Diagnostics))}
+	for _, d := range e.Diagnostics {
+		line := fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+		if d.Code != "" {
+			line += fmt.Sprintf(" [%s]", d.Code)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (opts LoadSchemaOptions) dialect() database.Dialect {
+	if opts.Dialect == "" {
+		return database.DialectPostgres
+	}
+	return opts.Dialect
+}
+
+// Errors returned when a schema path doesn't lead to any schema files. They
+// are wrapped with the path, so test for them with errors.Is.
+var (
+	// ErrPathNotFound is returned when the schema path doesn't exist
+	ErrPathNotFound = errors.New("schema path not found")
+	// ErrWrongExtension is returned when the schema path is a file whose name
+	// doesn't end in .lp.sql
+	ErrWrongExtension = errors.New("schema file name must end in .lp.sql")
+	// ErrNoSchemaFiles is returned when the schema path is a directory without
+	// any .lp.sql files
+	ErrNoSchemaFiles = errors.New("no .lp.sql files found")
+)
+
+// osFS reads files from the operating system by the names it is given. Unlike
+// os.DirFS it accepts absolute paths and paths outside the working directory,
+// so the files LoadSchema reads are reported by the paths it was given.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+
+// joinPath joins path elements with the separator of fsys: the operating
+// system's for osFS, and a slash for any other fs.FS
+func joinPath(fsys fs.FS, elem ...string) string {
+	if _, ok := fsys.(osFS); ok {
+		return filepath.Join(elem...)
+	}
+	return path.Join(elem...)
+}
+
+// findSchemaFiles returns the .lp.sql files in fsys to load for schemaPath, in
+// the order they should be parsed. schemaPath may be a single .lp.sql file or
+// a directory.
+func findSchemaFiles(fsys fs.FS, schemaPath string, opts LoadSchemaOptions) ([]string, error) {
+	info, err := fs.Stat(fsys, schemaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, schemaPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema path %s: %w", schemaPath, err)
+	}
+
+	if info.IsDir() {
+		var files []string
+		if opts.Recursive {
+			files, err = findSchemaFilesRecursive(fsys, schemaPath)
+		} else {
+			files, err = findSchemaFilesInDir(fsys, schemaPath)
+		}
+		if err != nil || (len(opts.Include) == 0 && len(opts.Exclude) == 0) {
+			return files, err
+		}
+		return filterSchemaFiles(files, schemaPath, opts)
 	}
 
 	// Check for .lp.sql extension
-	if _, err := os.Stat(path); err == nil && strings.HasSuffix(strings.ToLower(path), ".lp.sql") {
-		return loadSQLSchema(path)
+	if !strings.HasSuffix(strings.ToLower(schemaPath), ".lp.sql") {
+		return nil, fmt.Errorf("%w: %s", ErrWrongExtension, schemaPath)
+	}
+	return []string{schemaPath}, nil
+}
+
+// filterSchemaFiles keeps the files found in dir whose base names match
+// opts.Include, if set, and none of opts.Exclude
+func filterSchemaFiles(files []string, dir string, opts LoadSchemaOptions) ([]string, error) {
+	matchesAny := func(patterns []string, name string) (bool, error) {
+		for _, pattern := range patterns {
+			matched, err := path.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	var kept []string
+	for _, file := range files {
+		name := filepath.Base(file)
+		if len(opts.Include) > 0 {
+			included, err := matchesAny(opts.Include, name)
+			if err != nil {
+				return nil, err
+			}
+			if !included {
+				continue
+			}
+		}
+		excluded, err := matchesAny(opts.Exclude, name)
+		if err != nil {
+			return nil, err
+		}
+		if !excluded {
+			kept = append(kept, file)
+		}
 	}
 
-	return nil, fmt.Errorf("did not find .lp.sql file(s)")
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("%w matching the include and exclude patterns in directory %s", ErrNoSchemaFiles, dir)
+	}
+	return kept, nil
 }
 
-func loadSchemaFromDir(dir string) (*database.Schema, error) {
-	entries, err := os.ReadDir(dir)
+// findSchemaFilesInDir performs a shallow search of dir for .lp.sql files,
+// returning them sorted by name. Subdirectories and symlinks are ignored.
+func findSchemaFilesInDir(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read schema directory %s: %w", dir, err)
 	}
@@ -37,7 +268,7 @@ func loadSchemaFromDir(dir string) (*database.Schema, error) {
 			continue
 		}
 
-		if entry.Type()&os.ModeSymlink != 0 {
+		if entry.Type()&fs.ModeSymlink != 0 {
 			continue
 		}
 
@@ -46,84 +277,165 @@ func loadSchemaFromDir(dir string) (*database.Schema, error) {
 
 		// Only include .lp.sql files
 		if strings.HasSuffix(lowerName, ".lp.sql") {
-			sqlFiles = append(sqlFiles, filepath.Join(dir, name))
+			sqlFiles = append(sqlFiles, joinPath(fsys, dir, name))
 		}
 	}
 
 	if len(sqlFiles) == 0 {
-		return nil, fmt.Errorf("no .lp.sql files found in directory %s", dir)
+		return nil, fmt.Errorf("%w in directory %s", ErrNoSchemaFiles, dir)
 	}
 
 	sort.Strings(sqlFiles)
+	return sqlFiles, nil
+}
 
-	var builder strings.Builder
-	for _, file := range sqlFiles {
-		data, readErr := os.ReadFile(file)
-		if readErr != nil {
-			return nil, fmt.Errorf("failed to read SQL file %s: %w", file, readErr)
+// findSchemaFilesRecursive searches dir and all of its subdirectories for
+// .lp.sql files, returning them sorted by path relative to dir. Symlinks are
+// ignored.
+func findSchemaFilesRecursive(fsys fs.FS, dir string) ([]string, error) {
+	var sqlFiles []string
+	visit := func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
 
-		builder.WriteString(fmt.Sprintf("-- File: %s\n", file))
-		builder.Write(data)
-		if len(data) == 0 || data[len(data)-1] != '\n' {
-			builder.WriteByte('\n')
+		if entry.IsDir() || entry.Type()&fs.ModeSymlink != 0 {
+			return nil
 		}
-		builder.WriteByte('\n')
-	}
 
-	return loadSQLSchemaFromBytes([]byte(builder.String()))
-}
+		// Only include .lp.sql files
+		if strings.HasSuffix(strings.ToLower(entry.Name()), ".lp.sql") {
+			sqlFiles = append(sqlFiles, path)
+		}
+		return nil
+	}
 
-// LoadSQLSchemaWithOptions loads a SQL schema with optional parsing options.
-func loadSQLSchema(path string) (*database.Schema, error) {
-	// Read the SQL file
-	data, err := os.ReadFile(path)
+	var err error
+	if _, ok := fsys.(osFS); ok {
+		err = filepath.WalkDir(dir, visit)
+	} else {
+		err = fs.WalkDir(fsys, dir, visit)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read SQL file: %w", err)
+		return nil, fmt.Errorf("failed to read schema directory %s: %w", dir, err)
 	}
 
-	return loadSQLSchemaFromBytes(data)
+	if len(sqlFiles) == 0 {
+		return nil, fmt.Errorf("%w in directory %s", ErrNoSchemaFiles, dir)
+	}
+
+	// Every path shares the dir prefix, so this sorts by relative path
+	sort.Strings(sqlFiles)
+	return sqlFiles, nil
 }
 
-// LoadSQLSchemaFromBytes loads a SQL schema from a byte slice
-func loadSQLSchemaFromBytes(data []byte) (*database.Schema, error) {
-	schema, err := ParseSQLSchemaWithDialect(string(data), database.DialectPostgres)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse SQL DDL: %w", err)
+// parseSchemaFiles parses files in order into a single schema, so statements
+// in later files (e.g. ALTER TABLE) can refer to tables created in earlier
+// ones. It stops at the first file that fails. The result is not validated.
+func parseSchemaFiles(fsys fs.FS, files []string, opts LoadSchemaOptions, coverage *Coverage) (*database.Schema, error) {
+	schema, errs := parseSchemaFileErrors(fsys, files, opts, coverage, true)
+	if len(errs) > 0 {
+		return nil, errs[0]
 	}
+	return schema, nil
+}
 
-	// Validate that there are no duplicate table definitions
-	if err := validateNoDuplicateTables(schema); err != nil {
-		return nil, err
+// parseSchemaFileErrors is parseSchemaFiles returning the error of every file
+// that fails, in file order. With stopOnError it stops at the first one.
+// Otherwise the files after a failure are still parsed, against the schema as
+// far as it was built, and the schema is returned along with the errors. A
+// file that fails is left as applied up to the statement that failed.
+func parseSchemaFileErrors(fsys fs.FS, files []string, opts LoadSchemaOptions, coverage *Coverage, stopOnError bool) (*database.Schema, []error) {
+	schema := newSchema(opts.dialect())
+	cache := &parseCache{}
+	// Cache entries are keyed by path on disk
+	if _, ok := fsys.(osFS); ok {
+		cache.dir = opts.CacheDir
 	}
 
-	return schema, nil
+	// A file that can't be read is reported in its place in file order
+	sources := make([][]byte, len(files))
+	readErrs := make([]error, len(files))
+	var readable []string
+	var readableSources [][]byte
+	for i, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			readErrs[i] = fmt.Errorf("failed to read SQL file %s: %w", file, err)
+			if stopOnError {
+				break
+			}
+			continue
+		}
+		if opts.StripMetaCommands {
+			sql, _ := stripMetaCommands(string(data))
+			data = []byte(sql)
+		}
+		sources[i] = data
+		readable = append(readable, file)
+		readableSources = append(readableSources, data)
+	}
+
+	// The files are parsed concurrently, but their statements are applied in
+	// order, since a file can alter the tables of the files before it
+	cache.prepare(readable, readableSources, opts.dialect())
+	var errs []error
+	for i, file := range files {
+		err := readErrs[i]
+		if err == nil {
+			err = loadSQLSchemaFromBytesWithFilename(schema, sources[i], file, opts.dialect(), coverage, cache)
+		}
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		if stopOnError {
+			break
+		}
+	}
+
+	return schema, errs
+}
+
+// loadSQLSchemaFromBytesWithFilename parses SQL DDL from a byte slice into an
+// in-progress schema, attributing source locations to filename. cache may be
+// nil.
+func loadSQLSchemaFromBytesWithFilename(schema *database.Schema, data []byte, filename string, dialect database.Dialect, coverage *Coverage, cache *parseCache) error {
+	if errs := parseSQLSchemaStatements(schema, string(data), filename, dialect, coverage, cache, true); len(errs) > 0 {
+		return fmt.Errorf("failed to parse SQL DDL: %w", errs[0])
+	}
+
+	return nil
+}
+
+func newSchema(dialect database.Dialect) *database.Schema {
+	return &database.Schema{
+		Tables:  []database.Table{},
+		Dialect: dialect,
+	}
 }
 
 // validateNoDuplicateTables checks that each table is defined only once within its schema.
 // Tables with the same name can exist in different schemas (e.g., public.users and auth.users),
 // but the same table cannot be defined multiple times in the same schema.
-// If no schema is specified, it defaults to "public".
+// If no schema is specified, it defaults to "public". Duplicated tables are
+// listed once each, sorted by name, so the error doesn't depend on file order.
 func validateNoDuplicateTables(schema *database.Schema) error {
 	// Use (schema, name) as the key to identify unique tables
 	seen := make(map[string]bool)
 	var duplicates []string
 
-	for _, table := range schema.Tables {
-		// Default to "public" schema if not specified
-		tableSchema := table.Schema
-		if tableSchema == "" {
-			tableSchema = "public"
-		}
-
+	for i := range schema.Tables {
 		// Create a composite key: schema.table_name
-		key := fmt.Sprintf("%s.%s", tableSchema, table.Name)
+		key := qualifiedTableName(&schema.Tables[i])
 
 		if seen[key] {
 			duplicates = append(duplicates, key)
 		}
 		seen[key] = true
 	}
+	sort.Strings(duplicates)
+	duplicates = slices.Compact(duplicates)
 
 	if len(duplicates) > 0 {
 		if len(duplicates) == 1 {
@@ -134,3 +446,94 @@ func validateNoDuplicateTables(schema *database.Schema) error {
 
 	return nil
 }
+
+// ValidateDuplicateTablesAsDiagnostics reports each redefinition of a table
+// within its schema as an error diagnostic located at the redefinition. The
+// diagnostics are sorted by file, line and column.
+func ValidateDuplicateTablesAsDiagnostics(schema *database.Schema) []Diagnostic {
+	first := make(map[string]*database.Table)
+	var diagnostics []Diagnostic
+
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+		key := qualifiedTableName(table)
+
+		original, seen := first[key]
+		if !seen {
+			first[key] = table
+			continue
+		}
+
+		message := fmt.Sprintf("table %q is defined multiple times", key)
+		if original.SourceLocation != nil {
+			message += fmt.Sprintf(" (first defined at %s)", original.SourceLocation)
+		}
+		diagnostics = append(diagnostics, diagnosticAt(table.SourceLocation, CodeDuplicateTable, message))
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return diagnostics
+}
+
+// ValidateDuplicateIndexesAsDiagnostics reports each index whose name is
+// already used by another index in the same schema, which PostgreSQL rejects,
+// as an error diagnostic located at the later index. An index is in the
+// schema of its table or materialized view.
+func ValidateDuplicateIndexesAsDiagnostics(schema *database.Schema) []Diagnostic {
+	first := make(map[string]*database.Index)
+	var diagnostics []Diagnostic
+
+	check := func(relationSchema string, indexes []database.Index) {
+		if relationSchema == "" {
+			relationSchema = "public"
+		}
+
+		for j := range indexes {
+			index := &indexes[j]
+			if index.Name == "" {
+				continue
+			}
+			key := relationSchema + "." + index.Name
+
+			original, seen := first[key]
+			if !seen {
+				first[key] = index
+				continue
+			}
+
+			message := fmt.Sprintf("index %q is defined multiple times", key)
+			if original.SourceLocation != nil {
+				message += fmt.Sprintf(" (first defined at %s)", original.SourceLocation)
+			}
+			diagnostics = append(diagnostics, diagnosticAt(index.SourceLocation, CodeDuplicateIndexName, message))
+		}
+	}
+
+	for i := range schema.Tables {
+		check(schema.Tables[i].Schema, schema.Tables[i].Indexes)
+	}
+	for i := range schema.Views {
+		check(schema.Views[i].Schema, schema.Views[i].Indexes)
+	}
+
+	return diagnostics
+}
+
+// qualifiedTableName returns schema.name for a table, defaulting to the
+// "public" schema when none is specified.
+func qualifiedTableName(table *database.Table) string {
+	tableSchema := table.Schema
+	if tableSchema == "" {
+		tableSchema = "public"
+	}
+	return fmt.Sprintf("%s.%s", tableSchema, table.Name)
+}
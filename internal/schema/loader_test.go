@@ -1,10 +1,15 @@
 package schema
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/lockplane/lockplane/internal/database"
 )
@@ -169,6 +174,245 @@ func TestLoadSchemaIgnoresSubdirectories(t *testing.T) {
 	}
 }
 
+func TestLoadSchemaWithOptionsRecursive(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"users.lp.sql":             `CREATE TABLE users (id INTEGER);`,
+		"billing/invoices.lp.sql":  `CREATE TABLE invoices (id INTEGER);`,
+		"billing/a/charges.lp.sql": `CREATE TABLE charges (id INTEGER);`,
+		"auth/sessions.lp.sql":     `CREATE TABLE sessions (id INTEGER);`,
+		"auth/README.md":           `# not a schema file`,
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	schema, err := LoadSchemaWithOptions(tempDir, LoadSchemaOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+
+	// Files are loaded in order of their path relative to the schema directory
+	expected := []string{"sessions", "charges", "invoices", "users"}
+	if len(schema.Tables) != len(expected) {
+		t.Fatalf("Expected %d tables, got %d", len(expected), len(schema.Tables))
+	}
+	for i, name := range expected {
+		if schema.Tables[i].Name != name {
+			t.Errorf("Expected table %d to be %q, got %q", i, name, schema.Tables[i].Name)
+		}
+	}
+
+	if schema.Dialect != database.DialectPostgres {
+		t.Errorf("Expected dialect %q, got %q", database.DialectPostgres, schema.Dialect)
+	}
+}
+
+func TestLoadSchemaWithOptionsIncludeExclude(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"auth_users.lp.sql":    `CREATE TABLE users (id INTEGER);`,
+		"auth_sessions.lp.sql": `CREATE TABLE sessions (id INTEGER);`,
+		"auth_legacy.lp.sql":   `CREATE TABLE legacy (id INTEGER);`,
+		"billing.lp.sql":       `CREATE TABLE invoices (id INTEGER);`,
+	})
+
+	tests := []struct {
+		name    string
+		opts    LoadSchemaOptions
+		tables  []string
+		wantErr error
+	}{
+		{"include", LoadSchemaOptions{Include: []string{"auth_*"}}, []string{"legacy", "sessions", "users"}, nil},
+		{"exclude", LoadSchemaOptions{Exclude: []string{"auth_*"}}, []string{"invoices"}, nil},
+		{"include and exclude", LoadSchemaOptions{Include: []string{"auth_*", "billing.lp.sql"}, Exclude: []string{"*legacy*"}}, []string{"sessions", "users", "invoices"}, nil},
+		{"nothing left", LoadSchemaOptions{Include: []string{"orders_*"}}, nil, ErrNoSchemaFiles},
+		{"bad pattern", LoadSchemaOptions{Exclude: []string{"auth_["}}, nil, path.ErrBadPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := LoadSchemaWithOptions(dir, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+			}
+			var names []string
+			for _, table := range schema.Tables {
+				names = append(names, table.Name)
+			}
+			if !reflect.DeepEqual(names, tt.tables) {
+				t.Errorf("Expected tables %v, got %v", tt.tables, names)
+			}
+		})
+	}
+
+	// A single file is loaded whatever the patterns
+	file := filepath.Join(dir, "billing.lp.sql")
+	if _, err := LoadSchemaWithOptions(file, LoadSchemaOptions{Exclude: []string{"billing*"}}); err != nil {
+		t.Errorf("Expected a single file to be loaded, got %v", err)
+	}
+}
+
+func TestLoadSchemaWithOptionsSort(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"a.lp.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);",
+		"b.lp.sql": "CREATE TABLE auth.sessions (id INTEGER);\nCREATE TABLE accounts (id INTEGER);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	unsorted, err := LoadSchema(tempDir)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	if unsorted.Tables[0].Name != "users" {
+		t.Errorf("Expected LoadSchema to keep file order, got %s first", unsorted.Tables[0].Name)
+	}
+
+	schema, err := LoadSchemaWithOptions(tempDir, LoadSchemaOptions{Sort: true})
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+	var names []string
+	for _, table := range schema.Tables {
+		names = append(names, qualifiedTableName(&table))
+	}
+	expected := []string{"auth.sessions", "public.accounts", "public.users"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected tables %v, got %v", expected, names)
+	}
+
+	// Duplicates are still detected
+	if err := os.WriteFile(filepath.Join(tempDir, "c.lp.sql"), []byte("CREATE TABLE accounts (id BIGINT);"), 0600); err != nil {
+		t.Fatalf("Failed to write c.lp.sql: %v", err)
+	}
+	if _, err := LoadSchemaWithOptions(tempDir, LoadSchemaOptions{Sort: true}); err == nil || !strings.Contains(err.Error(), `"public.accounts" is defined multiple times`) {
+		t.Errorf("Expected a duplicate table error, got %v", err)
+	}
+}
+
+func TestLoadSchemaStrict(t *testing.T) {
+	dir := writeSchemaFiles(t, map[string]string{
+		"users.lp.sql": "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);\n",
+		"audit.lp.sql": "CREATE TABLE audit_log (\n  message TEXT\n);\n",
+	})
+
+	// LoadSchema stays lenient about warnings
+	if _, err := LoadSchema(dir); err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+
+	_, err := LoadSchemaStrict(dir)
+	var lintErr *LintError
+	if !errors.As(err, &lintErr) {
+		t.Fatalf("Expected a *LintError, got %v", err)
+	}
+	if len(lintErr.Diagnostics) != 1 || lintErr.Diagnostics[0].Code != CodeMissingPrimaryKey {
+		t.Fatalf("Expected the missing primary key warning, got %+v", lintErr.Diagnostics)
+	}
+	expected := fmt.Sprintf(`%s:1:14: warning: table "public.audit_log" has no primary key [%s]`, filepath.Join(dir, "audit.lp.sql"), CodeMissingPrimaryKey)
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected the error to locate the warning as %q, got:\n%v", expected, err)
+	}
+
+	// A schema without problems loads
+	if err := os.WriteFile(filepath.Join(dir, "audit.lp.sql"), []byte("CREATE TABLE audit_log (id INTEGER PRIMARY KEY);\n"), 0600); err != nil {
+		t.Fatalf("Failed to write audit.lp.sql: %v", err)
+	}
+	schema, err := LoadSchemaWithOptions(dir, LoadSchemaOptions{Strict: true, Sort: true})
+	if err != nil {
+		t.Fatalf("Expected a strict load to succeed, got %v", err)
+	}
+	if len(schema.Tables) != 2 || schema.Tables[0].Name != "audit_log" {
+		t.Errorf("Expected the sorted tables, got %+v", schema.Tables)
+	}
+}
+
+func TestLoadSchemaWithOptionsRecursiveIgnoresSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	outsideDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "users.lp.sql"), []byte(`CREATE TABLE users (id INTEGER);`), 0600); err != nil {
+		t.Fatalf("Failed to write SQL file: %v", err)
+	}
+	target := filepath.Join(outsideDir, "posts.lp.sql")
+	if err := os.WriteFile(target, []byte(`CREATE TABLE posts (id INTEGER);`), 0600); err != nil {
+		t.Fatalf("Failed to write SQL file: %v", err)
+	}
+
+	subdir := filepath.Join(tempDir, "nested")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	if err := os.Symlink(target, filepath.Join(subdir, "posts.lp.sql")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	schema, err := LoadSchemaWithOptions(tempDir, LoadSchemaOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("LoadSchemaWithOptions failed: %v", err)
+	}
+
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "users" {
+		t.Fatalf("Expected only table 'users', got %+v", schema.Tables)
+	}
+}
+
+func TestLoadSchemaWithOptionsRecursiveDuplicateTables(t *testing.T) {
+	tempDir := t.TempDir()
+
+	subdir := filepath.Join(tempDir, "legacy")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "users.lp.sql"), []byte(`CREATE TABLE users (id INTEGER);`), 0600); err != nil {
+		t.Fatalf("Failed to write SQL file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(subdir, "users.lp.sql"), []byte(`CREATE TABLE users (id BIGINT);`), 0600); err != nil {
+		t.Fatalf("Failed to write SQL file: %v", err)
+	}
+
+	_, err := LoadSchemaWithOptions(tempDir, LoadSchemaOptions{Recursive: true})
+	if err == nil {
+		t.Fatal("Expected error for duplicate table across nested files")
+	}
+	if !strings.Contains(err.Error(), `table "public.users" is defined multiple times`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// The shallow search never sees the nested file
+	if _, err := LoadSchema(tempDir); err != nil {
+		t.Errorf("LoadSchema failed: %v", err)
+	}
+}
+
+func TestLoadSchemaWithOptionsRecursiveEmptyTree(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "a", "b"), 0755); err != nil {
+		t.Fatalf("Failed to create subdirectories: %v", err)
+	}
+
+	_, err := LoadSchemaWithOptions(tempDir, LoadSchemaOptions{Recursive: true})
+	if !errors.Is(err, ErrNoSchemaFiles) {
+		t.Errorf("Expected ErrNoSchemaFiles, got %v", err)
+	}
+}
+
 func TestLoadSchemaEmptyDirectory(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -180,6 +424,12 @@ func TestLoadSchemaEmptyDirectory(t *testing.T) {
 	if err.Error() != "no .lp.sql files found in directory "+tempDir {
 		t.Errorf("Expected 'no .lp.sql files found' error, got %q", err.Error())
 	}
+	if !errors.Is(err, ErrNoSchemaFiles) {
+		t.Errorf("Expected ErrNoSchemaFiles, got %v", err)
+	}
+	if errors.Is(err, ErrPathNotFound) || errors.Is(err, ErrWrongExtension) {
+		t.Errorf("Expected only ErrNoSchemaFiles, got %v", err)
+	}
 }
 
 func TestLoadSchemaNonExistentPath(t *testing.T) {
@@ -187,6 +437,17 @@ func TestLoadSchemaNonExistentPath(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for non-existent path, got nil")
 	}
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "/nonexistent/path/file.lp.sql") {
+		t.Errorf("Expected the error to name the path, got %q", err.Error())
+	}
+
+	_, err = LoadSchema("/nonexistent/path")
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound for a missing directory, got %v", err)
+	}
 }
 
 func TestLoadSchemaInvalidSQL(t *testing.T) {
@@ -202,6 +463,15 @@ func TestLoadSchemaInvalidSQL(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for invalid SQL, got nil")
 	}
+
+	// The error points at the offending token
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %T: %v", err, err)
+	}
+	if parseErr.File != sqlFile || parseErr.Line != 1 || parseErr.Column != 20 {
+		t.Errorf("Expected the error at %s:1:20, got %v", sqlFile, parseErr)
+	}
 }
 
 func TestLoadSchemaWithoutExtension(t *testing.T) {
@@ -217,6 +487,9 @@ func TestLoadSchemaWithoutExtension(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for file without .lp.sql extension, got nil")
 	}
+	if !errors.Is(err, ErrWrongExtension) {
+		t.Errorf("Expected ErrWrongExtension, got %v", err)
+	}
 }
 
 func TestLoadSchemaCaseInsensitiveExtension(t *testing.T) {
@@ -275,6 +548,68 @@ func TestLoadSchemaPreservesDialect(t *testing.T) {
 	}
 }
 
+func TestLoadSchemaWithDialectSQLite(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"users.lp.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT);`,
+		"kv.lp.sql":    `CREATE TABLE kv (key TEXT PRIMARY KEY, value BLOB) WITHOUT ROWID;`,
+	}
+	for filename, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", filename, err)
+		}
+	}
+
+	schema, err := LoadSchemaWithDialect(tempDir, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("LoadSchemaWithDialect failed: %v", err)
+	}
+
+	if schema.Dialect != database.DialectSQLite {
+		t.Errorf("Expected dialect %q, got %q", database.DialectSQLite, schema.Dialect)
+	}
+	if len(schema.Tables) != 2 {
+		t.Fatalf("Expected 2 tables, got %d", len(schema.Tables))
+	}
+}
+
+func TestLoadSchemaStrictSQLite(t *testing.T) {
+	dir := t.TempDir()
+	sql := `CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, data);
+CREATE TABLE events (id INTEGER PRIMARY KEY, payload BLOB, created DATETIME);`
+	if err := os.WriteFile(filepath.Join(dir, "schema.lp.sql"), []byte(sql), 0600); err != nil {
+		t.Fatalf("Failed to write schema.lp.sql: %v", err)
+	}
+
+	// SQLite type names are normalized, so they aren't reported as unknown
+	schema, err := LoadSchemaWithOptions(dir, LoadSchemaOptions{Dialect: database.DialectSQLite, Strict: true})
+	if err != nil {
+		t.Fatalf("Expected a strict load to succeed, got %v", err)
+	}
+	if typ := schema.Tables[0].Columns[1].Type; typ != "bytea" {
+		t.Errorf("Expected the typeless column to be bytea, got %q", typ)
+	}
+}
+
+func TestLoadSchemaWithDialectSQLiteDuplicateTables(t *testing.T) {
+	tempDir := t.TempDir()
+	sqlFile := filepath.Join(tempDir, "dup.lp.sql")
+
+	sqlContent := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT);
+		CREATE TABLE users (id INTEGER);
+	`
+	if err := os.WriteFile(sqlFile, []byte(sqlContent), 0600); err != nil {
+		t.Fatalf("Failed to write SQL file: %v", err)
+	}
+
+	_, err := LoadSchemaWithDialect(sqlFile, database.DialectSQLite)
+	if err == nil {
+		t.Fatal("Expected error for duplicate table definition, got nil")
+	}
+}
+
 func TestLoadSchemaMultipleStatements(t *testing.T) {
 	tempDir := t.TempDir()
 	sqlFile := filepath.Join(tempDir, "schema.lp.sql")
@@ -403,6 +738,81 @@ func TestLoadSchemaMultipleFilesInOrder(t *testing.T) {
 	}
 }
 
+func TestLoadSchemaAlterTableAcrossFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"01_tables.lp.sql": `CREATE TABLE users (id BIGINT PRIMARY KEY);`,
+		"02_alter.lp.sql":  `ALTER TABLE users ADD COLUMN last_login TIMESTAMPTZ;`,
+	}
+	for name, sql := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(sql), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	schema, err := LoadSchema(tempDir)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	columns := schema.Tables[0].Columns
+	if len(columns) != 2 || columns[1].Name != "last_login" {
+		t.Errorf("Expected last_login to be added to users, got %+v", columns)
+	}
+
+	// Files are parsed in order, so an ALTER in an earlier file fails
+	if err := os.WriteFile(filepath.Join(tempDir, "00_alter.lp.sql"), []byte(`ALTER TABLE users ADD COLUMN name TEXT;`), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	_, err = LoadSchema(tempDir)
+	if err == nil || !strings.Contains(err.Error(), "00_alter.lp.sql:1:1") || !strings.Contains(err.Error(), `table "users" does not exist`) {
+		t.Errorf("Expected an error located in 00_alter.lp.sql, got %v", err)
+	}
+}
+
+func TestLoadSchemaManyFiles(t *testing.T) {
+	// Enough files to keep every parsing worker busy, each altering the
+	// table of the file before it
+	files := make(map[string]string)
+	for i := range 40 {
+		sql := fmt.Sprintf("CREATE TABLE t%02d (id BIGINT PRIMARY KEY);\n", i)
+		if i > 0 {
+			sql += fmt.Sprintf("ALTER TABLE t%02d ADD COLUMN next_id BIGINT REFERENCES t%02d;\n", i-1, i)
+		}
+		files[fmt.Sprintf("%02d.lp.sql", i)] = sql
+	}
+	dir := writeSchemaFiles(t, files)
+
+	schema, err := LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+	if len(schema.Tables) != 40 {
+		t.Fatalf("Expected 40 tables, got %d", len(schema.Tables))
+	}
+	for i, table := range schema.Tables {
+		if want := fmt.Sprintf("t%02d", i); table.Name != want {
+			t.Errorf("Expected table %d to be %s, got %s", i, want, table.Name)
+		}
+		if i < 39 && (len(table.ForeignKeys) != 1 || table.ForeignKeys[0].ReferencedTable != fmt.Sprintf("t%02d", i+1)) {
+			t.Errorf("Expected %s to reference the next table, got %+v", table.Name, table.ForeignKeys)
+		}
+	}
+
+	// The error reported is the one in the first file in order, however
+	// the files were parsed
+	for _, name := range []string{"12.lp.sql", "31.lp.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("CREATE TABLE broken id INTEGER);"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	_, err = LoadSchema(dir)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.File != filepath.Join(dir, "12.lp.sql") {
+		t.Errorf("Expected a parse error in 12.lp.sql, got %v", err)
+	}
+}
+
 func TestLoadSchemaDuplicateTableInSameFile(t *testing.T) {
 	tempDir := t.TempDir()
 	sqlFile := filepath.Join(tempDir, "duplicate.lp.sql")
@@ -489,6 +899,39 @@ func TestLoadSchemaMultipleDuplicateTables(t *testing.T) {
 	if !strings.Contains(err.Error(), "defined multiple times") {
 		t.Errorf("Expected error to say 'defined multiple times', got %q", err.Error())
 	}
+
+	// Each duplicated table is listed once, sorted by name
+	file3 := filepath.Join(tempDir, "03_more.lp.sql")
+	if err := os.WriteFile(file3, []byte(`CREATE TABLE users (id INTEGER);`), 0600); err != nil {
+		t.Fatalf("Failed to write file3: %v", err)
+	}
+	_, err = LoadSchema(tempDir)
+	if err == nil || !strings.HasSuffix(err.Error(), "tables are defined multiple times: [public.posts public.users]") {
+		t.Errorf("Expected the duplicates sorted by name, got %v", err)
+	}
+}
+
+func TestValidateDuplicateTablesAsDiagnosticsOrder(t *testing.T) {
+	at := func(file string, line, column int) *database.SourceLocation {
+		return &database.SourceLocation{File: file, Line: line, Column: column}
+	}
+	schema := &database.Schema{Tables: []database.Table{
+		{Name: "users", SourceLocation: at("b.lp.sql", 1, 14)},
+		{Name: "posts", SourceLocation: at("b.lp.sql", 2, 14)},
+		{Name: "users", SourceLocation: at("c.lp.sql", 1, 14)},
+		{Name: "posts", SourceLocation: at("a.lp.sql", 5, 14)},
+		{Name: "users", SourceLocation: at("a.lp.sql", 3, 20)},
+		{Name: "users", SourceLocation: at("a.lp.sql", 3, 14)},
+	}}
+
+	var got []string
+	for _, d := range ValidateDuplicateTablesAsDiagnostics(schema) {
+		got = append(got, fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column))
+	}
+	expected := []string{"a.lp.sql:3:14", "a.lp.sql:3:20", "a.lp.sql:5:14", "c.lp.sql:1:14"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected diagnostics at %v, got %v", expected, got)
+	}
 }
 
 func TestLoadSchemaDuplicateWithOtherTables(t *testing.T) {
@@ -673,3 +1116,104 @@ func TestLoadSchemaCaseSensitivity(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadSchemaPreservesQuoting(t *testing.T) {
+	sql := `CREATE TABLE "Auth"."Users" ("Id" BIGINT PRIMARY KEY, "email" TEXT, Name TEXT);
+CREATE TABLE app . posts (id BIGINT);
+CREATE TABLE comments (id BIGINT);
+`
+	dir := writeSchemaFiles(t, map[string]string{"schema.lp.sql": sql})
+	schema, err := LoadSchema(dir)
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+
+	var raw []string
+	for _, table := range schema.Tables {
+		raw = append(raw, table.Schema+"|"+table.Name+"|"+table.RawSchema+"|"+table.RawName)
+	}
+	expected := []string{`Auth|Users|"Auth"|"Users"`, `app|posts|app|posts`, `|comments||comments`}
+	if !reflect.DeepEqual(raw, expected) {
+		t.Errorf("Expected tables %v, got %v", expected, raw)
+	}
+	raw = nil
+	for _, col := range schema.Tables[0].Columns {
+		raw = append(raw, col.Name+"|"+col.RawName)
+	}
+	expected = []string{`Id|"Id"`, `email|"email"`, `name|Name`}
+	if !reflect.DeepEqual(raw, expected) {
+		t.Errorf("Expected columns %v, got %v", expected, raw)
+	}
+
+	// Names that need quoting keep it when the schema is written back
+	formatted, err := FormatSQL(sql, "schema.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+	for _, expected := range []string{`CREATE TABLE "Auth"."Users" (`, `  "Id" bigint,`, `  email text,`, `  name text,`, `CREATE TABLE app.posts (`} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("Expected the formatted SQL to contain %q, got:\n%s", expected, formatted)
+		}
+	}
+}
+
+func TestLoadSchemaFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/schema/b_posts.lp.sql":     {Data: []byte(`CREATE TABLE posts (id INTEGER PRIMARY KEY);`)},
+		"db/schema/a_users.lp.sql":     {Data: []byte(`CREATE TABLE users (id INTEGER PRIMARY KEY);`)},
+		"db/schema/README.md":          {Data: []byte(`not sql`)},
+		"db/schema/old/items.lp.sql":   {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"db/schema/c_comments.lp.sql":  {Data: []byte(`CREATE TABLE comments (id INTEGER PRIMARY KEY);`)},
+		"db/other/ignored_file.lp.sql": {Data: []byte(`CREATE TABLE ignored (id INTEGER);`)},
+	}
+
+	schema, err := LoadSchemaFS(fsys, "db/schema")
+	if err != nil {
+		t.Fatalf("LoadSchemaFS failed: %v", err)
+	}
+
+	var names []string
+	for _, table := range schema.Tables {
+		names = append(names, table.Name)
+	}
+	// Files are read in name order, and subdirectories are ignored
+	if want := []string{"users", "posts", "comments"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected tables %v, got %v", want, names)
+	}
+	if got := schema.Tables[0].SourceLocation.File; got != "db/schema/a_users.lp.sql" {
+		t.Errorf("Expected users to be located in db/schema/a_users.lp.sql, got %q", got)
+	}
+
+	schema, err = LoadSchemaFS(fsys, "db/schema/a_users.lp.sql")
+	if err != nil {
+		t.Fatalf("LoadSchemaFS of a single file failed: %v", err)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "users" {
+		t.Errorf("Expected only the users table, got %+v", schema.Tables)
+	}
+
+	if _, err := LoadSchemaFS(fsys, "db/missing"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound for a path that doesn't exist, got %v", err)
+	}
+	if _, err := LoadSchemaFS(fsys, "db/schema/README.md"); !errors.Is(err, ErrWrongExtension) {
+		t.Errorf("Expected ErrWrongExtension for a file that isn't .lp.sql, got %v", err)
+	}
+	if _, err := LoadSchemaFS(fsys, "db"); !errors.Is(err, ErrNoSchemaFiles) {
+		t.Errorf("Expected ErrNoSchemaFiles for a directory without schema files, got %v", err)
+	}
+}
+
+func TestLoadSchemaFSDuplicateTables(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.lp.sql": {Data: []byte(`CREATE TABLE users (id INTEGER);`)},
+		"b.lp.sql": {Data: []byte(`CREATE TABLE users (id INTEGER);`)},
+	}
+
+	_, err := LoadSchemaFS(fsys, ".")
+	if err == nil {
+		t.Fatal("Expected an error for duplicate tables")
+	}
+	if !strings.Contains(err.Error(), "users") {
+		t.Errorf("Expected the error to name the duplicate table, got: %v", err)
+	}
+}
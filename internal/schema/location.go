@@ -0,0 +1,365 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	"github.com/pganalyze/pg_query_go/v6/parser"
+)
+
+// fileMarker is the comment that starts a named section of a schema file, e.g.
+// "-- lockplane:file users". Locations after the marker report the section
+// name as their file, so a single large file can be attributed by section.
+const fileMarker = "lockplane:file"
+
+// generatedMarker is the comment that marks a schema file as generated by a
+// tool when it is the file's first line, e.g. "-- lockplane:generated"
+const generatedMarker = "lockplane:generated"
+
+// isGenerated reports whether the first line of sql is the generated marker
+func isGenerated(sql string) bool {
+	firstLine, _, _ := strings.Cut(sql, "\n")
+	comment, ok := strings.CutPrefix(strings.TrimSpace(firstLine), "--")
+	return ok && strings.TrimSpace(comment) == generatedMarker
+}
+
+// generatedFileNames returns the names diagnostics in sql are reported
+// against if it is a generated file: filename and the names of any sections
+// in it. It returns nil for other files.
+func generatedFileNames(sql string, filename string) []string {
+	if !isGenerated(sql) {
+		return nil
+	}
+	names := []string{filename}
+	for _, section := range findFileSections(sql) {
+		names = append(names, section.file)
+	}
+	return names
+}
+
+// locator converts the byte offsets reported by pg_query into source locations
+// within a single file.
+type locator struct {
+	sql        string
+	filename   string
+	lineStarts []int
+	// sections lists the file markers in the file, in order
+	sections []fileSection
+	// insertions lists the text inserted into sql when it was rewritten from
+	// another dialect, which isn't counted in reported columns
+	insertions []insertion
+}
+
+// insertion is text of length bytes inserted at offset into rewritten SQL
+type insertion struct {
+	offset int
+	length int
+}
+
+// fileSection is the part of a file that starts at a file marker
+type fileSection struct {
+	offset int
+	file   string
+}
+
+func newLocator(sql string, filename string) *locator {
+	lineStarts := []int{0}
+	for i := 0; i < len(sql); i++ {
+		if sql[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	return &locator{sql: sql, filename: filename, lineStarts: lineStarts, sections: findFileSections(sql)}
+}
+
+// findFileSections returns the sections started by file marker comments in
+// sql. The scanner is used so markers inside string literals are ignored.
+func findFileSections(sql string) []fileSection {
+	if !strings.Contains(sql, fileMarker) {
+		return nil
+	}
+	scan, err := pg_query.Scan(sql)
+	if err != nil {
+		return nil
+	}
+
+	var sections []fileSection
+	for _, token := range scan.Tokens {
+		if token.Token != pg_query.Token_SQL_COMMENT {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(sql[token.Start:token.End], "--"))
+		if len(fields) == 2 && fields[0] == fileMarker {
+			sections = append(sections, fileSection{offset: int(token.Start), file: fields[1]})
+		}
+	}
+	return sections
+}
+
+// fileAt returns the file to report for a byte offset: the name of the
+// section containing it, or the file name when there are no sections before it
+func (l *locator) fileAt(offset int) string {
+	i := sort.Search(len(l.sections), func(i int) bool {
+		return l.sections[i].offset > offset
+	})
+	if i == 0 {
+		return l.filename
+	}
+	return l.sections[i-1].file
+}
+
+// at returns the source location of a byte offset, or nil if pg_query didn't
+// report one (offset -1).
+func (l *locator) at(offset int32) *database.SourceLocation {
+	if offset < 0 || int(offset) > len(l.sql) {
+		return nil
+	}
+
+	lineIndex := sort.Search(len(l.lineStarts), func(i int) bool {
+		return l.lineStarts[i] > int(offset)
+	}) - 1
+	lineStart := l.lineStarts[lineIndex]
+
+	column := utf8.RuneCountInString(l.sql[lineStart:offset]) + 1
+	for _, ins := range l.insertions {
+		if ins.offset >= lineStart && ins.offset < int(offset) {
+			column -= min(ins.length, int(offset)-ins.offset)
+		}
+	}
+
+	return &database.SourceLocation{
+		File:   l.fileAt(int(offset)),
+		Line:   lineIndex + 1,
+		Column: column,
+	}
+}
+
+// span returns the source location of the text from byte offset start to
+// end. Without a known end only the start is located.
+func (l *locator) span(start int32, end int) *database.SourceLocation {
+	loc := l.at(start)
+	if loc == nil || end <= int(start) {
+		return loc
+	}
+	if endLoc := l.at(int32(end)); endLoc != nil {
+		loc.EndLine, loc.EndColumn = endLoc.Line, endLoc.Column
+	}
+	return loc
+}
+
+// statement returns the source location of a statement. pg_query reports a
+// statement as starting right after the previous one, so leading whitespace
+// and comments are skipped to point at the first keyword.
+func (l *locator) statement(offset int32) *database.SourceLocation {
+	return l.at(int32(skipWhitespaceAndComments(l.sql, int(offset))))
+}
+
+// statementSpan returns the source location of a statement spanning its
+// text, up to but not including the semicolon. pg_query reports a length of 0
+// for a last statement without a semicolon, which runs to the end of the
+// file.
+func (l *locator) statementSpan(stmt *pg_query.RawStmt) *database.SourceLocation {
+	start := skipWhitespaceAndComments(l.sql, int(stmt.StmtLocation))
+	end := len(l.sql)
+	if stmt.StmtLen > 0 {
+		end = min(int(stmt.StmtLocation+stmt.StmtLen), len(l.sql))
+	}
+	end = len(strings.TrimRight(l.sql[:end], " \t\r\n"))
+	return l.span(int32(start), end)
+}
+
+// nameAt returns the source location of the possibly qualified name starting
+// at offset, spanning the whole name as it is written
+func (l *locator) nameAt(offset int32) *database.SourceLocation {
+	if offset < 0 || int(offset) >= len(l.sql) {
+		return l.at(offset)
+	}
+	return l.span(offset, nameEnd(l.sql, int(offset)))
+}
+
+// identifierAt returns the identifier starting at offset as it is written,
+// with its quotes if it is quoted. For a qualified name such as app."Orders"
+// the last part is returned.
+func (l *locator) identifierAt(offset int32) string {
+	parts := l.identifierPartsAt(offset)
+	if len(parts) == 0 {
+		return ""
+	}
+	return parts[len(parts)-1]
+}
+
+// qualifierAt returns the schema of the qualified name starting at offset as
+// it is written, as app for app."Orders", or "" if the name isn't qualified
+func (l *locator) qualifierAt(offset int32) string {
+	parts := l.identifierPartsAt(offset)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// identifierPartsAt returns the parts of the possibly qualified name starting
+// at offset as they are written
+func (l *locator) identifierPartsAt(offset int32) []string {
+	i := int(offset)
+	if offset < 0 || i >= len(l.sql) {
+		return nil
+	}
+	var parts []string
+	for {
+		end := identifierEnd(l.sql, i)
+		if end == i {
+			return nil
+		}
+		parts = append(parts, l.sql[i:end])
+		next := skipWhitespaceAndComments(l.sql, end)
+		if next >= len(l.sql) || l.sql[next] != '.' {
+			return parts
+		}
+		i = skipWhitespaceAndComments(l.sql, next+1)
+	}
+}
+
+// nameEnd returns the offset just past the possibly qualified name starting at
+// offset, or offset itself if no name starts there
+func nameEnd(sql string, offset int) int {
+	end := offset
+	for i := offset; ; {
+		partEnd := identifierEnd(sql, i)
+		if partEnd == i {
+			return end
+		}
+		end = partEnd
+		next := skipWhitespaceAndComments(sql, end)
+		if next >= len(sql) || sql[next] != '.' {
+			return end
+		}
+		i = skipWhitespaceAndComments(sql, next+1)
+	}
+}
+
+// identifierEnd returns the offset just past the identifier starting at
+// offset, or offset itself if no identifier starts there
+func identifierEnd(sql string, offset int) int {
+	i := offset
+	if strings.HasPrefix(sql[i:], "U&\"") || strings.HasPrefix(sql[i:], "u&\"") {
+		i += 2
+	}
+	if i < len(sql) && sql[i] == '"' {
+		// Quoted identifiers end at the first quote that isn't doubled
+		for i++; i < len(sql); i++ {
+			if sql[i] != '"' {
+				continue
+			}
+			if i+1 < len(sql) && sql[i+1] == '"' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+		return offset
+	}
+	for i < len(sql) && (sql[i] == '_' || sql[i] == '$' || sql[i] >= 0x80 ||
+		'a' <= sql[i] && sql[i] <= 'z' || 'A' <= sql[i] && sql[i] <= 'Z' || '0' <= sql[i] && sql[i] <= '9') {
+		i++
+	}
+	return i
+}
+
+// parseError returns a ParseError located at the statement starting at offset
+func (l *locator) parseError(offset int32, err error) *ParseError {
+	parseErr := &ParseError{File: l.filename, Err: err}
+	if loc := l.statement(offset); loc != nil {
+		parseErr.File = loc.File
+		parseErr.Line = loc.Line
+		parseErr.Column = loc.Column
+	}
+	return parseErr
+}
+
+// syntaxError returns a ParseError for a statement pg_query failed to parse,
+// located at the token pg_query reported. Errors without a position are only
+// attributed to the file.
+func (l *locator) syntaxError(err error) *ParseError {
+	parseErr := &ParseError{File: l.filename, Err: fmt.Errorf("failed to parse SQL: %w", err)}
+
+	var pgErr *parser.Error
+	if !errors.As(err, &pgErr) || pgErr.Cursorpos <= 0 {
+		return parseErr
+	}
+	// Cursorpos is a 1-based character position, not a byte offset
+	if loc := l.at(int32(charToByteOffset(l.sql, pgErr.Cursorpos-1))); loc != nil {
+		parseErr.File = loc.File
+		parseErr.Line = loc.Line
+		parseErr.Column = loc.Column
+	}
+	return parseErr
+}
+
+// charToByteOffset converts a 0-based character position in sql into a byte
+// offset. Positions past the end map to the end.
+func charToByteOffset(sql string, chars int) int {
+	for offset := range sql {
+		if chars == 0 {
+			return offset
+		}
+		chars--
+	}
+	return len(sql)
+}
+
+// byteOffsetToLineColumn converts a byte offset in sql into a 1-based line and
+// column. Columns count characters rather than bytes.
+func byteOffsetToLineColumn(sql string, offset int) (line int, column int) {
+	if offset < 0 {
+		return 1, 1
+	}
+	if offset > len(sql) {
+		offset = len(sql)
+	}
+
+	line = 1
+	lineStart := 0
+	for i := 0; i < offset; i++ {
+		if sql[i] == '\n' {
+			line++
+			lineStart = i + 1
+		}
+	}
+
+	return line, utf8.RuneCountInString(sql[lineStart:offset]) + 1
+}
+
+// skipWhitespaceAndComments returns the offset of the first byte at or after
+// offset that isn't whitespace or part of a SQL comment.
+func skipWhitespaceAndComments(sql string, offset int) int {
+	i := offset
+	for i < len(sql) {
+		switch {
+		case isSpace(sql[i]):
+			i++
+		case sql[i] == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			for i < len(sql) && sql[i] != '\n' {
+				i++
+			}
+		case sql[i] == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			i += 2
+			for i+1 < len(sql) && !(sql[i] == '*' && sql[i+1] == '/') {
+				i++
+			}
+			i += 2
+		default:
+			return i
+		}
+	}
+	if i > len(sql) {
+		return len(sql)
+	}
+	return i
+}
@@ -0,0 +1,517 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// mysqlTableOptions are the MySQL table options, written after the column
+// list as NAME=value, that PostgreSQL's grammar does not accept. They carry no
+// information the schema model tracks, so they are blanked out. The = is
+// required, so columns with these names are left alone.
+var mysqlTableOptions = map[string]bool{
+	"engine":           true,
+	"charset":          true,
+	"row_format":       true,
+	"key_block_size":   true,
+	"avg_row_length":   true,
+	"max_rows":         true,
+	"min_rows":         true,
+	"pack_keys":        true,
+	"checksum":         true,
+	"stats_persistent": true,
+}
+
+// mysqlIntegerTypes are the integer types that accept a display width, as in
+// int(11). PostgreSQL has no display widths, so the width is blanked out.
+var mysqlIntegerTypes = map[string]bool{
+	"tinyint":   true,
+	"smallint":  true,
+	"mediumint": true,
+	"int":       true,
+	"integer":   true,
+	"bigint":    true,
+}
+
+// parseMySQLSQLSchemaWithFilename parses MySQL-flavored DDL into the shared
+// schema model.
+//
+// Like SQLite, MySQL DDL is rewritten into PostgreSQL syntax and parsed with
+// pg_query. The rewrite is always of the same length, so locations still point
+// at the original source.
+// AUTO_INCREMENT columns are recorded as identity columns once parsed, and
+// MySQL type names are normalized to their PostgreSQL equivalents.
+func parseMySQLSQLSchemaWithFilename(schema *database.Schema, sql string, filename string, coverage *Coverage, cache *parseCache, stopOnError bool) []error {
+	rewrite := rewriteMySQLDDL(sql)
+	errs := parsePostgresSQLSchemaWithFilename(schema, rewrite.sql, filename, coverage, cache, stopOnError)
+
+	for i := range schema.Tables {
+		for j := range schema.Tables[i].Columns {
+			col := &schema.Tables[i].Columns[j]
+			col.Type = database.NormalizeMySQLType(col.Type)
+		}
+	}
+
+	locate := newLocator(sql, filename)
+	for _, offset := range rewrite.autoIncrements {
+		table, col := columnDeclaredBefore(schema, locate.at(int32(offset)))
+		if col != nil && col.Identity == nil {
+			col.Identity = &database.IdentitySpec{Sequence: identitySequenceName(table.Name, col.Name)}
+			col.Nullable = false // AUTO_INCREMENT columns are keys, so NOT NULL
+		}
+	}
+	for _, key := range rewrite.indexes {
+		table := tableDeclaredBefore(schema, locate.at(int32(key.start)))
+		if table == nil {
+			continue
+		}
+		index := key.index
+		index.SourceLocation = locate.span(int32(key.start), key.end)
+		table.Indexes = append(table.Indexes, index)
+	}
+	return errs
+}
+
+// columnDeclaredBefore returns the column whose name is the last one before loc
+// in the same file, i.e. the column a column attribute at loc belongs to, and
+// its table
+func columnDeclaredBefore(schema *database.Schema, loc *database.SourceLocation) (*database.Table, *database.Column) {
+	if loc == nil {
+		return nil, nil
+	}
+
+	var table *database.Table
+	var found *database.Column
+	var foundLoc *database.SourceLocation
+	for i := range schema.Tables {
+		for j := range schema.Tables[i].Columns {
+			col := &schema.Tables[i].Columns[j]
+			at := col.SourceLocation
+			if at == nil || at.File != loc.File || !locationBefore(at, loc) {
+				continue
+			}
+			if foundLoc == nil || locationBefore(foundLoc, at) {
+				table, found, foundLoc = &schema.Tables[i], col, at
+			}
+		}
+	}
+	return table, found
+}
+
+// tableDeclaredBefore returns the table whose name is the last one before loc
+// in the same file, i.e. the table a definition at loc in a column list
+// belongs to
+func tableDeclaredBefore(schema *database.Schema, loc *database.SourceLocation) *database.Table {
+	if loc == nil {
+		return nil
+	}
+
+	var found *database.Table
+	for i := range schema.Tables {
+		at := schema.Tables[i].SourceLocation
+		if at == nil || at.File != loc.File || !locationBefore(at, loc) {
+			continue
+		}
+		if found == nil || locationBefore(found.SourceLocation, at) {
+			found = &schema.Tables[i]
+		}
+	}
+	return found
+}
+
+// locationBefore reports whether a comes before b in the same file
+func locationBefore(a *database.SourceLocation, b *database.SourceLocation) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
+// rewriteMySQLDDL converts MySQL-specific syntax into PostgreSQL-compatible
+// syntax of the same length:
+//   - `ident` quoting becomes "ident"
+//   - # comments, table options such as ENGINE=InnoDB and DEFAULT
+//     CHARSET=utf8mb4, CHARACTER SET and COMMENT clauses, UNSIGNED, ZEROFILL and
+//     ON UPDATE CURRENT_TIMESTAMP are replaced with spaces
+//   - integer display widths are removed, except that tinyint(1), MySQL's
+//     boolean, becomes boolean
+//   - DOUBLE becomes float8, since PostgreSQL only accepts DOUBLE PRECISION
+//   - AUTO_INCREMENT is replaced with spaces, and the offsets of the column
+//     attributes (as opposed to the AUTO_INCREMENT=n table option) returned
+//   - KEY, INDEX and UNIQUE KEY definitions in a column list, which
+//     PostgreSQL only accepts as CREATE INDEX statements, are replaced with
+//     spaces along with the comma before them, and returned as indexes
+//
+// String literals, quoted identifiers and other comments are left untouched.
+func rewriteMySQLDDL(sql string) mysqlRewrite {
+	out := []byte(sql)
+	n := len(out)
+	rewrite := mysqlRewrite{}
+
+	for i := 0; i < n; {
+		switch c := out[i]; {
+		case c == '\'' || c == '"':
+			i = skipQuoted(out, i, c)
+
+		case c == '-' && i+1 < n && out[i+1] == '-':
+			for i < n && out[i] != '\n' {
+				i++
+			}
+
+		case c == '#':
+			for i < n && out[i] != '\n' {
+				out[i] = ' '
+				i++
+			}
+
+		case c == '/' && i+1 < n && out[i+1] == '*':
+			end := strings.Index(string(out[i+2:]), "*/")
+			if end == -1 {
+				rewrite.sql = string(out)
+				return rewrite
+			}
+			i += end + 4
+
+		case c == '`':
+			out[i] = '"'
+			end := skipQuoted(out, i, '`')
+			if end <= n && out[end-1] == '`' {
+				out[end-1] = '"'
+			}
+			i = end
+
+		case isIdentStart(c):
+			start := i
+			for i < n && isIdentChar(out[i]) {
+				i++
+			}
+			i = rewriteMySQLWord(out, start, i, &rewrite)
+
+		default:
+			i++
+		}
+	}
+
+	rewrite.sql = string(out)
+	return rewrite
+}
+
+// mysqlRewrite is MySQL DDL rewritten into PostgreSQL syntax, with what the
+// rewrite removed but the schema model records
+type mysqlRewrite struct {
+	sql string
+	// autoIncrements are the offsets of AUTO_INCREMENT column attributes
+	autoIncrements []int
+	indexes        []mysqlIndex
+}
+
+// mysqlIndex is a KEY, INDEX or UNIQUE KEY definition removed from a column
+// list, at sql[start:end]
+type mysqlIndex struct {
+	index database.Index
+	start int
+	end   int
+}
+
+// rewriteMySQLWord rewrites the MySQL-only syntax starting with the word
+// out[start:end], returning the offset to continue scanning from
+func rewriteMySQLWord(out []byte, start int, end int, rewrite *mysqlRewrite) int {
+	word := strings.ToLower(string(out[start:end]))
+	next, nextEnd := nextWord(out, end)
+
+	switch {
+	case (word == "key" || word == "index" || word == "unique" && (next == "key" || next == "index")) && startsListElement(out, start):
+		return rewriteMySQLIndex(out, start, end, rewrite)
+
+	case word == "auto_increment":
+		if afterEquals(out, end) == end {
+			rewrite.autoIncrements = append(rewrite.autoIncrements, start)
+			blank(out, start, end)
+			return end
+		}
+		return blankOption(out, start, end)
+
+	case (mysqlTableOptions[word] || word == "collate" || word == "comment") && afterEquals(out, end) != end:
+		// Column COLLATE is valid PostgreSQL, so only COLLATE= is removed
+		return blankOption(out, start, end)
+
+	case word == "comment" && startsQuoted(out, end):
+		// Column COMMENT 'text'
+		return blankOption(out, start, end)
+
+	case word == "character" && next == "set":
+		return blankOption(out, start, nextEnd)
+
+	case word == "default" && (next == "charset" || next == "collate" || next == "character"):
+		blank(out, start, end)
+		return end
+
+	case word == "unsigned" || word == "zerofill":
+		blank(out, start, end)
+		return end
+
+	case word == "double" && next != "precision":
+		copy(out[start:end], "float8")
+		return end
+
+	case word == "on" && next == "update":
+		after, afterEnd := nextWord(out, nextEnd)
+		if after != "current_timestamp" && after != "now" {
+			return end
+		}
+		stop := skipParens(out, afterEnd)
+		blank(out, start, stop)
+		return stop
+
+	case mysqlIntegerTypes[word]:
+		stop := skipParens(out, end)
+		if stop == end {
+			return end
+		}
+		if word == "tinyint" && strings.TrimSpace(strings.Trim(strings.TrimSpace(string(out[end:stop])), "()")) == "1" {
+			blank(out, start, stop)
+			copy(out[start:], "boolean")
+			return stop
+		}
+		blank(out, end, stop)
+		return stop
+	}
+	return end
+}
+
+// startsListElement reports whether the word at offset starts an element of a
+// parenthesized list, i.e. follows a comma or an opening parenthesis
+func startsListElement(out []byte, offset int) bool {
+	i := offset - 1
+	for i >= 0 && isSpace(out[i]) {
+		i--
+	}
+	return i >= 0 && (out[i] == ',' || out[i] == '(')
+}
+
+// rewriteMySQLIndex records the KEY, INDEX or UNIQUE KEY definition starting
+// with the word out[start:end] as an index, and blanks it out along with the
+// comma that separates it from the rest of the column list. A key without a
+// name is named after its first column, as MySQL does.
+func rewriteMySQLIndex(out []byte, start int, end int, rewrite *mysqlRewrite) int {
+	index := database.Index{}
+	i := end
+	if strings.EqualFold(string(out[start:end]), "unique") {
+		index.Unique = true
+		_, i = nextWord(out, end)
+	}
+
+	i = skipSpace(out, i)
+	if i < len(out) && out[i] != '(' {
+		var name string
+		name, i = mysqlIdentifier(out, i)
+		if strings.EqualFold(name, "using") {
+			index.Method, i = nextWord(out, i)
+		} else {
+			index.Name = name
+		}
+	}
+	if word, after := nextWord(out, i); word == "using" {
+		index.Method, i = nextWord(out, after)
+	}
+
+	i = skipSpace(out, i)
+	if i >= len(out) || out[i] != '(' {
+		return end
+	}
+	close := matchingParen(out, i)
+	if close == -1 {
+		return end
+	}
+	for _, part := range splitTopLevel(string(out[i+1 : close])) {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "(") {
+			index.Expressions = append(index.Expressions, strings.ReplaceAll(strings.TrimSpace(part[1:len(part)-1]), "`", `"`))
+			continue
+		}
+		column, _ := mysqlIdentifier([]byte(part), 0)
+		index.Columns = append(index.Columns, column)
+	}
+	if word, after := nextWord(out, close+1); word == "using" {
+		index.Method, _ = nextWord(out, after)
+	}
+	if index.Name == "" && len(index.Columns) > 0 {
+		index.Name = index.Columns[0]
+	}
+
+	// The definition ends at the comma or parenthesis that ends the element
+	stop := close + 1
+	for depth := 0; stop < len(out); stop++ {
+		c := out[stop]
+		if c == '\'' || c == '"' || c == '`' {
+			stop = skipQuoted(out, stop, c) - 1
+			continue
+		}
+		if c == '(' {
+			depth++
+		} else if c == ')' && depth > 0 {
+			depth--
+		} else if (c == ',' || c == ')') && depth == 0 {
+			break
+		}
+	}
+
+	// Blank the comma before the definition, or after it when it comes first
+	from, to := start, stop
+	if comma := strings.LastIndexByte(string(out[:start]), ','); comma != -1 && strings.TrimSpace(string(out[comma+1:start])) == "" {
+		from = comma
+	} else if to < len(out) && out[to] == ',' {
+		to++
+	}
+	rewrite.indexes = append(rewrite.indexes, mysqlIndex{index: index, start: start, end: close + 1})
+	blank(out, from, to)
+	return to
+}
+
+// mysqlIdentifier returns the identifier at offset, skipping whitespace, and
+// the offset just past it. Backtick and double-quoted names are unquoted and
+// keep their case; other names are lowercased, as the parser folds them.
+func mysqlIdentifier(out []byte, offset int) (string, int) {
+	i := skipSpace(out, offset)
+	if i < len(out) && (out[i] == '`' || out[i] == '"') {
+		quote := out[i]
+		end := skipQuoted(out, i, quote)
+		name := string(out[i+1 : max(i+1, end-1)])
+		return strings.ReplaceAll(name, string([]byte{quote, quote}), string(quote)), end
+	}
+	return nextWord(out, i)
+}
+
+// skipSpace returns the offset of the first non-space byte at or after offset
+func skipSpace(out []byte, offset int) int {
+	for offset < len(out) && isSpace(out[offset]) {
+		offset++
+	}
+	return offset
+}
+
+// matchingParen returns the offset of the parenthesis closing the one at
+// open, or -1 if it isn't closed
+func matchingParen(out []byte, open int) int {
+	depth := 0
+	for i := open; i < len(out); i++ {
+		switch c := out[i]; c {
+		case '\'', '"', '`':
+			i = skipQuoted(out, i, c) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits a comma-separated list on the commas that aren't
+// inside parentheses or quotes
+func splitTopLevel(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; c {
+		case '\'', '"', '`':
+			i = skipQuoted([]byte(list), i, c) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, list[start:])
+}
+
+// nextWord returns the lowercased identifier following offset, skipping
+// whitespace, and the offset just past it
+func nextWord(out []byte, offset int) (string, int) {
+	i := offset
+	for i < len(out) && isSpace(out[i]) {
+		i++
+	}
+	start := i
+	for i < len(out) && isIdentChar(out[i]) {
+		i++
+	}
+	return strings.ToLower(string(out[start:i])), i
+}
+
+// afterEquals returns the offset just past an = following offset, or offset
+// if there is none
+func afterEquals(out []byte, offset int) int {
+	i := offset
+	for i < len(out) && isSpace(out[i]) {
+		i++
+	}
+	if i < len(out) && out[i] == '=' {
+		return i + 1
+	}
+	return offset
+}
+
+// blankOption blanks an option name out[start:end] along with its optional =
+// and its value, returning the offset past the value
+func blankOption(out []byte, start int, end int) int {
+	i := afterEquals(out, end)
+	for i < len(out) && isSpace(out[i]) {
+		i++
+	}
+	switch {
+	case i < len(out) && (out[i] == '\'' || out[i] == '"'):
+		i = skipQuoted(out, i, out[i])
+	default:
+		for i < len(out) && isIdentChar(out[i]) {
+			i++
+		}
+	}
+	blank(out, start, i)
+	return i
+}
+
+// skipParens returns the offset past a parenthesized list following offset, or
+// offset if none follows
+func skipParens(out []byte, offset int) int {
+	i := offset
+	for i < len(out) && isSpace(out[i]) {
+		i++
+	}
+	if i >= len(out) || out[i] != '(' {
+		return offset
+	}
+	end := strings.IndexByte(string(out[i:]), ')')
+	if end == -1 {
+		return offset
+	}
+	return i + end + 1
+}
+
+// startsQuoted reports whether a string literal follows offset
+func startsQuoted(out []byte, offset int) bool {
+	i := offset
+	for i < len(out) && isSpace(out[i]) {
+		i++
+	}
+	return i < len(out) && out[i] == '\''
+}
+
+// blank replaces out[start:end] with spaces, keeping newlines so line numbers
+// are preserved
+func blank(out []byte, start int, end int) {
+	for j := start; j < end; j++ {
+		if out[j] != '\n' {
+			out[j] = ' '
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+const mysqlUsersSQL = "CREATE TABLE `users` (\n" +
+	"  `id` int(11) unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `email` varchar(255) CHARACTER SET utf8mb4 NOT NULL COMMENT 'login email',\n" +
+	"  `active` tinyint(1) NOT NULL DEFAULT '1',\n" +
+	"  `level` tinyint(4) DEFAULT NULL,\n" +
+	"  `score` double DEFAULT NULL,\n" +
+	"  `bio` longtext,\n" +
+	"  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+	"  `comment` text, # free-form notes\n" +
+	"  PRIMARY KEY (`id`)\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='application users';\n"
+
+func TestParseMySQL(t *testing.T) {
+	schema, err := ParseSQLSchemaWithDialect(mysqlUsersSQL, database.DialectMySQL)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if schema.Dialect != database.DialectMySQL {
+		t.Errorf("Expected dialect %q, got %q", database.DialectMySQL, schema.Dialect)
+	}
+	if len(schema.Tables) != 1 || schema.Tables[0].Name != "users" {
+		t.Fatalf("Expected a users table, got %+v", schema.Tables)
+	}
+
+	table := schema.Tables[0]
+	types := map[string]string{}
+	for _, col := range table.Columns {
+		types[col.Name] = col.Type
+	}
+	expected := map[string]string{
+		"id":         "integer",
+		"email":      "varchar(255)",
+		"active":     "boolean",
+		"level":      "smallint",
+		"score":      "double precision",
+		"bio":        "text",
+		"created_at": "timestamp without time zone",
+		"comment":    "text",
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected column types %v, got %v", expected, types)
+	}
+
+	id := table.Columns[0]
+	if id.Identity == nil || id.Identity.Always || id.Nullable || !id.IsPrimaryKey {
+		t.Errorf("Expected id to be a NOT NULL identity primary key, got %+v", id)
+	}
+	for _, col := range table.Columns[1:] {
+		if col.Identity != nil {
+			t.Errorf("Expected only id to be AUTO_INCREMENT, got %s", col.Name)
+		}
+	}
+	if loc := table.Columns[1].SourceLocation; loc == nil || loc.Line != 3 || loc.Column != 3 {
+		t.Errorf("Expected email at 3:3, got %v", loc)
+	}
+}
+
+func TestRewriteMySQLDDLPreservesOffsets(t *testing.T) {
+	rewrite := rewriteMySQLDDL(mysqlUsersSQL)
+	rewritten, autoIncrements := rewrite.sql, rewrite.autoIncrements
+	if len(rewritten) != len(mysqlUsersSQL) {
+		t.Fatalf("Expected the rewrite to keep the length %d, got %d", len(mysqlUsersSQL), len(rewritten))
+	}
+	if len(autoIncrements) != 1 || mysqlUsersSQL[autoIncrements[0]:autoIncrements[0]+len("AUTO_INCREMENT")] != "AUTO_INCREMENT" {
+		t.Errorf("Expected the offset of the AUTO_INCREMENT column attribute, got %v", autoIncrements)
+	}
+}
+
+func TestLoadSchemaWithDialectMySQLDuplicateTables(t *testing.T) {
+	tempDir := t.TempDir()
+	for name, sql := range map[string]string{
+		"01_users.lp.sql": mysqlUsersSQL,
+		"02_again.lp.sql": "CREATE TABLE `users` (`id` bigint(20) NOT NULL AUTO_INCREMENT PRIMARY KEY) ENGINE=InnoDB;\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(sql), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	if _, err := LoadSchemaWithDialect(tempDir, database.DialectMySQL); err == nil {
+		t.Fatal("Expected a duplicate table error")
+	}
+
+	files, err := findSchemaFiles(osFS{}, tempDir, LoadSchemaOptions{})
+	if err != nil {
+		t.Fatalf("findSchemaFiles failed: %v", err)
+	}
+	schema, err := parseSchemaFiles(osFS{}, files, LoadSchemaOptions{Dialect: database.DialectMySQL}, nil)
+	if err != nil {
+		t.Fatalf("parseSchemaFiles failed: %v", err)
+	}
+	duplicates := ValidateDuplicateTablesAsDiagnostics(schema)
+	if len(duplicates) != 1 || duplicates[0].Line != 1 || duplicates[0].Column != 14 {
+		t.Errorf("Expected a duplicate table diagnostic at 1:14, got %+v", duplicates)
+	}
+	if id := schema.Tables[1].Columns[0]; id.Type != "bigint" || id.Identity == nil {
+		t.Errorf("Expected a bigint identity column, got %+v", id)
+	}
+}
+
+func TestParseMySQLInlineKeys(t *testing.T) {
+	sql := "CREATE TABLE `posts` (\n" +
+		"  `id` bigint(20) NOT NULL AUTO_INCREMENT,\n" +
+		"  `user_id` int(11) NOT NULL,\n" +
+		"  `slug` varchar(255) NOT NULL,\n" +
+		"  `title` varchar(255) DEFAULT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `posts_slug` (`slug`),\n" +
+		"  KEY `idx_user_title` (`user_id`,`title`(20)) USING BTREE,\n" +
+		"  INDEX (`title` DESC)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n"
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectMySQL)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	table := schema.Tables[0]
+
+	var names []string
+	for _, col := range table.Columns {
+		names = append(names, col.Name)
+	}
+	if want := []string{"id", "user_id", "slug", "title"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected columns %v, got %v", want, names)
+	}
+	if !reflect.DeepEqual(table.PrimaryKey, []string{"id"}) {
+		t.Errorf("Expected primary key [id], got %v", table.PrimaryKey)
+	}
+	if id := table.Columns[0]; id.Identity == nil || id.Identity.Sequence != "posts_id_seq" {
+		t.Errorf("Expected id to be an identity column with sequence posts_id_seq, got %+v", id.Identity)
+	}
+
+	expected := []database.Index{
+		{Name: "posts_slug", Unique: true, Columns: []string{"slug"},
+			SourceLocation: &database.SourceLocation{Line: 7, Column: 3, EndLine: 7, EndColumn: 35}},
+		{Name: "idx_user_title", Method: "btree", Columns: []string{"user_id", "title"},
+			SourceLocation: &database.SourceLocation{Line: 8, Column: 3, EndLine: 8, EndColumn: 47}},
+		{Name: "title", Columns: []string{"title"},
+			SourceLocation: &database.SourceLocation{Line: 9, Column: 3, EndLine: 9, EndColumn: 23}},
+	}
+	if !reflect.DeepEqual(table.Indexes, expected) {
+		t.Errorf("Expected indexes\n%+v\ngot\n%+v", expected, table.Indexes)
+	}
+}
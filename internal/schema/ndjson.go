@@ -0,0 +1,53 @@
+package schema
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// NDJSON line types, recorded in each line's "type" field
+const (
+	ndjsonDiagnostic = "diagnostic"
+	ndjsonSummary    = "summary"
+)
+
+// NDJSONWriter writes a check report as newline-delimited JSON: one compact
+// object per diagnostic, then a summary line. Every line has a "type" field,
+// "diagnostic" or "summary", alongside the fields of Diagnostic or Summary.
+//
+// NDJSONWriter is a DiagnosticSink, so diagnostics can be written as a check
+// finds them by setting it as CheckOptions.Sink.
+type NDJSONWriter struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewNDJSONWriter returns an NDJSONWriter that writes to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
+
+// Report writes a diagnostic line
+func (w *NDJSONWriter) Report(d Diagnostic) {
+	w.write(struct {
+		Type string `json:"type"`
+		Diagnostic
+	}{ndjsonDiagnostic, d})
+}
+
+// WriteSummary writes the summary line, which ends the report, and returns
+// the first error encountered writing any line
+func (w *NDJSONWriter) WriteSummary(s Summary) error {
+	w.write(struct {
+		Type string `json:"type"`
+		Summary
+	}{ndjsonSummary, s})
+	return w.err
+}
+
+// write encodes v as one line, unless an earlier line failed
+func (w *NDJSONWriter) write(v any) {
+	if w.err == nil {
+		w.err = w.enc.Encode(v)
+	}
+}
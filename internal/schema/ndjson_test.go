@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"bufio"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewNDJSONWriter(&out)
+
+	output := CheckSQL("CREATE TABLE users (id BIGINT);\nCREATE TABLE users (id BIGINT PRIMARY KEY);", "users.lp.sql", CheckOptions{Sink: w})
+	if err := w.WriteSummary(output.Summary); err != nil {
+		t.Fatalf("WriteSummary failed: %v", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != len(output.Diagnostics)+1 {
+		t.Fatalf("Expected a line per diagnostic and a summary, got:\n%s", out.String())
+	}
+
+	for i, d := range output.Diagnostics {
+		var line struct {
+			Type string `json:"type"`
+			Diagnostic
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &line); err != nil {
+			t.Fatalf("Line %d isn't JSON: %v", i+1, err)
+		}
+		if line.Type != "diagnostic" || line.Diagnostic != d {
+			t.Errorf("Expected diagnostic %+v on line %d, got %s", d, i+1, lines[i])
+		}
+	}
+
+	var summary struct {
+		Type string `json:"type"`
+		Summary
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatalf("Summary line isn't JSON: %v", err)
+	}
+	if summary.Type != "summary" || !reflect.DeepEqual(summary.Summary, output.Summary) || summary.Errors != 1 {
+		t.Errorf("Expected summary %+v, got %s", output.Summary, lines[len(lines)-1])
+	}
+}
@@ -2,83 +2,397 @@ package schema
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/lockplane/lockplane/internal/database"
 	pg_query "github.com/pganalyze/pg_query_go/v6"
 )
 
-// ParseSQLSchemaWithDialect parses SQL DDL for the requested dialect.
+// ParseError describes a failure to parse a schema file. Line and Column are
+// 1-based, and are 0 when the position of the failure isn't known.
+type ParseError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+	case e.File != "":
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+	default:
+		return e.Err.Error()
+	}
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseSchemaString parses a schema from PostgreSQL DDL held in memory, such
+// as the concatenated contents of several schema files, and validates it as
+// LoadSchema does: a table defined more than once is an error. It is the entry
+// point for parsing a schema without reading files. Parse errors are located
+// by line and column in sql.
+func ParseSchemaString(sql string) (*database.Schema, error) {
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNoDuplicateTables(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// ParseSQLSchemaWithDialect parses SQL DDL for the requested dialect. It fails
+// on the first statement that can't be parsed; see ParseWithDiagnostics for
+// reporting every problem at once. Unlike ParseSchemaString, the schema isn't
+// checked for duplicate tables.
 func ParseSQLSchemaWithDialect(sql string, dialect database.Dialect) (*database.Schema, error) {
+	schema := newSchema(dialect)
+
+	if err := parseSQLSchemaWithFilename(schema, sql, "", dialect, nil); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// ParseWithDiagnostics parses SQL DDL for the requested dialect, reporting
+// problems as diagnostics located in filename instead of failing. A statement
+// that can't be applied is reported and skipped, and parsing continues with the
+// next one, so an editor can show every problem in a file at once. The schema
+// holds whatever could be parsed; it is empty when the SQL has a syntax error.
+func ParseWithDiagnostics(sql string, dialect database.Dialect, filename string) (*database.Schema, []Diagnostic) {
+	schema := newSchema(dialect)
+	diagnostics := []Diagnostic{}
+	for _, err := range parseSQLSchemaStatements(schema, sql, filename, dialect, nil, nil, false) {
+		d := parseErrorToDiagnostic(err, filename)
+		d.HelpURI = helpURI(d.Code)
+		diagnostics = append(diagnostics, d)
+	}
+	return schema, diagnostics
+}
+
+// parseSQLSchemaWithFilename parses SQL DDL for the requested dialect, applying
+// the statements to an in-progress schema. The filename is recorded in source
+// locations so diagnostics can point back at the file. If coverage is non-nil,
+// each statement is tallied in it.
+func parseSQLSchemaWithFilename(schema *database.Schema, sql string, filename string, dialect database.Dialect, coverage *Coverage) error {
+	if errs := parseSQLSchemaStatements(schema, sql, filename, dialect, coverage, nil, true); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// parseSQLSchemaStatements is parseSQLSchemaWithFilename returning every
+// failure. With stopOnError it stops at the first statement that fails;
+// otherwise that statement is skipped and the rest are still applied. The
+// parse tree is read from cache when it has one for the file.
+func parseSQLSchemaStatements(schema *database.Schema, sql string, filename string, dialect database.Dialect, coverage *Coverage, cache *parseCache, stopOnError bool) []error {
 	switch dialect {
 	case database.DialectPostgres:
-		return parsePostgresSQLSchema(sql)
+		return parsePostgresSQLSchemaWithFilename(schema, sql, filename, coverage, cache, stopOnError)
+	case database.DialectSQLite:
+		return parseSQLiteSQLSchemaWithFilename(schema, sql, filename, coverage, cache, stopOnError)
+	case database.DialectMySQL:
+		return parseMySQLSQLSchemaWithFilename(schema, sql, filename, coverage, cache, stopOnError)
 	default:
-		return nil, fmt.Errorf("unsupported dialect %v", dialect)
+		return []error{fmt.Errorf("unsupported dialect %v", dialect)}
+	}
+}
+
+// postgresSQL returns the SQL pg_query parses for schema files in dialect,
+// which are rewritten into PostgreSQL syntax first
+func postgresSQL(sql string, dialect database.Dialect) string {
+	switch dialect {
+	case database.DialectSQLite:
+		return rewriteSQLiteDDL(sql).sql
+	case database.DialectMySQL:
+		return rewriteMySQLDDL(sql).sql
 	}
+	return sql
+}
+
+// parsePostgresSQLSchemaWithFilename parses SQL DDL via pg_query for PostgreSQL
+// schemas, applying the statements to an in-progress schema. A syntax error
+// stops parsing; see parseSQLSchemaStatements for stopOnError.
+func parsePostgresSQLSchemaWithFilename(schema *database.Schema, sql string, filename string, coverage *Coverage, cache *parseCache, stopOnError bool) []error {
+	return parseLocatedSQLSchema(schema, sql, newLocator(sql, filename), coverage, cache, stopOnError)
 }
 
-// parsePostgresSQLSchema parses SQL DDL via pg_query for PostgreSQL schemas.
-func parsePostgresSQLSchema(sql string) (*database.Schema, error) {
+// parseLocatedSQLSchema is parsePostgresSQLSchemaWithFilename with the
+// locator for sql given, for rewritten SQL whose locations need adjusting
+func parseLocatedSQLSchema(schema *database.Schema, sql string, locate *locator, coverage *Coverage, cache *parseCache, stopOnError bool) []error {
 	// Parse the SQL
-	tree, err := pg_query.Parse(sql)
+	tree, err := cache.parse(locate.filename, sql)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SQL: %w", err)
+		return []error{locate.syntaxError(err)}
+	}
+
+	// Failures are kept with their offsets so they can be reported in source
+	// order, including those of deferred statements
+	type failure struct {
+		offset int32
+		err    error
+	}
+	var failures []failure
+	fail := func(offset int32, err error) bool {
+		failures = append(failures, failure{offset, locate.parseError(offset, err)})
+		return stopOnError
+	}
+	errs := func() []error {
+		slices.SortStableFunc(failures, func(a, b failure) int { return int(a.offset - b.offset) })
+		var errs []error
+		for _, f := range failures {
+			errs = append(errs, f.err)
+		}
+		return errs
 	}
 
-	schema := &database.Schema{
-		Tables:  []database.Table{},
-		Dialect: database.DialectPostgres,
+	// A statement on a table whose CREATE TABLE comes later, as happens when
+	// files are concatenated, is deferred until just after that CREATE TABLE.
+	// Everything else is applied in source order, so DROP TABLE and LIKE see
+	// the tables as they were at that point.
+	createdAt := tableCreations(tree.Stmts)
+	deferred := make(map[int][]int)
+	apply := func(i int) bool {
+		stmt := tree.Stmts[i]
+		modeled, tracked, err := applyStatement(schema, stmt, locate)
+		if err != nil {
+			return fail(stmt.StmtLocation, err)
+		}
+		if tracked {
+			coverage.recordTracked()
+		} else {
+			coverage.record(stmt.Stmt, modeled)
+		}
+		return false
 	}
 
-	// Walk the parse tree
-	for _, stmt := range tree.Stmts {
+	for i, stmt := range tree.Stmts {
 		if stmt.Stmt == nil {
 			continue
 		}
-
-		switch node := stmt.Stmt.Node.(type) {
-		case *pg_query.Node_CreateStmt:
-			table, err := parseCreateTable(node.CreateStmt)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse CREATE TABLE: %w", err)
+		if ref, ok := statementTable(stmt.Stmt); ok && findTableIndex(schema, ref.Schema, ref.Table) == -1 {
+			key := qualifiedTableName(&database.Table{Schema: ref.Schema, Name: ref.Table})
+			if j := slices.IndexFunc(createdAt[key], func(j int) bool { return j > i }); j != -1 {
+				create := createdAt[key][j]
+				deferred[create] = append(deferred[create], i)
+				continue
 			}
-			schema.Tables = append(schema.Tables, *table)
+		}
 
-		case *pg_query.Node_AlterTableStmt:
-			// Handle ALTER TABLE for RLS and other commands
-			err := parseAlterTable(schema, node.AlterTableStmt)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ALTER TABLE: %w", err)
+		if apply(i) {
+			return errs()
+		}
+		for _, d := range deferred[i] {
+			if apply(d) {
+				return errs()
 			}
+		}
+	}
+
+	return errs()
+}
+
+// tableCreations maps each table created by a CREATE TABLE statement, by
+// qualified name, to the indexes of the statements that create it
+func tableCreations(stmts []*pg_query.RawStmt) map[string][]int {
+	created := make(map[string][]int)
+	for i, stmt := range stmts {
+		if create := stmt.Stmt.GetCreateStmt(); create != nil && create.Relation != nil {
+			key := qualifiedTableName(&database.Table{Schema: create.Relation.Schemaname, Name: create.Relation.Relname})
+			created[key] = append(created[key], i)
+		}
+	}
+	return created
+}
 
-			// 	case *pg_query.Node_IndexStmt:
-			// 		// Handle CREATE INDEX separately (will add to existing table)
-			// 		err := parseCreateIndex(schema, node.IndexStmt)
-			// 		if err != nil {
-			// 			return nil, fmt.Errorf("failed to parse CREATE INDEX: %w", err)
-			// 		}
+// statementTable returns the table an ALTER TABLE, CREATE INDEX, CREATE
+// POLICY or COMMENT ON TABLE/COLUMN statement applies to
+func statementTable(stmt *pg_query.Node) (database.TableRef, bool) {
+	var relation *pg_query.RangeVar
+	switch node := stmt.GetNode().(type) {
+	case *pg_query.Node_AlterTableStmt:
+		relation = node.AlterTableStmt.Relation
+	case *pg_query.Node_IndexStmt:
+		relation = node.IndexStmt.Relation
+	case *pg_query.Node_CreatePolicyStmt:
+		relation = node.CreatePolicyStmt.Table
+	case *pg_query.Node_CommentStmt:
+		names := constraintKeys(node.CommentStmt.Object.GetList().GetItems())
+		if node.CommentStmt.Objtype == pg_query.ObjectType_OBJECT_COLUMN && len(names) > 0 {
+			names = names[:len(names)-1]
+		} else if node.CommentStmt.Objtype != pg_query.ObjectType_OBJECT_TABLE {
+			return database.TableRef{}, false
+		}
+		switch len(names) {
+		case 1:
+			return database.TableRef{Table: names[0]}, true
+		case 2:
+			return database.TableRef{Schema: names[0], Table: names[1]}, true
 		}
 	}
+	if relation == nil {
+		return database.TableRef{}, false
+	}
+	return database.TableRef{Schema: relation.Schemaname, Table: relation.Relname}, true
+}
 
-	return schema, nil
+// applyStatement applies one statement to the schema. modeled reports whether
+// the statement was fully applied, and tracked whether it was recorded in
+// Schema.OtherObjects instead.
+func applyStatement(schema *database.Schema, stmt *pg_query.RawStmt, locate *locator) (modeled bool, tracked bool, err error) {
+	switch node := stmt.Stmt.Node.(type) {
+	case *pg_query.Node_CreateStmt:
+		table, err := parseCreateTable(schema, node.CreateStmt, locate)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE TABLE: %w", err)
+		}
+		schema.Tables = append(schema.Tables, *table)
+		addPartition(schema, table)
+		return true, false, nil
+
+	case *pg_query.Node_AlterTableStmt:
+		modeled, err := parseAlterTable(schema, node.AlterTableStmt, locate)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse ALTER TABLE: %w", err)
+		}
+		return modeled, false, nil
+
+	case *pg_query.Node_IndexStmt:
+		modeled, err := parseCreateIndex(schema, node.IndexStmt, locate.statementSpan(stmt))
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE INDEX: %w", err)
+		}
+		return modeled, false, nil
+
+	case *pg_query.Node_DropStmt:
+		modeled, err := parseDropTable(schema, node.DropStmt)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse DROP TABLE: %w", err)
+		}
+		return modeled, false, nil
+
+	case *pg_query.Node_CommentStmt:
+		return parseComment(schema, node.CommentStmt), false, nil
+
+	case *pg_query.Node_CreatePolicyStmt:
+		modeled, err := parseCreatePolicy(schema, node.CreatePolicyStmt, locate.statementSpan(stmt))
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE POLICY: %w", err)
+		}
+		return modeled, false, nil
+
+	case *pg_query.Node_CreateDomainStmt:
+		domain, err := parseCreateDomain(node.CreateDomainStmt, locate.statementSpan(stmt), locate)
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE DOMAIN: %w", err)
+		}
+		schema.Domains = append(schema.Domains, *domain)
+		return true, false, nil
+
+	case *pg_query.Node_CreateEnumStmt:
+		enum, err := parseCreateEnum(node.CreateEnumStmt, locate.statementSpan(stmt))
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE TYPE: %w", err)
+		}
+		schema.Enums = append(schema.Enums, *enum)
+		return true, false, nil
+
+	case *pg_query.Node_CreateExtensionStmt:
+		extension, err := parseCreateExtension(node.CreateExtensionStmt, locate.statementSpan(stmt))
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE EXTENSION: %w", err)
+		}
+		schema.Extensions = append(schema.Extensions, *extension)
+		return true, false, nil
+
+	case *pg_query.Node_CreateSeqStmt:
+		sequence, err := parseCreateSequence(node.CreateSeqStmt, locate.statementSpan(stmt))
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE SEQUENCE: %w", err)
+		}
+		schema.Sequences = append(schema.Sequences, *sequence)
+		return true, false, nil
+
+	case *pg_query.Node_ViewStmt:
+		if err := parseCreateView(schema, node.ViewStmt, locate); err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE VIEW: %w", err)
+		}
+		return true, false, nil
+
+	case *pg_query.Node_CreateTableAsStmt:
+		if node.CreateTableAsStmt.Objtype != pg_query.ObjectType_OBJECT_MATVIEW {
+			return false, false, nil
+		}
+		if err := parseCreateMaterializedView(schema, node.CreateTableAsStmt, locate); err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE MATERIALIZED VIEW: %w", err)
+		}
+		return true, false, nil
+
+	case *pg_query.Node_CreateSchemaStmt:
+		modeled, err := parseCreateSchema(schema, node.CreateSchemaStmt, locate.statementSpan(stmt))
+		if err != nil {
+			return false, false, fmt.Errorf("failed to parse CREATE SCHEMA: %w", err)
+		}
+		return modeled, false, nil
+
+	case *pg_query.Node_DefineStmt:
+		if object := parseDefineStmt(node.DefineStmt, locate.statementSpan(stmt)); object != nil {
+			schema.OtherObjects = append(schema.OtherObjects, *object)
+			return false, true, nil
+		}
+	}
+	return false, false, nil
 }
 
-// parseCreateTable converts a CreateStmt AST node to a Table
-func parseCreateTable(stmt *pg_query.CreateStmt) (*database.Table, error) {
+// parseCreateTable converts a CreateStmt AST node to a Table. LIKE clauses are
+// resolved against the tables already in schema.
+func parseCreateTable(schema *database.Schema, stmt *pg_query.CreateStmt, locate *locator) (*database.Table, error) {
 	if stmt.Relation == nil {
 		return nil, fmt.Errorf("CREATE TABLE missing relation")
 	}
 
 	table := &database.Table{
-		Name:    stmt.Relation.Relname,
-		Schema:  stmt.Relation.Schemaname, // Extract schema name if specified
-		Columns: []database.Column{},
-		// Indexes:     []database.Index{},
+		Name:           stmt.Relation.Relname,
+		Schema:         stmt.Relation.Schemaname, // Extract schema name if specified
+		Columns:        []database.Column{},
+		RawName:        locate.identifierAt(stmt.Relation.Location),
+		RawSchema:      locate.qualifierAt(stmt.Relation.Location),
+		SourceLocation: locate.nameAt(stmt.Relation.Location),
 		// ForeignKeys: []database.ForeignKey{},
+		WithOids:    hasOidsOption(stmt.Options),
+		Options:     storageOptions(stmt.Options),
+		Persistence: tablePersistence(stmt.Relation.Relpersistence),
+		PartitionBy: partitionSpec(stmt.Partspec),
+	}
+
+	// PARTITION OF is reported as the only inherited relation, with a bound
+	for _, inh := range stmt.InhRelations {
+		parent := inh.GetRangeVar()
+		if parent == nil {
+			continue
+		}
+		if stmt.Partbound != nil {
+			table.PartitionOf = &database.TableRef{Schema: parent.Schemaname, Table: parent.Relname}
+		} else {
+			table.Inherits = append(table.Inherits, database.TableRef{Schema: parent.Schemaname, Table: parent.Relname})
+		}
 	}
 
-	// Parse columns and constraints
+	// Parse columns first; table constraints may reference columns declared
+	// after them, so they are applied once every column is known.
+	var constraints []*pg_query.Constraint
 	for _, elt := range stmt.TableElts {
 		if elt.Node == nil {
 			continue
@@ -86,197 +400,845 @@ func parseCreateTable(stmt *pg_query.CreateStmt) (*database.Table, error) {
 
 		switch node := elt.Node.(type) {
 		case *pg_query.Node_ColumnDef:
-			col, err := parseColumnDef(node.ColumnDef)
-			if err != nil {
+			if _, err := addColumn(table, node.ColumnDef, locate); err != nil {
 				return nil, err
 			}
-			table.Columns = append(table.Columns, *col)
 
-			// case *pg_query.Node_Constraint:
-			// 	err := parseTableConstraint(table, node.Constraint)
-			// 	if err != nil {
-			// 		return nil, err
-			// 	}
+		case *pg_query.Node_Constraint:
+			constraints = append(constraints, node.Constraint)
+
+		case *pg_query.Node_TableLikeClause:
+			if err := applyLikeClause(schema, table, node.TableLikeClause, locate); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, constraint := range constraints {
+		if err := parseTableConstraint(table, constraint, locate); err != nil {
+			return nil, err
 		}
 	}
 
 	return table, nil
 }
 
-// parseColumnDef converts a ColumnDef AST node to a Column
-func parseColumnDef(colDef *pg_query.ColumnDef) (*database.Column, error) {
-	if colDef.Colname == "" {
-		return nil, fmt.Errorf("column missing name")
+// addColumn adds a column definition to a table, along with the constraints
+// declared inline on the column
+func addColumn(table *database.Table, colDef *pg_query.ColumnDef, locate *locator) (*database.Column, error) {
+	col, err := parseColumnDef(colDef, locate)
+	if err != nil {
+		return nil, err
 	}
-
-	col := &database.Column{
-		Name:         colDef.Colname,
-		Nullable:     true, // Default to nullable unless NOT NULL is specified
-		IsPrimaryKey: false,
+	if col.Identity != nil && col.Identity.Sequence == "" {
+		col.Identity.Sequence = identitySequenceName(table.Name, col.Name)
+	}
+	table.Columns = append(table.Columns, *col)
+	if col.IsPrimaryKey {
+		table.PrimaryKey = append(table.PrimaryKey, col.Name)
+	}
+	// lastForeignKey is the foreign key constraint attributes apply to, if the
+	// previous constraint was one
+	lastForeignKey := -1
+	for _, cons := range colDef.Constraints {
+		c := cons.GetConstraint()
+		if c == nil {
+			continue
+		}
+		switch c.Contype {
+		case pg_query.ConstrType_CONSTR_ATTR_DEFERRABLE, pg_query.ConstrType_CONSTR_ATTR_NOT_DEFERRABLE,
+			pg_query.ConstrType_CONSTR_ATTR_DEFERRED, pg_query.ConstrType_CONSTR_ATTR_IMMEDIATE:
+			if lastForeignKey >= 0 {
+				applyConstraintAttr(&table.ForeignKeys[lastForeignKey], c.Contype)
+			}
+			continue
+		}
+		lastForeignKey = -1
+		switch c.Contype {
+		case pg_query.ConstrType_CONSTR_PRIMARY:
+			table.PrimaryKeyName = primaryKeyName(table.Name, c)
+		case pg_query.ConstrType_CONSTR_CHECK:
+			table.CheckConstraints = append(table.CheckConstraints, parseCheckConstraint(table, c, locate))
+		case pg_query.ConstrType_CONSTR_UNIQUE:
+			table.UniqueConstraints = append(table.UniqueConstraints, parseUniqueConstraint(table.Name, []string{col.Name}, c, locate))
+		case pg_query.ConstrType_CONSTR_FOREIGN:
+			table.ForeignKeys = append(table.ForeignKeys, parseForeignKey(table.Name, []string{col.Name}, c, locate))
+			lastForeignKey = len(table.ForeignKeys) - 1
+		}
 	}
+	return col, nil
+}
 
-	// Parse type
-	if colDef.TypeName != nil {
-		colType := formatTypeName(colDef.TypeName)
-		col.Type = colType
+// hasOidsOption reports whether the WITH options of a CREATE TABLE turn on
+// oids, as in WITH (OIDS) or WITH (oids = true). The bare WITH OIDS form is a
+// syntax error in the PostgreSQL 17 grammar.
+func hasOidsOption(options []*pg_query.Node) bool {
+	for _, option := range options {
+		def := option.GetDefElem()
+		if def == nil || !strings.EqualFold(def.Defname, "oids") {
+			continue
+		}
+		switch arg := def.Arg.GetNode().(type) {
+		case nil:
+			return true
+		case *pg_query.Node_String_:
+			return slices.Contains([]string{"true", "on", "yes", "1"}, strings.ToLower(arg.String_.Sval))
+		case *pg_query.Node_Integer:
+			return arg.Integer.Ival != 0
+		}
 	}
+	return false
+}
 
-	// Parse constraints (NOT NULL, DEFAULT, PRIMARY KEY, etc.)
-	for _, constraint := range colDef.Constraints {
-		if constraint.Node == nil {
+// storageOptions returns the storage parameters in a WITH (...) or SET (...)
+// list, or nil if there are none. OIDS isn't a storage parameter and is left
+// to hasOidsOption. A parameter given without a value is set to true.
+func storageOptions(options []*pg_query.Node) map[string]string {
+	var params map[string]string
+	for _, option := range options {
+		def := option.GetDefElem()
+		if def == nil || (def.Defnamespace == "" && strings.EqualFold(def.Defname, "oids")) {
 			continue
 		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[storageOptionName(def)] = storageOptionValue(def.Arg)
+	}
+	return params
+}
 
-		if cons, ok := constraint.Node.(*pg_query.Node_Constraint); ok {
-			parseColumnConstraint(col, cons.Constraint)
+// partitionStrategies maps pg_query's partitioning strategies to the
+// strategies recorded in PartitionSpec
+var partitionStrategies = map[pg_query.PartitionStrategy]database.PartitionStrategy{
+	pg_query.PartitionStrategy_PARTITION_STRATEGY_RANGE: database.PartitionStrategyRange,
+	pg_query.PartitionStrategy_PARTITION_STRATEGY_LIST:  database.PartitionStrategyList,
+	pg_query.PartitionStrategy_PARTITION_STRATEGY_HASH:  database.PartitionStrategyHash,
+}
+
+// partitionSpec converts a PARTITION BY clause, or returns nil for a table
+// that isn't partitioned
+func partitionSpec(spec *pg_query.PartitionSpec) *database.PartitionSpec {
+	if spec == nil {
+		return nil
+	}
+	partitionBy := &database.PartitionSpec{Strategy: partitionStrategies[spec.Strategy], Columns: []string{}}
+	for _, param := range spec.PartParams {
+		elem := param.GetPartitionElem()
+		if elem == nil {
+			continue
+		}
+		if elem.Name != "" {
+			partitionBy.Columns = append(partitionBy.Columns, elem.Name)
+		} else {
+			partitionBy.Columns = append(partitionBy.Columns, formatExpr(elem.Expr))
 		}
 	}
+	return partitionBy
+}
 
-	return col, nil
+// tablePersistence returns the persistence of a relation created with the
+// given relpersistence: p for ordinary tables, u for UNLOGGED and t for
+// TEMPORARY
+func tablePersistence(relpersistence string) database.TablePersistence {
+	switch relpersistence {
+	case "u":
+		return database.TablePersistenceUnlogged
+	case "t":
+		return database.TablePersistenceTemporary
+	}
+	return ""
 }
 
-// formatTypeName converts TypeName AST to a string representation with metadata.
-func formatTypeName(typeName *pg_query.TypeName) string {
-	if len(typeName.Names) == 0 {
-		return ""
+// storageOptionName returns a storage parameter's name, prefixed with its
+// namespace if it has one
+func storageOptionName(def *pg_query.DefElem) string {
+	if def.Defnamespace != "" {
+		return def.Defnamespace + "." + def.Defname
 	}
+	return def.Defname
+}
 
-	// Get the type name (last element in Names)
-	var parts []string
-	for _, name := range typeName.Names {
-		if nameNode, ok := name.Node.(*pg_query.Node_String_); ok {
-			parts = append(parts, nameNode.String_.Sval)
+// storageOptionValue renders a storage parameter's value
+func storageOptionValue(arg *pg_query.Node) string {
+	switch arg := arg.GetNode().(type) {
+	case nil:
+		return "true"
+	case *pg_query.Node_String_:
+		return arg.String_.Sval
+	case *pg_query.Node_Integer:
+		return strconv.Itoa(int(arg.Integer.Ival))
+	case *pg_query.Node_Float:
+		return arg.Float.Fval
+	case *pg_query.Node_Boolean:
+		return strconv.FormatBool(arg.Boolean.Boolval)
+	}
+	return formatExpr(arg)
+}
+
+// setStorageOptions applies ALTER TABLE ... SET (...)
+func setStorageOptions(table *database.Table, cmd *pg_query.AlterTableCmd) error {
+	list := cmd.Def.GetList()
+	if list == nil {
+		return fmt.Errorf("SET missing storage parameters")
+	}
+	for name, value := range storageOptions(list.Items) {
+		if table.Options == nil {
+			table.Options = make(map[string]string)
 		}
+		table.Options[name] = value
 	}
+	return nil
+}
 
-	rawBase := strings.Join(parts, ".")
-	typeStr := rawBase
+// resetStorageOptions applies ALTER TABLE ... RESET (...), returning the
+// parameters to their defaults
+func resetStorageOptions(table *database.Table, cmd *pg_query.AlterTableCmd) error {
+	list := cmd.Def.GetList()
+	if list == nil {
+		return fmt.Errorf("RESET missing storage parameters")
+	}
+	for _, item := range list.Items {
+		if def := item.GetDefElem(); def != nil {
+			delete(table.Options, storageOptionName(def))
+		}
+	}
+	if len(table.Options) == 0 {
+		table.Options = nil
+	}
+	return nil
+}
 
-	if len(parts) > 1 && parts[0] == "pg_catalog" {
-		typeStr = parts[len(parts)-1]
+// parseTableConstraint applies a table-level constraint (e.g. PRIMARY KEY (a, b))
+// to a Table
+func parseTableConstraint(table *database.Table, constraint *pg_query.Constraint, locate *locator) error {
+	// A constraint added USING INDEX takes its columns from the index
+	if constraint.Indexname != "" {
+		return addConstraintUsingIndex(table, constraint, locate)
 	}
 
-	// Normalize PostgreSQL internal types to standard SQL types
-	typeStr = normalizePostgreSQLType(typeStr)
+	switch constraint.Contype {
+	case pg_query.ConstrType_CONSTR_PRIMARY:
+		keys := constraintKeys(constraint.Keys)
+		for _, key := range keys {
+			col := findColumn(table, key)
+			if col == nil {
+				return fmt.Errorf("primary key column %q does not exist in table %q", key, table.Name)
+			}
+			col.IsPrimaryKey = true
+			col.Nullable = false // PRIMARY KEY implies NOT NULL
+		}
+		table.PrimaryKey = keys
+		table.PrimaryKeyName = primaryKeyName(table.Name, constraint)
+
+	case pg_query.ConstrType_CONSTR_CHECK:
+		table.CheckConstraints = append(table.CheckConstraints, parseCheckConstraint(table, constraint, locate))
 
-	// Add type modifiers (e.g., VARCHAR(255))
-	if len(typeName.Typmods) > 0 {
-		var mods []string
-		for _, mod := range typeName.Typmods {
-			if constNode, ok := mod.Node.(*pg_query.Node_AConst); ok {
-				if ival := constNode.AConst.GetIval(); ival != nil {
-					mods = append(mods, fmt.Sprintf("%d", ival.Ival))
-				}
+	case pg_query.ConstrType_CONSTR_UNIQUE:
+		keys := constraintKeys(constraint.Keys)
+		for _, key := range keys {
+			if findColumn(table, key) == nil {
+				return fmt.Errorf("unique column %q does not exist in table %q", key, table.Name)
 			}
 		}
-		if len(mods) > 0 {
-			modStr := strings.Join(mods, ",")
-			typeStr = fmt.Sprintf("%s(%s)", typeStr, modStr)
+		table.UniqueConstraints = append(table.UniqueConstraints, parseUniqueConstraint(table.Name, keys, constraint, locate))
+
+	case pg_query.ConstrType_CONSTR_FOREIGN:
+		columns := constraintKeys(constraint.FkAttrs)
+		for _, column := range columns {
+			if findColumn(table, column) == nil {
+				return fmt.Errorf("foreign key column %q does not exist in table %q", column, table.Name)
+			}
+		}
+		table.ForeignKeys = append(table.ForeignKeys, parseForeignKey(table.Name, columns, constraint, locate))
+
+	case pg_query.ConstrType_CONSTR_EXCLUSION:
+		exclusion, err := parseExclusionConstraint(table, constraint, locate)
+		if err != nil {
+			return err
 		}
+		table.ExclusionConstraints = append(table.ExclusionConstraints, exclusion)
 	}
 
-	// Add array notation if needed
-	if len(typeName.ArrayBounds) > 0 {
-		typeStr += "[]"
+	return nil
+}
+
+// parseExclusionConstraint converts an EXCLUDE constraint to an
+// ExclusionConstraint. Unnamed constraints get the name PostgreSQL would
+// generate: <table>_<columns>_excl, with "expr" for expression elements.
+func parseExclusionConstraint(table *database.Table, constraint *pg_query.Constraint, locate *locator) (database.ExclusionConstraint, error) {
+	exclusion := database.ExclusionConstraint{
+		Name:           constraint.Conname,
+		Method:         constraint.AccessMethod,
+		SourceLocation: locate.at(constraint.Location),
+	}
+	if exclusion.Method == "" {
+		exclusion.Method = "btree"
 	}
 
-	return typeStr
+	nameParts := []string{table.Name}
+	for _, item := range constraint.Exclusions {
+		// Each item pairs an index element with its operator name
+		pair := item.GetList().GetItems()
+		if len(pair) != 2 || pair[0].GetIndexElem() == nil {
+			return exclusion, fmt.Errorf("unexpected EXCLUDE element")
+		}
+		elem := pair[0].GetIndexElem()
+
+		element := database.ExclusionElement{
+			Column:   elem.Name,
+			OpClass:  strings.Join(constraintKeys(elem.Opclass), "."),
+			Operator: strings.Join(constraintKeys(pair[1].GetList().GetItems()), "."),
+		}
+		if element.Column != "" {
+			if findColumn(table, element.Column) == nil {
+				return exclusion, fmt.Errorf("exclusion column %q does not exist in table %q", element.Column, table.Name)
+			}
+			nameParts = append(nameParts, element.Column)
+		} else {
+			element.Expression = formatExpr(elem.Expr)
+			nameParts = append(nameParts, "expr")
+		}
+		exclusion.Elements = append(exclusion.Elements, element)
+	}
+
+	if expr := buildExpr(constraint.WhereClause); expr != nil {
+		exclusion.Where = expr.String()
+	}
+	if exclusion.Name == "" {
+		exclusion.Name = strings.Join(append(nameParts, "excl"), "_")
+	}
+	return exclusion, nil
 }
 
-var typeMap = map[string]string{
-	// Integer types
-	"int2":    "smallint",
-	"int4":    "integer",
-	"int8":    "bigint",
-	"serial":  "serial",
-	"serial2": "smallserial",
-	"serial4": "serial",
-	"serial8": "bigserial",
+// addConstraintUsingIndex applies ADD PRIMARY KEY USING INDEX or ADD CONSTRAINT
+// ... UNIQUE USING INDEX, which turn an existing unique index into a
+// constraint. Unnamed constraints take the index's name.
+func addConstraintUsingIndex(table *database.Table, constraint *pg_query.Constraint, locate *locator) error {
+	var index *database.Index
+	for i := range table.Indexes {
+		if table.Indexes[i].Name == constraint.Indexname {
+			index = &table.Indexes[i]
+			break
+		}
+	}
+	if index == nil {
+		return fmt.Errorf("index %q does not exist on table %q", constraint.Indexname, table.Name)
+	}
+	if !index.Unique || len(index.Expressions) > 0 {
+		return fmt.Errorf("index %q is not a unique index on columns", index.Name)
+	}
 
-	// Boolean
-	"bool": "boolean",
+	switch constraint.Contype {
+	case pg_query.ConstrType_CONSTR_PRIMARY:
+		for _, key := range index.Columns {
+			col := findColumn(table, key)
+			if col == nil {
+				return fmt.Errorf("primary key column %q does not exist in table %q", key, table.Name)
+			}
+			col.IsPrimaryKey = true
+			col.Nullable = false // PRIMARY KEY implies NOT NULL
+		}
+		table.PrimaryKey = slices.Clone(index.Columns)
+		table.PrimaryKeyIndex = index.Name
+		table.PrimaryKeyName = constraint.Conname
+		if table.PrimaryKeyName == "" {
+			table.PrimaryKeyName = index.Name
+		}
 
-	// Character types
-	"varchar": "varchar",
-	"bpchar":  "char",
+	case pg_query.ConstrType_CONSTR_UNIQUE:
+		name := constraint.Conname
+		if name == "" {
+			name = index.Name
+		}
+		table.UniqueConstraints = append(table.UniqueConstraints, database.UniqueConstraint{
+			Name:           name,
+			Columns:        slices.Clone(index.Columns),
+			Index:          index.Name,
+			SourceLocation: locate.at(constraint.Location),
+		})
 
-	// Floating point
-	"float4": "real",
-	"float8": "double precision",
+	default:
+		return fmt.Errorf("only PRIMARY KEY and UNIQUE constraints can use an index")
+	}
+	return nil
+}
 
-	// Timestamp types
-	"timestamp":   "timestamp without time zone",
-	"timestamptz": "timestamp with time zone",
-	"time":        "time without time zone",
-	"timetz":      "time with time zone",
+// parseUniqueConstraint converts a UNIQUE constraint on columns to a
+// UniqueConstraint, named <table>_<columns>_key unless a name was given
+func parseUniqueConstraint(tableName string, columns []string, constraint *pg_query.Constraint, locate *locator) database.UniqueConstraint {
+	name := constraint.Conname
+	if name == "" {
+		name = uniqueConstraintName(tableName, columns)
+	}
+	return database.UniqueConstraint{
+		Name:           name,
+		Columns:        columns,
+		SourceLocation: locate.at(constraint.Location),
+	}
+}
 
-	// Text (keep as-is, but explicitly map)
-	"text": "text",
+// primaryKeyName returns the name of a primary key constraint, which
+// PostgreSQL names <table>_pkey unless a name was given
+func primaryKeyName(tableName string, constraint *pg_query.Constraint) string {
+	if constraint.Conname != "" {
+		return constraint.Conname
+	}
+	return tableName + "_pkey"
+}
 
-	// Numeric
-	"numeric": "numeric",
-	"decimal": "decimal",
+// uniqueConstraintName returns the name PostgreSQL gives an unnamed unique
+// constraint
+func uniqueConstraintName(tableName string, columns []string) string {
+	return fmt.Sprintf("%s_%s_key", tableName, strings.Join(columns, "_"))
 }
 
-// normalizePostgreSQLType converts PostgreSQL internal type names to standard SQL types
-// This is necessary because we use pg_query (PostgreSQL parser) for all SQL parsing,
-// and it normalizes types to PostgreSQL internal names like "int4", "int8", "bool", etc.
-func normalizePostgreSQLType(pgType string) string {
-	// Map PostgreSQL internal types to standard SQL types
-	if normalized, ok := typeMap[strings.ToLower(pgType)]; ok {
-		return normalized
+// parseForeignKey converts a FOREIGN KEY or REFERENCES constraint on columns
+// to a ForeignKey, named <table>_<columns>_fkey unless a name was given
+func parseForeignKey(tableName string, columns []string, constraint *pg_query.Constraint, locate *locator) database.ForeignKey {
+	name := constraint.Conname
+	if name == "" {
+		name = fmt.Sprintf("%s_%s_fkey", tableName, strings.Join(columns, "_"))
 	}
 
-	return pgType
+	fk := database.ForeignKey{
+		Name:              name,
+		Columns:           columns,
+		ReferencedColumns: constraintKeys(constraint.PkAttrs),
+		OnDelete:          foreignKeyActions[constraint.FkDelAction],
+		OnDeleteColumns:   constraintKeys(constraint.FkDelSetCols),
+		OnUpdate:          foreignKeyActions[constraint.FkUpdAction],
+		Match:             foreignKeyMatches[constraint.FkMatchtype],
+		Deferrable:        constraint.Deferrable || constraint.Initdeferred,
+		InitiallyDeferred: constraint.Initdeferred,
+		SourceLocation:    locate.at(constraint.Location),
+	}
+	if constraint.Pktable != nil {
+		fk.ReferencedSchema = constraint.Pktable.Schemaname
+		fk.ReferencedTable = constraint.Pktable.Relname
+	}
+	return fk
 }
 
-// parseColumnConstraint applies a column-level constraint to a Column
-func parseColumnConstraint(col *database.Column, constraint *pg_query.Constraint) {
-	switch constraint.Contype {
-	case pg_query.ConstrType_CONSTR_NOTNULL:
-		col.Nullable = false
+// foreignKeyActions maps pg_query's referential action codes to the actions
+// recorded in ForeignKey. NO ACTION ("a") is the default and isn't recorded.
+var foreignKeyActions = map[string]database.FKAction{
+	"r": database.FKActionRestrict,
+	"c": database.FKActionCascade,
+	"n": database.FKActionSetNull,
+	"d": database.FKActionSetDefault,
+}
 
-	case pg_query.ConstrType_CONSTR_NULL:
-		col.Nullable = true
+// foreignKeyMatches maps pg_query's MATCH type codes to the types recorded in
+// ForeignKey. MATCH SIMPLE ("s") is the default and isn't recorded.
+var foreignKeyMatches = map[string]database.FKMatch{
+	"f": database.FKMatchFull,
+	"p": database.FKMatchPartial,
+}
 
-	case pg_query.ConstrType_CONSTR_DEFAULT:
-		if constraint.RawExpr != nil {
-			// Format the default expression
-			defaultStr := formatExpr(constraint.RawExpr)
-			col.Default = &defaultStr
+// applyConstraintAttr applies a DEFERRABLE or INITIALLY DEFERRED attribute
+// following a column's foreign key constraint, which pg_query reports as a
+// separate constraint. INITIALLY DEFERRED implies DEFERRABLE, as in
+// PostgreSQL.
+func applyConstraintAttr(fk *database.ForeignKey, attr pg_query.ConstrType) {
+	switch attr {
+	case pg_query.ConstrType_CONSTR_ATTR_DEFERRABLE:
+		fk.Deferrable = true
+	case pg_query.ConstrType_CONSTR_ATTR_NOT_DEFERRABLE:
+		fk.Deferrable = false
+	case pg_query.ConstrType_CONSTR_ATTR_DEFERRED:
+		fk.Deferrable = true
+		fk.InitiallyDeferred = true
+	case pg_query.ConstrType_CONSTR_ATTR_IMMEDIATE:
+		fk.InitiallyDeferred = false
+	}
+}
+
+// parseCheckConstraint converts a CHECK constraint on table to a
+// CheckConstraint. Unnamed constraints get the name PostgreSQL generates,
+// whether they are declared on a column or on the table: <table>_<column>_check
+// when the expression references exactly one column, and <table>_check
+// otherwise, numbered when the table already has a constraint of that name.
+func parseCheckConstraint(table *database.Table, constraint *pg_query.Constraint, locate *locator) database.CheckConstraint {
+	expr := buildExpr(constraint.RawExpr)
+
+	name := constraint.Conname
+	if name == "" {
+		var columns []string
+		for _, ref := range exprColumnRefs(expr) {
+			ref = ref[strings.LastIndex(ref, ".")+1:]
+			if !slices.Contains(columns, ref) {
+				columns = append(columns, ref)
+			}
+		}
+		column := ""
+		if len(columns) == 1 {
+			column = columns[0]
 		}
+		name = chooseConstraintName(table, column, "check")
+	}
 
-	case pg_query.ConstrType_CONSTR_PRIMARY:
-		col.IsPrimaryKey = true
-		col.Nullable = false // PRIMARY KEY implies NOT NULL
+	check := database.CheckConstraint{
+		Name:           name,
+		Expr:           expr,
+		SourceLocation: locate.at(constraint.Location),
 	}
+	if expr != nil {
+		check.Expression = expr.String()
+	}
+	return check
 }
 
-// formatExpr converts an expression AST to string
-func formatExpr(node *pg_query.Node) string {
-	if node == nil {
-		return ""
+// chooseConstraintName returns the name PostgreSQL generates for an unnamed
+// constraint: <table>_<column>_<label>, or <table>_<label> without a column.
+// If the table already has a constraint of that name, the label is numbered
+// from 1 until the name is free.
+func chooseConstraintName(table *database.Table, column string, label string) string {
+	base := table.Name
+	if column != "" {
+		base += "_" + column
+	}
+	name := base + "_" + label
+	for pass := 1; hasConstraintNamed(table, name); pass++ {
+		name = fmt.Sprintf("%s_%s%d", base, label, pass)
 	}
+	return name
+}
 
-	switch expr := node.Node.(type) {
-	case *pg_query.Node_AConst:
-		// Check different types of constants
-		if ival := expr.AConst.GetIval(); ival != nil {
-			return fmt.Sprintf("%d", ival.Ival)
+// hasConstraintNamed reports whether the table has a constraint called name
+func hasConstraintNamed(table *database.Table, name string) bool {
+	if table.PrimaryKeyName == name {
+		return true
+	}
+	for _, check := range table.CheckConstraints {
+		if check.Name == name {
+			return true
 		}
-		if fval := expr.AConst.GetFval(); fval != nil {
-			return fval.Fval
+	}
+	for _, unique := range table.UniqueConstraints {
+		if unique.Name == name {
+			return true
 		}
-		if sval := expr.AConst.GetSval(); sval != nil {
-			return fmt.Sprintf("'%s'", sval.Sval)
+	}
+	for _, fk := range table.ForeignKeys {
+		if fk.Name == name {
+			return true
 		}
-		if bsval := expr.AConst.GetBsval(); bsval != nil {
-			return bsval.Bsval
+	}
+	for _, exclusion := range table.ExclusionConstraints {
+		if exclusion.Name == name {
+			return true
 		}
-		if boolval := expr.AConst.GetBoolval(); boolval != nil {
-			return fmt.Sprintf("%t", boolval.Boolval)
+	}
+	return false
+}
+
+// constraintKeys extracts the column names from a constraint's key list
+func constraintKeys(keys []*pg_query.Node) []string {
+	var names []string
+	for _, key := range keys {
+		if nameNode, ok := key.Node.(*pg_query.Node_String_); ok {
+			names = append(names, nameNode.String_.Sval)
 		}
-		if nullval := expr.AConst.GetIsnull(); nullval {
+	}
+	return names
+}
+
+// findColumn returns a pointer to the named column in the table, or nil
+func findColumn(table *database.Table, name string) *database.Column {
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
+
+// parseColumnDef converts a ColumnDef AST node to a Column
+func parseColumnDef(colDef *pg_query.ColumnDef, locate *locator) (*database.Column, error) {
+	if colDef.Colname == "" {
+		return nil, fmt.Errorf("column missing name")
+	}
+
+	col := &database.Column{
+		Name:           colDef.Colname,
+		Nullable:       true, // Default to nullable unless NOT NULL is specified
+		IsPrimaryKey:   false,
+		RawName:        locate.identifierAt(colDef.Location),
+		SourceLocation: locate.nameAt(colDef.Location),
+	}
+
+	// Parse type
+	if colDef.TypeName != nil {
+		colType := formatTypeName(colDef.TypeName)
+		col.Type = colType
+		col.Precision, col.Scale, col.Length = database.TypeModifiers(colType)
+		col.ArrayDims = database.ArrayDims(colType)
+	}
+
+	// Parse constraints (NOT NULL, DEFAULT, PRIMARY KEY, etc.)
+	for _, constraint := range colDef.Constraints {
+		if constraint.Node == nil {
+			continue
+		}
+
+		if cons, ok := constraint.Node.(*pg_query.Node_Constraint); ok {
+			parseColumnConstraint(col, cons.Constraint)
+		}
+	}
+
+	// Boolean defaults can be spelled several ways (true, 't', 1, 'yes', ...).
+	// Canonicalize them so they compare equal to an introspected database.
+	if col.Type == "boolean" && col.Default != nil {
+		normalized := normalizeBooleanDefault(*col.Default)
+		col.Default = &normalized
+	}
+
+	return col, nil
+}
+
+// intervalFields maps the masks PostgreSQL records for the fields of an
+// interval type, as in INTERVAL DAY TO SECOND, to the fields. An interval
+// with all fields has a mask of its own, and no fields are written.
+var intervalFields = map[int]string{
+	1 << 2:                       "year",
+	1 << 1:                       "month",
+	1 << 3:                       "day",
+	1 << 10:                      "hour",
+	1 << 11:                      "minute",
+	1 << 12:                      "second",
+	1<<2 | 1<<1:                  "year to month",
+	1<<3 | 1<<10:                 "day to hour",
+	1<<3 | 1<<10 | 1<<11:         "day to minute",
+	1<<3 | 1<<10 | 1<<11 | 1<<12: "day to second",
+	1<<10 | 1<<11:                "hour to minute",
+	1<<10 | 1<<11 | 1<<12:        "hour to second",
+	1<<11 | 1<<12:                "minute to second",
+}
+
+// formatTypeName converts TypeName AST to a string representation with metadata.
+func formatTypeName(typeName *pg_query.TypeName) string {
+	if len(typeName.Names) == 0 {
+		return ""
+	}
+
+	// Get the type name (last element in Names)
+	var parts []string
+	for _, name := range typeName.Names {
+		if nameNode, ok := name.Node.(*pg_query.Node_String_); ok {
+			parts = append(parts, nameNode.String_.Sval)
+		}
+	}
+
+	var typeStr string
+	if len(parts) > 1 && parts[0] == "pg_catalog" {
+		// Normalize PostgreSQL internal types to standard SQL types
+		typeStr = database.NormalizePostgreSQLType(parts[len(parts)-1])
+	} else {
+		// Other types keep their schema, and names that need quoting keep
+		// their quotes, so "MyType" stays distinct from mytype. Only plain
+		// names are normalized.
+		quoted := make([]string, len(parts))
+		for i, part := range parts {
+			quoted[i] = quoteIdent(part)
+		}
+		typeStr = strings.Join(quoted, ".")
+		if len(parts) == 1 && quoted[0] == parts[0] {
+			typeStr = database.NormalizePostgreSQLType(typeStr)
+		}
+	}
+
+	// Add type modifiers (e.g., VARCHAR(255)). Besides numbers, extension
+	// types may take keywords (geometry(Point,4326)) or strings.
+	var mods []string
+	for _, mod := range typeName.Typmods {
+		switch node := mod.Node.(type) {
+		case *pg_query.Node_AConst:
+			if ival := node.AConst.GetIval(); ival != nil {
+				mods = append(mods, fmt.Sprintf("%d", ival.Ival))
+			} else if sval := node.AConst.GetSval(); sval != nil {
+				mods = append(mods, quoteLiteral(sval.Sval))
+			}
+		case *pg_query.Node_ColumnRef:
+			mods = append(mods, formatExpr(mod))
+		}
+	}
+	if typeStr == "interval" && len(mods) > 0 {
+		// An interval's first modifier is the mask of the fields it is
+		// restricted to, as in INTERVAL DAY TO SECOND, and the second its
+		// precision
+		if mask, err := strconv.Atoi(mods[0]); err == nil && intervalFields[mask] != "" {
+			typeStr += " " + intervalFields[mask]
+		}
+		mods = mods[1:]
+	}
+	if len(mods) > 0 {
+		typeStr = database.WithTypeModifiers(typeStr, "("+strings.Join(mods, ",")+")")
+	}
+
+	// Add array notation, one pair of brackets per dimension, keeping any
+	// declared size (e.g. INT[3][])
+	for _, bound := range typeName.ArrayBounds {
+		if size := bound.GetInteger().GetIval(); size >= 0 {
+			typeStr += fmt.Sprintf("[%d]", size)
+		} else {
+			typeStr += "[]"
+		}
+	}
+
+	return typeStr
+}
+
+// booleanLiterals maps the accepted PostgreSQL boolean input spellings to their
+// canonical form. See https://www.postgresql.org/docs/current/datatype-boolean.html
+var booleanLiterals = map[string]string{
+	"true":  "true",
+	"t":     "true",
+	"yes":   "true",
+	"y":     "true",
+	"on":    "true",
+	"1":     "true",
+	"false": "false",
+	"f":     "false",
+	"no":    "false",
+	"n":     "false",
+	"off":   "false",
+	"0":     "false",
+}
+
+// normalizeBooleanDefault canonicalizes a boolean default expression to true or
+// false. Expressions that aren't boolean literals (e.g. function calls) are
+// returned unchanged.
+func normalizeBooleanDefault(def string) string {
+	literal := strings.TrimSpace(def)
+	if len(literal) >= 2 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") {
+		literal = strings.TrimSpace(literal[1 : len(literal)-1])
+	}
+
+	if canonical, ok := booleanLiterals[strings.ToLower(literal)]; ok {
+		return canonical
+	}
+
+	return def
+}
+
+// parseColumnConstraint applies a column-level constraint to a Column
+func parseColumnConstraint(col *database.Column, constraint *pg_query.Constraint) {
+	switch constraint.Contype {
+	case pg_query.ConstrType_CONSTR_NOTNULL:
+		col.Nullable = false
+		col.NotNullExplicit = true
+
+	case pg_query.ConstrType_CONSTR_NULL:
+		col.Nullable = true
+		col.NotNullExplicit = false
+
+	case pg_query.ConstrType_CONSTR_DEFAULT:
+		if constraint.RawExpr != nil {
+			// Format the default expression
+			defaultStr := formatExpr(constraint.RawExpr)
+			col.Default = &defaultStr
+		}
+
+	case pg_query.ConstrType_CONSTR_PRIMARY:
+		col.IsPrimaryKey = true
+		col.Nullable = false // PRIMARY KEY implies NOT NULL
+
+	case pg_query.ConstrType_CONSTR_IDENTITY:
+		col.Identity = parseIdentity(constraint)
+		col.Nullable = false // identity columns are implicitly NOT NULL
+
+	case pg_query.ConstrType_CONSTR_GENERATED:
+		// The parser only accepts STORED, which it doesn't record. The
+		// expression is rendered like a CHECK, since formatExpr only handles
+		// the simple expressions used as defaults.
+		col.Generated = &database.GeneratedColumn{
+			Expression: generationExpression(constraint.RawExpr),
+			Storage:    database.GeneratedStored,
+		}
+	}
+}
+
+// parseIdentity converts a GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY
+// constraint to an IdentitySpec, including any sequence options
+func parseIdentity(constraint *pg_query.Constraint) *database.IdentitySpec {
+	identity := &database.IdentitySpec{
+		Always: constraint.GeneratedWhen == "a",
+	}
+	for _, option := range constraint.Options {
+		def := option.GetDefElem()
+		if def == nil {
+			continue
+		}
+		switch def.Defname {
+		case "sequence_name":
+			if list, ok := def.Arg.GetNode().(*pg_query.Node_List); ok {
+				identity.Sequence = strings.Join(constraintKeys(list.List.Items), ".")
+			}
+		case "start":
+			identity.Start = sequenceOptionValue(def.Arg)
+		case "increment":
+			identity.Increment = sequenceOptionValue(def.Arg)
+		case "minvalue":
+			identity.MinValue = sequenceOptionValue(def.Arg)
+		case "maxvalue":
+			identity.MaxValue = sequenceOptionValue(def.Arg)
+		case "cache":
+			identity.Cache = sequenceOptionValue(def.Arg)
+		case "cycle":
+			identity.Cycle = def.Arg.GetBoolean().GetBoolval()
+		}
+	}
+	return identity
+}
+
+// sequenceOptionValue returns the value of a numeric sequence option, or nil
+// for NO MINVALUE and NO MAXVALUE, which have no argument. Values too large for
+// an int4 are reported as floats.
+func sequenceOptionValue(arg *pg_query.Node) *int64 {
+	switch v := arg.GetNode().(type) {
+	case *pg_query.Node_Integer:
+		n := int64(v.Integer.Ival)
+		return &n
+	case *pg_query.Node_Float:
+		if n, err := strconv.ParseInt(v.Float.Fval, 10, 64); err == nil {
+			return &n
+		}
+	}
+	return nil
+}
+
+// identitySequenceName returns the name PostgreSQL gives the sequence of an
+// identity column declared without SEQUENCE NAME
+func identitySequenceName(tableName string, columnName string) string {
+	return fmt.Sprintf("%s_%s_seq", tableName, columnName)
+}
+
+// formatExpr converts an expression AST to string
+func formatExpr(node *pg_query.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	switch expr := node.Node.(type) {
+	case *pg_query.Node_AConst:
+		// Check different types of constants
+		if ival := expr.AConst.GetIval(); ival != nil {
+			return fmt.Sprintf("%d", ival.Ival)
+		}
+		if fval := expr.AConst.GetFval(); fval != nil {
+			return fval.Fval
+		}
+		if sval := expr.AConst.GetSval(); sval != nil {
+			return fmt.Sprintf("'%s'", sval.Sval)
+		}
+		if bsval := expr.AConst.GetBsval(); bsval != nil {
+			return bsval.Bsval
+		}
+		if boolval := expr.AConst.GetBoolval(); boolval != nil {
+			return fmt.Sprintf("%t", boolval.Boolval)
+		}
+		if nullval := expr.AConst.GetIsnull(); nullval {
 			return "NULL"
 		}
 
@@ -300,6 +1262,42 @@ func formatExpr(node *pg_query.Node) string {
 			}
 		}
 
+	case *pg_query.Node_ColumnRef:
+		// Handle column references like email or users.email
+		var fields []string
+		for _, field := range expr.ColumnRef.Fields {
+			switch f := field.Node.(type) {
+			case *pg_query.Node_String_:
+				fields = append(fields, f.String_.Sval)
+			case *pg_query.Node_AStar:
+				fields = append(fields, "*")
+			}
+		}
+		if len(fields) > 0 {
+			return strings.Join(fields, ".")
+		}
+
+	case *pg_query.Node_AExpr:
+		// Handle unary minus and plus. The parser folds them into numeric
+		// constants (-1, -2.5), but not when the operand is cast or
+		// parenthesized, e.g. -1::integer.
+		if e := expr.AExpr; e.Kind == pg_query.A_Expr_Kind_AEXPR_OP && e.Lexpr == nil && e.Rexpr != nil && len(e.Name) == 1 {
+			if op, ok := e.Name[0].Node.(*pg_query.Node_String_); ok && (op.String_.Sval == "-" || op.String_.Sval == "+") {
+				operand := formatExpr(e.Rexpr)
+				if operand == "UNDEFINED_EXPRESSION" {
+					return operand
+				}
+				if op.String_.Sval == "+" {
+					return operand
+				}
+				// Double negation: -(-1)
+				if negated, ok := strings.CutPrefix(operand, "-"); ok {
+					return negated
+				}
+				return "-" + operand
+			}
+		}
+
 	case *pg_query.Node_TypeCast:
 		// Handle type casts
 		if expr.TypeCast.Arg != nil {
@@ -348,58 +1346,815 @@ func formatExpr(node *pg_query.Node) string {
 	return "UNDEFINED_EXPRESSION"
 }
 
-// parseAlterTable handles ALTER TABLE statements, currently focusing on RLS
-func parseAlterTable(schema *database.Schema, stmt *pg_query.AlterTableStmt) error {
+// parseAlterTable applies ALTER TABLE statements to the table they alter,
+// which must already have been created. Files are parsed in order, so the
+// CREATE TABLE has to come first, either earlier in the same file or in an
+// earlier file. ALTER TABLE IF EXISTS on a missing table is skipped.
+func parseAlterTable(schema *database.Schema, stmt *pg_query.AlterTableStmt, locate *locator) (bool, error) {
 	if stmt.Relation == nil {
-		return fmt.Errorf("ALTER TABLE missing relation")
+		return false, fmt.Errorf("ALTER TABLE missing relation")
+	}
+
+	tableIndex := findTableIndex(schema, stmt.Relation.Schemaname, stmt.Relation.Relname)
+	if tableIndex == -1 {
+		if stmt.MissingOk {
+			return false, nil
+		}
+		name := stmt.Relation.Relname
+		if stmt.Relation.Schemaname != "" {
+			name = stmt.Relation.Schemaname + "." + name
+		}
+		return false, fmt.Errorf("table %q does not exist; ALTER TABLE must come after its CREATE TABLE, in the same file or an earlier one", name)
+	}
+	table := &schema.Tables[tableIndex]
+
+	// Process each command in the ALTER TABLE statement. The statement is only
+	// modeled if every command is.
+	modeled := true
+	for _, cmd := range stmt.Cmds {
+		if cmd.Node == nil {
+			continue
+		}
+
+		if alterCmd, ok := cmd.Node.(*pg_query.Node_AlterTableCmd); ok {
+			switch alterCmd.AlterTableCmd.Subtype {
+			case pg_query.AlterTableType_AT_EnableRowSecurity:
+				table.RLSEnabled = true
+			case pg_query.AlterTableType_AT_DisableRowSecurity:
+				table.RLSEnabled = false
+			case pg_query.AlterTableType_AT_DropColumn:
+				if err := dropColumn(table, alterCmd.AlterTableCmd); err != nil {
+					return false, err
+				}
+			case pg_query.AlterTableType_AT_AddIdentity:
+				if err := addIdentity(table, alterCmd.AlterTableCmd); err != nil {
+					return false, err
+				}
+			case pg_query.AlterTableType_AT_DropIdentity:
+				if err := dropIdentity(table, alterCmd.AlterTableCmd); err != nil {
+					return false, err
+				}
+			case pg_query.AlterTableType_AT_AddColumn:
+				if err := alterAddColumn(table, alterCmd.AlterTableCmd, locate); err != nil {
+					return false, err
+				}
+			case pg_query.AlterTableType_AT_AddConstraint:
+				added, err := alterAddConstraint(table, alterCmd.AlterTableCmd, locate)
+				if err != nil {
+					return false, err
+				}
+				modeled = modeled && added
+			case pg_query.AlterTableType_AT_SetRelOptions:
+				if err := setStorageOptions(table, alterCmd.AlterTableCmd); err != nil {
+					return false, err
+				}
+			case pg_query.AlterTableType_AT_ResetRelOptions:
+				if err := resetStorageOptions(table, alterCmd.AlterTableCmd); err != nil {
+					return false, err
+				}
+			case pg_query.AlterTableType_AT_SetLogged:
+				table.Persistence = ""
+			case pg_query.AlterTableType_AT_SetUnLogged:
+				table.Persistence = database.TablePersistenceUnlogged
+			case pg_query.AlterTableType_AT_AttachPartition:
+				attached, err := attachPartition(schema, tableIndex, alterCmd.AlterTableCmd)
+				if err != nil {
+					return false, err
+				}
+				modeled = modeled && attached
+			case pg_query.AlterTableType_AT_DetachPartition:
+				detached, err := detachPartition(schema, tableIndex, alterCmd.AlterTableCmd)
+				if err != nil {
+					return false, err
+				}
+				modeled = modeled && detached
+			default:
+				modeled = false
+			}
+		}
+	}
+
+	return modeled, nil
+}
+
+// alterAddColumn applies ADD COLUMN. With IF NOT EXISTS, adding a column the
+// table already has does nothing.
+func alterAddColumn(table *database.Table, cmd *pg_query.AlterTableCmd, locate *locator) error {
+	colDef := cmd.Def.GetColumnDef()
+	if colDef == nil {
+		return fmt.Errorf("ADD COLUMN missing column definition")
+	}
+	if findColumn(table, colDef.Colname) != nil {
+		if cmd.MissingOk {
+			return nil
+		}
+		return fmt.Errorf("column %q of table %q already exists", colDef.Colname, table.Name)
+	}
+
+	hadPrimaryKey := hasPrimaryKey(table)
+	col, err := addColumn(table, colDef, locate)
+	if err != nil {
+		return err
+	}
+	if col.IsPrimaryKey && hadPrimaryKey {
+		return fmt.Errorf("table %q already has a primary key", table.Name)
+	}
+	return nil
+}
+
+// alterAddConstraint applies ADD CONSTRAINT, reporting whether the constraint
+// is one lockplane models
+func alterAddConstraint(table *database.Table, cmd *pg_query.AlterTableCmd, locate *locator) (bool, error) {
+	constraint := cmd.Def.GetConstraint()
+	if constraint == nil {
+		return false, nil
+	}
+
+	switch constraint.Contype {
+	case pg_query.ConstrType_CONSTR_PRIMARY:
+		if hasPrimaryKey(table) {
+			return false, fmt.Errorf("table %q already has a primary key", table.Name)
+		}
+	case pg_query.ConstrType_CONSTR_UNIQUE, pg_query.ConstrType_CONSTR_CHECK, pg_query.ConstrType_CONSTR_FOREIGN,
+		pg_query.ConstrType_CONSTR_EXCLUSION:
+	default:
+		return false, nil
+	}
+
+	if err := parseTableConstraint(table, constraint, locate); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// parseComment applies COMMENT ON TABLE and COMMENT ON COLUMN to the schema.
+// A comment on a table that isn't part of this schema, or on a column it
+// doesn't have, isn't applied. It reports whether the comment was applied.
+func parseComment(schema *database.Schema, stmt *pg_query.CommentStmt) bool {
+	list, ok := stmt.Object.GetNode().(*pg_query.Node_List)
+	if !ok {
+		return false
+	}
+	names := constraintKeys(list.List.Items)
+
+	var columnName string
+	switch stmt.Objtype {
+	case pg_query.ObjectType_OBJECT_TABLE:
+	case pg_query.ObjectType_OBJECT_COLUMN:
+		if len(names) < 2 {
+			return false
+		}
+		columnName = names[len(names)-1]
+		names = names[:len(names)-1]
+	default:
+		return false
+	}
+
+	var tableSchema, tableName string
+	switch len(names) {
+	case 1:
+		tableName = names[0]
+	case 2:
+		tableSchema, tableName = names[0], names[1]
+	default:
+		return false
+	}
+
+	tableIndex := findTableIndex(schema, tableSchema, tableName)
+	if tableIndex == -1 {
+		return false
 	}
+	table := &schema.Tables[tableIndex]
 
-	tableName := stmt.Relation.Relname
-	tableSchema := stmt.Relation.Schemaname
+	// COMMENT ... IS NULL removes the comment, leaving stmt.Comment empty
+	if columnName == "" {
+		table.Comment = stmt.Comment
+		return true
+	}
+	col := findColumn(table, columnName)
+	if col == nil {
+		return false
+	}
+	col.Comment = stmt.Comment
+	return true
+}
 
-	// If no schema specified, default to "public" (matches CREATE TABLE behavior)
+// parseCreatePolicy adds a CREATE POLICY to its table. Like CREATE INDEX, a
+// policy on a table that isn't part of this schema is skipped, since the table
+// may already exist in the database.
+func parseCreatePolicy(schema *database.Schema, stmt *pg_query.CreatePolicyStmt, loc *database.SourceLocation) (bool, error) {
+	if stmt.Table == nil {
+		return false, fmt.Errorf("CREATE POLICY missing relation")
+	}
+	tableIndex := findTableIndex(schema, stmt.Table.Schemaname, stmt.Table.Relname)
+	if tableIndex == -1 {
+		return false, nil
+	}
+	table := &schema.Tables[tableIndex]
+	for _, policy := range table.Policies {
+		if policy.Name == stmt.PolicyName {
+			return false, fmt.Errorf("policy %q for table %q already exists", stmt.PolicyName, table.Name)
+		}
+	}
+
+	policy := database.Policy{
+		Name:           stmt.PolicyName,
+		Command:        strings.ToUpper(stmt.CmdName),
+		Permissive:     stmt.Permissive,
+		SourceLocation: loc,
+	}
+	for _, role := range stmt.Roles {
+		if name := roleSpecName(role.GetRoleSpec()); name != "" {
+			policy.Roles = append(policy.Roles, name)
+		}
+	}
+	if expr := buildExpr(stmt.Qual); expr != nil {
+		policy.Using = expr.String()
+	}
+	if expr := buildExpr(stmt.WithCheck); expr != nil {
+		policy.WithCheck = expr.String()
+	}
+	table.Policies = append(table.Policies, policy)
+	return true, nil
+}
+
+// roleSpecName returns the role a RoleSpec names, "public" for PUBLIC or the
+// keyword for CURRENT_USER and the like
+func roleSpecName(role *pg_query.RoleSpec) string {
+	if role == nil {
+		return ""
+	}
+	switch role.Roletype {
+	case pg_query.RoleSpecType_ROLESPEC_CSTRING:
+		return role.Rolename
+	case pg_query.RoleSpecType_ROLESPEC_PUBLIC:
+		return "public"
+	case pg_query.RoleSpecType_ROLESPEC_CURRENT_ROLE:
+		return "CURRENT_ROLE"
+	case pg_query.RoleSpecType_ROLESPEC_CURRENT_USER:
+		return "CURRENT_USER"
+	case pg_query.RoleSpecType_ROLESPEC_SESSION_USER:
+		return "SESSION_USER"
+	}
+	return ""
+}
+
+// findTableIndex returns the index of the table matching (schema, name) in the
+// schema, or -1 if there is none. An empty schema name is treated as "public"
+// (matches CREATE TABLE behavior).
+func findTableIndex(schema *database.Schema, tableSchema string, tableName string) int {
 	if tableSchema == "" {
 		tableSchema = "public"
 	}
 
-	// Find the table in the schema (match by both schema and name)
-	var tableIndex = -1
 	for i, table := range schema.Tables {
-		// Match by (schema, name) - treating empty schema as "public"
 		tblSchema := table.Schema
 		if tblSchema == "" {
 			tblSchema = "public"
 		}
 
 		if table.Name == tableName && tblSchema == tableSchema {
-			tableIndex = i
-			break
+			return i
+		}
+	}
+
+	return -1
+}
+
+// dropColumn applies ALTER TABLE ... DROP COLUMN to a table. Dropping a column
+// that doesn't exist is an error unless IF EXISTS was given. Indexes that
+// reference the column are left in place so they can be reported by the
+// index-on-missing-column lint.
+func dropColumn(table *database.Table, cmd *pg_query.AlterTableCmd) error {
+	for i, col := range table.Columns {
+		if col.Name != cmd.Name {
+			continue
+		}
+
+		table.Columns = append(table.Columns[:i], table.Columns[i+1:]...)
+
+		// Like PostgreSQL, dropping a primary key column drops the primary key
+		for _, key := range table.PrimaryKey {
+			if key == cmd.Name {
+				for j := range table.Columns {
+					table.Columns[j].IsPrimaryKey = false
+				}
+				table.PrimaryKey = nil
+				table.PrimaryKeyName = ""
+				table.PrimaryKeyIndex = ""
+				break
+			}
 		}
+		dropColumnConstraints(table, cmd.Name)
+		return nil
 	}
 
-	// If table doesn't exist yet, we can't apply ALTER TABLE to it
+	if cmd.MissingOk {
+		return nil
+	}
+	return fmt.Errorf("column %q of table %q does not exist", cmd.Name, table.Name)
+}
+
+// partitionCmdTable returns the index of the table named by an ATTACH or
+// DETACH PARTITION command, or -1 if it isn't in the schema
+func partitionCmdTable(schema *database.Schema, cmd *pg_query.AlterTableCmd) (int, *pg_query.RangeVar) {
+	partitionCmd := cmd.Def.GetPartitionCmd()
+	if partitionCmd == nil || partitionCmd.Name == nil {
+		return -1, nil
+	}
+	return findTableIndex(schema, partitionCmd.Name.Schemaname, partitionCmd.Name.Relname), partitionCmd.Name
+}
+
+// attachPartition applies ALTER TABLE parent ATTACH PARTITION child. It
+// reports false, leaving the schema unchanged, when the child table isn't in
+// the schema.
+func attachPartition(schema *database.Schema, parentIndex int, cmd *pg_query.AlterTableCmd) (bool, error) {
+	childIndex, name := partitionCmdTable(schema, cmd)
+	if childIndex == -1 {
+		return false, nil
+	}
+	parent := &schema.Tables[parentIndex]
+	child := &schema.Tables[childIndex]
+
+	if child.PartitionOf != nil {
+		return false, fmt.Errorf("table %q is already a partition of %q", rangeVarName(name), child.PartitionOf.Table)
+	}
+	child.PartitionOf = &database.TableRef{Schema: parent.Schema, Table: parent.Name}
+	parent.Partitions = append(parent.Partitions, database.TableRef{Schema: child.Schema, Table: child.Name})
+	return true, nil
+}
+
+// detachPartition applies ALTER TABLE parent DETACH PARTITION child. It
+// reports false, leaving the schema unchanged, when the child table isn't in
+// the schema.
+func detachPartition(schema *database.Schema, parentIndex int, cmd *pg_query.AlterTableCmd) (bool, error) {
+	childIndex, name := partitionCmdTable(schema, cmd)
+	if childIndex == -1 {
+		return false, nil
+	}
+	parent := &schema.Tables[parentIndex]
+	child := &schema.Tables[childIndex]
+
+	for i, partition := range parent.Partitions {
+		if refersTo(partition, child) {
+			parent.Partitions = slices.Delete(parent.Partitions, i, i+1)
+			child.PartitionOf = nil
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("table %q is not a partition of %q", rangeVarName(name), parent.Name)
+}
+
+// addPartition records a table created PARTITION OF a parent in the parent's
+// Partitions. It's called once the table has been added to the schema, so a
+// CREATE TABLE that fails to parse leaves its parent unchanged.
+func addPartition(schema *database.Schema, table *database.Table) {
+	if table.PartitionOf == nil {
+		return
+	}
+	if i := findTableIndex(schema, table.PartitionOf.Schema, table.PartitionOf.Table); i != -1 {
+		schema.Tables[i].Partitions = append(schema.Tables[i].Partitions, database.TableRef{Schema: table.Schema, Table: table.Name})
+	}
+}
+
+// refersTo reports whether ref names table, treating an empty schema as public
+func refersTo(ref database.TableRef, table *database.Table) bool {
+	return qualifiedTableName(&database.Table{Schema: ref.Schema, Name: ref.Table}) == qualifiedTableName(table)
+}
+
+// dropColumnConstraints removes the constraints PostgreSQL drops along with a
+// column: unique, foreign key, check and exclusion constraints that use it
+func dropColumnConstraints(table *database.Table, column string) {
+	uniques := table.UniqueConstraints[:0]
+	for _, unique := range table.UniqueConstraints {
+		if !slices.Contains(unique.Columns, column) {
+			uniques = append(uniques, unique)
+		}
+	}
+	table.UniqueConstraints = uniques
+
+	fks := table.ForeignKeys[:0]
+	for _, fk := range table.ForeignKeys {
+		if !slices.Contains(fk.Columns, column) {
+			fks = append(fks, fk)
+		}
+	}
+	table.ForeignKeys = fks
+
+	checks := table.CheckConstraints[:0]
+	for _, check := range table.CheckConstraints {
+		if !exprReferencesColumn(check.Expr, column) {
+			checks = append(checks, check)
+		}
+	}
+	table.CheckConstraints = checks
+
+	exclusions := table.ExclusionConstraints[:0]
+	for _, exclusion := range table.ExclusionConstraints {
+		if !slices.ContainsFunc(exclusion.Elements, func(e database.ExclusionElement) bool { return e.Column == column }) {
+			exclusions = append(exclusions, exclusion)
+		}
+	}
+	table.ExclusionConstraints = exclusions
+}
+
+// addIdentity applies ALTER TABLE ... ALTER COLUMN ... ADD GENERATED ... AS
+// IDENTITY. As in PostgreSQL, the column must exist, be NOT NULL and not
+// already be an identity column.
+func addIdentity(table *database.Table, cmd *pg_query.AlterTableCmd) error {
+	col := findColumn(table, cmd.Name)
+	if col == nil {
+		return fmt.Errorf("column %q of table %q does not exist", cmd.Name, table.Name)
+	}
+	if col.Identity != nil {
+		return fmt.Errorf("column %q of table %q is already an identity column", cmd.Name, table.Name)
+	}
+	if col.Nullable {
+		return fmt.Errorf("column %q of table %q must be declared NOT NULL before identity can be added", cmd.Name, table.Name)
+	}
+
+	constraint, ok := cmd.Def.GetNode().(*pg_query.Node_Constraint)
+	if !ok {
+		return fmt.Errorf("ADD GENERATED on column %q missing identity definition", cmd.Name)
+	}
+	col.Identity = parseIdentity(constraint.Constraint)
+	if col.Identity.Sequence == "" {
+		col.Identity.Sequence = identitySequenceName(table.Name, col.Name)
+	}
+	return nil
+}
+
+// dropIdentity applies ALTER TABLE ... ALTER COLUMN ... DROP IDENTITY. The
+// column stays NOT NULL. Dropping the identity of a column that doesn't have
+// one is an error unless IF EXISTS was given.
+func dropIdentity(table *database.Table, cmd *pg_query.AlterTableCmd) error {
+	col := findColumn(table, cmd.Name)
+	if col == nil {
+		return fmt.Errorf("column %q of table %q does not exist", cmd.Name, table.Name)
+	}
+	if col.Identity == nil {
+		if cmd.MissingOk {
+			return nil
+		}
+		return fmt.Errorf("column %q of table %q is not an identity column", cmd.Name, table.Name)
+	}
+
+	col.Identity = nil
+	return nil
+}
+
+// parseCreateIndex handles CREATE INDEX statements, attaching the index to the
+// table it's defined on
+func parseCreateIndex(schema *database.Schema, stmt *pg_query.IndexStmt, loc *database.SourceLocation) (bool, error) {
+	if stmt.Relation == nil {
+		return false, fmt.Errorf("CREATE INDEX missing relation")
+	}
+
+	index := database.Index{
+		Name:           stmt.Idxname,
+		Unique:         stmt.Unique,
+		Method:         stmt.AccessMethod,
+		SourceLocation: loc,
+	}
+
+	for _, param := range stmt.IndexParams {
+		elem, ok := param.Node.(*pg_query.Node_IndexElem)
+		if !ok {
+			continue
+		}
+		if elem.IndexElem.Name != "" {
+			index.Columns = append(index.Columns, elem.IndexElem.Name)
+		} else if elem.IndexElem.Expr != nil {
+			index.Expressions = append(index.Expressions, formatExpr(elem.IndexElem.Expr))
+		}
+	}
+	for _, param := range stmt.IndexIncludingParams {
+		if elem := param.GetIndexElem(); elem != nil && elem.Name != "" {
+			index.Include = append(index.Include, elem.Name)
+		}
+	}
+	if stmt.WhereClause != nil {
+		index.WhereExpr = buildExpr(stmt.WhereClause)
+		index.Where = index.WhereExpr.String()
+	}
+
+	// An index may be on a materialized view instead of a table
+	if viewIndex := findViewIndex(schema, stmt.Relation.Schemaname, stmt.Relation.Relname); viewIndex != -1 {
+		view := &schema.Views[viewIndex]
+		if !view.Materialized {
+			return false, fmt.Errorf("view %q can't be indexed because it isn't materialized", view.Name)
+		}
+		if index.Name == "" {
+			index.Name = defaultIndexName(view.Name, index)
+		}
+		view.Indexes = append(view.Indexes, index)
+		return true, nil
+	}
+
+	tableIndex := findTableIndex(schema, stmt.Relation.Schemaname, stmt.Relation.Relname)
+
+	// An index on a table that isn't part of this schema may be on a table
+	// that already exists in the database, so it isn't modeled. Unlike ALTER
+	// TABLE, it isn't an error.
 	if tableIndex == -1 {
-		// This is OK - ALTER TABLE might come after CREATE TABLE in the same schema
-		// or might reference a table that already exists in the database
-		// For now, we'll skip it
+		return false, nil
+	}
+	table := &schema.Tables[tableIndex]
+
+	if index.Name == "" {
+		index.Name = defaultIndexName(table.Name, index)
+	}
+
+	table.Indexes = append(table.Indexes, index)
+	return true, nil
+}
+
+// parseCreateSchema records the schema created by a CREATE SCHEMA statement.
+// CREATE SCHEMA AUTHORIZATION without a name creates a schema named after the
+// role. Objects created inside the statement aren't modeled, so such a
+// statement isn't counted as modeled.
+func parseCreateSchema(schema *database.Schema, stmt *pg_query.CreateSchemaStmt, loc *database.SourceLocation) (bool, error) {
+	name := stmt.Schemaname
+	if name == "" {
+		name = stmt.GetAuthrole().GetRolename()
+	}
+	if name == "" {
+		return false, fmt.Errorf("CREATE SCHEMA missing name")
+	}
+
+	if slices.Contains(schema.Schemas, name) {
+		if stmt.IfNotExists {
+			return len(stmt.SchemaElts) == 0, nil
+		}
+		return false, fmt.Errorf("schema %q already exists", name)
+	}
+	schema.Schemas = append(schema.Schemas, name)
+	if schema.SchemaLocations == nil {
+		schema.SchemaLocations = make(map[string]*database.SourceLocation)
+	}
+	schema.SchemaLocations[name] = loc
+	return len(stmt.SchemaElts) == 0, nil
+}
+
+// parseCreateView adds the view defined by a CREATE VIEW statement to schema.
+// CREATE OR REPLACE VIEW replaces a view already in the schema.
+func parseCreateView(schema *database.Schema, stmt *pg_query.ViewStmt, locate *locator) error {
+	if stmt.View == nil {
+		return fmt.Errorf("CREATE VIEW missing relation")
+	}
+	query, err := deparseQuery(stmt.Query)
+	if err != nil {
+		return err
+	}
+
+	return addView(schema, database.View{
+		Name:           stmt.View.Relname,
+		Schema:         stmt.View.Schemaname,
+		Query:          query,
+		SourceLocation: locate.nameAt(stmt.View.Location),
+	}, stmt.Replace)
+}
+
+// parseCreateMaterializedView adds the materialized view defined by a CREATE
+// MATERIALIZED VIEW statement to schema
+func parseCreateMaterializedView(schema *database.Schema, stmt *pg_query.CreateTableAsStmt, locate *locator) error {
+	relation := stmt.GetInto().GetRel()
+	if relation == nil {
+		return fmt.Errorf("CREATE MATERIALIZED VIEW missing relation")
+	}
+	if stmt.IfNotExists && findViewIndex(schema, relation.Schemaname, relation.Relname) != -1 {
 		return nil
 	}
+	query, err := deparseQuery(stmt.Query)
+	if err != nil {
+		return err
+	}
 
-	// Process each command in the ALTER TABLE statement
-	for _, cmd := range stmt.Cmds {
-		if cmd.Node == nil {
+	return addView(schema, database.View{
+		Name:           relation.Relname,
+		Schema:         relation.Schemaname,
+		Query:          query,
+		Materialized:   true,
+		WithData:       !stmt.Into.SkipData,
+		SourceLocation: locate.nameAt(relation.Location),
+	}, false)
+}
+
+// addView adds a view to schema. A view with the same name is an error
+// unless replace is set, in which case the new view takes its place.
+func addView(schema *database.Schema, view database.View, replace bool) error {
+	existing := findViewIndex(schema, view.Schema, view.Name)
+	switch {
+	case existing == -1:
+		schema.Views = append(schema.Views, view)
+	case replace:
+		schema.Views[existing] = view
+	default:
+		return fmt.Errorf("view %q already exists", view.Name)
+	}
+	return nil
+}
+
+// deparseQuery renders the defining query of a view as SQL
+func deparseQuery(query *pg_query.Node) (string, error) {
+	if query == nil {
+		return "", fmt.Errorf("view missing query")
+	}
+	sql, err := pg_query.Deparse(&pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: query}}})
+	if err != nil {
+		return "", fmt.Errorf("failed to deparse view query: %w", err)
+	}
+	return sql, nil
+}
+
+// findViewIndex returns the index of a view in schema.Views, or -1. An empty
+// schema name means "public".
+func findViewIndex(schema *database.Schema, viewSchema string, viewName string) int {
+	if viewSchema == "" {
+		viewSchema = "public"
+	}
+	for i, view := range schema.Views {
+		schemaName := view.Schema
+		if schemaName == "" {
+			schemaName = "public"
+		}
+		if view.Name == viewName && schemaName == viewSchema {
+			return i
+		}
+	}
+	return -1
+}
+
+// defaultIndexName mirrors the name PostgreSQL chooses for an unnamed index:
+// <table>_<columns>_idx, using "expr" when the index is on expressions.
+func defaultIndexName(tableName string, index database.Index) string {
+	parts := []string{tableName}
+	if len(index.Columns) > 0 {
+		parts = append(parts, index.Columns...)
+	} else {
+		parts = append(parts, "expr")
+	}
+	parts = append(parts, "idx")
+	return strings.Join(parts, "_")
+}
+
+// parseCreateEnum converts a CREATE TYPE ... AS ENUM statement to an Enum
+func parseCreateEnum(stmt *pg_query.CreateEnumStmt, loc *database.SourceLocation) (*database.Enum, error) {
+	names := constraintKeys(stmt.TypeName)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("CREATE TYPE missing name")
+	}
+
+	enum := &database.Enum{
+		Name:           names[len(names)-1],
+		Values:         constraintKeys(stmt.Vals),
+		SourceLocation: loc,
+	}
+	if len(names) > 1 {
+		enum.Schema = names[len(names)-2]
+	}
+	if enum.Values == nil {
+		enum.Values = []string{}
+	}
+	return enum, nil
+}
+
+// parseCreateExtension converts a CREATE EXTENSION statement to an Extension.
+// VERSION and CASCADE aren't recorded.
+func parseCreateExtension(stmt *pg_query.CreateExtensionStmt, loc *database.SourceLocation) (*database.Extension, error) {
+	if stmt.Extname == "" {
+		return nil, fmt.Errorf("CREATE EXTENSION missing name")
+	}
+
+	extension := &database.Extension{
+		Name:           stmt.Extname,
+		IfNotExists:    stmt.IfNotExists,
+		SourceLocation: loc,
+	}
+	for _, option := range stmt.Options {
+		if def := option.GetDefElem(); def != nil && def.Defname == "schema" {
+			extension.Schema = def.Arg.GetString_().GetSval()
+		}
+	}
+	return extension, nil
+}
+
+// parseCreateSequence converts a CREATE SEQUENCE statement to a Sequence.
+// Options other than START and INCREMENT aren't recorded.
+func parseCreateSequence(stmt *pg_query.CreateSeqStmt, loc *database.SourceLocation) (*database.Sequence, error) {
+	if stmt.Sequence == nil || stmt.Sequence.Relname == "" {
+		return nil, fmt.Errorf("CREATE SEQUENCE missing name")
+	}
+
+	sequence := &database.Sequence{
+		Name:           stmt.Sequence.Relname,
+		Schema:         stmt.Sequence.Schemaname,
+		SourceLocation: loc,
+	}
+	for _, option := range stmt.Options {
+		def := option.GetDefElem()
+		if def == nil {
 			continue
 		}
+		switch def.Defname {
+		case "start":
+			sequence.Start = sequenceOptionValue(def.Arg)
+		case "increment":
+			sequence.Increment = sequenceOptionValue(def.Arg)
+		}
+	}
+	return sequence, nil
+}
 
-		if alterCmd, ok := cmd.Node.(*pg_query.Node_AlterTableCmd); ok {
-			switch alterCmd.AlterTableCmd.Subtype {
-			case pg_query.AlterTableType_AT_EnableRowSecurity:
-				schema.Tables[tableIndex].RLSEnabled = true
-			case pg_query.AlterTableType_AT_DisableRowSecurity:
-				schema.Tables[tableIndex].RLSEnabled = false
+// parseDefineStmt returns the object created by a CREATE AGGREGATE or CREATE
+// OPERATOR statement, or nil for the other kinds of DefineStmt
+func parseDefineStmt(stmt *pg_query.DefineStmt, loc *database.SourceLocation) *database.ObjectRef {
+	var kind string
+	switch stmt.Kind {
+	case pg_query.ObjectType_OBJECT_AGGREGATE:
+		kind = "aggregate"
+	case pg_query.ObjectType_OBJECT_OPERATOR:
+		kind = "operator"
+	default:
+		return nil
+	}
+
+	names := constraintKeys(stmt.Defnames)
+	if len(names) == 0 {
+		return nil
+	}
+
+	object := &database.ObjectRef{
+		Kind:           kind,
+		Name:           names[len(names)-1],
+		SourceLocation: loc,
+	}
+	if len(names) > 1 {
+		object.Schema = names[len(names)-2]
+	}
+	return object
+}
+
+// parseCreateDomain converts a CreateDomainStmt AST node to a Domain. Unnamed
+// CHECK constraints are named as PostgreSQL names them; see domainCheckName.
+func parseCreateDomain(stmt *pg_query.CreateDomainStmt, loc *database.SourceLocation, locate *locator) (*database.Domain, error) {
+	names := constraintKeys(stmt.Domainname)
+	if len(names) == 0 || stmt.TypeName == nil {
+		return nil, fmt.Errorf("CREATE DOMAIN missing name or type")
+	}
+
+	domain := &database.Domain{
+		Name:           names[len(names)-1],
+		BaseType:       formatTypeName(stmt.TypeName),
+		SourceLocation: loc,
+	}
+	if len(names) > 1 {
+		domain.Schema = names[len(names)-2]
+	}
+
+	for _, c := range stmt.Constraints {
+		constraint, ok := c.Node.(*pg_query.Node_Constraint)
+		if !ok {
+			continue
+		}
+
+		switch constraint.Constraint.Contype {
+		case pg_query.ConstrType_CONSTR_NOTNULL:
+			domain.NotNull = true
+		case pg_query.ConstrType_CONSTR_NULL:
+			domain.NotNull = false
+		case pg_query.ConstrType_CONSTR_DEFAULT:
+			if constraint.Constraint.RawExpr != nil {
+				defaultStr := formatExpr(constraint.Constraint.RawExpr)
+				domain.Default = &defaultStr
+			}
+		case pg_query.ConstrType_CONSTR_CHECK:
+			expr := buildExpr(constraint.Constraint.RawExpr)
+			check := database.CheckConstraint{
+				Name:           constraint.Constraint.Conname,
+				Expr:           expr,
+				SourceLocation: locate.at(constraint.Constraint.Location),
 			}
+			if expr != nil {
+				check.Expression = expr.String()
+			}
+			if check.Name == "" {
+				check.Name = domainCheckName(domain)
+			}
+			domain.CheckConstraints = append(domain.CheckConstraints, check)
 		}
 	}
 
-	return nil
+	return domain, nil
+}
+
+// domainCheckName returns the name PostgreSQL generates for an unnamed CHECK
+// constraint of a domain: <domain>_check, numbered from 1 like
+// chooseConstraintName until the domain has no constraint of that name
+func domainCheckName(domain *database.Domain) string {
+	name := domain.Name + "_check"
+	for pass := 1; slices.ContainsFunc(domain.CheckConstraints, func(c database.CheckConstraint) bool { return c.Name == name }); pass++ {
+		name = fmt.Sprintf("%s_check%d", domain.Name, pass)
+	}
+	return name
 }
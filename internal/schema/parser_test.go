@@ -1,6 +1,11 @@
 package schema
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/lockplane/lockplane/internal/database"
@@ -42,6 +47,36 @@ func TestParseBasicCreateTable(t *testing.T) {
 	}
 }
 
+func TestParseSchemaString(t *testing.T) {
+	sql := `CREATE TABLE users (id INTEGER PRIMARY KEY);
+CREATE TABLE auth.users (id INTEGER PRIMARY KEY);
+CREATE INDEX ON users (id);`
+
+	schema, err := ParseSchemaString(sql)
+	if err != nil {
+		t.Fatalf("ParseSchemaString failed: %v", err)
+	}
+	if len(schema.Tables) != 2 || len(schema.Tables[0].Indexes) != 1 || schema.Dialect != database.DialectPostgres {
+		t.Errorf("Expected both tables and the index, got %+v", schema)
+	}
+
+	// Duplicate tables are rejected, unlike with ParseSQLSchemaWithDialect
+	duplicated := sql + "\nCREATE TABLE public.users (id BIGINT);"
+	if _, err := ParseSQLSchemaWithDialect(duplicated, database.DialectPostgres); err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if _, err := ParseSchemaString(duplicated); err == nil || !strings.Contains(err.Error(), `"public.users" is defined multiple times`) {
+		t.Errorf("Expected a duplicate table error, got %v", err)
+	}
+
+	// Parse errors are located in the string
+	_, err = ParseSchemaString("CREATE TABLE users (id INTEGER);\nCREATE TABLE posts (id INTEGER REFERENCES);")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 2 {
+		t.Errorf("Expected a parse error on line 2, got %v", err)
+	}
+}
+
 func TestParseTableWithMultipleColumns(t *testing.T) {
 	sql := `
 		CREATE TABLE products (
@@ -240,6 +275,41 @@ func TestParseTimestampTypeNormalization(t *testing.T) {
 		{"TIME", "CREATE TABLE t (col TIME);", "time without time zone"},
 		{"TIMETZ", "CREATE TABLE t (col TIMETZ);", "time with time zone"},
 		{"TIME_WITH_TIME_ZONE", "CREATE TABLE t (col TIME WITH TIME ZONE);", "time with time zone"},
+		// The precision keeps its place before the time zone, as PostgreSQL
+		// spells the type
+		{"TIMESTAMP_6", "CREATE TABLE t (col TIMESTAMP(6));", "timestamp(6) without time zone"},
+		{"TIMESTAMPTZ_3", "CREATE TABLE t (col TIMESTAMP(3) WITH TIME ZONE);", "timestamp(3) with time zone"},
+		{"TIME_3_WITH_TIME_ZONE", "CREATE TABLE t (col TIME(3) WITH TIME ZONE);", "time(3) with time zone"},
+		{"TIMESTAMPTZ_ARRAY", "CREATE TABLE t (col TIMESTAMPTZ(0)[]);", "timestamp(0) with time zone[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSQLSchemaWithDialect(tt.sql, database.DialectPostgres)
+			if err != nil {
+				t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+			}
+
+			col := schema.Tables[0].Columns[0]
+			if col.Type != tt.expectedType {
+				t.Errorf("Expected type %q, got %q", tt.expectedType, col.Type)
+			}
+		})
+	}
+}
+
+func TestParseIntervalFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		sql          string
+		expectedType string
+	}{
+		{"INTERVAL_3", "CREATE TABLE t (col INTERVAL(3));", "interval(3)"},
+		{"YEAR_TO_MONTH", "CREATE TABLE t (col INTERVAL YEAR TO MONTH);", "interval year to month"},
+		{"DAY_TO_SECOND", "CREATE TABLE t (col INTERVAL DAY TO SECOND);", "interval day to second"},
+		{"DAY_TO_SECOND_3", "CREATE TABLE t (col INTERVAL DAY TO SECOND(3));", "interval day to second(3)"},
+		{"MINUTE", "CREATE TABLE t (col INTERVAL MINUTE);", "interval minute"},
+		{"SECOND_ARRAY", "CREATE TABLE t (col INTERVAL SECOND(2)[]);", "interval second(2)[]"},
 	}
 
 	for _, tt := range tests {
@@ -253,6 +323,9 @@ func TestParseTimestampTypeNormalization(t *testing.T) {
 			if col.Type != tt.expectedType {
 				t.Errorf("Expected type %q, got %q", tt.expectedType, col.Type)
 			}
+			if !schema.IsKnownType(col.Type) {
+				t.Errorf("Expected %q to be a known type", col.Type)
+			}
 		})
 	}
 }
@@ -290,10 +363,16 @@ func TestParseArrayTypes(t *testing.T) {
 		name         string
 		sql          string
 		expectedType string
+		expectedDims []int
 	}{
-		{"INTEGER_ARRAY", "CREATE TABLE t (col INTEGER[]);", "integer[]"},
-		{"TEXT_ARRAY", "CREATE TABLE t (col TEXT[]);", "text[]"},
-		{"VARCHAR_ARRAY", "CREATE TABLE t (col VARCHAR(50)[]);", "varchar(50)[]"},
+		{"INTEGER_ARRAY", "CREATE TABLE t (col INTEGER[]);", "integer[]", []int{-1}},
+		{"TEXT_ARRAY", "CREATE TABLE t (col TEXT[]);", "text[]", []int{-1}},
+		{"VARCHAR_ARRAY", "CREATE TABLE t (col VARCHAR(50)[]);", "varchar(50)[]", []int{-1}},
+		{"NUMERIC_ARRAY", "CREATE TABLE t (col NUMERIC(10,2)[]);", "numeric(10,2)[]", []int{-1}},
+		{"TWO_DIMENSIONS", "CREATE TABLE t (col INT[][]);", "integer[][]", []int{-1, -1}},
+		{"FIXED_SIZES", "CREATE TABLE t (col INT[3][3]);", "integer[3][3]", []int{3, 3}},
+		{"ARRAY_KEYWORD", "CREATE TABLE t (col INT ARRAY[4]);", "integer[4]", []int{4}},
+		{"NOT_ARRAY", "CREATE TABLE t (col INT);", "integer", nil},
 	}
 
 	for _, tt := range tests {
@@ -307,6 +386,9 @@ func TestParseArrayTypes(t *testing.T) {
 			if col.Type != tt.expectedType {
 				t.Errorf("Expected type %q, got %q", tt.expectedType, col.Type)
 			}
+			if !reflect.DeepEqual(col.ArrayDims, tt.expectedDims) {
+				t.Errorf("Expected array dims %v, got %v", tt.expectedDims, col.ArrayDims)
+			}
 		})
 	}
 }
@@ -356,6 +438,190 @@ func TestParsePrimaryKeyConstraint(t *testing.T) {
 	}
 }
 
+func TestParseNotNullExplicit(t *testing.T) {
+	sql := `CREATE TABLE users (
+		id INTEGER PRIMARY KEY,
+		email TEXT NOT NULL,
+		bio TEXT,
+		nickname TEXT NOT NULL NULL
+	);
+	CREATE TABLE accounts (id INTEGER NOT NULL PRIMARY KEY);
+	CREATE TABLE memberships (user_id INTEGER, org_id INTEGER NOT NULL, PRIMARY KEY (user_id, org_id));
+	CREATE TABLE members (LIKE memberships);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	tests := []struct {
+		table, column             string
+		nullable, notNullExplicit bool
+	}{
+		// NOT NULL implied by the primary key alone
+		{"users", "id", false, false},
+		{"users", "email", false, true},
+		{"users", "bio", true, false},
+		{"users", "nickname", true, false},
+		// Both declared NOT NULL and part of the primary key
+		{"accounts", "id", false, true},
+		{"memberships", "user_id", false, false},
+		{"memberships", "org_id", false, true},
+		// LIKE copies NOT NULL without the primary key
+		{"members", "user_id", false, true},
+	}
+	for _, tt := range tests {
+		table := &schema.Tables[findTableIndex(schema, "", tt.table)]
+		col := findColumn(table, tt.column)
+		if col.Nullable != tt.nullable || col.NotNullExplicit != tt.notNullExplicit {
+			t.Errorf("Expected %s.%s to have Nullable %t and NotNullExplicit %t, got %t and %t",
+				tt.table, tt.column, tt.nullable, tt.notNullExplicit, col.Nullable, col.NotNullExplicit)
+		}
+	}
+}
+
+func TestParseInlinePrimaryKeyPopulatesTablePrimaryKey(t *testing.T) {
+	sql := `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	if len(table.PrimaryKey) != 1 || table.PrimaryKey[0] != "id" {
+		t.Errorf("Expected primary key [id], got %v", table.PrimaryKey)
+	}
+}
+
+func TestParseCompositePrimaryKey(t *testing.T) {
+	sql := `CREATE TABLE memberships (user_id INT, group_id INT, role TEXT, PRIMARY KEY (group_id, user_id));`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	expectedPK := []string{"group_id", "user_id"}
+	if len(table.PrimaryKey) != len(expectedPK) {
+		t.Fatalf("Expected primary key %v, got %v", expectedPK, table.PrimaryKey)
+	}
+	for i, name := range expectedPK {
+		if table.PrimaryKey[i] != name {
+			t.Errorf("Expected primary key column %d to be %q, got %q", i, name, table.PrimaryKey[i])
+		}
+	}
+
+	for _, col := range table.Columns {
+		isKey := col.Name == "user_id" || col.Name == "group_id"
+		if col.IsPrimaryKey != isKey {
+			t.Errorf("Column %q: expected IsPrimaryKey=%v, got %v", col.Name, isKey, col.IsPrimaryKey)
+		}
+		if isKey && col.Nullable {
+			t.Errorf("Column %q: expected primary key column to be NOT NULL", col.Name)
+		}
+	}
+}
+
+func TestParsePrimaryKeyName(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{"named table constraint", `CREATE TABLE users (id INT, CONSTRAINT pk_users PRIMARY KEY (id));`, "pk_users"},
+		{"anonymous table constraint", `CREATE TABLE users (id INT, PRIMARY KEY (id));`, "users_pkey"},
+		{"named column constraint", `CREATE TABLE users (id INT CONSTRAINT pk_users PRIMARY KEY);`, "pk_users"},
+		{"anonymous column constraint", `CREATE TABLE users (id INT PRIMARY KEY);`, "users_pkey"},
+		{"added constraint", "CREATE TABLE users (id INT);\nALTER TABLE users ADD CONSTRAINT pk_users PRIMARY KEY (id);", "pk_users"},
+		{"no primary key", `CREATE TABLE users (id INT);`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSQLSchemaWithDialect(tt.sql, database.DialectPostgres)
+			if err != nil {
+				t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+			}
+			if name := schema.Tables[0].PrimaryKeyName; name != tt.expected {
+				t.Errorf("Expected primary key name %q, got %q", tt.expected, name)
+			}
+		})
+	}
+}
+
+func TestParsePrimaryKeyConstraintBeforeColumns(t *testing.T) {
+	sql := `CREATE TABLE t (PRIMARY KEY (a), a INT);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if !schema.Tables[0].Columns[0].IsPrimaryKey {
+		t.Error("Expected column 'a' to be PRIMARY KEY")
+	}
+}
+
+func TestParsePrimaryKeyUnknownColumn(t *testing.T) {
+	sql := `CREATE TABLE t (a INT, PRIMARY KEY (a, missing));`
+
+	_, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err == nil {
+		t.Fatal("Expected error for primary key on unknown column, got nil")
+	}
+	if !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("Expected error to mention the missing column, got %q", err.Error())
+	}
+}
+
+func TestParseTypeModifiers(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		sqlType   string
+		precision *int
+		scale     *int
+		length    *int
+	}{
+		{"NUMERIC(10,2)", intPtr(10), intPtr(2), nil},
+		{"DECIMAL(12)", intPtr(12), intPtr(0), nil},
+		{"NUMERIC(10,2)[]", intPtr(10), intPtr(2), nil},
+		{"VARCHAR(255)", nil, nil, intPtr(255)},
+		{"CHARACTER VARYING(64)[]", nil, nil, intPtr(64)},
+		{"CHAR(3)", nil, nil, intPtr(3)},
+		{"NUMERIC", nil, nil, nil},
+		{"TIMESTAMP(3)", nil, nil, nil},
+	}
+
+	equal := func(a, b *int) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+	}
+	format := func(n *int) string {
+		if n == nil {
+			return "nil"
+		}
+		return fmt.Sprint(*n)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sqlType, func(t *testing.T) {
+			schema, err := ParseSQLSchemaWithDialect("CREATE TABLE t (col "+tt.sqlType+");", database.DialectPostgres)
+			if err != nil {
+				t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+			}
+
+			col := schema.Tables[0].Columns[0]
+			if !equal(col.Precision, tt.precision) || !equal(col.Scale, tt.scale) || !equal(col.Length, tt.length) {
+				t.Errorf("Expected precision=%s scale=%s length=%s, got precision=%s scale=%s length=%s (type %q)",
+					format(tt.precision), format(tt.scale), format(tt.length),
+					format(col.Precision), format(col.Scale), format(col.Length), col.Type)
+			}
+		})
+	}
+}
+
 func TestParseDefaultIntegerLiteral(t *testing.T) {
 	sql := `CREATE TABLE users (age INTEGER DEFAULT 0);`
 
@@ -424,6 +690,45 @@ func TestParseDefaultNowFunction(t *testing.T) {
 	}
 }
 
+func TestParseDefaultNegativeNumbers(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		expectedValue string
+	}{
+		{"negative integer", "CREATE TABLE t (col INTEGER DEFAULT -1);", "-1"},
+		{"negative float", "CREATE TABLE t (col NUMERIC DEFAULT -2.5);", "-2.5"},
+		{"negative pi", "CREATE TABLE t (col DOUBLE PRECISION DEFAULT -3.14);", "-3.14"},
+		{"negative fraction", "CREATE TABLE t (col NUMERIC DEFAULT -0.5);", "-0.5"},
+		{"negative parenthesized", "CREATE TABLE t (col NUMERIC DEFAULT -(0.5));", "-0.5"},
+		{"negative with space", "CREATE TABLE t (col INTEGER DEFAULT - 1);", "-1"},
+		{"negative bigint", "CREATE TABLE t (col BIGINT DEFAULT -9223372036854775808);", "-9223372036854775808"},
+		{"negative cast", "CREATE TABLE t (col INTEGER DEFAULT -1::integer);", "-1"},
+		{"negative parenthesized cast", "CREATE TABLE t (col NUMERIC DEFAULT -(2.5::numeric));", "-2.5"},
+		{"double negation", "CREATE TABLE t (col INTEGER DEFAULT -(-1::integer));", "1"},
+		{"unary plus", "CREATE TABLE t (col INTEGER DEFAULT +(5::integer));", "5"},
+		{"false", "CREATE TABLE t (col BOOLEAN DEFAULT false);", "false"},
+		{"false cast", "CREATE TABLE t (col BOOLEAN DEFAULT false::boolean);", "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSQLSchemaWithDialect(tt.sql, database.DialectPostgres)
+			if err != nil {
+				t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+			}
+
+			col := schema.Tables[0].Columns[0]
+			if col.Default == nil {
+				t.Fatal("Expected column to have default value")
+			}
+			if *col.Default != tt.expectedValue {
+				t.Errorf("Expected default value %q, got %q", tt.expectedValue, *col.Default)
+			}
+		})
+	}
+}
+
 func TestParseSQLValueFunctions(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -573,12 +878,12 @@ func TestParseEmptySQL(t *testing.T) {
 func TestParseUnsupportedDialect(t *testing.T) {
 	sql := `CREATE TABLE users (id INTEGER);`
 
-	_, err := ParseSQLSchemaWithDialect(sql, "mysql")
+	_, err := ParseSQLSchemaWithDialect(sql, "oracle")
 	if err == nil {
 		t.Fatal("Expected error for unsupported dialect, got nil")
 	}
-	if err.Error() != "unsupported dialect mysql" {
-		t.Errorf("Expected error message 'unsupported dialect mysql', got %q", err.Error())
+	if err.Error() != "unsupported dialect oracle" {
+		t.Errorf("Expected error message 'unsupported dialect oracle', got %q", err.Error())
 	}
 }
 
@@ -723,6 +1028,60 @@ func TestParseDefaultBooleanLiteral(t *testing.T) {
 	}
 }
 
+func TestParseDefaultBooleanNormalization(t *testing.T) {
+	tests := []struct {
+		name            string
+		sql             string
+		expectedDefault string
+	}{
+		{"keyword_true", "CREATE TABLE t (col BOOLEAN DEFAULT true);", "true"},
+		{"string_true", "CREATE TABLE t (col BOOLEAN DEFAULT 'true');", "true"},
+		{"string_t", "CREATE TABLE t (col BOOLEAN DEFAULT 't');", "true"},
+		{"string_yes", "CREATE TABLE t (col BOOLEAN DEFAULT 'yes');", "true"},
+		{"string_on", "CREATE TABLE t (col BOOL DEFAULT 'on');", "true"},
+		{"integer_1", "CREATE TABLE t (col BOOLEAN DEFAULT 1);", "true"},
+		{"cast_true", "CREATE TABLE t (col BOOLEAN DEFAULT 'true'::boolean);", "true"},
+		{"keyword_false", "CREATE TABLE t (col BOOLEAN DEFAULT false);", "false"},
+		{"string_false", "CREATE TABLE t (col BOOLEAN DEFAULT 'false');", "false"},
+		{"string_f", "CREATE TABLE t (col BOOLEAN DEFAULT 'f');", "false"},
+		{"string_no", "CREATE TABLE t (col BOOLEAN DEFAULT 'no');", "false"},
+		{"integer_0", "CREATE TABLE t (col BOOLEAN DEFAULT 0);", "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSQLSchemaWithDialect(tt.sql, database.DialectPostgres)
+			if err != nil {
+				t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+			}
+
+			col := schema.Tables[0].Columns[0]
+			if col.Default == nil {
+				t.Fatal("Expected column to have default value")
+			}
+			if *col.Default != tt.expectedDefault {
+				t.Errorf("Expected default value %q, got %q", tt.expectedDefault, *col.Default)
+			}
+		})
+	}
+}
+
+func TestParseDefaultNonBooleanColumnNotNormalized(t *testing.T) {
+	sql := `CREATE TABLE t (a TEXT DEFAULT 't', b INTEGER DEFAULT 1);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if got := *schema.Tables[0].Columns[0].Default; got != "'t'" {
+		t.Errorf("Expected text default \"'t'\", got %q", got)
+	}
+	if got := *schema.Tables[0].Columns[1].Default; got != "1" {
+		t.Errorf("Expected integer default '1', got %q", got)
+	}
+}
+
 func TestParseDefaultNullLiteral(t *testing.T) {
 	sql := `CREATE TABLE users (middle_name TEXT DEFAULT NULL);`
 
@@ -885,6 +1244,55 @@ func TestParseRLSMultipleTables(t *testing.T) {
 	}
 }
 
+func TestParseCreatePolicy(t *testing.T) {
+	sql := `CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id UUID, published BOOLEAN);
+CREATE POLICY posts_read ON posts FOR SELECT USING (published);
+CREATE POLICY "Authors manage" ON public.posts AS RESTRICTIVE TO authenticated, CURRENT_USER
+  USING (author_id = auth.uid()) WITH CHECK (author_id = auth.uid() AND published IS NOT NULL);
+CREATE POLICY storage_read ON storage.objects USING (true);`
+
+	coverage := &Coverage{}
+	schema := newSchema(database.DialectPostgres)
+	if err := parseSQLSchemaWithFilename(schema, sql, "policies.lp.sql", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	policies := schema.Tables[0].Policies
+	if len(policies) != 2 {
+		t.Fatalf("Expected 2 policies, got %+v", policies)
+	}
+
+	read := policies[0]
+	if read.Name != "posts_read" || read.Command != "SELECT" || !read.Permissive {
+		t.Errorf("Unexpected policy: %+v", read)
+	}
+	if !reflect.DeepEqual(read.Roles, []string{"public"}) || read.Using != "published" || read.WithCheck != "" {
+		t.Errorf("Expected a policy for public using published, got %+v", read)
+	}
+	expectLocation(t, "policy", read.SourceLocation, "policies.lp.sql", 2, 1)
+
+	manage := policies[1]
+	if manage.Name != "Authors manage" || manage.Command != "ALL" || manage.Permissive {
+		t.Errorf("Unexpected policy: %+v", manage)
+	}
+	if !reflect.DeepEqual(manage.Roles, []string{"authenticated", "CURRENT_USER"}) {
+		t.Errorf("Expected roles [authenticated CURRENT_USER], got %v", manage.Roles)
+	}
+	if manage.Using != "author_id = auth.uid()" || manage.WithCheck != "(author_id = auth.uid()) AND (published IS NOT NULL)" {
+		t.Errorf("Unexpected expressions: USING %q WITH CHECK %q", manage.Using, manage.WithCheck)
+	}
+
+	// A policy on a table outside the schema isn't modeled
+	if coverage.Modeled != 3 || coverage.Statements != 4 {
+		t.Errorf("Expected 3 of 4 statements to be modeled, got %+v", coverage)
+	}
+
+	_, err := ParseSQLSchemaWithDialect(sql+"\nCREATE POLICY posts_read ON posts USING (true);", database.DialectPostgres)
+	if err == nil || !strings.Contains(err.Error(), `policy "posts_read" for table "posts" already exists`) {
+		t.Errorf("Expected a duplicate policy error, got %v", err)
+	}
+}
+
 func TestParseAlterTableDefaultsToPublicSchema(t *testing.T) {
 	// Test that ALTER TABLE without schema qualifier defaults to public schema,
 	// not the first matching table name
@@ -973,3 +1381,1658 @@ func TestParseAlterTableExplicitSchema(t *testing.T) {
 		t.Error("Expected public.users to have RLS disabled (ALTER TABLE should not affect it)")
 	}
 }
+
+func TestParseCreateIndex(t *testing.T) {
+	sql := `
+		CREATE TABLE users (id INTEGER, email TEXT, org_id INTEGER);
+		CREATE UNIQUE INDEX users_email_key ON users (email);
+		CREATE INDEX ON users (org_id, id);
+		CREATE INDEX users_lower_email_idx ON users USING btree (lower(email));
+	`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	indexes := schema.Tables[0].Indexes
+	if len(indexes) != 3 {
+		t.Fatalf("Expected 3 indexes, got %d", len(indexes))
+	}
+
+	if indexes[0].Name != "users_email_key" || !indexes[0].Unique {
+		t.Errorf("Expected unique index users_email_key, got %+v", indexes[0])
+	}
+	if len(indexes[0].Columns) != 1 || indexes[0].Columns[0] != "email" {
+		t.Errorf("Expected index on [email], got %v", indexes[0].Columns)
+	}
+
+	if indexes[1].Name != "users_org_id_id_idx" {
+		t.Errorf("Expected default index name 'users_org_id_id_idx', got %q", indexes[1].Name)
+	}
+	if len(indexes[1].Columns) != 2 || indexes[1].Columns[0] != "org_id" || indexes[1].Columns[1] != "id" {
+		t.Errorf("Expected index on [org_id id], got %v", indexes[1].Columns)
+	}
+
+	if len(indexes[2].Columns) != 0 || len(indexes[2].Expressions) != 1 || indexes[2].Expressions[0] != "lower(email)" {
+		t.Errorf("Expected expression index on lower(email), got %+v", indexes[2])
+	}
+	if indexes[2].Method != "btree" {
+		t.Errorf("Expected btree method, got %q", indexes[2].Method)
+	}
+}
+
+func TestParsePartialAndCoveringIndexes(t *testing.T) {
+	sql := `
+		CREATE TABLE users (id INTEGER, email TEXT, name TEXT, active BOOLEAN);
+		CREATE INDEX users_active_email_idx ON users (email) WHERE active;
+		CREATE INDEX users_email_idx ON users (email);
+		CREATE UNIQUE INDEX users_email_name_idx ON users (email) INCLUDE (name, id) WHERE users.active AND name IS NOT NULL;
+	`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	indexes := schema.Tables[0].Indexes
+	if len(indexes) != 3 {
+		t.Fatalf("Expected 3 indexes, got %d", len(indexes))
+	}
+
+	// A partial index is told apart from the full index on the same column
+	if indexes[0].Where != "active" || indexes[0].WhereExpr == nil {
+		t.Errorf("Expected a partial index WHERE active, got %+v", indexes[0])
+	}
+	if indexes[1].Where != "" || indexes[1].WhereExpr != nil || len(indexes[1].Include) != 0 {
+		t.Errorf("Expected a full index without INCLUDE columns, got %+v", indexes[1])
+	}
+
+	if !reflect.DeepEqual(indexes[2].Columns, []string{"email"}) || !reflect.DeepEqual(indexes[2].Include, []string{"name", "id"}) {
+		t.Errorf("Expected key column email including name and id, got %+v", indexes[2])
+	}
+	if indexes[2].Where != "users.active AND (name IS NOT NULL)" {
+		t.Errorf("Unexpected predicate %q", indexes[2].Where)
+	}
+}
+
+func TestParseCreateIndexOnUnknownTable(t *testing.T) {
+	sql := `CREATE INDEX ON other_table (id);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if len(schema.Tables) != 0 {
+		t.Errorf("Expected no tables, got %d", len(schema.Tables))
+	}
+}
+
+func TestParseAlterTableDropColumn(t *testing.T) {
+	sql := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, legacy TEXT);
+		ALTER TABLE users DROP COLUMN legacy;
+		ALTER TABLE users DROP COLUMN IF EXISTS missing;
+	`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	columns := schema.Tables[0].Columns
+	if len(columns) != 2 {
+		t.Fatalf("Expected 2 columns after DROP COLUMN, got %d", len(columns))
+	}
+	if columns[0].Name != "id" || columns[1].Name != "email" {
+		t.Errorf("Expected columns [id email], got [%s %s]", columns[0].Name, columns[1].Name)
+	}
+}
+
+func TestParseAlterTableDropPrimaryKeyColumn(t *testing.T) {
+	sql := `
+		CREATE TABLE m (a INT, b INT, PRIMARY KEY (a, b));
+		ALTER TABLE m DROP COLUMN a;
+	`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	if len(table.PrimaryKey) != 0 {
+		t.Errorf("Expected primary key to be dropped, got %v", table.PrimaryKey)
+	}
+	if table.Columns[0].IsPrimaryKey {
+		t.Error("Expected remaining column to no longer be PRIMARY KEY")
+	}
+}
+
+func TestParseAlterTableDropMissingColumn(t *testing.T) {
+	sql := `
+		CREATE TABLE users (id INTEGER);
+		ALTER TABLE users DROP COLUMN missing;
+	`
+
+	_, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err == nil {
+		t.Fatal("Expected error for dropping a missing column, got nil")
+	}
+	if !strings.Contains(err.Error(), `column "missing" of table "users" does not exist`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestParseAlterTableAddColumn(t *testing.T) {
+	sql := `
+		CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);
+		ALTER TABLE users ADD COLUMN last_login TIMESTAMPTZ;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS email TEXT;
+		ALTER TABLE users
+			ADD COLUMN team_id BIGINT NOT NULL DEFAULT 0 REFERENCES teams ON DELETE CASCADE,
+			ADD COLUMN handle TEXT UNIQUE CHECK (handle <> '');
+	`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	var columns []string
+	for _, col := range table.Columns {
+		columns = append(columns, col.Name)
+	}
+	if !reflect.DeepEqual(columns, []string{"id", "email", "last_login", "team_id", "handle"}) {
+		t.Fatalf("Unexpected columns %v", columns)
+	}
+
+	lastLogin := table.Columns[2]
+	if lastLogin.Type != "timestamp with time zone" || !lastLogin.Nullable {
+		t.Errorf("Expected nullable timestamp with time zone last_login, got %+v", lastLogin)
+	}
+	team := table.Columns[3]
+	if team.Nullable || team.Default == nil || *team.Default != "0" {
+		t.Errorf("Expected NOT NULL team_id with default 0, got %+v", team)
+	}
+	if len(table.ForeignKeys) != 1 || table.ForeignKeys[0].Name != "users_team_id_fkey" || table.ForeignKeys[0].ReferencedTable != "teams" {
+		t.Errorf("Expected foreign key users_team_id_fkey, got %+v", table.ForeignKeys)
+	}
+	if len(table.UniqueConstraints) != 1 || table.UniqueConstraints[0].Name != "users_handle_key" {
+		t.Errorf("Expected unique constraint users_handle_key, got %+v", table.UniqueConstraints)
+	}
+	if len(table.CheckConstraints) != 1 || table.CheckConstraints[0].Name != "users_handle_check" {
+		t.Errorf("Expected check constraint users_handle_check, got %+v", table.CheckConstraints)
+	}
+}
+
+func TestParseAlterTableAddConstraint(t *testing.T) {
+	sql := `
+		CREATE TABLE users (id BIGINT NOT NULL, email TEXT);
+		CREATE TABLE posts (id BIGINT PRIMARY KEY, author_id BIGINT);
+		ALTER TABLE users ADD PRIMARY KEY (id);
+		ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+		ALTER TABLE users ADD CONSTRAINT users_email_check CHECK (email LIKE '%@%') NOT VALID;
+		ALTER TABLE posts ADD CONSTRAINT posts_author_fk FOREIGN KEY (author_id) REFERENCES users (id);
+	`
+
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %+v", coverage)
+	}
+
+	users := schema.Tables[0]
+	if !reflect.DeepEqual(users.PrimaryKey, []string{"id"}) || !users.Columns[0].IsPrimaryKey {
+		t.Errorf("Expected primary key (id), got %v", users.PrimaryKey)
+	}
+	if len(users.UniqueConstraints) != 1 || users.UniqueConstraints[0].Name != "users_email_key" {
+		t.Errorf("Expected unique constraint users_email_key, got %+v", users.UniqueConstraints)
+	}
+	if len(users.CheckConstraints) != 1 || users.CheckConstraints[0].Name != "users_email_check" {
+		t.Errorf("Expected check constraint users_email_check, got %+v", users.CheckConstraints)
+	}
+
+	posts := schema.Tables[1]
+	if len(posts.ForeignKeys) != 1 {
+		t.Fatalf("Expected 1 foreign key, got %+v", posts.ForeignKeys)
+	}
+	fk := posts.ForeignKeys[0]
+	if fk.Name != "posts_author_fk" || !reflect.DeepEqual(fk.Columns, []string{"author_id"}) || fk.ReferencedTable != "users" {
+		t.Errorf("Unexpected foreign key %+v", fk)
+	}
+}
+
+func TestParseStatementsBeforeCreateTable(t *testing.T) {
+	sql := `ALTER TABLE users ADD COLUMN email TEXT NOT NULL;
+CREATE INDEX users_email_idx ON users (email);
+COMMENT ON COLUMN users.email IS 'Login';
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+CREATE POLICY users_self ON users USING (true);
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+CREATE TABLE admins (LIKE users INCLUDING ALL);`
+
+	coverage := &Coverage{}
+	schema := newSchema(database.DialectPostgres)
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %+v", coverage)
+	}
+
+	users := schema.Tables[0]
+	email := findColumn(&users, "email")
+	if email == nil || email.Nullable || email.Comment != "Login" {
+		t.Fatalf("Expected the added, commented email column, got %+v", users.Columns)
+	}
+	if len(users.Indexes) != 1 || !users.RLSEnabled || len(users.Policies) != 1 {
+		t.Errorf("Expected the index, RLS and policy to be applied, got %+v", users)
+	}
+
+	// The deferred statements are applied before later statements see the
+	// table
+	if admins := schema.Tables[1]; findColumn(&admins, "email") == nil || len(admins.Indexes) != 1 {
+		t.Errorf("Expected LIKE to copy the altered table, got %+v", admins)
+	}
+
+	// Failures of deferred statements are reported in source order
+	_, diagnostics := ParseWithDiagnostics(`ALTER TABLE users ADD COLUMN id INT;
+CREATE TABLE users (id BIGINT, PRIMARY KEY (missing));
+CREATE TABLE users (id BIGINT);
+ALTER TABLE posts ADD COLUMN title TEXT;`, database.DialectPostgres, "schema.lp.sql")
+	var lines []int
+	for _, d := range diagnostics {
+		lines = append(lines, d.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{1, 2, 4}) {
+		t.Errorf("Expected failures on lines 1, 2 and 4, got %+v", diagnostics)
+	}
+}
+
+func TestParseAlterTableAddErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name:     "alter before create",
+			sql:      `ALTER TABLE accounts ADD COLUMN email TEXT;`,
+			expected: `table "accounts" does not exist; ALTER TABLE must come after its CREATE TABLE`,
+		},
+		{
+			name:     "alter before create in another schema",
+			sql:      `ALTER TABLE auth.users ENABLE ROW LEVEL SECURITY;`,
+			expected: `table "auth.users" does not exist`,
+		},
+		{
+			name:     "add existing column",
+			sql:      `ALTER TABLE users ADD COLUMN name TEXT;`,
+			expected: `column "name" of table "users" already exists`,
+		},
+		{
+			name:     "add second primary key",
+			sql:      `ALTER TABLE users ADD PRIMARY KEY (name);`,
+			expected: `table "users" already has a primary key`,
+		},
+		{
+			name:     "add primary key column",
+			sql:      `ALTER TABLE users ADD COLUMN uuid UUID PRIMARY KEY;`,
+			expected: `table "users" already has a primary key`,
+		},
+		{
+			name:     "add constraint on missing column",
+			sql:      `ALTER TABLE users ADD UNIQUE (missing);`,
+			expected: `unique column "missing" does not exist in table "users"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := "CREATE TABLE users (id BIGINT PRIMARY KEY, name TEXT);\n" + tt.sql
+			_, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// IF EXISTS skips a missing table
+	if _, err := ParseSQLSchemaWithDialect(`ALTER TABLE IF EXISTS accounts ADD COLUMN email TEXT;`, database.DialectPostgres); err != nil {
+		t.Errorf("Expected ALTER TABLE IF EXISTS to be skipped, got %v", err)
+	}
+}
+
+func TestParseStorageOptions(t *testing.T) {
+	sql := `CREATE TABLE events (id BIGINT PRIMARY KEY) WITH (fillfactor = 90, autovacuum_enabled = false, toast.autovacuum_enabled);
+ALTER TABLE events SET (fillfactor = 70, autovacuum_vacuum_scale_factor = 0.05);
+ALTER TABLE events RESET (autovacuum_enabled, toast.autovacuum_enabled);
+CREATE TABLE legacy (id BIGINT PRIMARY KEY) WITH (OIDS = false);
+`
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"fillfactor":                     "70",
+		"autovacuum_vacuum_scale_factor": "0.05",
+	}
+	if !reflect.DeepEqual(schema.Tables[0].Options, expected) {
+		t.Errorf("Expected options %v, got %v", expected, schema.Tables[0].Options)
+	}
+	if schema.Tables[1].Options != nil {
+		t.Errorf("Expected OIDS not to be recorded as a storage parameter, got %v", schema.Tables[1].Options)
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %d of %d", coverage.Modeled, coverage.Statements)
+	}
+
+	// Resetting every parameter leaves no options
+	schema, err := ParseSQLSchemaWithDialect(`CREATE TABLE events (id BIGINT) WITH (fillfactor = 90);
+ALTER TABLE events RESET (fillfactor);`, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if schema.Tables[0].Options != nil {
+		t.Errorf("Expected no options after RESET, got %v", schema.Tables[0].Options)
+	}
+}
+
+func TestParseTablePersistence(t *testing.T) {
+	sql := `CREATE UNLOGGED TABLE cache (key TEXT PRIMARY KEY, value JSONB) WITH (fillfactor = 70);
+CREATE TEMPORARY TABLE scratch (id BIGINT);
+CREATE TABLE events (id BIGINT);
+CREATE TABLE imports (id BIGINT);
+ALTER TABLE events SET UNLOGGED;
+ALTER TABLE cache SET LOGGED;
+`
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	expected := []database.TablePersistence{"", database.TablePersistenceTemporary, database.TablePersistenceUnlogged, ""}
+	for i, table := range schema.Tables {
+		if table.Persistence != expected[i] {
+			t.Errorf("Expected %s to have persistence %q, got %q", table.Name, expected[i], table.Persistence)
+		}
+	}
+	if !reflect.DeepEqual(schema.Tables[0].Options, map[string]string{"fillfactor": "70"}) {
+		t.Errorf("Expected fillfactor 70, got %v", schema.Tables[0].Options)
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %d of %d", coverage.Modeled, coverage.Statements)
+	}
+
+	formatted, err := FormatSQL("CREATE UNLOGGED TABLE cache (key TEXT PRIMARY KEY) WITH (fillfactor = 70);", "cache.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+	if !strings.HasPrefix(formatted, "CREATE UNLOGGED TABLE cache (") || !strings.Contains(formatted, ") WITH (fillfactor = 70);") {
+		t.Errorf("Expected an unlogged table with fillfactor 70, got:\n%s", formatted)
+	}
+}
+
+func TestParseIdentityColumn(t *testing.T) {
+	sql := `CREATE TABLE users (
+  id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  legacy_id INTEGER GENERATED BY DEFAULT AS IDENTITY,
+  name TEXT
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	cols := schema.Tables[0].Columns
+	if cols[0].Identity == nil || !cols[0].Identity.Always {
+		t.Errorf("Expected id to be GENERATED ALWAYS AS IDENTITY, got %+v", cols[0].Identity)
+	}
+	if cols[1].Identity == nil || cols[1].Identity.Always {
+		t.Errorf("Expected legacy_id to be GENERATED BY DEFAULT AS IDENTITY, got %+v", cols[1].Identity)
+	}
+	if cols[1].Nullable {
+		t.Error("Expected identity column to be NOT NULL")
+	}
+	if cols[2].Identity != nil {
+		t.Errorf("Expected name not to be an identity column, got %+v", cols[2].Identity)
+	}
+}
+
+func TestParseIdentitySequenceName(t *testing.T) {
+	sql := `CREATE TABLE users (
+  id BIGINT GENERATED ALWAYS AS IDENTITY,
+  legacy_id INTEGER GENERATED BY DEFAULT AS IDENTITY (SEQUENCE NAME legacy.user_ids START 100),
+  other_id BIGINT NOT NULL
+);
+ALTER TABLE users ALTER COLUMN other_id ADD GENERATED BY DEFAULT AS IDENTITY;
+CREATE TABLE archived_users (LIKE users INCLUDING IDENTITY);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	tests := []struct {
+		table    int
+		column   int
+		sequence string
+	}{
+		{0, 0, "users_id_seq"},
+		{0, 1, "legacy.user_ids"},
+		{0, 2, "users_other_id_seq"},
+		{1, 0, "archived_users_id_seq"},
+	}
+	for _, tt := range tests {
+		table := schema.Tables[tt.table]
+		col := table.Columns[tt.column]
+		if col.Identity == nil {
+			t.Errorf("Expected %s.%s to be an identity column", table.Name, col.Name)
+			continue
+		}
+		if col.Identity.Sequence != tt.sequence {
+			t.Errorf("Expected %s.%s to use sequence %q, got %q", table.Name, col.Name, tt.sequence, col.Identity.Sequence)
+		}
+	}
+}
+
+func TestParseIdentitySequenceOptions(t *testing.T) {
+	sql := `CREATE TABLE events (
+  id BIGINT GENERATED ALWAYS AS IDENTITY (START WITH 10 INCREMENT BY -2 MINVALUE -100 MAXVALUE 9223372036854775807 CACHE 5 CYCLE),
+  legacy_id INTEGER GENERATED BY DEFAULT AS IDENTITY (NO MINVALUE NO CYCLE)
+);
+CREATE TABLE archived_events (LIKE events INCLUDING IDENTITY);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	int64Ptr := func(n int64) *int64 { return &n }
+	expected := &database.IdentitySpec{
+		Always:    true,
+		Sequence:  "events_id_seq",
+		Start:     int64Ptr(10),
+		Increment: int64Ptr(-2),
+		MinValue:  int64Ptr(-100),
+		MaxValue:  int64Ptr(9223372036854775807),
+		Cache:     int64Ptr(5),
+		Cycle:     true,
+	}
+	if id := schema.Tables[0].Columns[0]; !reflect.DeepEqual(id.Identity, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, id.Identity)
+	}
+
+	legacy := schema.Tables[0].Columns[1].Identity
+	if legacy == nil || legacy.MinValue != nil || legacy.Cycle || legacy.Start != nil {
+		t.Errorf("Expected NO MINVALUE and NO CYCLE to leave the defaults, got %+v", legacy)
+	}
+
+	// LIKE copies the options to the new table's sequence
+	archived := schema.Tables[1].Columns[0].Identity
+	if archived == nil || archived.Sequence != "archived_events_id_seq" || archived.Start == nil || *archived.Start != 10 || !archived.Cycle {
+		t.Errorf("Expected LIKE to copy the sequence options, got %+v", archived)
+	}
+}
+
+func TestParseAlterTableAddIdentity(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT NOT NULL, name TEXT);
+ALTER TABLE users ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	id := schema.Tables[0].Columns[0]
+	if id.Identity == nil || !id.Identity.Always {
+		t.Errorf("Expected id to be GENERATED ALWAYS AS IDENTITY, got %+v", id.Identity)
+	}
+}
+
+func TestParseAlterTableDropIdentity(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT GENERATED BY DEFAULT AS IDENTITY, name TEXT);
+ALTER TABLE users ALTER COLUMN id DROP IDENTITY;
+ALTER TABLE users ALTER COLUMN name DROP IDENTITY IF EXISTS;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	id := schema.Tables[0].Columns[0]
+	if id.Identity != nil {
+		t.Errorf("Expected identity to be dropped, got %+v", id.Identity)
+	}
+	if id.Nullable {
+		t.Error("Expected id to stay NOT NULL after dropping identity")
+	}
+}
+
+func TestParseAlterTableIdentityErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name:     "add identity to unknown column",
+			sql:      `ALTER TABLE users ALTER COLUMN missing ADD GENERATED ALWAYS AS IDENTITY;`,
+			expected: `column "missing" of table "users" does not exist`,
+		},
+		{
+			name:     "drop identity from unknown column",
+			sql:      `ALTER TABLE users ALTER COLUMN missing DROP IDENTITY;`,
+			expected: `column "missing" of table "users" does not exist`,
+		},
+		{
+			name:     "add identity to nullable column",
+			sql:      `ALTER TABLE users ALTER COLUMN name ADD GENERATED ALWAYS AS IDENTITY;`,
+			expected: `column "name" of table "users" must be declared NOT NULL before identity can be added`,
+		},
+		{
+			name:     "add identity twice",
+			sql:      `ALTER TABLE users ALTER COLUMN id ADD GENERATED BY DEFAULT AS IDENTITY;`,
+			expected: `column "id" of table "users" is already an identity column`,
+		},
+		{
+			name:     "drop missing identity",
+			sql:      `ALTER TABLE users ALTER COLUMN name DROP IDENTITY;`,
+			expected: `column "name" of table "users" is not an identity column`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql := "CREATE TABLE users (id BIGINT GENERATED ALWAYS AS IDENTITY, name TEXT);\n" + tt.sql
+			_, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseSourceLocations(t *testing.T) {
+	sql := "-- users\nCREATE TABLE users (\n  id INTEGER,\n  email TEXT\n);\n\nCREATE INDEX users_email_idx ON users (email);\n"
+
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "users.lp.sql", database.DialectPostgres, nil); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	expectLocation(t, "table", table.SourceLocation, "users.lp.sql", 2, 14)
+	expectLocation(t, "id column", table.Columns[0].SourceLocation, "users.lp.sql", 3, 3)
+	expectLocation(t, "email column", table.Columns[1].SourceLocation, "users.lp.sql", 4, 3)
+	expectLocation(t, "index", table.Indexes[0].SourceLocation, "users.lp.sql", 7, 1)
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	sql := "CREATE TABLE ok (id INT);\n\nCREATE TABLE bad (a INT, PRIMARY KEY (missing));\n"
+
+	schema := &database.Schema{}
+	err := parseSQLSchemaWithFilename(schema, sql, "bad.lp.sql", database.DialectPostgres, nil)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	if err.Error() != `bad.lp.sql:3:1: failed to parse CREATE TABLE: primary key column "missing" does not exist in table "bad"` {
+		t.Errorf("Unexpected error message: %q", err.Error())
+	}
+}
+
+func TestParseWithDiagnostics(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);
+CREATE TABLE bad (a INT, PRIMARY KEY (missing));
+ALTER TABLE IF EXISTS missing ENABLE ROW LEVEL SECURITY;
+  ALTER TABLE users DROP COLUMN nope;
+CREATE TABLE posts (id BIGINT PRIMARY KEY);
+`
+
+	schema, diagnostics := ParseWithDiagnostics(sql, database.DialectPostgres, "app.lp.sql")
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d: %v", len(diagnostics), diagnostics)
+	}
+
+	expected := []Diagnostic{
+		{
+			Severity: SeverityError,
+			Code:     CodeParseError,
+			Message:  `failed to parse CREATE TABLE: primary key column "missing" does not exist in table "bad"`,
+			File:     "app.lp.sql",
+			Line:     2,
+			Column:   1,
+			HelpURI:  helpURI(CodeParseError),
+		},
+		{
+			Severity: SeverityError,
+			Code:     CodeParseError,
+			Message:  `failed to parse ALTER TABLE: column "nope" of table "users" does not exist`,
+			File:     "app.lp.sql",
+			Line:     4,
+			Column:   3,
+			HelpURI:  helpURI(CodeParseError),
+		},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("Expected diagnostics %+v, got %+v", expected, diagnostics)
+	}
+
+	// Parsing continued past the failing statements
+	var tables []string
+	for _, table := range schema.Tables {
+		tables = append(tables, table.Name)
+	}
+	if !reflect.DeepEqual(tables, []string{"users", "posts"}) {
+		t.Errorf("Expected tables users and posts, got %v", tables)
+	}
+
+	// The error-returning API stops at the first failure
+	if _, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("Expected the first failure, got %v", err)
+	}
+
+	schema, diagnostics = ParseWithDiagnostics("CREATE TABLE (", database.DialectPostgres, "broken.lp.sql")
+	if len(diagnostics) != 1 || diagnostics[0].File != "broken.lp.sql" || len(schema.Tables) != 0 {
+		t.Errorf("Expected a single syntax error diagnostic, got %v", diagnostics)
+	}
+}
+
+func TestParseWithDiagnosticsSyntaxErrorPosition(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		file   string
+		line   int
+		column int
+	}{
+		{"offending token", "CREATE TABLE users (id BIGINT);\nCREATE TABLE posts id BIGINT);\n", "app.lp.sql", 2, 20},
+		{"multibyte characters before the error", "-- café\nCREATE TABLE posts id BIGINT);\n", "app.lp.sql", 2, 20},
+		{"end of input", "CREATE TABLE posts (id BIGINT", "app.lp.sql", 1, 30},
+		{"file section", "CREATE TABLE users (id BIGINT);\n-- lockplane:file posts\nCREATE TABLE posts id BIGINT);\n", "posts", 3, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostics := ParseWithDiagnostics(tt.sql, database.DialectPostgres, "app.lp.sql")
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+			}
+			d := diagnostics[0]
+			if d.Code != CodeParseError || d.File != tt.file || d.Line != tt.line || d.Column != tt.column {
+				t.Errorf("Expected a parse error at %s:%d:%d, got %+v", tt.file, tt.line, tt.column, d)
+			}
+		})
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT);
+CREATE TABLE auth.users (id BIGINT PRIMARY KEY);
+COMMENT ON TABLE users IS 'application users';
+COMMENT ON COLUMN users.email IS 'login email';
+COMMENT ON COLUMN public.users.id IS 'surrogate key';
+COMMENT ON TABLE auth.users IS 'auth users';
+COMMENT ON TABLE auth.users IS NULL;
+COMMENT ON TABLE missing IS 'ignored';
+COMMENT ON COLUMN users.missing IS 'ignored';`
+
+	coverage := &Coverage{}
+	schema := &database.Schema{Tables: []database.Table{}}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	users := schema.Tables[0]
+	if users.Comment != "application users" {
+		t.Errorf("Expected table comment 'application users', got %q", users.Comment)
+	}
+	if users.Columns[0].Comment != "surrogate key" {
+		t.Errorf("Expected id comment 'surrogate key', got %q", users.Columns[0].Comment)
+	}
+	if users.Columns[1].Comment != "login email" {
+		t.Errorf("Expected email comment 'login email', got %q", users.Columns[1].Comment)
+	}
+	if authUsers := schema.Tables[1]; authUsers.Comment != "" {
+		t.Errorf("Expected COMMENT ... IS NULL to remove the comment, got %q", authUsers.Comment)
+	}
+
+	if coverage.Ignored != 2 || coverage.IgnoredByKind["CommentStmt"] != 2 {
+		t.Errorf("Expected the 2 comments on unknown objects to be ignored, got %+v", coverage)
+	}
+}
+
+func TestParseInheritsAndPartitionOf(t *testing.T) {
+	sql := `CREATE TABLE events (id BIGINT, created_at DATE) PARTITION BY RANGE (created_at);
+CREATE TABLE audit.events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+CREATE TABLE archived_events (archived_at DATE) INHERITS (events, audit.events_2024);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	partition := schema.Tables[1]
+	if partition.PartitionOf == nil || partition.PartitionOf.Table != "events" || partition.PartitionOf.Schema != "" {
+		t.Errorf("Expected audit.events_2024 to be a partition of events, got %+v", partition.PartitionOf)
+	}
+	if len(partition.Inherits) != 0 {
+		t.Errorf("Expected a partition not to list inherited tables, got %+v", partition.Inherits)
+	}
+
+	expected := []database.TableRef{{Table: "events"}, {Schema: "audit", Table: "events_2024"}}
+	if child := schema.Tables[2]; !reflect.DeepEqual(child.Inherits, expected) || child.PartitionOf != nil {
+		t.Errorf("Expected archived_events to inherit %+v, got %+v (partition of %+v)", expected, child.Inherits, child.PartitionOf)
+	}
+}
+
+func TestParsePartitionBy(t *testing.T) {
+	sql := `CREATE TABLE events (id BIGINT, created_at DATE) PARTITION BY RANGE (created_at, id);
+CREATE TABLE users (id BIGINT, email TEXT) PARTITION BY HASH (lower(email));
+CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01', 0) TO ('2025-01-01', 0) PARTITION BY LIST (id);
+CREATE TABLE notes (id BIGINT);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	expected := []*database.PartitionSpec{
+		{Strategy: database.PartitionStrategyRange, Columns: []string{"created_at", "id"}},
+		{Strategy: database.PartitionStrategyHash, Columns: []string{"lower(email)"}},
+		{Strategy: database.PartitionStrategyList, Columns: []string{"id"}},
+		nil,
+	}
+	for i, want := range expected {
+		if got := schema.Tables[i].PartitionBy; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected table %s to be partitioned by %+v, got %+v", schema.Tables[i].Name, want, got)
+		}
+	}
+	if partition := schema.Tables[2]; partition.PartitionOf == nil || partition.PartitionOf.Table != "events" {
+		t.Errorf("Expected events_2024 to be a partition of events, got %+v", partition.PartitionOf)
+	}
+}
+
+func TestParseForeignKeysAndUniqueConstraints(t *testing.T) {
+	sql := `CREATE TABLE memberships (
+  org_id BIGINT REFERENCES orgs,
+  user_id BIGINT NOT NULL,
+  email TEXT UNIQUE,
+  CONSTRAINT memberships_user_fk FOREIGN KEY (user_id, email) REFERENCES auth.users (id, email),
+  UNIQUE (org_id, user_id)
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	table := schema.Tables[0]
+
+	if len(table.ForeignKeys) != 2 {
+		t.Fatalf("Expected 2 foreign keys, got %+v", table.ForeignKeys)
+	}
+	orgFK := table.ForeignKeys[0]
+	if orgFK.Name != "memberships_org_id_fkey" || !reflect.DeepEqual(orgFK.Columns, []string{"org_id"}) ||
+		orgFK.ReferencedTable != "orgs" || orgFK.ReferencedSchema != "" || len(orgFK.ReferencedColumns) != 0 {
+		t.Errorf("Unexpected column foreign key %+v", orgFK)
+	}
+	expectLocation(t, "column foreign key", orgFK.SourceLocation, "", 2, 17)
+
+	userFK := table.ForeignKeys[1]
+	if userFK.Name != "memberships_user_fk" || !reflect.DeepEqual(userFK.Columns, []string{"user_id", "email"}) ||
+		userFK.ReferencedSchema != "auth" || userFK.ReferencedTable != "users" ||
+		!reflect.DeepEqual(userFK.ReferencedColumns, []string{"id", "email"}) {
+		t.Errorf("Unexpected table foreign key %+v", userFK)
+	}
+	expectLocation(t, "table foreign key", userFK.SourceLocation, "", 5, 3)
+
+	expectedUniques := []struct {
+		name    string
+		columns []string
+	}{
+		{"memberships_email_key", []string{"email"}},
+		{"memberships_org_id_user_id_key", []string{"org_id", "user_id"}},
+	}
+	if len(table.UniqueConstraints) != len(expectedUniques) {
+		t.Fatalf("Expected %d unique constraints, got %+v", len(expectedUniques), table.UniqueConstraints)
+	}
+	for i, expected := range expectedUniques {
+		unique := table.UniqueConstraints[i]
+		if unique.Name != expected.name || !reflect.DeepEqual(unique.Columns, expected.columns) {
+			t.Errorf("Expected unique constraint %s %v, got %s %v", expected.name, expected.columns, unique.Name, unique.Columns)
+		}
+	}
+}
+
+func TestParseForeignKeyActions(t *testing.T) {
+	sql := `CREATE TABLE posts (
+  id BIGINT PRIMARY KEY,
+  author_id BIGINT REFERENCES users ON DELETE SET NULL ON UPDATE RESTRICT DEFERRABLE INITIALLY DEFERRED,
+  editor_id BIGINT REFERENCES users ON DELETE NO ACTION INITIALLY DEFERRED,
+  org_id BIGINT,
+  team_id BIGINT,
+  FOREIGN KEY (org_id, team_id) REFERENCES teams MATCH FULL ON DELETE CASCADE ON UPDATE CASCADE DEFERRABLE
+);
+ALTER TABLE posts ADD COLUMN reviewer_id BIGINT REFERENCES users ON DELETE SET DEFAULT NOT DEFERRABLE;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	expected := []struct {
+		name              string
+		onDelete          database.FKAction
+		onUpdate          database.FKAction
+		match             database.FKMatch
+		deferrable        bool
+		initiallyDeferred bool
+	}{
+		{"posts_author_id_fkey", database.FKActionSetNull, database.FKActionRestrict, "", true, true},
+		// NO ACTION is the default, and INITIALLY DEFERRED implies DEFERRABLE
+		{"posts_editor_id_fkey", "", "", "", true, true},
+		{"posts_org_id_team_id_fkey", database.FKActionCascade, database.FKActionCascade, database.FKMatchFull, true, false},
+		{"posts_reviewer_id_fkey", database.FKActionSetDefault, "", "", false, false},
+	}
+	keys := schema.Tables[0].ForeignKeys
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected %d foreign keys, got %+v", len(expected), keys)
+	}
+	for i, want := range expected {
+		fk := keys[i]
+		if fk.Name != want.name || fk.OnDelete != want.onDelete || fk.OnUpdate != want.onUpdate || fk.Match != want.match ||
+			fk.Deferrable != want.deferrable || fk.InitiallyDeferred != want.initiallyDeferred {
+			t.Errorf("Expected %+v, got %+v", want, fk)
+		}
+	}
+}
+
+func TestParseForeignKeyMissingLocalColumn(t *testing.T) {
+	sql := `CREATE TABLE posts (id BIGINT, FOREIGN KEY (author_id) REFERENCES users (id));`
+
+	_, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err == nil || !strings.Contains(err.Error(), `foreign key column "author_id" does not exist`) {
+		t.Errorf("Expected missing foreign key column error, got %v", err)
+	}
+}
+
+func TestParseDropColumnDropsConstraints(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY, email TEXT UNIQUE CHECK (email <> ''), org_id BIGINT REFERENCES orgs);
+ALTER TABLE users DROP COLUMN email, DROP COLUMN org_id;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	if len(table.UniqueConstraints) != 0 || len(table.ForeignKeys) != 0 || len(table.CheckConstraints) != 0 {
+		t.Errorf("Expected constraints on dropped columns to be dropped, got %+v %+v %+v",
+			table.UniqueConstraints, table.ForeignKeys, table.CheckConstraints)
+	}
+}
+
+func TestParseAttachPartition(t *testing.T) {
+	sql := `CREATE TABLE events (id BIGINT, created_at DATE) PARTITION BY RANGE (created_at);
+CREATE TABLE events_2023 PARTITION OF events FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');
+CREATE TABLE archive.events_2024 (id BIGINT, created_at DATE);
+ALTER TABLE events ATTACH PARTITION archive.events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	expected := []database.TableRef{{Table: "events_2023"}, {Schema: "archive", Table: "events_2024"}}
+	if parent := schema.Tables[0]; !reflect.DeepEqual(parent.Partitions, expected) {
+		t.Errorf("Expected partitions %+v, got %+v", expected, parent.Partitions)
+	}
+	if child := schema.Tables[2]; child.PartitionOf == nil || child.PartitionOf.Table != "events" {
+		t.Errorf("Expected archive.events_2024 to be a partition of events, got %+v", child.PartitionOf)
+	}
+}
+
+func TestParseDetachPartition(t *testing.T) {
+	sql := `CREATE TABLE events (id BIGINT, created_at DATE) PARTITION BY RANGE (created_at);
+CREATE TABLE events_2023 PARTITION OF events FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');
+CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+ALTER TABLE events DETACH PARTITION events_2023 CONCURRENTLY;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	expected := []database.TableRef{{Table: "events_2024"}}
+	if parent := schema.Tables[0]; !reflect.DeepEqual(parent.Partitions, expected) {
+		t.Errorf("Expected partitions %+v, got %+v", expected, parent.Partitions)
+	}
+	if detached := schema.Tables[1]; detached.PartitionOf != nil {
+		t.Errorf("Expected events_2023 to no longer be a partition, got %+v", detached.PartitionOf)
+	}
+}
+
+func TestParsePartitionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		err  string
+	}{
+		{
+			name: "attach an existing partition",
+			sql: `CREATE TABLE a (id INT) PARTITION BY LIST (id);
+CREATE TABLE b (id INT) PARTITION BY LIST (id);
+CREATE TABLE a_1 PARTITION OF a FOR VALUES IN (1);
+ALTER TABLE b ATTACH PARTITION a_1 FOR VALUES IN (1);`,
+			err: `table "public.a_1" is already a partition of "a"`,
+		},
+		{
+			name: "detach a table that isn't a partition",
+			sql: `CREATE TABLE a (id INT) PARTITION BY LIST (id);
+CREATE TABLE other (id INT);
+ALTER TABLE a DETACH PARTITION other;`,
+			err: `table "public.other" is not a partition of "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSQLSchemaWithDialect(tt.sql, database.DialectPostgres)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseFailedPartitionOf(t *testing.T) {
+	sql := `CREATE TABLE events (id INT) PARTITION BY LIST (id);
+CREATE TABLE events_1 PARTITION OF events (PRIMARY KEY (missing)) FOR VALUES IN (1);`
+	schema, diagnostics := ParseWithDiagnostics(sql, database.DialectPostgres, "app.lp.sql")
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", diagnostics)
+	}
+	if len(schema.Tables) != 1 {
+		t.Fatalf("Expected only the parent table, got %d tables", len(schema.Tables))
+	}
+	if partitions := schema.Tables[0].Partitions; len(partitions) != 0 {
+		t.Errorf("Expected the failed partition not to be registered, got %+v", partitions)
+	}
+}
+
+func TestParseCreateExtension(t *testing.T) {
+	sql := `CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA extensions;
+CREATE EXTENSION citext;
+`
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	expected := []database.Extension{
+		{Name: "uuid-ossp", Schema: "extensions", IfNotExists: true, SourceLocation: &database.SourceLocation{Line: 1, Column: 1, EndLine: 1, EndColumn: 66}},
+		{Name: "citext", SourceLocation: &database.SourceLocation{Line: 2, Column: 1, EndLine: 2, EndColumn: 24}},
+	}
+	if !reflect.DeepEqual(schema.Extensions, expected) {
+		t.Errorf("Expected extensions %+v, got %+v", expected, schema.Extensions)
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %d of %d", coverage.Modeled, coverage.Statements)
+	}
+
+	formatted, err := FormatSQL(sql, "extensions.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+	if want := "CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\" WITH SCHEMA extensions;\n\nCREATE EXTENSION citext;\n"; formatted != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, formatted)
+	}
+	if _, err := FormatSQL("CREATE EXTENSION postgis VERSION '3.4' CASCADE;", "extensions.lp.sql"); !errors.Is(err, ErrCannotFormat) {
+		t.Errorf("Expected a versioned extension not to be formatted, got %v", err)
+	}
+}
+
+func TestParseCreateSequence(t *testing.T) {
+	sql := `CREATE SEQUENCE app.users_id_seq START 100 INCREMENT BY 5;
+CREATE SEQUENCE counters;
+`
+	coverage := &Coverage{}
+	schema := &database.Schema{}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	start, increment := int64(100), int64(5)
+	expected := []database.Sequence{
+		{Name: "users_id_seq", Schema: "app", Start: &start, Increment: &increment, SourceLocation: &database.SourceLocation{Line: 1, Column: 1, EndLine: 1, EndColumn: 58}},
+		{Name: "counters", SourceLocation: &database.SourceLocation{Line: 2, Column: 1, EndLine: 2, EndColumn: 25}},
+	}
+	if !reflect.DeepEqual(schema.Sequences, expected) {
+		t.Errorf("Expected sequences %+v, got %+v", expected, schema.Sequences)
+	}
+	if coverage.Modeled != coverage.Statements {
+		t.Errorf("Expected every statement to be modeled, got %d of %d", coverage.Modeled, coverage.Statements)
+	}
+
+	formatted, err := FormatSQL(sql, "sequences.lp.sql")
+	if err != nil {
+		t.Fatalf("FormatSQL failed: %v", err)
+	}
+	if want := "CREATE SEQUENCE app.users_id_seq START 100 INCREMENT 5;\n\nCREATE SEQUENCE counters;\n"; formatted != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, formatted)
+	}
+	if _, err := FormatSQL("CREATE SEQUENCE counters MAXVALUE 1000 CYCLE;", "sequences.lp.sql"); !errors.Is(err, ErrCannotFormat) {
+		t.Errorf("Expected a sequence with other options not to be formatted, got %v", err)
+	}
+}
+
+func TestParseCreateDomain(t *testing.T) {
+	sql := `CREATE DOMAIN auth.email AS VARCHAR(255) NOT NULL DEFAULT '' CHECK (VALUE LIKE '%@%');
+CREATE TABLE auth.users (
+  id SERIAL PRIMARY KEY,
+  email auth.email
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if len(schema.Domains) != 1 {
+		t.Fatalf("Expected 1 domain, got %d", len(schema.Domains))
+	}
+	domain := schema.Domains[0]
+	if domain.Name != "email" || domain.Schema != "auth" {
+		t.Errorf("Expected domain auth.email, got %s.%s", domain.Schema, domain.Name)
+	}
+	if domain.BaseType != "varchar(255)" {
+		t.Errorf("Expected base type 'varchar(255)', got %q", domain.BaseType)
+	}
+	if !domain.NotNull {
+		t.Error("Expected domain to be NOT NULL")
+	}
+	if domain.Default == nil || *domain.Default != "''" {
+		t.Errorf("Expected default '', got %v", domain.Default)
+	}
+	expectLocation(t, "domain", domain.SourceLocation, "", 1, 1)
+	if len(domain.CheckConstraints) != 1 {
+		t.Fatalf("Expected 1 check constraint, got %+v", domain.CheckConstraints)
+	}
+	check := domain.CheckConstraints[0]
+	if check.Name != "email_check" || check.Expression != "value LIKE '%@%'" {
+		t.Errorf("Expected check email_check (value LIKE '%%@%%'), got %s (%s)", check.Name, check.Expression)
+	}
+	expectLocation(t, "domain check", check.SourceLocation, "", 1, 62)
+
+	emailType, err := schema.EffectiveColumnType("auth.users", "email")
+	if err != nil {
+		t.Fatalf("EffectiveColumnType failed: %v", err)
+	}
+	if emailType.Type != "varchar(255)" || !emailType.NotNull {
+		t.Errorf("Expected NOT NULL varchar(255), got %+v", emailType)
+	}
+
+	idType, err := schema.EffectiveColumnType("auth.users", "id")
+	if err != nil {
+		t.Fatalf("EffectiveColumnType failed: %v", err)
+	}
+	if idType.Type != "integer" || !idType.Serial {
+		t.Errorf("Expected serial integer, got %+v", idType)
+	}
+}
+
+func TestParseCreateDomainCheckNames(t *testing.T) {
+	sql := `CREATE DOMAIN code AS TEXT CHECK (VALUE <> '') CONSTRAINT code_check1 CHECK (length(VALUE) < 10) CHECK (VALUE = upper(VALUE)) CHECK (VALUE !~ ' ');`
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	var names []string
+	for _, check := range schema.Domains[0].CheckConstraints {
+		names = append(names, check.Name)
+	}
+	if expected := []string{"code_check", "code_check1", "code_check2", "code_check3"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected checks %v, got %v", expected, names)
+	}
+}
+
+func TestParseGeneratedColumn(t *testing.T) {
+	sql := `CREATE TABLE line_items (
+  price NUMERIC NOT NULL,
+  qty INTEGER NOT NULL,
+  amount NUMERIC GENERATED ALWAYS AS (price * qty) STORED
+);
+CREATE TABLE archived_line_items (LIKE line_items INCLUDING GENERATED);
+CREATE TABLE draft_line_items (LIKE line_items);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	amount := schema.Tables[0].Columns[2]
+	if amount.Generated == nil {
+		t.Fatal("Expected amount to be a generated column")
+	}
+	if amount.Generated.Expression != "price * qty" || amount.Generated.Storage != database.GeneratedStored {
+		t.Errorf("Expected STORED column generated from 'price * qty', got %+v", amount.Generated)
+	}
+	if amount.Default != nil {
+		t.Errorf("Expected a generated column to have no default, got %q", *amount.Default)
+	}
+	if !amount.Nullable {
+		t.Error("Expected generated column to be nullable")
+	}
+	if schema.Tables[0].Columns[0].Generated != nil {
+		t.Error("Expected price not to be generated")
+	}
+
+	if archived := schema.Tables[1].Columns[2]; archived.Generated == nil || archived.Generated.Expression != "price * qty" {
+		t.Errorf("Expected INCLUDING GENERATED to copy the expression, got %+v", archived.Generated)
+	}
+	if draft := schema.Tables[2].Columns[2]; draft.Generated != nil {
+		t.Errorf("Expected LIKE without INCLUDING GENERATED not to copy the expression, got %+v", draft.Generated)
+	}
+}
+
+func TestParseCreateEnum(t *testing.T) {
+	sql := `CREATE TYPE mood AS ENUM ('happy', 'sad', 'ok');
+CREATE TYPE auth.role AS ENUM ();
+CREATE TABLE people (
+  id BIGINT PRIMARY KEY,
+  current_mood mood,
+  role auth.role
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if len(schema.Enums) != 2 {
+		t.Fatalf("Expected 2 enums, got %d", len(schema.Enums))
+	}
+	mood := schema.Enums[0]
+	if mood.Name != "mood" || mood.Schema != "" {
+		t.Errorf("Expected enum mood, got %s.%s", mood.Schema, mood.Name)
+	}
+	if !reflect.DeepEqual(mood.Values, []string{"happy", "sad", "ok"}) {
+		t.Errorf("Expected values in declaration order, got %v", mood.Values)
+	}
+	expectLocation(t, "enum", mood.SourceLocation, "", 1, 1)
+
+	role := schema.Enums[1]
+	if role.Name != "role" || role.Schema != "auth" || len(role.Values) != 0 {
+		t.Errorf("Expected empty enum auth.role, got %+v", role)
+	}
+	expectLocation(t, "enum", role.SourceLocation, "", 2, 1)
+
+	if schema.FindEnum("mood") != &schema.Enums[0] || schema.FindEnum("auth.role") != &schema.Enums[1] {
+		t.Error("Expected FindEnum to find both enums")
+	}
+	if schema.FindEnum("auth.mood") != nil {
+		t.Error("Expected FindEnum to respect the schema")
+	}
+	for _, typ := range []string{"mood", "auth.role[]", "varchar(20)", "timestamp with time zone"} {
+		if !schema.IsKnownType(typ) {
+			t.Errorf("Expected %q to be a known type", typ)
+		}
+	}
+	if schema.IsKnownType("public.role") {
+		t.Error("Expected public.role not to be a known type")
+	}
+}
+
+func TestParseCreateSchema(t *testing.T) {
+	sql := `CREATE SCHEMA auth;
+CREATE SCHEMA IF NOT EXISTS auth;
+CREATE SCHEMA AUTHORIZATION reporting;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if !reflect.DeepEqual(schema.Schemas, []string{"auth", "reporting"}) {
+		t.Errorf("Expected schemas auth and reporting, got %v", schema.Schemas)
+	}
+	expectLocation(t, "schema auth", schema.SchemaLocations["auth"], "", 1, 1)
+	expectLocation(t, "schema reporting", schema.SchemaLocations["reporting"], "", 3, 1)
+
+	if _, err := ParseSQLSchemaWithDialect("CREATE SCHEMA auth;\nCREATE SCHEMA auth;", database.DialectPostgres); err == nil || !strings.Contains(err.Error(), `schema "auth" already exists`) {
+		t.Errorf("Expected a duplicate schema error, got %v", err)
+	}
+}
+
+func TestParseMaterializedViews(t *testing.T) {
+	sql := `CREATE TABLE orders (id BIGINT PRIMARY KEY, customer_id BIGINT, total NUMERIC);
+CREATE MATERIALIZED VIEW customer_totals AS
+  SELECT customer_id, sum(total) AS total FROM orders GROUP BY customer_id
+  WITH NO DATA;
+CREATE UNIQUE INDEX ON customer_totals (customer_id);
+CREATE MATERIALIZED VIEW reports.order_count AS SELECT count(*) FROM orders;
+CREATE VIEW big_orders AS SELECT * FROM orders WHERE total > 100;
+CREATE OR REPLACE VIEW big_orders AS SELECT * FROM orders WHERE total > 1000;`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if len(schema.Views) != 3 {
+		t.Fatalf("Expected 3 views, got %+v", schema.Views)
+	}
+
+	totals := schema.Views[0]
+	if totals.Name != "customer_totals" || !totals.Materialized || totals.WithData {
+		t.Errorf("Expected materialized view customer_totals WITH NO DATA, got %+v", totals)
+	}
+	if totals.Query != "SELECT customer_id, sum(total) AS total FROM orders GROUP BY customer_id" {
+		t.Errorf("Unexpected query %q", totals.Query)
+	}
+	expectLocation(t, "materialized view", totals.SourceLocation, "", 2, 26)
+	if len(totals.Indexes) != 1 {
+		t.Fatalf("Expected the index to be on the materialized view, got %+v", totals.Indexes)
+	}
+	if index := totals.Indexes[0]; index.Name != "customer_totals_customer_id_idx" || !index.Unique || !reflect.DeepEqual(index.Columns, []string{"customer_id"}) {
+		t.Errorf("Unexpected index %+v", index)
+	}
+	if len(schema.Tables[0].Indexes) != 0 {
+		t.Errorf("Expected no indexes on orders, got %+v", schema.Tables[0].Indexes)
+	}
+
+	if count := schema.Views[1]; count.Schema != "reports" || !count.Materialized || !count.WithData {
+		t.Errorf("Expected materialized view reports.order_count WITH DATA, got %+v", count)
+	}
+	if big := schema.Views[2]; big.Materialized || big.Query != "SELECT * FROM orders WHERE total > 1000" {
+		t.Errorf("Expected the replaced view big_orders, got %+v", big)
+	}
+
+	errorTests := map[string]string{
+		"index on a view": "CREATE VIEW v AS SELECT 1 AS n;\nCREATE INDEX ON v (n);",
+		"duplicate view":  "CREATE VIEW v AS SELECT 1;\nCREATE MATERIALIZED VIEW v AS SELECT 2;",
+	}
+	for name, sql := range errorTests {
+		if _, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseQuotedTypeNames(t *testing.T) {
+	sql := `CREATE TYPE "MyType" AS ENUM ('a', 'b');
+CREATE TYPE auth."Role" AS ENUM ('admin');
+CREATE TABLE t (
+  quoted "MyType",
+  folded mytype,
+  qualified auth."Role",
+  qualified_folded auth.role,
+  quoted_array "MyType"[],
+  builtin INT4
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"quoted":           `"MyType"`,
+		"folded":           "mytype",
+		"qualified":        `auth."Role"`,
+		"qualified_folded": "auth.role",
+		"quoted_array":     `"MyType"[]`,
+		"builtin":          "integer",
+	}
+	for _, col := range schema.Tables[0].Columns {
+		if col.Type != expected[col.Name] {
+			t.Errorf("Expected column %s to have type %s, got %s", col.Name, expected[col.Name], col.Type)
+		}
+	}
+
+	if schema.Enums[0].Name != "MyType" || schema.Enums[1].Schema != "auth" || schema.Enums[1].Name != "Role" {
+		t.Fatalf("Expected enums MyType and auth.Role, got %+v", schema.Enums)
+	}
+	for _, typ := range []string{`"MyType"`, `auth."Role"`, `"MyType"[]`} {
+		if !schema.IsKnownType(typ) {
+			t.Errorf("Expected %s to be a known type", typ)
+		}
+	}
+	for _, typ := range []string{"mytype", "auth.role"} {
+		if schema.IsKnownType(typ) {
+			t.Errorf("Expected %s not to be a known type", typ)
+		}
+	}
+}
+
+func TestParseCheckConstraints(t *testing.T) {
+	sql := `CREATE TABLE products (
+  id BIGINT PRIMARY KEY,
+  price NUMERIC CHECK (price > 0),
+  CONSTRAINT always_true CHECK (1 = 1)
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	checks := schema.Tables[0].CheckConstraints
+	if len(checks) != 2 {
+		t.Fatalf("Expected 2 check constraints, got %d", len(checks))
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expr       *database.Expr
+		line       int
+		column     int
+	}{
+		{
+			name:       "products_price_check",
+			expression: "price > 0",
+			expr: &database.Expr{Kind: database.ExprOperator, Op: ">", Args: []*database.Expr{
+				{Kind: database.ExprColumn, Name: "price"},
+				{Kind: database.ExprLiteral, LiteralType: database.LiteralInteger, Value: "0"},
+			}},
+			line:   3,
+			column: 17,
+		},
+		{
+			name:       "always_true",
+			expression: "1 = 1",
+			expr: &database.Expr{Kind: database.ExprOperator, Op: "=", Args: []*database.Expr{
+				{Kind: database.ExprLiteral, LiteralType: database.LiteralInteger, Value: "1"},
+				{Kind: database.ExprLiteral, LiteralType: database.LiteralInteger, Value: "1"},
+			}},
+			line:   4,
+			column: 3,
+		},
+	}
+
+	for i, tt := range tests {
+		check := checks[i]
+		if check.Name != tt.name {
+			t.Errorf("Expected constraint %q, got %q", tt.name, check.Name)
+		}
+		if check.Expression != tt.expression {
+			t.Errorf("Expected %s expression %q, got %q", tt.name, tt.expression, check.Expression)
+		}
+		if !reflect.DeepEqual(check.Expr, tt.expr) {
+			got, _ := json.Marshal(check.Expr)
+			want, _ := json.Marshal(tt.expr)
+			t.Errorf("Expected %s tree %s, got %s", tt.name, want, got)
+		}
+		expectLocation(t, tt.name, check.SourceLocation, "", tt.line, tt.column)
+	}
+}
+
+func TestParseCheckConstraintNames(t *testing.T) {
+	sql := `CREATE TABLE t (
+  a INT CHECK (a > b),
+  b INT CHECK (b > 0) CHECK (true),
+  CHECK (a > 0),
+  CHECK (a > 1),
+  CHECK (t.a < 100),
+  CONSTRAINT t_check2 CHECK (a <> b),
+  CHECK (a + b > 0)
+);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	// Only an expression referencing a single column names the column, even
+	// on a column constraint, and taken names are numbered as PostgreSQL does
+	var names []string
+	for _, check := range schema.Tables[0].CheckConstraints {
+		names = append(names, check.Name)
+	}
+	expected := []string{"t_check", "t_b_check", "t_check1", "t_a_check", "t_a_check1", "t_a_check2", "t_check2", "t_check3"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected check constraints %v, got %v", expected, names)
+	}
+}
+
+func TestParseCheckExpressionText(t *testing.T) {
+	tests := []struct {
+		check    string
+		expected string
+	}{
+		{"status IN ('active', 'inactive')", "status IN ('active', 'inactive')"},
+		{"price > 0 AND price < 100", "(price > 0) AND (price < 100)"},
+		{"NOT (deleted_at IS NULL)", "NOT (deleted_at IS NULL)"},
+		{"quantity BETWEEN 1 AND 10", "quantity BETWEEN 1 AND 10"},
+		{"length(name) > 0", "length(name) > 0"},
+		{"name LIKE 'a%'", "name LIKE 'a%'"},
+	}
+
+	for _, tt := range tests {
+		sql := "CREATE TABLE t (status TEXT, price INT, deleted_at TIMESTAMP, quantity INT, name TEXT, CHECK (" + tt.check + "));"
+		schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+		if err != nil {
+			t.Fatalf("ParseSQLSchemaWithDialect(%q) failed: %v", tt.check, err)
+		}
+		if got := schema.Tables[0].CheckConstraints[0].Expression; got != tt.expected {
+			t.Errorf("CHECK (%s): expected %q, got %q", tt.check, tt.expected, got)
+		}
+	}
+}
+
+func expectLocation(t *testing.T, what string, loc *database.SourceLocation, file string, line int, column int) {
+	t.Helper()
+	if loc == nil {
+		t.Fatalf("Expected %s to have a source location", what)
+	}
+	if loc.File != file || loc.Line != line || loc.Column != column {
+		t.Errorf("Expected %s at %s:%d:%d, got %s", what, file, line, column, loc)
+	}
+}
+
+func TestParseAggregatesAndOperatorsAsOtherObjects(t *testing.T) {
+	sql := `CREATE AGGREGATE analytics.array_accum (anyelement) (
+    sfunc = array_append,
+    stype = anyarray,
+    initcond = '{}'
+);
+CREATE OPERATOR === (
+    leftarg = box,
+    rightarg = box,
+    function = box_eq
+);
+GRANT USAGE ON SCHEMA public TO app;`
+
+	coverage := &Coverage{}
+	schema := &database.Schema{Tables: []database.Table{}}
+	if err := parseSQLSchemaWithFilename(schema, sql, "objects.lp.sql", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	if len(schema.OtherObjects) != 2 {
+		t.Fatalf("Expected 2 other objects, got %+v", schema.OtherObjects)
+	}
+
+	aggregate := schema.OtherObjects[0]
+	if aggregate.Kind != "aggregate" || aggregate.Schema != "analytics" || aggregate.Name != "array_accum" {
+		t.Errorf("Unexpected aggregate %+v", aggregate)
+	}
+	expectLocation(t, "aggregate", aggregate.SourceLocation, "objects.lp.sql", 1, 1)
+
+	operator := schema.OtherObjects[1]
+	if operator.Kind != "operator" || operator.Schema != "" || operator.Name != "===" {
+		t.Errorf("Unexpected operator %+v", operator)
+	}
+	expectLocation(t, "operator", operator.SourceLocation, "objects.lp.sql", 6, 1)
+
+	// Tracked objects aren't counted as ignored statements
+	if coverage.Statements != 3 || coverage.Tracked != 2 || coverage.Ignored != 1 || coverage.Modeled != 0 {
+		t.Errorf("Expected 2 tracked and 1 ignored statement, got %+v", coverage)
+	}
+	if _, ok := coverage.IgnoredByKind["DefineStmt"]; ok {
+		t.Errorf("Expected no ignored DefineStmt, got %v", coverage.IgnoredByKind)
+	}
+}
+
+func TestParseFileMarkers(t *testing.T) {
+	sql := `CREATE TABLE settings (key TEXT PRIMARY KEY);
+
+-- lockplane:file users
+CREATE TABLE users (
+  id BIGINT PRIMARY KEY,
+  bio TEXT DEFAULT '-- lockplane:file not_a_marker'
+);
+CREATE INDEX users_bio_idx ON users (bio);
+
+-- lockplane:file billing
+CREATE TABLE invoices (id BIGINT PRIMARY KEY);`
+
+	schema := &database.Schema{Tables: []database.Table{}}
+	if err := parseSQLSchemaWithFilename(schema, sql, "schema.lp.sql", database.DialectPostgres, nil); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	expectLocation(t, "settings", schema.Tables[0].SourceLocation, "schema.lp.sql", 1, 14)
+	expectLocation(t, "users", schema.Tables[1].SourceLocation, "users", 4, 14)
+	expectLocation(t, "users.bio", schema.Tables[1].Columns[1].SourceLocation, "users", 6, 3)
+	expectLocation(t, "users_bio_idx", schema.Tables[1].Indexes[0].SourceLocation, "users", 8, 1)
+	expectLocation(t, "invoices", schema.Tables[2].SourceLocation, "billing", 11, 14)
+
+	// Parse errors are attributed to the section too
+	err := parseSQLSchemaWithFilename(&database.Schema{}, sql+"\nALTER TABLE invoices DETACH PARTITION users;", "schema.lp.sql", database.DialectPostgres, nil)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if parseErr.File != "billing" || parseErr.Line != 12 {
+		t.Errorf("Expected the error at billing:12, got %s:%d", parseErr.File, parseErr.Line)
+	}
+}
+
+func TestParseExclusionConstraint(t *testing.T) {
+	sql := `CREATE TABLE reservations (
+  room INT,
+  during TSTZRANGE,
+  cancelled BOOLEAN,
+  EXCLUDE USING gist (room gist_int4_ops WITH =, during WITH &&) WHERE (NOT cancelled)
+);
+ALTER TABLE reservations ADD CONSTRAINT reservations_day_excl EXCLUDE ((lower(during)) WITH OPERATOR(pg_catalog.=));`
+
+	coverage := &Coverage{}
+	schema := newSchema(database.DialectPostgres)
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	exclusions := schema.Tables[0].ExclusionConstraints
+	if len(exclusions) != 2 {
+		t.Fatalf("Expected 2 exclusion constraints, got %+v", exclusions)
+	}
+
+	rooms := exclusions[0]
+	if rooms.Name != "reservations_room_during_excl" || rooms.Method != "gist" || rooms.Where != "NOT cancelled" {
+		t.Errorf("Unexpected exclusion constraint: %+v", rooms)
+	}
+	expected := []database.ExclusionElement{
+		{Column: "room", OpClass: "gist_int4_ops", Operator: "="},
+		{Column: "during", Operator: "&&"},
+	}
+	if !reflect.DeepEqual(rooms.Elements, expected) {
+		t.Errorf("Expected elements %+v, got %+v", expected, rooms.Elements)
+	}
+	expectLocation(t, "exclusion constraint", rooms.SourceLocation, "", 5, 3)
+
+	day := exclusions[1]
+	expected = []database.ExclusionElement{{Expression: "lower(during)", Operator: "pg_catalog.="}}
+	if day.Name != "reservations_day_excl" || day.Method != "btree" || day.Where != "" || !reflect.DeepEqual(day.Elements, expected) {
+		t.Errorf("Unexpected exclusion constraint: %+v", day)
+	}
+	if coverage.Modeled != 2 {
+		t.Errorf("Expected every statement to be modeled, got %+v", coverage)
+	}
+
+	// Dropping a column drops the constraints that use it
+	dropped, err := ParseSQLSchemaWithDialect(sql+"\nALTER TABLE reservations DROP COLUMN room;", database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+	if len(dropped.Tables[0].ExclusionConstraints) != 1 {
+		t.Errorf("Expected only the expression constraint to remain, got %+v", dropped.Tables[0].ExclusionConstraints)
+	}
+
+	_, err = ParseSQLSchemaWithDialect(`CREATE TABLE t (a INT, EXCLUDE (missing WITH =));`, database.DialectPostgres)
+	if err == nil || !strings.Contains(err.Error(), `exclusion column "missing" does not exist`) {
+		t.Errorf("Expected an error for an unknown column, got %v", err)
+	}
+}
+
+func TestParseConstraintUsingIndex(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT NOT NULL, email TEXT, tenant_id BIGINT);
+CREATE UNIQUE INDEX users_email_idx ON users (tenant_id, email);
+CREATE UNIQUE INDEX users_id_idx ON users (id);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE USING INDEX users_email_idx;
+ALTER TABLE users ADD PRIMARY KEY USING INDEX users_id_idx;`
+
+	coverage := &Coverage{}
+	schema := &database.Schema{Tables: []database.Table{}}
+	if err := parseSQLSchemaWithFilename(schema, sql, "", database.DialectPostgres, coverage); err != nil {
+		t.Fatalf("parseSQLSchemaWithFilename failed: %v", err)
+	}
+
+	users := schema.Tables[0]
+	if len(users.UniqueConstraints) != 1 {
+		t.Fatalf("Expected 1 unique constraint, got %+v", users.UniqueConstraints)
+	}
+	unique := users.UniqueConstraints[0]
+	if unique.Name != "users_email_key" || unique.Index != "users_email_idx" {
+		t.Errorf("Expected users_email_key using users_email_idx, got %+v", unique)
+	}
+	if !reflect.DeepEqual(unique.Columns, []string{"tenant_id", "email"}) {
+		t.Errorf("Expected the index's columns, got %v", unique.Columns)
+	}
+	expectLocation(t, "unique constraint", unique.SourceLocation, "", 4, 23)
+
+	if !reflect.DeepEqual(users.PrimaryKey, []string{"id"}) || users.PrimaryKeyIndex != "users_id_idx" {
+		t.Errorf("Expected primary key (id) using users_id_idx, got %v using %q", users.PrimaryKey, users.PrimaryKeyIndex)
+	}
+	if users.PrimaryKeyName != "users_id_idx" {
+		t.Errorf("Expected the primary key to take the index's name, got %q", users.PrimaryKeyName)
+	}
+	if !users.Columns[0].IsPrimaryKey {
+		t.Error("Expected id to be marked as a primary key column")
+	}
+	if coverage.Modeled != 5 {
+		t.Errorf("Expected every statement to be modeled, got %+v", coverage)
+	}
+
+	for _, bad := range []struct {
+		sql string
+		err string
+	}{
+		{"ALTER TABLE users ADD CONSTRAINT k UNIQUE USING INDEX missing_idx;", `index "missing_idx" does not exist on table "users"`},
+		{"CREATE INDEX users_tenant_idx ON users (tenant_id);\nALTER TABLE users ADD CONSTRAINT k UNIQUE USING INDEX users_tenant_idx;", `index "users_tenant_idx" is not a unique index`},
+	} {
+		_, err := ParseSQLSchemaWithDialect("CREATE TABLE users (id BIGINT, tenant_id BIGINT);\n"+bad.sql, database.DialectPostgres)
+		if err == nil || !strings.Contains(err.Error(), bad.err) {
+			t.Errorf("Expected error containing %q, got %v", bad.err, err)
+		}
+	}
+}
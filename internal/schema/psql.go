@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// metaCommand is a psql meta-command line, such as \connect app, found in a
+// schema file
+type metaCommand struct {
+	offset int
+	// text is the line from the backslash on, without trailing whitespace
+	text string
+}
+
+// stripMetaCommands replaces the psql meta-command lines of sql, which pg_query
+// can't parse, with spaces. Newlines are kept so offsets into the SQL, and so
+// source locations, don't change. A meta-command is a line whose first
+// non-blank character is a backslash outside a string literal, quoted
+// identifier or comment.
+func stripMetaCommands(sql string) (string, []metaCommand) {
+	if !strings.Contains(sql, `\`) {
+		return sql, nil
+	}
+
+	out := []byte(sql)
+	n := len(out)
+	var commands []metaCommand
+	lineStart := true
+
+	for i := 0; i < n; {
+		c := out[i]
+		if c == '\n' {
+			lineStart = true
+			i++
+			continue
+		}
+		if isSpace(c) {
+			i++
+			continue
+		}
+		if c == '\\' && lineStart {
+			end := i
+			for end < n && out[end] != '\n' {
+				end++
+			}
+			commands = append(commands, metaCommand{offset: i, text: strings.TrimRight(sql[i:end], " \t\r")})
+			blank(out, i, end)
+			i = end
+			continue
+		}
+
+		lineStart = false
+		switch {
+		case c == '"':
+			i = skipQuoted(out, i, c)
+
+		case c == '\'':
+			i = skipStringLiteral(out, i, i > 0 && (out[i-1] == 'E' || out[i-1] == 'e'))
+
+		case c == '$':
+			i = skipDollarQuoted(out, i)
+
+		case c == '-' && i+1 < n && out[i+1] == '-':
+			for i < n && out[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < n && out[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				return string(out), commands
+			}
+			i += end + 4
+
+		case isIdentStart(c):
+			for i < n && isIdentChar(out[i]) {
+				i++
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return string(out), commands
+}
+
+// skipStringLiteral returns the offset just past the string literal starting
+// at start. In an escape string (E'...') a backslash escapes the next
+// character.
+func skipStringLiteral(src []byte, start int, escapes bool) int {
+	if !escapes {
+		return skipQuoted(src, start, '\'')
+	}
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\'':
+			if i+1 < len(src) && src[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// skipDollarQuoted returns the offset just past the dollar-quoted string
+// starting at start, such as a function body quoted with $$ or $body$. A $
+// that doesn't start a dollar quote, as in a $1 parameter, is skipped alone.
+func skipDollarQuoted(src []byte, start int) int {
+	i := start + 1
+	if i < len(src) && isIdentStart(src[i]) {
+		for i < len(src) && isIdentChar(src[i]) && src[i] != '$' {
+			i++
+		}
+	}
+	if i >= len(src) || src[i] != '$' {
+		return start + 1
+	}
+
+	tag := string(src[start : i+1])
+	end := strings.Index(string(src[i+1:]), tag)
+	if end == -1 {
+		return len(src)
+	}
+	return i + 1 + end + len(tag)
+}
+
+// metaCommandDiagnostics returns a warning for each psql meta-command in sql,
+// which is stripped before parsing when CheckOptions.StripMetaCommands is set
+func metaCommandDiagnostics(sql string, filename string) []Diagnostic {
+	_, commands := stripMetaCommands(sql)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	locate := newLocator(sql, filename)
+	diagnostics := make([]Diagnostic, 0, len(commands))
+	for _, command := range commands {
+		diagnostics = append(diagnostics, diagnosticAt(locate.at(int32(command.offset)), CodeMetaCommand,
+			fmt.Sprintf("psql meta-command %q was ignored", command.text)))
+	}
+	return diagnostics
+}
+
+// findMetaCommands returns a warning for each psql meta-command in files
+func findMetaCommands(files []string) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SQL file %s: %w", file, err)
+		}
+		diagnostics = append(diagnostics, metaCommandDiagnostics(string(data), file)...)
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripMetaCommands(t *testing.T) {
+	sql := `\connect app
+\set ON_ERROR_STOP on
+CREATE TABLE users (id BIGINT PRIMARY KEY);
+  \i other.sql  
+CREATE FUNCTION f() RETURNS text AS $body$
+\not a meta-command
+$body$ LANGUAGE sql;
+COMMENT ON TABLE users IS 'a
+\still a string';
+SELECT E'it\'s
+\escaped';
+/* block
+\comment */
+`
+
+	stripped, commands := stripMetaCommands(sql)
+	if len(stripped) != len(sql) || strings.Count(stripped, "\n") != strings.Count(sql, "\n") {
+		t.Fatalf("Expected offsets and lines to be kept, got %q", stripped)
+	}
+
+	var texts []string
+	for _, command := range commands {
+		texts = append(texts, command.text)
+	}
+	if want := []string{`\connect app`, `\set ON_ERROR_STOP on`, `\i other.sql`}; !reflect.DeepEqual(texts, want) {
+		t.Fatalf("Expected meta-commands %q, got %q", want, texts)
+	}
+	if commands[2].offset != strings.Index(sql, `\i`) {
+		t.Errorf("Expected \\i at offset %d, got %d", strings.Index(sql, `\i`), commands[2].offset)
+	}
+
+	for _, kept := range []string{`\not a meta-command`, `\still a string`, `\escaped`, `\comment`} {
+		if !strings.Contains(stripped, kept) {
+			t.Errorf("Expected %q to be kept, got %q", kept, stripped)
+		}
+	}
+	if strings.Contains(stripped, `\connect`) || strings.Contains(stripped, `\i other.sql`) {
+		t.Errorf("Expected meta-commands to be blanked, got %q", stripped)
+	}
+}
+
+func TestStripMetaCommandsWithoutBackslashes(t *testing.T) {
+	sql := `CREATE TABLE users (id BIGINT PRIMARY KEY);`
+	stripped, commands := stripMetaCommands(sql)
+	if stripped != sql || commands != nil {
+		t.Errorf("Expected SQL without backslashes to be unchanged, got %q, %+v", stripped, commands)
+	}
+}
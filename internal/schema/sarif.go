@@ -0,0 +1,143 @@
+package schema
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// SARIF 2.1.0, the static analysis format read by editors and by GitHub code
+// scanning. Only the properties lockplane fills in are modeled.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+	HelpURI          string        `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// MarshalSARIF converts a check report to a SARIF 2.1.0 log with a single run.
+// Each diagnostic becomes a result whose rule is the diagnostic code; the
+// rules reported are listed on the tool driver. toolVersion may be empty.
+func MarshalSARIF(output *CheckOutput, toolVersion string) ([]byte, error) {
+	var codes []string
+	seen := map[string]bool{}
+	for _, d := range output.Diagnostics {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			codes = append(codes, d.Code)
+		}
+	}
+	sort.Strings(codes)
+
+	rules := make([]sarifRule, len(codes))
+	ruleIndex := make(map[string]int, len(codes))
+	for i, code := range codes {
+		rules[i] = sarifRule{ID: code, HelpURI: helpURI(code)}
+		if description := codeDescription(code); description != "" {
+			rules[i].ShortDescription = &sarifMessage{Text: description}
+		}
+		ruleIndex[code] = i
+	}
+
+	results := make([]sarifResult, len(output.Diagnostics))
+	for i, d := range output.Diagnostics {
+		results[i] = sarifResult{
+			RuleID:    d.Code,
+			RuleIndex: ruleIndex[d.Code],
+			Level:     sarifLevel(d.Severity),
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.File)},
+					Region: sarifRegion{
+						StartLine:   max(d.Line, 1),
+						StartColumn: max(d.Column, 1),
+						EndLine:     d.EndLine,
+						EndColumn:   d.EndColumn,
+					},
+				},
+			}},
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "lockplane",
+				Version:        toolVersion,
+				InformationURI: "https://github.com/lockplane/lockplane",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	return json.MarshalIndent(log, "", "  ")
+}
+
+// sarifLevel maps a diagnostic severity to a SARIF result level
+func sarifLevel(severity string) string {
+	if severity == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalSARIF(t *testing.T) {
+	output := NewCheckOutput()
+	output.AddWarning(Diagnostic{Code: CodeMissingPrimaryKey, Message: `table "events" has no primary key`, File: `schema/events.lp.sql`, Line: 3, Column: 1})
+	output.AddError(Diagnostic{Code: CodeParseError, Message: "syntax error", File: "schema/users.lp.sql", Line: 1, Column: 8, EndLine: 1, EndColumn: 13})
+	output.AddWarning(Diagnostic{Code: CodeMissingPrimaryKey, Message: `table "logs" has no primary key`, File: "schema/logs.lp.sql", Line: 1, Column: 1})
+
+	data, err := MarshalSARIF(output, "v1.2.3")
+	if err != nil {
+		t.Fatalf("MarshalSARIF failed: %v", err)
+	}
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+					Rules   []struct {
+						ID               string `json:"id"`
+						ShortDescription struct {
+							Text string `json:"text"`
+						} `json:"shortDescription"`
+						HelpURI string `json:"helpUri"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+							EndLine     int `json:"endLine"`
+							EndColumn   int `json:"endColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("SARIF output is not valid JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Expected a SARIF 2.1.0 log with one run, got version %q and %d run(s)", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "lockplane" || run.Tool.Driver.Version != "v1.2.3" {
+		t.Errorf("Unexpected driver %+v", run.Tool.Driver)
+	}
+
+	rules := run.Tool.Driver.Rules
+	if len(rules) != 2 || rules[0].ID != CodeParseError || rules[1].ID != CodeMissingPrimaryKey {
+		t.Fatalf("Expected rules [%s %s], got %+v", CodeParseError, CodeMissingPrimaryKey, rules)
+	}
+	if rules[1].ShortDescription.Text != "A table has no primary key" {
+		t.Errorf("Unexpected rule description %q", rules[1].ShortDescription.Text)
+	}
+	if rules[1].HelpURI != "https://github.com/lockplane/lockplane/blob/main/docs/rules.md#lp001" {
+		t.Errorf("Unexpected rule help URI %q", rules[1].HelpURI)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(run.Results))
+	}
+	result := run.Results[1]
+	if result.RuleID != CodeParseError || result.RuleIndex != 0 || result.Level != "error" || result.Message.Text != "syntax error" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	loc := result.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "schema/users.lp.sql" || loc.Region.StartLine != 1 || loc.Region.StartColumn != 8 ||
+		loc.Region.EndLine != 1 || loc.Region.EndColumn != 13 {
+		t.Errorf("Unexpected location %+v", loc)
+	}
+	if region := run.Results[2].Locations[0].PhysicalLocation.Region; region.EndLine != 0 || region.EndColumn != 0 {
+		t.Errorf("Expected no end for a diagnostic without a range, got %+v", region)
+	}
+	if run.Results[0].Level != "warning" || run.Results[0].RuleIndex != 1 {
+		t.Errorf("Expected a warning for rule 1, got %+v", run.Results[0])
+	}
+}
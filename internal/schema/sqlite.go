@@ -0,0 +1,349 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+// sqliteOnlyKeywords are SQLite column modifiers that PostgreSQL's grammar
+// does not accept. They carry no information the schema model tracks, so they
+// are blanked out before parsing.
+var sqliteOnlyKeywords = [][]string{
+	{"autoincrement"},
+}
+
+// sqliteTableOptions are the options SQLite accepts after the closing
+// parenthesis of CREATE TABLE. They are blanked out there, and only there, so
+// a column named strict is kept.
+var sqliteTableOptions = [][]string{
+	{"without", "rowid"},
+	{"strict"},
+}
+
+// sqliteTypelessColumnType is the type given to a column declared without
+// one, which SQLite gives BLOB affinity. Like other SQLite type names, it's
+// normalized once parsed, to bytea.
+const sqliteTypelessColumnType = "blob"
+
+// sqliteTableConstraintKeywords start the table constraints of a CREATE TABLE
+// column list; any other element is a column
+var sqliteTableConstraintKeywords = []string{"constraint", "primary", "unique", "check", "foreign"}
+
+// sqliteColumnConstraintKeywords start the constraints that may follow the
+// name of a column declared without a type
+var sqliteColumnConstraintKeywords = []string{
+	"constraint", "primary", "not", "null", "unique", "check", "default", "collate", "references", "generated", "as",
+}
+
+// sqliteRewrite is SQLite DDL rewritten into PostgreSQL syntax, with the
+// types inserted for typeless columns
+type sqliteRewrite struct {
+	sql        string
+	insertions []insertion
+}
+
+// parseSQLiteSQLSchemaWithFilename parses SQLite-flavored DDL into the shared
+// schema model.
+//
+// SQLite's DDL is close enough to PostgreSQL's that, once the SQLite-only syntax
+// is rewritten, pg_query can parse it. The rewrite preserves byte offsets, apart
+// from the types it inserts, which the locator discounts, so locations
+// reported by the parser still point at the original source. SQLite type
+// names are normalized to their PostgreSQL equivalents once parsed.
+func parseSQLiteSQLSchemaWithFilename(schema *database.Schema, sql string, filename string, coverage *Coverage, cache *parseCache, stopOnError bool) []error {
+	rewrite := rewriteSQLiteDDL(sql)
+	locate := newLocator(rewrite.sql, filename)
+	locate.insertions = rewrite.insertions
+	errs := parseLocatedSQLSchema(schema, rewrite.sql, locate, coverage, cache, stopOnError)
+
+	for i := range schema.Tables {
+		for j := range schema.Tables[i].Columns {
+			col := &schema.Tables[i].Columns[j]
+			col.Type = database.NormalizeSQLiteType(col.Type)
+		}
+	}
+	return errs
+}
+
+// rewriteSQLiteDDL converts SQLite-specific syntax into PostgreSQL-compatible
+// syntax of the same length:
+//   - `ident` and [ident] quoting becomes "ident"
+//   - AUTOINCREMENT, and the WITHOUT ROWID and STRICT table options, are
+//     replaced with spaces
+//
+// The one rewrite that changes the length is then made: a column declared
+// without a type is given sqliteTypelessColumnType. String literals, quoted
+// identifiers and comments are left untouched.
+func rewriteSQLiteDDL(sql string) sqliteRewrite {
+	out := []byte(sql)
+	n := len(out)
+
+	// columnLists holds the offsets of the parentheses opening CREATE TABLE
+	// column lists, and closedAt the offset just past the last closed
+	// parenthesis at depth 0, which table options follow
+	var columnLists []int
+	depth, closedAt := 0, -1
+
+	for i := 0; i < n; {
+		switch c := out[i]; {
+		case c == '(':
+			depth++
+			i++
+
+		case c == ')':
+			depth--
+			if depth == 0 {
+				closedAt = i + 1
+			}
+			i++
+
+		case c == '\'' || c == '"':
+			i = skipQuoted(out, i, c)
+
+		case c == '-' && i+1 < n && out[i+1] == '-':
+			for i < n && out[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && i+1 < n && out[i+1] == '*':
+			end := strings.Index(string(out[i+2:]), "*/")
+			if end == -1 {
+				return insertColumnTypes(out, columnLists)
+			}
+			i += end + 4
+
+		case c == '`':
+			out[i] = '"'
+			end := skipQuoted(out, i, '`')
+			if end <= n && out[end-1] == '`' {
+				out[end-1] = '"'
+			}
+			i = end
+
+		case c == '[':
+			end := strings.IndexByte(string(out[i:]), ']')
+			if end == -1 {
+				return insertColumnTypes(out, columnLists)
+			}
+			out[i] = '"'
+			out[i+end] = '"'
+			i += end + 1
+
+		case isIdentStart(c):
+			start := i
+			for i < n && isIdentChar(out[i]) {
+				i++
+			}
+			end, ok := matchKeywords(out, start, sqliteOnlyKeywords)
+			if !ok && depth == 0 && followsTableDefinition(out, start, closedAt) {
+				end, ok = matchKeywords(out, start, sqliteTableOptions)
+			}
+			if ok {
+				// A comma separating table options goes with them
+				if depth == 0 && followsTableDefinition(out, start, closedAt) {
+					start = closedAt
+				}
+				for j := start; j < end; j++ {
+					if out[j] != '\n' {
+						out[j] = ' '
+					}
+				}
+				i = end
+			} else if strings.EqualFold(string(out[start:i]), "table") && depth == 0 {
+				if open := columnListAfter(out, i); open != -1 {
+					columnLists = append(columnLists, open)
+				}
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return insertColumnTypes(out, columnLists)
+}
+
+// followsTableDefinition reports whether offset follows the closing
+// parenthesis of a column list at closedAt, or a table option after it,
+// separated only by whitespace, blanked options and commas
+func followsTableDefinition(out []byte, offset int, closedAt int) bool {
+	if closedAt == -1 || closedAt > offset {
+		return false
+	}
+	for _, c := range out[closedAt:offset] {
+		if !isSpace(c) && c != ',' {
+			return false
+		}
+	}
+	return true
+}
+
+// columnListAfter returns the offset of the parenthesis opening the column
+// list of a CREATE TABLE whose TABLE keyword ends at offset, or -1 if there is
+// none, as for CREATE TABLE ... AS
+func columnListAfter(out []byte, offset int) int {
+	sql := string(out)
+	i := skipWhitespaceAndComments(sql, offset)
+	if word, end := nextWord(out, i); word == "if" {
+		if word, end = nextWord(out, end); word == "not" {
+			if word, end = nextWord(out, end); word == "exists" {
+				i = skipWhitespaceAndComments(sql, end)
+			}
+		}
+	}
+	for {
+		switch {
+		case i < len(out) && out[i] == '"':
+			i = skipQuoted(out, i, '"')
+		case i < len(out) && isIdentStart(out[i]):
+			for i < len(out) && isIdentChar(out[i]) {
+				i++
+			}
+		default:
+			return -1
+		}
+		i = skipWhitespaceAndComments(sql, i)
+		if i >= len(out) || out[i] != '.' {
+			break
+		}
+		i = skipWhitespaceAndComments(sql, i+1)
+	}
+	if i < len(out) && out[i] == '(' {
+		return i
+	}
+	return -1
+}
+
+// insertColumnTypes gives each column declared without a type in the column
+// lists opened at lists the type sqliteTypelessColumnType, recording where
+// the types were inserted
+func insertColumnTypes(out []byte, lists []int) sqliteRewrite {
+	sql := string(out)
+	var offsets []int
+	for _, open := range lists {
+		end := matchingParen(out, open)
+		if end == -1 {
+			continue
+		}
+		start := open + 1
+		for i := start; i <= end; i++ {
+			switch c := out[i]; {
+			case c == '\'' || c == '"':
+				i = skipQuoted(out, i, c) - 1
+			case c == '-' && i+1 < end && out[i+1] == '-', c == '/' && i+1 < end && out[i+1] == '*':
+				i = skipWhitespaceAndComments(sql, i) - 1
+			case c == '(':
+				i = matchingParen(out, i)
+				if i == -1 {
+					i = end
+				}
+			case c == ',' || i == end:
+				if offset, ok := typelessColumnName(sql, start, i); ok {
+					offsets = append(offsets, offset)
+				}
+				start = i + 1
+			}
+		}
+	}
+
+	rewrite := sqliteRewrite{}
+	var b strings.Builder
+	last := 0
+	for _, offset := range offsets {
+		b.WriteString(sql[last:offset])
+		rewrite.insertions = append(rewrite.insertions, insertion{offset: b.Len(), length: len(sqliteTypelessColumnType) + 1})
+		b.WriteString(" " + sqliteTypelessColumnType)
+		last = offset
+	}
+	b.WriteString(sql[last:])
+	rewrite.sql = b.String()
+	return rewrite
+}
+
+// typelessColumnName returns the offset just past the name of the column
+// defined by sql[start:end], an element of a column list, if the column is
+// declared without a type
+func typelessColumnName(sql string, start int, end int) (int, bool) {
+	i := skipWhitespaceAndComments(sql, start)
+	nameEnd := i
+	switch {
+	case i < end && sql[i] == '"':
+		nameEnd = skipQuoted([]byte(sql), i, '"')
+	case i < end && isIdentStart(sql[i]):
+		for nameEnd < end && isIdentChar(sql[nameEnd]) {
+			nameEnd++
+		}
+		if slices.Contains(sqliteTableConstraintKeywords, strings.ToLower(sql[i:nameEnd])) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	next := skipWhitespaceAndComments(sql, nameEnd)
+	if next >= end {
+		return nameEnd, true
+	}
+	word, _ := nextWord([]byte(sql), next)
+	return nameEnd, slices.Contains(sqliteColumnConstraintKeywords, word)
+}
+
+// matchKeywords reports whether one of keywords starts at start, returning the
+// offset just past the match.
+func matchKeywords(src []byte, start int, keywords [][]string) (int, bool) {
+	for _, words := range keywords {
+		pos := start
+		matched := true
+		for w, word := range words {
+			if w > 0 {
+				for pos < len(src) && isSpace(src[pos]) {
+					pos++
+				}
+			}
+			end := pos
+			for end < len(src) && isIdentChar(src[end]) {
+				end++
+			}
+			if !strings.EqualFold(string(src[pos:end]), word) {
+				matched = false
+				break
+			}
+			pos = end
+		}
+		if matched {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+// skipQuoted returns the offset just past the quoted token starting at start.
+// A doubled quote character is treated as an escaped quote.
+func skipQuoted(src []byte, start int, quote byte) int {
+	i := start + 1
+	for i < len(src) {
+		if src[i] == quote {
+			if i+1 < len(src) && src[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(src)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
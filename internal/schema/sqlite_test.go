@@ -0,0 +1,162 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+func TestParseSQLiteAutoIncrement(t *testing.T) {
+	sql := `CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL
+	);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if schema.Dialect != database.DialectSQLite {
+		t.Errorf("Expected dialect %q, got %q", database.DialectSQLite, schema.Dialect)
+	}
+
+	if len(schema.Tables) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(schema.Tables))
+	}
+
+	table := schema.Tables[0]
+	if len(table.Columns) != 2 {
+		t.Fatalf("Expected 2 columns, got %d", len(table.Columns))
+	}
+
+	id := table.Columns[0]
+	if id.Name != "id" || id.Type != "integer" {
+		t.Errorf("Expected id integer column, got %s %s", id.Name, id.Type)
+	}
+	if !id.IsPrimaryKey {
+		t.Error("Expected id to be PRIMARY KEY")
+	}
+
+	email := table.Columns[1]
+	if email.Nullable {
+		t.Error("Expected email to be NOT NULL")
+	}
+}
+
+func TestParseSQLiteWithoutRowIDAndStrict(t *testing.T) {
+	sql := `
+		CREATE TABLE kv (key TEXT PRIMARY KEY, value BLOB) WITHOUT ROWID;
+		CREATE TABLE counters (name TEXT, n INTEGER) strict;
+		CREATE TABLE flags (strict INTEGER, without TEXT) WITHOUT ROWID, STRICT;
+	`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	if len(schema.Tables) != 3 {
+		t.Fatalf("Expected 3 tables, got %d", len(schema.Tables))
+	}
+	if schema.Tables[0].Name != "kv" || schema.Tables[1].Name != "counters" {
+		t.Errorf("Unexpected tables: %q, %q", schema.Tables[0].Name, schema.Tables[1].Name)
+	}
+
+	// Columns named like the table options are kept
+	flags := schema.Tables[2]
+	if len(flags.Columns) != 2 || flags.Columns[0].Name != "strict" || flags.Columns[1].Name != "without" {
+		t.Errorf("Expected columns strict and without, got %+v", flags.Columns)
+	}
+}
+
+func TestParseSQLiteTypelessColumns(t *testing.T) {
+	sql := `CREATE TABLE IF NOT EXISTS notes (
+  id INTEGER PRIMARY KEY,
+  body, "title" NOT NULL, extra,
+  tag DEFAULT 'x',
+  CHECK (length(body) > 0)
+);
+CREATE TABLE pairs (a, b);`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	notes := schema.Tables[0]
+	expected := []string{"integer", "bytea", "bytea", "bytea", "bytea"}
+	if len(notes.Columns) != len(expected) {
+		t.Fatalf("Expected %d columns, got %+v", len(expected), notes.Columns)
+	}
+	for i, typ := range expected {
+		if got := notes.Columns[i].Type; got != typ {
+			t.Errorf("Column %s: expected type %q, got %q", notes.Columns[i].Name, typ, got)
+		}
+	}
+	if notes.Columns[2].Nullable || notes.Columns[3].Default != nil || *notes.Columns[4].Default != "'x'" {
+		t.Errorf("Expected the constraints of typeless columns to be kept, got %+v", notes.Columns)
+	}
+	if len(notes.CheckConstraints) != 1 {
+		t.Errorf("Expected the table CHECK to be kept, got %+v", notes.CheckConstraints)
+	}
+	if len(schema.Tables[1].Columns) != 2 || schema.Tables[1].Columns[1].Type != "bytea" {
+		t.Errorf("Expected pairs to have two bytea columns, got %+v", schema.Tables[1].Columns)
+	}
+
+	// Locations after an inserted type still point at the original source
+	expectLocation(t, "extra", notes.Columns[3].SourceLocation, "", 3, 27)
+	expectLocation(t, "tag", notes.Columns[4].SourceLocation, "", 4, 3)
+}
+
+func TestParseSQLiteQuotedIdentifiers(t *testing.T) {
+	sql := "CREATE TABLE `Users` ([Full Name] TEXT, \"id\" INTEGER);"
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	table := schema.Tables[0]
+	if table.Name != "Users" {
+		t.Errorf("Expected table name 'Users', got %q", table.Name)
+	}
+	if table.Columns[0].Name != "Full Name" {
+		t.Errorf("Expected column name 'Full Name', got %q", table.Columns[0].Name)
+	}
+	if table.Columns[1].Name != "id" {
+		t.Errorf("Expected column name 'id', got %q", table.Columns[1].Name)
+	}
+}
+
+func TestParseSQLiteLooseTypes(t *testing.T) {
+	sql := `CREATE TABLE events (payload BLOB, created DATETIME, amount NUMERIC, label VARCHAR(20));`
+
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectSQLite)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	expected := []string{"bytea", "timestamp without time zone", "numeric", "varchar(20)"}
+	for i, typ := range expected {
+		if got := schema.Tables[0].Columns[i].Type; got != typ {
+			t.Errorf("Column %d: expected type %q, got %q", i, typ, got)
+		}
+	}
+}
+
+func TestRewriteSQLiteDDLPreservesLiteralsAndOffsets(t *testing.T) {
+	sql := "CREATE TABLE t (note TEXT DEFAULT 'autoincrement [x]') -- strict\n;"
+
+	rewritten := rewriteSQLiteDDL(sql).sql
+	if len(rewritten) != len(sql) {
+		t.Fatalf("Expected rewrite to preserve length %d, got %d", len(sql), len(rewritten))
+	}
+	if rewritten != sql {
+		t.Errorf("Expected literals and comments to be untouched, got %q", rewritten)
+	}
+
+	if got := rewriteSQLiteDDL("id INTEGER AUTOINCREMENT").sql; got != "id INTEGER              " {
+		t.Errorf("Expected AUTOINCREMENT to be blanked, got %q", got)
+	}
+}
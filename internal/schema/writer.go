@@ -0,0 +1,582 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lockplane/lockplane/internal/database"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+)
+
+// WriteSchema writes the schema as PostgreSQL DDL in lockplane's canonical
+// style: schemas, then extensions, then sequences, then enums, then domains,
+// then each table followed by its indexes, row level security, policies and
+// comments, then views, each materialized view followed by its indexes.
+// Tables list one column per line, followed by the primary key, named only if
+// its name isn't the default, and the unique, check, foreign key and
+// exclusion constraints, each kind sorted by name.
+//
+// Objects the model can't express as DDL, such as partitions and objects
+// recorded only in OtherObjects, are an error.
+func WriteSchema(w io.Writer, schema *database.Schema) error {
+	if len(schema.OtherObjects) > 0 {
+		object := schema.OtherObjects[0]
+		return fmt.Errorf("can't write %s %q", object.Kind, object.Name)
+	}
+
+	var statements []string
+	for _, name := range schema.Schemas {
+		statements = append(statements, fmt.Sprintf("CREATE SCHEMA %s;", quoteIdent(name)))
+	}
+
+	for _, extension := range schema.Extensions {
+		statement := "CREATE EXTENSION "
+		if extension.IfNotExists {
+			statement += "IF NOT EXISTS "
+		}
+		statement += quoteIdent(extension.Name)
+		if extension.Schema != "" {
+			statement += " WITH SCHEMA " + quoteIdent(extension.Schema)
+		}
+		statements = append(statements, statement+";")
+	}
+
+	for _, sequence := range schema.Sequences {
+		statement := "CREATE SEQUENCE " + qualifiedIdent(sequence.Schema, sequence.Name)
+		if sequence.Start != nil {
+			statement += fmt.Sprintf(" START %d", *sequence.Start)
+		}
+		if sequence.Increment != nil {
+			statement += fmt.Sprintf(" INCREMENT %d", *sequence.Increment)
+		}
+		statements = append(statements, statement+";")
+	}
+
+	for _, enum := range schema.Enums {
+		values := make([]string, len(enum.Values))
+		for i, value := range enum.Values {
+			values[i] = quoteLiteral(value)
+		}
+		statements = append(statements, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);", qualifiedIdent(enum.Schema, enum.Name), strings.Join(values, ", ")))
+	}
+
+	for _, domain := range schema.Domains {
+		statement := fmt.Sprintf("CREATE DOMAIN %s AS %s", qualifiedIdent(domain.Schema, domain.Name), domain.BaseType)
+		if domain.Default != nil {
+			statement += " DEFAULT " + *domain.Default
+		}
+		if domain.NotNull {
+			statement += " NOT NULL"
+		}
+		checks := slices.Clone(domain.CheckConstraints)
+		sort.SliceStable(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+		for _, check := range checks {
+			statement += fmt.Sprintf(" CONSTRAINT %s CHECK (%s)", quoteIdent(check.Name), check.Expression)
+		}
+		statements = append(statements, statement+";")
+	}
+
+	for i := range schema.Tables {
+		tableStatements, err := tableDDL(schema, &schema.Tables[i])
+		if err != nil {
+			return fmt.Errorf("can't write table %q: %w", qualifiedTableName(&schema.Tables[i]), err)
+		}
+		statements = append(statements, tableStatements...)
+	}
+
+	for i := range schema.Views {
+		viewStatements, err := viewDDL(&schema.Views[i])
+		if err != nil {
+			return fmt.Errorf("can't write view %q: %w", schema.Views[i].Name, err)
+		}
+		statements = append(statements, viewStatements...)
+	}
+
+	if len(statements) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w, strings.Join(statements, "\n\n")+"\n")
+	return err
+}
+
+// tableDDL returns the statements that create a table: CREATE TABLE, then
+// CREATE INDEX, ENABLE ROW LEVEL SECURITY, CREATE POLICY and COMMENT ON. The
+// table's LIKE clauses are written where their columns were copied, in place
+// of what they copy.
+func tableDDL(schema *database.Schema, table *database.Table) ([]string, error) {
+	switch {
+	case table.PartitionOf != nil || len(table.Partitions) > 0:
+		return nil, fmt.Errorf("partitioned tables aren't supported")
+	case table.PrimaryKeyIndex != "":
+		return nil, fmt.Errorf("primary keys added USING INDEX aren't supported")
+	}
+
+	name := qualifiedIdent(table.Schema, table.Name)
+	table, likes, err := splitLikeClauses(schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for i, col := range table.Columns {
+		lines = append(lines, likes[i]...)
+		lines = append(lines, columnDDL(table, col))
+	}
+	lines = append(lines, likes[len(table.Columns)]...)
+
+	if len(table.PrimaryKey) > 0 {
+		primaryKey := fmt.Sprintf("PRIMARY KEY (%s)", identList(table.PrimaryKey))
+		if table.PrimaryKeyName != "" && table.PrimaryKeyName != table.Name+"_pkey" {
+			primaryKey = fmt.Sprintf("CONSTRAINT %s %s", quoteIdent(table.PrimaryKeyName), primaryKey)
+		}
+		lines = append(lines, primaryKey)
+	}
+
+	uniques := slices.Clone(table.UniqueConstraints)
+	sort.SliceStable(uniques, func(i, j int) bool { return uniques[i].Name < uniques[j].Name })
+	for _, unique := range uniques {
+		if unique.Index != "" {
+			return nil, fmt.Errorf("unique constraints added USING INDEX aren't supported")
+		}
+		lines = append(lines, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", quoteIdent(unique.Name), identList(unique.Columns)))
+	}
+
+	checks := slices.Clone(table.CheckConstraints)
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+	for _, check := range checks {
+		lines = append(lines, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteIdent(check.Name), check.Expression))
+	}
+
+	fks := slices.Clone(table.ForeignKeys)
+	sort.SliceStable(fks, func(i, j int) bool { return fks[i].Name < fks[j].Name })
+	for _, fk := range fks {
+		lines = append(lines, foreignKeyDDL(fk))
+	}
+
+	exclusions := slices.Clone(table.ExclusionConstraints)
+	sort.SliceStable(exclusions, func(i, j int) bool { return exclusions[i].Name < exclusions[j].Name })
+	for _, exclusion := range exclusions {
+		lines = append(lines, exclusionDDL(exclusion))
+	}
+
+	create := "CREATE TABLE"
+	switch table.Persistence {
+	case database.TablePersistenceUnlogged:
+		create = "CREATE UNLOGGED TABLE"
+	case database.TablePersistenceTemporary:
+		create = "CREATE TEMPORARY TABLE"
+	}
+	statement := fmt.Sprintf("%s %s (\n  %s\n)", create, name, strings.Join(lines, ",\n  "))
+	if len(lines) == 0 {
+		statement = fmt.Sprintf("%s %s ()", create, name)
+	}
+	if len(table.Inherits) > 0 {
+		var parents []string
+		for _, parent := range table.Inherits {
+			parents = append(parents, qualifiedIdent(parent.Schema, parent.Table))
+		}
+		statement += fmt.Sprintf(" INHERITS (%s)", strings.Join(parents, ", "))
+	}
+	if options := tableOptionsDDL(table); options != "" {
+		statement += " WITH (" + options + ")"
+	}
+	statements := []string{statement + ";"}
+
+	for _, index := range table.Indexes {
+		ddl, err := indexDDL(name, index)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, ddl)
+	}
+
+	if table.RLSEnabled {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", name))
+	}
+	for _, policy := range table.Policies {
+		statements = append(statements, policyDDL(name, policy))
+	}
+
+	if table.Comment != "" {
+		statements = append(statements, fmt.Sprintf("COMMENT ON TABLE %s IS %s;", name, quoteLiteral(table.Comment)))
+	}
+	for _, col := range table.Columns {
+		if col.Comment != "" {
+			statements = append(statements, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", name, quoteIdent(col.Name), quoteLiteral(col.Comment)))
+		}
+	}
+	return statements, nil
+}
+
+// splitLikeClauses returns a copy of the table, without source locations,
+// from which what its LIKE clauses copied has been removed, along with the
+// clauses as DDL keyed by the index of the remaining column they come before.
+// What a clause copies is worked out again from its source table, so it's an
+// error if the source or the copy changed after the table was created.
+func splitLikeClauses(schema *database.Schema, table *database.Table) (*database.Table, map[int][]string, error) {
+	own := withoutLocations(table)
+	likes := map[int][]string{}
+	for _, clause := range table.LikeClauses {
+		sourceIndex := findTableIndex(schema, clause.Schema, clause.Table)
+		if sourceIndex == -1 {
+			return nil, nil, fmt.Errorf("LIKE source table %q isn't in the schema", clause.Table)
+		}
+		copied := likeCopy(&schema.Tables[sourceIndex], table.Name, likeOptions(clause.Including))
+		changed := fmt.Errorf("what LIKE %s copied has since changed", clause.Table)
+
+		start := len(own.Columns)
+		if len(copied.Columns) > 0 {
+			start = slices.IndexFunc(own.Columns, func(col database.Column) bool { return col.Name == copied.Columns[0].Name })
+			if start == -1 || start+len(copied.Columns) > len(own.Columns) ||
+				!reflect.DeepEqual(own.Columns[start:start+len(copied.Columns)], copied.Columns) {
+				return nil, nil, changed
+			}
+			own.Columns = slices.Delete(own.Columns, start, start+len(copied.Columns))
+		}
+
+		for _, check := range copied.CheckConstraints {
+			i := slices.IndexFunc(own.CheckConstraints, func(c database.CheckConstraint) bool { return reflect.DeepEqual(c, check) })
+			if i == -1 {
+				return nil, nil, changed
+			}
+			own.CheckConstraints = slices.Delete(own.CheckConstraints, i, i+1)
+		}
+		if len(copied.PrimaryKey) > 0 {
+			if !slices.Equal(own.PrimaryKey, copied.PrimaryKey) || own.PrimaryKeyName != copied.PrimaryKeyName {
+				return nil, nil, changed
+			}
+			own.PrimaryKey = nil
+			own.PrimaryKeyName = ""
+		}
+		for _, index := range copied.Indexes {
+			i := slices.IndexFunc(own.Indexes, func(idx database.Index) bool { return reflect.DeepEqual(idx, index) })
+			if i == -1 {
+				return nil, nil, changed
+			}
+			own.Indexes = slices.Delete(own.Indexes, i, i+1)
+		}
+		for _, unique := range copied.UniqueConstraints {
+			i := slices.IndexFunc(own.UniqueConstraints, func(u database.UniqueConstraint) bool { return reflect.DeepEqual(u, unique) })
+			if i == -1 {
+				return nil, nil, changed
+			}
+			own.UniqueConstraints = slices.Delete(own.UniqueConstraints, i, i+1)
+		}
+
+		like := "LIKE " + qualifiedIdent(clause.Schema, clause.Table)
+		for _, option := range clause.Including {
+			like += " INCLUDING " + option
+		}
+		likes[start] = append(likes[start], like)
+	}
+	return own, likes, nil
+}
+
+// withoutLocations returns a copy of the table with the source locations of
+// its columns, constraints and indexes cleared, so they can be compared
+func withoutLocations(table *database.Table) *database.Table {
+	own := *table
+	own.SourceLocation = nil
+	own.Columns = slices.Clone(table.Columns)
+	for i := range own.Columns {
+		own.Columns[i].SourceLocation = nil
+	}
+	own.CheckConstraints = slices.Clone(table.CheckConstraints)
+	for i := range own.CheckConstraints {
+		own.CheckConstraints[i].SourceLocation = nil
+	}
+	own.Indexes = slices.Clone(table.Indexes)
+	for i := range own.Indexes {
+		own.Indexes[i].SourceLocation = nil
+	}
+	own.UniqueConstraints = slices.Clone(table.UniqueConstraints)
+	for i := range own.UniqueConstraints {
+		own.UniqueConstraints[i].SourceLocation = nil
+	}
+	return &own
+}
+
+// viewDDL returns the statements that create a view: CREATE VIEW, or CREATE
+// MATERIALIZED VIEW followed by CREATE INDEX
+func viewDDL(view *database.View) ([]string, error) {
+	name := qualifiedIdent(view.Schema, view.Name)
+	if !view.Materialized {
+		return []string{fmt.Sprintf("CREATE VIEW %s AS %s;", name, view.Query)}, nil
+	}
+
+	statement := fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS %s", name, view.Query)
+	if !view.WithData {
+		statement += " WITH NO DATA"
+	}
+	statements := []string{statement + ";"}
+	for _, index := range view.Indexes {
+		ddl, err := indexDDL(name, index)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, ddl)
+	}
+	return statements, nil
+}
+
+// columnDDL returns a column definition. The primary key is written as a
+// table constraint, which makes its columns NOT NULL, so neither is repeated
+// here.
+func columnDDL(table *database.Table, col database.Column) string {
+	ddl := quoteIdent(col.Name) + " " + database.NormalizePostgreSQLType(col.Type)
+	if !col.Nullable && !col.IsPrimaryKey {
+		ddl += " NOT NULL"
+	}
+
+	switch {
+	case col.Identity != nil:
+		ddl += " " + identityDDL(table, col)
+	case col.Generated != nil:
+		ddl += fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", col.Generated.Expression, col.Generated.Storage)
+	case col.Default != nil:
+		ddl += " DEFAULT " + *col.Default
+	}
+	return ddl
+}
+
+// identityDDL returns the GENERATED ... AS IDENTITY clause of a column, with
+// its sequence options. The sequence name is only given when it isn't the
+// one PostgreSQL would choose.
+func identityDDL(table *database.Table, col database.Column) string {
+	identity := col.Identity
+	clause := "GENERATED BY DEFAULT AS IDENTITY"
+	if identity.Always {
+		clause = "GENERATED ALWAYS AS IDENTITY"
+	}
+
+	var options []string
+	if identity.Sequence != "" && identity.Sequence != identitySequenceName(table.Name, col.Name) {
+		options = append(options, "SEQUENCE NAME "+quoteIdent(identity.Sequence))
+	}
+	for _, option := range []struct {
+		name  string
+		value *int64
+	}{
+		{"START WITH", identity.Start},
+		{"INCREMENT BY", identity.Increment},
+		{"MINVALUE", identity.MinValue},
+		{"MAXVALUE", identity.MaxValue},
+		{"CACHE", identity.Cache},
+	} {
+		if option.value != nil {
+			options = append(options, fmt.Sprintf("%s %d", option.name, *option.value))
+		}
+	}
+	if identity.Cycle {
+		options = append(options, "CYCLE")
+	}
+	if len(options) > 0 {
+		clause += " (" + strings.Join(options, " ") + ")"
+	}
+	return clause
+}
+
+// foreignKeyDDL returns a foreign key table constraint
+func foreignKeyDDL(fk database.ForeignKey) string {
+	ddl := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s", quoteIdent(fk.Name), identList(fk.Columns), qualifiedIdent(fk.ReferencedSchema, fk.ReferencedTable))
+	if len(fk.ReferencedColumns) > 0 {
+		ddl += fmt.Sprintf(" (%s)", identList(fk.ReferencedColumns))
+	}
+	if fk.Match != "" {
+		ddl += " MATCH " + string(fk.Match)
+	}
+	if fk.OnDelete != "" {
+		ddl += " ON DELETE " + string(fk.OnDelete)
+		if len(fk.OnDeleteColumns) > 0 {
+			ddl += fmt.Sprintf(" (%s)", identList(fk.OnDeleteColumns))
+		}
+	}
+	if fk.OnUpdate != "" {
+		ddl += " ON UPDATE " + string(fk.OnUpdate)
+	}
+	if fk.InitiallyDeferred {
+		ddl += " DEFERRABLE INITIALLY DEFERRED"
+	} else if fk.Deferrable {
+		ddl += " DEFERRABLE"
+	}
+	return ddl
+}
+
+// exclusionDDL returns an EXCLUDE table constraint
+func exclusionDDL(exclusion database.ExclusionConstraint) string {
+	elements := make([]string, len(exclusion.Elements))
+	for i, element := range exclusion.Elements {
+		elem := quoteIdent(element.Column)
+		if element.Column == "" {
+			elem = "(" + element.Expression + ")"
+		}
+		if element.OpClass != "" {
+			elem += " " + element.OpClass
+		}
+		operator := element.Operator
+		if strings.Contains(operator, ".") {
+			operator = "OPERATOR(" + operator + ")"
+		}
+		elements[i] = elem + " WITH " + operator
+	}
+
+	ddl := fmt.Sprintf("CONSTRAINT %s EXCLUDE", quoteIdent(exclusion.Name))
+	if exclusion.Method != "" && exclusion.Method != "btree" {
+		ddl += " USING " + exclusion.Method
+	}
+	ddl += fmt.Sprintf(" (%s)", strings.Join(elements, ", "))
+	if exclusion.Where != "" {
+		ddl += fmt.Sprintf(" WHERE (%s)", exclusion.Where)
+	}
+	return ddl
+}
+
+// indexDDL returns the CREATE INDEX statement for an index on table. The
+// model keeps an index's columns and expressions apart, so an index on both
+// can't be written in its original order.
+func indexDDL(table string, index database.Index) (string, error) {
+	var elements []string
+	switch {
+	case len(index.Columns) > 0 && len(index.Expressions) > 0:
+		return "", fmt.Errorf("index %q mixes columns and expressions", index.Name)
+	case len(index.Columns) > 0:
+		for _, column := range index.Columns {
+			elements = append(elements, quoteIdent(column))
+		}
+	default:
+		for _, expr := range index.Expressions {
+			elements = append(elements, "("+expr+")")
+		}
+	}
+
+	ddl := "CREATE INDEX"
+	if index.Unique {
+		ddl = "CREATE UNIQUE INDEX"
+	}
+	ddl += fmt.Sprintf(" %s ON %s", quoteIdent(index.Name), table)
+	if index.Method != "" && index.Method != "btree" {
+		ddl += " USING " + index.Method
+	}
+	ddl += fmt.Sprintf(" (%s)", strings.Join(elements, ", "))
+	if len(index.Include) > 0 {
+		include := make([]string, len(index.Include))
+		for i, column := range index.Include {
+			include[i] = quoteIdent(column)
+		}
+		ddl += fmt.Sprintf(" INCLUDE (%s)", strings.Join(include, ", "))
+	}
+	if index.Where != "" {
+		ddl += fmt.Sprintf(" WHERE (%s)", index.Where)
+	}
+	return ddl + ";", nil
+}
+
+// policyDDL returns the CREATE POLICY statement for a policy on table,
+// leaving out the defaults: PERMISSIVE, FOR ALL and TO PUBLIC
+func policyDDL(table string, policy database.Policy) string {
+	ddl := fmt.Sprintf("CREATE POLICY %s ON %s", quoteIdent(policy.Name), table)
+	if !policy.Permissive {
+		ddl += " AS RESTRICTIVE"
+	}
+	if policy.Command != "" && policy.Command != "ALL" {
+		ddl += " FOR " + policy.Command
+	}
+	if len(policy.Roles) > 0 && !slices.Equal(policy.Roles, []string{"public"}) {
+		roles := make([]string, len(policy.Roles))
+		for i, role := range policy.Roles {
+			switch role {
+			case "public":
+				roles[i] = "PUBLIC"
+			case "CURRENT_ROLE", "CURRENT_USER", "SESSION_USER":
+				roles[i] = role
+			default:
+				roles[i] = quoteIdent(role)
+			}
+		}
+		ddl += " TO " + strings.Join(roles, ", ")
+	}
+	if policy.Using != "" {
+		ddl += fmt.Sprintf(" USING (%s)", policy.Using)
+	}
+	if policy.WithCheck != "" {
+		ddl += fmt.Sprintf(" WITH CHECK (%s)", policy.WithCheck)
+	}
+	return ddl + ";"
+}
+
+// tableOptionsDDL returns a table's storage parameters, sorted by name, as
+// the contents of a WITH (...) clause
+func tableOptionsDDL(table *database.Table) string {
+	var options []string
+	if table.WithOids {
+		options = append(options, "oids")
+	}
+	names := make([]string, 0, len(table.Options))
+	for name := range table.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := table.Options[name]
+		if !bareOptionValue.MatchString(value) {
+			value = quoteLiteral(value)
+		}
+		options = append(options, name+" = "+value)
+	}
+	return strings.Join(options, ", ")
+}
+
+// bareOptionValue matches storage parameter values that can be written
+// without quotes
+var bareOptionValue = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+// plainIdent matches identifiers that don't need quoting, unless they are
+// reserved words
+var plainIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// quoteIdent quotes an identifier if it isn't lowercase or is a reserved word
+func quoteIdent(name string) string {
+	if plainIdent.MatchString(name) && !reservedWord(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// reservedWord reports whether name is a keyword that can't be used as a
+// table or column name without quoting
+func reservedWord(name string) bool {
+	scan, err := pg_query.Scan(name)
+	if err != nil || len(scan.Tokens) != 1 {
+		return true
+	}
+	kind := scan.Tokens[0].KeywordKind
+	return kind == pg_query.KeywordKind_RESERVED_KEYWORD || kind == pg_query.KeywordKind_TYPE_FUNC_NAME_KEYWORD
+}
+
+// qualifiedIdent returns a possibly schema-qualified name, leaving out the
+// public schema
+func qualifiedIdent(schemaName string, name string) string {
+	if schemaName == "" || schemaName == "public" {
+		return quoteIdent(name)
+	}
+	return quoteIdent(schemaName) + "." + quoteIdent(name)
+}
+
+// identList returns a comma-separated list of identifiers
+func identList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// quoteLiteral returns s as a SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
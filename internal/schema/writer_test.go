@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lockplane/lockplane/internal/database"
+)
+
+func TestWriteSchema(t *testing.T) {
+	sql := `CREATE SCHEMA auth;
+CREATE TYPE mood AS ENUM ('happy', 'it''s complicated');
+CREATE DOMAIN email AS TEXT NOT NULL CHECK (VALUE LIKE '%@%');
+create table Users (
+    id BIGINT GENERATED ALWAYS AS IDENTITY (START WITH 100) PRIMARY KEY,
+    "Email" email UNIQUE,
+    name VARCHAR(100) DEFAULT 'anonymous',
+    "user" INT4,
+    score NUMERIC(10,2) DEFAULT -1.5 CHECK (score >= 0),
+    mood mood,
+    CONSTRAINT a_name_check CHECK (name <> '')
+);
+CREATE TABLE auth.posts (
+    id BIGSERIAL CONSTRAINT posts_pk PRIMARY KEY,
+    author_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    editor_id BIGINT REFERENCES users,
+    tags TEXT[],
+    body TEXT,
+    during TSTZRANGE,
+    EXCLUDE USING gist (author_id gist_int8_ops WITH =, during WITH &&) WHERE (editor_id IS NULL)
+) WITH (fillfactor = 70, autovacuum_enabled = false);
+CREATE INDEX ON auth.posts (author_id);
+CREATE INDEX posts_tags ON auth.posts USING gin (tags);
+CREATE INDEX posts_unedited ON auth.posts (author_id) INCLUDE (body) WHERE editor_id IS NULL;
+CREATE UNIQUE INDEX posts_lower_body ON auth.posts (lower(body));
+ALTER TABLE auth.posts ENABLE ROW LEVEL SECURITY;
+CREATE POLICY posts_read ON auth.posts FOR SELECT USING (true);
+CREATE POLICY "Authors edit" ON auth.posts AS RESTRICTIVE TO authenticated, admin USING (author_id = auth.uid()) WITH CHECK (author_id = auth.uid());
+COMMENT ON TABLE auth.posts IS 'Blog posts';
+COMMENT ON COLUMN auth.posts.body IS 'Markdown';
+CREATE VIEW recent_posts AS SELECT id, body FROM auth.posts WHERE id > 100;
+CREATE MATERIALIZED VIEW post_counts AS SELECT author_id, count(*) AS posts FROM auth.posts GROUP BY author_id WITH NO DATA;
+CREATE UNIQUE INDEX ON post_counts (author_id);
+`
+	schema, err := ParseSQLSchemaWithDialect(sql, database.DialectPostgres)
+	if err != nil {
+		t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+	}
+
+	var out strings.Builder
+	if err := WriteSchema(&out, schema); err != nil {
+		t.Fatalf("WriteSchema failed: %v", err)
+	}
+
+	expected := `CREATE SCHEMA auth;
+
+CREATE TYPE mood AS ENUM ('happy', 'it''s complicated');
+
+CREATE DOMAIN email AS text NOT NULL CONSTRAINT email_check CHECK (value LIKE '%@%');
+
+CREATE TABLE users (
+  id bigint GENERATED ALWAYS AS IDENTITY (START WITH 100),
+  "Email" email,
+  name varchar(100) DEFAULT 'anonymous',
+  "user" integer,
+  score numeric(10,2) DEFAULT -1.5,
+  mood mood,
+  PRIMARY KEY (id),
+  CONSTRAINT "users_Email_key" UNIQUE ("Email"),
+  CONSTRAINT a_name_check CHECK (name <> ''),
+  CONSTRAINT users_score_check CHECK (score >= 0)
+);
+
+CREATE TABLE auth.posts (
+  id bigserial,
+  author_id bigint NOT NULL,
+  editor_id bigint,
+  tags text[],
+  body text,
+  during tstzrange,
+  CONSTRAINT posts_pk PRIMARY KEY (id),
+  CONSTRAINT posts_author_id_fkey FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT posts_editor_id_fkey FOREIGN KEY (editor_id) REFERENCES users,
+  CONSTRAINT posts_author_id_during_excl EXCLUDE USING gist (author_id gist_int8_ops WITH =, during WITH &&) WHERE (editor_id IS NULL)
+) WITH (autovacuum_enabled = false, fillfactor = 70);
+
+CREATE INDEX posts_author_id_idx ON auth.posts (author_id);
+
+CREATE INDEX posts_tags ON auth.posts USING gin (tags);
+
+CREATE INDEX posts_unedited ON auth.posts (author_id) INCLUDE (body) WHERE (editor_id IS NULL);
+
+CREATE UNIQUE INDEX posts_lower_body ON auth.posts ((lower(body)));
+
+ALTER TABLE auth.posts ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY posts_read ON auth.posts FOR SELECT USING (true);
+
+CREATE POLICY "Authors edit" ON auth.posts AS RESTRICTIVE TO authenticated, admin USING (author_id = auth.uid()) WITH CHECK (author_id = auth.uid());
+
+COMMENT ON TABLE auth.posts IS 'Blog posts';
+
+COMMENT ON COLUMN auth.posts.body IS 'Markdown';
+
+CREATE VIEW recent_posts AS SELECT id, body FROM auth.posts WHERE id > 100;
+
+CREATE MATERIALIZED VIEW post_counts AS SELECT author_id, count(*) AS posts FROM auth.posts GROUP BY author_id WITH NO DATA;
+
+CREATE UNIQUE INDEX post_counts_author_id_idx ON post_counts (author_id);
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestWriteSchemaUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{
+			name: "partitions",
+			sql: `CREATE TABLE events (id BIGINT, at DATE) PARTITION BY RANGE (at);
+CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');`,
+			expected: `can't write table "public.events": partitioned tables aren't supported`,
+		},
+		{
+			name:     "tracked objects",
+			sql:      `CREATE AGGREGATE total (integer) (SFUNC = int4pl, STYPE = integer);`,
+			expected: `can't write aggregate "total"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSQLSchemaWithDialect(tt.sql, database.DialectPostgres)
+			if err != nil {
+				t.Fatalf("ParseSQLSchemaWithDialect failed: %v", err)
+			}
+			var out strings.Builder
+			if err := WriteSchema(&out, schema); err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := map[string]string{
+		"users":      "users",
+		"user_id":    "user_id",
+		"Users":      `"Users"`,
+		"user":       `"user"`,
+		"order":      `"order"`,
+		"name":       "name",
+		"with space": `"with space"`,
+		`say"hi`:     `"say""hi"`,
+	}
+	for name, expected := range tests {
+		if got := quoteIdent(name); got != expected {
+			t.Errorf("quoteIdent(%q) = %s, expected %s", name, got, expected)
+		}
+	}
+}